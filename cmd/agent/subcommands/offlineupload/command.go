@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package offlineupload implements 'agent offline-upload'.
+package offlineupload
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/core/log"
+	pkgconfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+const defaultTimeout = 20 * time.Second
+
+// cliParams are the command-line arguments for this subcommand
+type cliParams struct {
+	*command.GlobalParams
+
+	// subcommand-specific flags
+
+	storagePath string
+	timeout     time.Duration
+}
+
+// Commands returns a slice of subcommands for the 'agent' command.
+func Commands(globalParams *command.GlobalParams) []*cobra.Command {
+	cliParams := &cliParams{
+		GlobalParams: globalParams,
+	}
+
+	offlineUploadCmd := &cobra.Command{
+		Use:   "offline-upload",
+		Short: "Upload the payloads written in offline mode",
+		Long: `Sends to the intake the payloads written by an Agent with 'forwarder_offline_mode.enabled',
+the files being removed once uploaded. The api_key and site/dd_url settings must be the ones
+of the Agent which wrote the files.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fxutil.OneShot(offlineUpload,
+				fx.Supply(cliParams),
+				fx.Supply(core.BundleParams{
+					ConfigParams: config.NewAgentParamsWithSecrets(globalParams.ConfFilePath),
+					LogParams:    log.LogForOneShot("CORE", "info", true)}),
+				core.Bundle,
+			)
+		},
+	}
+	offlineUploadCmd.Flags().StringVarP(&cliParams.storagePath, "path", "p", "", "Folder of the files to upload (defaults to 'forwarder_offline_mode.storage_path').")
+	offlineUploadCmd.Flags().DurationVarP(&cliParams.timeout, "timeout", "t", defaultTimeout, "Timeout of each request to the intake.")
+
+	return []*cobra.Command{offlineUploadCmd}
+}
+
+func offlineUpload(log log.Component, config config.Component, cliParams *cliParams) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Stop between two requests on an interruption, the remaining transactions being kept.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	go func() {
+		select {
+		case <-sigs:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	storagePath := cliParams.storagePath
+	if storagePath == "" {
+		storagePath = forwarder.OfflineStoragePath()
+	}
+
+	keysPerDomain, err := pkgconfig.GetMultipleEndpoints()
+	if err != nil {
+		return fmt.Errorf("Misconfiguration of agent endpoints: %v", err)
+	}
+
+	sentCount, err := forwarder.UploadOfflineTransactions(ctx, forwarder.NewOptions(keysPerDomain), storagePath, cliParams.timeout)
+	fmt.Printf("%d transaction(s) uploaded from %s\n", sentCount, storagePath)
+	if err != nil {
+		return fmt.Errorf("the upload stopped, run the command again to resume it: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package offlineupload
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"offline-upload", "--path", "/tmp/offline", "--timeout", "5s"},
+		offlineUpload,
+		func(cliParams *cliParams, coreParams core.BundleParams) {
+			require.Equal(t, "/tmp/offline", cliParams.storagePath)
+			require.Equal(t, 5*time.Second, cliParams.timeout)
+			require.Equal(t, true, coreParams.ConfigLoadSecrets())
+		})
+}
@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+
+	dogstatsdServer "github.com/DataDog/datadog-agent/comp/dogstatsd/server"
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// DsdTagFiltersRuntimeSetting wraps operations to change the rules filtering the tags of the dogstatsd metrics at runtime.
+type DsdTagFiltersRuntimeSetting struct {
+	Server dogstatsdServer.Component
+}
+
+func NewDsdTagFiltersRuntimeSetting(server dogstatsdServer.Component) *DsdTagFiltersRuntimeSetting {
+	return &DsdTagFiltersRuntimeSetting{Server: server}
+}
+
+// Description returns the runtime setting's description
+func (s DsdTagFiltersRuntimeSetting) Description() string {
+	return `Set the rules filtering the tags of the dogstatsd metrics, as a JSON list, e.g. [{"metric_name":"kafka.*","action":"exclude","tags":["pod_name"]}]`
+}
+
+// Hidden returns whether or not this setting is hidden from the list of runtime settings
+func (s DsdTagFiltersRuntimeSetting) Hidden() bool {
+	return false
+}
+
+// Name returns the name of the runtime setting
+func (s DsdTagFiltersRuntimeSetting) Name() string {
+	return "dogstatsd_tag_filters"
+}
+
+// Get returns the current value of the runtime setting
+func (s DsdTagFiltersRuntimeSetting) Get() (interface{}, error) {
+	return s.Server.GetTagFilterRules(), nil
+}
+
+// Set changes the value of the runtime setting
+func (s DsdTagFiltersRuntimeSetting) Set(v interface{}) error {
+	var rules []config.TagFilterRule
+
+	switch v := v.(type) {
+	case string:
+		// to be cautious, take care of both calls with a string (cli) or rules (programmaticaly)
+		if err := json.Unmarshal([]byte(v), &rules); err != nil {
+			return fmt.Errorf("DsdTagFiltersRuntimeSetting: can't parse the rules: %v", err)
+		}
+	case []config.TagFilterRule:
+		rules = v
+	default:
+		return fmt.Errorf("DsdTagFiltersRuntimeSetting: bad parameter value provided: %v", v)
+	}
+
+	if err := s.Server.SetTagFilterRules(rules); err != nil {
+		return fmt.Errorf("DsdTagFiltersRuntimeSetting: %v", err)
+	}
+
+	config.Datadog.Set("dogstatsd_tag_filters", rules)
+	return nil
+}
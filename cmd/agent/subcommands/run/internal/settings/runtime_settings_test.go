@@ -18,6 +18,7 @@ import (
 	"go.uber.org/fx"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 )
 
@@ -98,3 +99,58 @@ func TestDogstatsdMetricsStats(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal(v, true)
 }
+
+func TestDogstatsdTagFilters(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := aggregator.DefaultAgentDemultiplexerOptions(nil)
+	opts.DontStartForwarders = true
+	demux := aggregator.InitAndStartAgentDemultiplexer(opts, "hostname")
+	defer demux.Stop(false)
+
+	deps := fxutil.Test[testDeps](t, fx.Options(
+		core.MockBundle,
+		fx.Supply(core.BundleParams{}),
+		fx.Supply(server.Params{
+			Serverless: false,
+		}),
+		dogstatsd.Bundle,
+	))
+
+	s := NewDsdTagFiltersRuntimeSetting(deps.Server)
+
+	// the rules can't be set before the server is started
+
+	assert.NotNil(s.Set(`[{"metric_name":"kafka.*","action":"exclude","tags":["pod_name"]}]`))
+
+	deps.Server.Start(demux)
+	defer deps.Server.Stop()
+	expected := []config.TagFilterRule{
+		{MetricName: "kafka.*", Action: "exclude", Tags: []string{"pod_name"}},
+	}
+
+	// JSON string
+
+	err := s.Set(`[{"metric_name":"kafka.*","action":"exclude","tags":["pod_name"]}]`)
+	assert.Nil(err)
+	assert.Equal(expected, deps.Server.GetTagFilterRules())
+	v, err := s.Get()
+	assert.Nil(err)
+	assert.Equal(expected, v)
+
+	// rules
+
+	expected = []config.TagFilterRule{
+		{MetricName: "http.request.*", Action: "include", Tags: []string{"service", "env", "route"}},
+	}
+	err = s.Set(expected)
+	assert.Nil(err)
+	assert.Equal(expected, deps.Server.GetTagFilterRules())
+
+	// invalid rules leave the current ones untouched
+
+	assert.NotNil(s.Set(`[{"metric_name":"kafka.*","action":"drop"}]`))
+	assert.NotNil(s.Set(`not json`))
+	assert.NotNil(s.Set(42))
+	assert.Equal(expected, deps.Server.GetTagFilterRules())
+}
@@ -7,12 +7,13 @@ package run
 
 import (
 	"github.com/DataDog/datadog-agent/cmd/agent/subcommands/run/internal/settings"
+	dogstatsdServer "github.com/DataDog/datadog-agent/comp/dogstatsd/server"
 	dogstatsdDebug "github.com/DataDog/datadog-agent/comp/dogstatsd/serverDebug"
 	commonsettings "github.com/DataDog/datadog-agent/pkg/config/settings"
 )
 
 // initRuntimeSettings builds the map of runtime settings configurable at runtime.
-func initRuntimeSettings(serverDebug dogstatsdDebug.Component) error {
+func initRuntimeSettings(server dogstatsdServer.Component, serverDebug dogstatsdDebug.Component) error {
 	// Runtime-editable settings must be registered here to dynamically populate command-line information
 	if err := commonsettings.RegisterRuntimeSetting(commonsettings.LogLevelRuntimeSetting{}); err != nil {
 		return err
@@ -26,6 +27,9 @@ func initRuntimeSettings(serverDebug dogstatsdDebug.Component) error {
 	if err := commonsettings.RegisterRuntimeSetting(settings.NewDsdStatsRuntimeSetting(serverDebug)); err != nil {
 		return err
 	}
+	if err := commonsettings.RegisterRuntimeSetting(settings.NewDsdTagFiltersRuntimeSetting(server)); err != nil {
+		return err
+	}
 	if err := commonsettings.RegisterRuntimeSetting(settings.DsdCaptureDurationRuntimeSetting("dogstatsd_capture_duration")); err != nil {
 		return err
 	}
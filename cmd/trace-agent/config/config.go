@@ -15,6 +15,7 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -217,6 +218,50 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 	if coreconfig.Datadog.IsSet("apm_config.rare_sampler.cardinality") {
 		c.RareSamplerCardinality = coreconfig.Datadog.GetInt("apm_config.rare_sampler.cardinality")
 	}
+	if coreconfig.Datadog.IsSet("apm_config.stats_extra_dimensions") {
+		c.StatsExtraDimensions = coreconfig.Datadog.GetStringSlice("apm_config.stats_extra_dimensions")
+	}
+	if coreconfig.Datadog.IsSet("apm_config.stats_extra_dimensions_max_cardinality") {
+		c.StatsExtraDimensionsMaxCardinality = coreconfig.Datadog.GetInt("apm_config.stats_extra_dimensions_max_cardinality")
+	}
+	if coreconfig.Datadog.IsSet("apm_config.tail_sampling.enabled") {
+		c.TailSampling.Enabled = coreconfig.Datadog.GetBool("apm_config.tail_sampling.enabled")
+	}
+	if coreconfig.Datadog.IsSet("apm_config.tail_sampling.decision_wait") {
+		c.TailSampling.DecisionWait = coreconfig.Datadog.GetDuration("apm_config.tail_sampling.decision_wait")
+	}
+	if coreconfig.Datadog.IsSet("apm_config.tail_sampling.max_spans") {
+		c.TailSampling.MaxSpans = coreconfig.Datadog.GetInt("apm_config.tail_sampling.max_spans")
+	}
+	if k := "apm_config.tail_sampling.policies"; coreconfig.Datadog.IsSet(k) {
+		policies, err := parseTailSamplingPolicies(k)
+		if err != nil {
+			log.Errorf("Bad format for %q it should be a list of policies with a name, and an optional service, min_duration, error and tags, error: %v", k, err)
+		} else {
+			c.TailSampling.Policies = policies
+		}
+	}
+	if k := "apm_config.span_metrics"; coreconfig.Datadog.IsSet(k) {
+		rules, err := parseSpanMetricRules(k)
+		if err != nil {
+			log.Errorf("Bad format for %q it should be a list of rules with a name, an optional type, service, operation_name, resource, error, tags, value and group_by, error: %v", k, err)
+		} else {
+			c.SpanMetrics = rules
+		}
+	}
+	if coreconfig.Datadog.IsSet("apm_config.capture.enabled") {
+		c.Capture.Enabled = coreconfig.Datadog.GetBool("apm_config.capture.enabled")
+	}
+	c.Capture.Dir = coreconfig.Datadog.GetString("apm_config.capture.dir")
+	if c.Capture.Dir == "" {
+		c.Capture.Dir = filepath.Join(coreconfig.Datadog.GetString("run_path"), "trace-capture")
+	}
+	if coreconfig.Datadog.IsSet("apm_config.capture.max_file_size") {
+		c.Capture.MaxFileSize = coreconfig.Datadog.GetInt64("apm_config.capture.max_file_size")
+	}
+	if coreconfig.Datadog.IsSet("apm_config.capture.max_files") {
+		c.Capture.MaxFiles = coreconfig.Datadog.GetInt("apm_config.capture.max_files")
+	}
 
 	if coreconfig.Datadog.IsSet("apm_config.max_remote_traces_per_second") {
 		c.MaxRemoteTPS = coreconfig.Datadog.GetFloat64("apm_config.max_remote_traces_per_second")
@@ -285,6 +330,8 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 		SpanNameAsResourceName: coreconfig.Datadog.GetBool("otlp_config.traces.span_name_as_resource_name"),
 		ProbabilisticSampling:  coreconfig.Datadog.GetFloat64("otlp_config.traces.probabilistic_sampler.sampling_percentage"),
 	}
+	c.ZipkinReceiverEnabled = coreconfig.Datadog.GetBool("apm_config.zipkin_receiver_enabled")
+	c.JaegerReceiverEnabled = coreconfig.Datadog.GetBool("apm_config.jaeger_receiver_enabled")
 
 	if coreconfig.Datadog.GetBool("apm_config.telemetry.enabled") {
 		c.TelemetryConfig.Enabled = true
@@ -402,6 +449,16 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 	if v := coreconfig.Datadog.GetInt("apm_config.max_catalog_entries"); v > 0 {
 		c.MaxCatalogEntries = v
 	}
+	if coreconfig.Datadog.IsSet("apm_config.sampler_state.enabled") {
+		c.SamplerState.Enabled = coreconfig.Datadog.GetBool("apm_config.sampler_state.enabled")
+	}
+	c.SamplerState.Path = coreconfig.Datadog.GetString("apm_config.sampler_state.path")
+	if c.SamplerState.Path == "" {
+		c.SamplerState.Path = filepath.Join(coreconfig.Datadog.GetString("run_path"), "trace-sampler-state.json")
+	}
+	if coreconfig.Datadog.IsSet("apm_config.sampler_state.max_age") {
+		c.SamplerState.MaxAge = coreconfig.Datadog.GetDuration("apm_config.sampler_state.max_age")
+	}
 	if k := "apm_config.profiling_dd_url"; coreconfig.Datadog.IsSet(k) {
 		c.ProfilingProxy.DDURL = coreconfig.Datadog.GetString(k)
 	}
@@ -557,6 +614,93 @@ func splitTag(tag string) *config.Tag {
 	return kv
 }
 
+// parseTailSamplingPolicies reads the tail sampling policies of the given key.
+func parseTailSamplingPolicies(key string) ([]*config.TailSamplingPolicy, error) {
+	var raw []struct {
+		Name        string        `mapstructure:"name"`
+		Service     string        `mapstructure:"service"`
+		MinDuration time.Duration `mapstructure:"min_duration"`
+		Error       bool          `mapstructure:"error"`
+		Tags        []string      `mapstructure:"tags"`
+	}
+	if err := coreconfig.Datadog.UnmarshalKey(key, &raw); err != nil {
+		return nil, err
+	}
+	policies := make([]*config.TailSamplingPolicy, 0, len(raw))
+	for _, r := range raw {
+		if r.Name == "" {
+			return nil, errors.New("all tail sampling policies must have a name")
+		}
+		policy := &config.TailSamplingPolicy{
+			Name:        r.Name,
+			Service:     r.Service,
+			MinDuration: r.MinDuration,
+			Error:       r.Error,
+		}
+		for _, tag := range r.Tags {
+			policy.Tags = append(policy.Tags, splitTag(tag))
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// parseSpanMetricRules reads the span metric rules found at key.
+func parseSpanMetricRules(key string) ([]*config.SpanMetricRule, error) {
+	var raw []struct {
+		Name          string   `mapstructure:"name"`
+		Type          string   `mapstructure:"type"`
+		Service       string   `mapstructure:"service"`
+		OperationName string   `mapstructure:"operation_name"`
+		Resource      string   `mapstructure:"resource"`
+		Error         bool     `mapstructure:"error"`
+		Tags          []string `mapstructure:"tags"`
+		Value         string   `mapstructure:"value"`
+		GroupBy       []string `mapstructure:"group_by"`
+	}
+	if err := coreconfig.Datadog.UnmarshalKey(key, &raw); err != nil {
+		return nil, err
+	}
+	rules := make([]*config.SpanMetricRule, 0, len(raw))
+	for _, r := range raw {
+		if r.Name == "" {
+			return nil, errors.New("all span metric rules must have a name")
+		}
+		rule := &config.SpanMetricRule{
+			Name:          r.Name,
+			Type:          r.Type,
+			Service:       r.Service,
+			OperationName: r.OperationName,
+			Error:         r.Error,
+			Value:         r.Value,
+			GroupBy:       r.GroupBy,
+		}
+		switch rule.Type {
+		case "":
+			rule.Type = config.SpanMetricCount
+		case config.SpanMetricCount:
+		case config.SpanMetricDistribution:
+			if rule.Value == "" {
+				return nil, fmt.Errorf("span metric rule %s: a distribution needs a value", r.Name)
+			}
+		default:
+			return nil, fmt.Errorf("span metric rule %s: unknown type %q", r.Name, r.Type)
+		}
+		if r.Resource != "" {
+			re, err := regexp.Compile(r.Resource)
+			if err != nil {
+				return nil, fmt.Errorf("span metric rule %s: invalid resource: %v", r.Name, err)
+			}
+			rule.Resource = re
+		}
+		for _, tag := range r.Tags {
+			rule.Tags = append(rule.Tags, splitTag(tag))
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
 // validate validates if the current configuration is good for the agent to start with.
 func validate(c *config.AgentConfig) error {
 	if len(c.Endpoints) == 0 || c.Endpoints[0].APIKey == "" {
@@ -478,6 +478,49 @@ func TestFullYamlConfig(t *testing.T) {
 
 	assert.EqualValues([]string{"/health", "/500"}, c.Ignore["resource"])
 
+	assert.Equal([]string{"peer.service", "customer.tier"}, c.StatsExtraDimensions)
+	assert.Equal(50, c.StatsExtraDimensionsMaxCardinality)
+
+	assert.True(c.TailSampling.Enabled)
+	assert.Equal(30*time.Second, c.TailSampling.DecisionWait)
+	assert.Equal(5000, c.TailSampling.MaxSpans)
+	assert.Equal([]*config.TailSamplingPolicy{
+		{Name: "slow_checkout", Service: "checkout", MinDuration: 2 * time.Second},
+		{Name: "errors", Error: true},
+		{Name: "beta", Tags: []*config.Tag{{K: "customer.tier", V: "beta"}, {K: "debug"}}},
+	}, c.TailSampling.Policies)
+
+	assert.Equal([]*config.SpanMetricRule{
+		{
+			Name:    "checkout.errors",
+			Type:    config.SpanMetricCount,
+			Service: "checkout",
+			Error:   true,
+			Tags:    []*config.Tag{{K: "http.route", V: "/checkout"}},
+			GroupBy: []string{"customer.tier"},
+		},
+		{
+			Name:          "db.query.duration",
+			Type:          config.SpanMetricDistribution,
+			OperationName: "postgres.query",
+			Resource:      regexp.MustCompile("^SELECT"),
+			Value:         config.SpanMetricDuration,
+		},
+	}, c.SpanMetrics)
+	assert.True(c.ZipkinReceiverEnabled)
+	assert.True(c.JaegerReceiverEnabled)
+	assert.Equal(config.CaptureConfig{
+		Enabled:     true,
+		Dir:         "/var/run/datadog/trace-capture",
+		MaxFileSize: 1048576,
+		MaxFiles:    3,
+	}, c.Capture)
+	assert.Equal(config.SamplerStateConfig{
+		Enabled: true,
+		Path:    "/var/run/datadog/sampler.json",
+		MaxAge:  5 * time.Minute,
+	}, c.SamplerState)
+
 	o := c.Obfuscation
 	assert.NotNil(o)
 	assert.True(o.ES.Enabled)
@@ -489,6 +532,12 @@ func TestFullYamlConfig(t *testing.T) {
 	assert.True(o.RemoveStackTraces)
 	assert.True(o.Redis.Enabled)
 	assert.True(o.Memcached.Enabled)
+	assert.True(o.GraphQL.Enabled)
+	assert.EqualValues([]string{"first"}, o.GraphQL.KeepVariables)
+	assert.True(o.DynamoDB.Enabled)
+	assert.EqualValues([]string{"Key"}, o.DynamoDB.KeepValues)
+	assert.True(o.Kafka.Enabled)
+	assert.True(o.Kafka.KeepMessageKeys)
 	assert.True(o.CreditCards.Enabled)
 	assert.True(o.CreditCards.Luhn)
 }
@@ -822,6 +871,51 @@ func TestLoadEnv(t *testing.T) {
 		assert.Equal(cfg.RequireTags, []*config.Tag{{K: "important1", V: "value with a space"}})
 	})
 
+	env = "DD_APM_STATS_EXTRA_DIMENSIONS"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		t.Setenv(env, `peer.service db.instance`)
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal([]string{"peer.service", "db.instance"}, cfg.StatsExtraDimensions)
+	})
+
+	env = "DD_APM_ZIPKIN_RECEIVER_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		t.Setenv(env, "false")
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.False(cfg.ZipkinReceiverEnabled)
+		assert.True(cfg.JaegerReceiverEnabled)
+	})
+
+	env = "DD_APM_CAPTURE_DIR"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		t.Setenv(env, "/tmp/capture")
+		t.Setenv("DD_APM_CAPTURE_MAX_FILES", "10")
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal("/tmp/capture", cfg.Capture.Dir)
+		assert.Equal(10, cfg.Capture.MaxFiles)
+	})
+
+	env = "DD_APM_SAMPLER_STATE_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		t.Setenv(env, "false")
+		t.Setenv("DD_APM_SAMPLER_STATE_MAX_AGE", "1h")
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.False(cfg.SamplerState.Enabled)
+		assert.Equal(time.Hour, cfg.SamplerState.MaxAge)
+	})
+
 	env = "DD_APM_FILTER_TAGS_REJECT"
 	t.Run(env, func(t *testing.T) {
 		defer cleanConfig()()
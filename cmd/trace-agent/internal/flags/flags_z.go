@@ -27,6 +27,14 @@ var (
 	// MemProfile specifies the path to output memory profiling information to.
 	// When empty, memory profiling is disabled.
 	MemProfile string
+
+	// Replay specifies the path of a capture file, or of a directory of capture files,
+	// to replay into the running trace agent.
+	Replay string
+
+	// ReplaySpeed specifies the factor by which the replay of a capture is accelerated.
+	// When 0, the captured requests are replayed as fast as possible.
+	ReplaySpeed float64
 )
 
 // Win holds a set of flags which will be populated only during the Windows build.
@@ -41,6 +49,8 @@ func init() {
 	flag.StringVar(&PIDFilePath, "pid", "", "Path to set pidfile for process")
 	flag.BoolVar(&Version, "version", false, "Show version information and exit")
 	flag.BoolVar(&Info, "info", false, "Show info about running trace agent process and exit")
+	flag.StringVar(&Replay, "replay", "", "Replay the capture `file` or directory into the running trace agent and exit")
+	flag.Float64Var(&ReplaySpeed, "replay-speed", 1, "Speed factor of the replay, 0 to replay as fast as possible")
 
 	// profiling
 	flag.StringVar(&CPUProfile, "cpuprofile", "", "Write cpu profile to file")
@@ -0,0 +1,32 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/internal/flags"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+)
+
+// replayCapture replays the capture given with the -replay flag into the trace agent
+// listening on the receiver port of cfg.
+func replayCapture(ctx context.Context, cfg *config.AgentConfig) error {
+	files, err := api.CaptureFiles(flags.Replay)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no capture files found in %s", flags.Replay)
+	}
+	addr := fmt.Sprintf("http://localhost:%d", cfg.ReceiverPort)
+	fmt.Printf("Replaying %d capture file(s) to %s\n", len(files), addr)
+	stats, err := api.ReplayCapture(ctx, files, addr, flags.ReplaySpeed)
+	fmt.Printf("Replayed %d requests, %d refused by the trace agent.\n", stats.Requests, stats.Errors)
+	return err
+}
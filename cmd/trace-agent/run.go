@@ -85,6 +85,13 @@ func Run(ctx context.Context) {
 		return
 	}
 
+	if flags.Replay != "" {
+		if err := replayCapture(ctx, cfg); err != nil {
+			osutil.Exitf("Failed to replay capture: %s", err)
+		}
+		return
+	}
+
 	telemetryCollector := telemetry.NewCollector(cfg)
 
 	if err := coreconfig.SetupLogger(
@@ -8,6 +8,7 @@ package server
 
 import (
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 	"go.uber.org/fx"
 )
@@ -34,6 +35,12 @@ type Component interface {
 
 	// SetExtraTags sets extra tags. All metrics sent to the DogstatsD will be tagged with them.
 	SetExtraTags(tags []string)
+
+	// SetTagFilterRules replaces the rules filtering the tags of the metrics.
+	SetTagFilterRules(rules []config.TagFilterRule) error
+
+	// GetTagFilterRules returns the rules filtering the tags of the metrics.
+	GetTagFilterRules() []config.TagFilterRule
 }
 
 // Mock implements mock-specific methods.
@@ -331,6 +331,24 @@ func (s *server) Start(demultiplexer aggregator.Demultiplexer) error {
 		}
 	}
 
+	streamSocketPath := s.config.GetString("dogstatsd_stream_socket")
+	if len(streamSocketPath) > 0 {
+		unixStreamListener, err := listeners.NewUDSStreamListener(packetsChannel, sharedPacketPoolManager, s.tCapture)
+		if err != nil {
+			s.log.Errorf(err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, unixStreamListener)
+		}
+	}
+	if s.config.GetInt("dogstatsd_tcp_port") > 0 {
+		tcpListener, err := listeners.NewTCPListener(packetsChannel, sharedPacketPoolManager, s.tCapture)
+		if err != nil {
+			s.log.Errorf(err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, tcpListener)
+		}
+	}
+
 	pipeName := s.config.GetString("dogstatsd_pipe_name")
 	if len(pipeName) > 0 {
 		namedPipeListener, err := listeners.NewNamedPipeListener(pipeName, packetsChannel, sharedPacketPoolManager, s.tCapture)
@@ -429,6 +447,23 @@ func (s *server) SetExtraTags(tags []string) {
 	s.extraTags = tags
 }
 
+// SetTagFilterRules replaces the rules filtering the tags of the metrics. The tags
+// are filtered by the demultiplexer, once the origin tags have been added.
+func (s *server) SetTagFilterRules(rules []config.TagFilterRule) error {
+	if s.demultiplexer == nil {
+		return fmt.Errorf("the DogStatsD server isn't started")
+	}
+	return s.demultiplexer.SetTagFilterRules(rules)
+}
+
+// GetTagFilterRules returns the rules filtering the tags of the metrics.
+func (s *server) GetTagFilterRules() []config.TagFilterRule {
+	if s.demultiplexer == nil {
+		return nil
+	}
+	return s.demultiplexer.GetTagFilterRules()
+}
+
 func (s *server) handleMessages() {
 	if s.Statistics != nil {
 		go s.Statistics.Process()
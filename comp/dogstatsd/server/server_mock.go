@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config"
 )
 
 type serverMock struct {
@@ -43,3 +44,11 @@ func (s *serverMock) UdsListenerRunning() bool {
 func (s *serverMock) ServerlessFlush() {}
 
 func (s *serverMock) SetExtraTags(tags []string) {}
+
+func (s *serverMock) SetTagFilterRules(rules []config.TagFilterRule) error {
+	return nil
+}
+
+func (s *serverMock) GetTagFilterRules() []config.TagFilterRule {
+	return nil
+}
@@ -188,6 +188,7 @@ func init() {
 	tagsetTlm = newTagsetTelemetry([]uint64{90, 100})
 
 	aggregatorExpvars.Set("MetricTags", expvar.Func(expMetricTags))
+	aggregatorExpvars.Set("ContextLimitHits", expvar.Func(contextLimitHits.exp))
 }
 
 // BufferedAggregator aggregates metrics in buckets for dogstatsd Metrics
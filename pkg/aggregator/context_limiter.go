@@ -0,0 +1,227 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/spf13/cast"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// contextLimiterActionDrop drops the new contexts exceeding a limit
+	contextLimiterActionDrop = "drop"
+	// contextLimiterActionFold folds the tags of the new contexts exceeding a limit
+	contextLimiterActionFold = "fold"
+
+	// overflowTagValue is the value of the folded tags
+	overflowTagValue = "overflow"
+)
+
+// contextLimitHitsMaxNames is the maximum number of metric names whose hits are listed on
+// the status page, the hits of the other metrics being aggregated under otherMetricsName.
+const contextLimitHitsMaxNames = 100
+
+// otherMetricsName is the name the hits of the metrics not listed on the status page are recorded under.
+const otherMetricsName = "other metrics"
+
+var (
+	tlmContextLimitHits = telemetry.NewCounter("aggregator", "context_limit_hits",
+		[]string{"action"}, "Count of new contexts dropped or folded because of a context limit")
+
+	contextLimitHits = newContextLimitHitsStats(contextLimitHitsMaxNames)
+)
+
+// contextLimitHitsStats holds the number of new contexts dropped or folded for the first
+// maxNames metric names hitting a limit, as shown on the status page. It is shared by all
+// the context limiters.
+type contextLimitHitsStats struct {
+	mu       sync.Mutex
+	maxNames int
+	hits     map[string]map[string]int64
+}
+
+func newContextLimitHitsStats(maxNames int) *contextLimitHitsStats {
+	return &contextLimitHitsStats{maxNames: maxNames, hits: make(map[string]map[string]int64)}
+}
+
+func (s *contextLimitHitsStats) inc(name, action string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	byAction, ok := s.hits[name]
+	if !ok {
+		if len(s.hits) >= s.maxNames {
+			name = otherMetricsName
+			byAction = s.hits[name]
+		}
+		if byAction == nil {
+			byAction = make(map[string]int64)
+			s.hits[name] = byAction
+		}
+	}
+	byAction[action]++
+}
+
+func (s *contextLimitHitsStats) exp() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rv := make(map[string]map[string]int64, len(s.hits))
+	for name, byAction := range s.hits {
+		rv[name] = make(map[string]int64, len(byAction))
+		for action, count := range byAction {
+			rv[name][action] = count
+		}
+	}
+	return rv
+}
+
+// contextLimiter limits the number of live contexts of the context resolvers it is shared by,
+// globally and by metric name. Once a limit is reached, the new contexts are either dropped
+// or have the values of their tags folded into a single overflow value, so that they are
+// merged into one context.
+//
+// A single limiter is shared by the time samplers of the DogStatsD pipelines, so that the
+// limits apply to all of them together. As a context is checked and tracked separately,
+// concurrent pipelines can exceed a limit by at most one context each.
+type contextLimiter struct {
+	globalLimit  int
+	metricLimit  int
+	metricLimits map[string]int
+	fold         bool
+	// foldTags holds the keys of the tags to fold, all the metric tags are folded if empty.
+	foldTags map[string]struct{}
+
+	mu           sync.Mutex
+	total        int
+	countsByName map[string]int
+}
+
+// newContextLimiterFromConfig returns the context limiter configured for DogStatsD, or nil if
+// no limit is configured.
+func newContextLimiterFromConfig() *contextLimiter {
+	metricLimits := make(map[string]int)
+	for name, limit := range config.Datadog.GetStringMap("dogstatsd_context_limiter.metric_limits") {
+		l, err := cast.ToIntE(limit)
+		if err != nil {
+			log.Errorf("Invalid DogStatsD context limit for metric %q: %v", name, err)
+			continue
+		}
+		metricLimits[name] = l
+	}
+
+	action := config.Datadog.GetString("dogstatsd_context_limiter.action")
+	if action != contextLimiterActionDrop && action != contextLimiterActionFold {
+		log.Errorf("Invalid DogStatsD context limiter action %q, using %q", action, contextLimiterActionDrop)
+		action = contextLimiterActionDrop
+	}
+
+	return newContextLimiter(
+		config.Datadog.GetInt("dogstatsd_context_limiter.global_limit"),
+		config.Datadog.GetInt("dogstatsd_context_limiter.metric_limit"),
+		metricLimits,
+		action == contextLimiterActionFold,
+		config.Datadog.GetStringSlice("dogstatsd_context_limiter.fold_tags"),
+	)
+}
+
+// newContextLimiter returns a context limiter, or nil if none of the limits is set. A limit
+// of 0 means no limit, metricLimits overrides metricLimit for some metric names.
+func newContextLimiter(globalLimit, metricLimit int, metricLimits map[string]int, fold bool, foldTags []string) *contextLimiter {
+	limited := globalLimit > 0 || metricLimit > 0
+	for _, limit := range metricLimits {
+		limited = limited || limit > 0
+	}
+	if !limited {
+		return nil
+	}
+
+	l := &contextLimiter{
+		globalLimit:  globalLimit,
+		metricLimit:  metricLimit,
+		metricLimits: metricLimits,
+		fold:         fold,
+		foldTags:     make(map[string]struct{}, len(foldTags)),
+		countsByName: make(map[string]int),
+	}
+	for _, key := range foldTags {
+		l.foldTags[key] = struct{}{}
+	}
+	return l
+}
+
+// limit returns the limit on the number of contexts of the metric name, 0 if none.
+func (l *contextLimiter) limit(name string) int {
+	if limit, ok := l.metricLimits[name]; ok {
+		return limit
+	}
+	return l.metricLimit
+}
+
+// allow returns whether a new context of the metric name can be tracked.
+func (l *contextLimiter) allow(name string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.globalLimit > 0 && l.total >= l.globalLimit {
+		return false
+	}
+	limit := l.limit(name)
+	return limit <= 0 || l.countsByName[name] < limit
+}
+
+// hit records that a new context of the metric name exceeded a limit.
+func (l *contextLimiter) hit(name string) {
+	action := contextLimiterActionDrop
+	if l.fold {
+		action = contextLimiterActionFold
+	}
+	tlmContextLimitHits.Inc(action)
+	contextLimitHits.inc(name, action)
+}
+
+// foldTagValues replaces the values of the tags to fold with the overflow value.
+func (l *contextLimiter) foldTagValues(tags *tagset.HashingTagsAccumulator) {
+	folded := make([]string, 0, len(tags.Get()))
+	for _, tag := range tags.Get() {
+		key, _, ok := strings.Cut(tag, ":")
+		if !ok {
+			// tags without a value are left as is
+			folded = append(folded, tag)
+			continue
+		}
+		if _, fold := l.foldTags[key]; fold || len(l.foldTags) == 0 {
+			tag = key + ":" + overflowTagValue
+		}
+		folded = append(folded, tag)
+	}
+	tags.Reset()
+	tags.Append(folded...)
+}
+
+// track records a new context of the metric name.
+func (l *contextLimiter) track(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total++
+	l.countsByName[name]++
+}
+
+// untrack records the removal of a context of the metric name.
+func (l *contextLimiter) untrack(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total--
+	if l.countsByName[name] <= 1 {
+		delete(l.countsByName, name)
+		return
+	}
+	l.countsByName[name]--
+}
@@ -42,6 +42,10 @@ type contextResolver struct {
 	keyGenerator  *ckey.KeyGenerator
 	taggerBuffer  *tagset.HashingTagsAccumulator
 	metricBuffer  *tagset.HashingTagsAccumulator
+	// limiter limits the number of contexts, nil if there is no limit
+	limiter *contextLimiter
+	// tagFilters filters the tags of the contexts, nil if the tags aren't filtered
+	tagFilters *tagFilterList
 }
 
 // generateContextKey generates the contextKey associated with the context of the metricSample
@@ -60,22 +64,22 @@ func newContextResolver(cache *tags.Store) *contextResolver {
 	}
 }
 
-// trackContext returns the contextKey associated with the context of the metricSample and tracks that context
+// trackContext returns the contextKey associated with the context of the metricSample and tracks that context.
+// The zero contextKey is returned if the context is dropped by the limiter.
 func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext) ckey.ContextKey {
-	metricSampleContext.GetTags(cr.taggerBuffer, cr.metricBuffer)                  // tags here are not sorted and can contain duplicates
+	metricSampleContext.GetTags(cr.taggerBuffer, cr.metricBuffer) // tags here are not sorted and can contain duplicates
+	// the tags are filtered once the origin tags are resolved, so that they are filtered as well
+	cr.tagFilters.filter(metricSampleContext.GetName(), cr.taggerBuffer, cr.metricBuffer)
 	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
 
 	if _, ok := cr.contextsByKey[contextKey]; !ok {
-		mtype := metricSampleContext.GetMetricType()
-		cr.contextsByKey[contextKey] = &Context{
-			Name:       metricSampleContext.GetName(),
-			taggerTags: cr.tagsCache.Insert(taggerKey, cr.taggerBuffer),
-			metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
-			Host:       metricSampleContext.GetHost(),
-			mtype:      mtype,
-			noIndex:    metricSampleContext.IsNoIndex(),
+		if cr.limiter != nil {
+			contextKey, taggerKey, metricKey = cr.limitContext(metricSampleContext, contextKey, taggerKey, metricKey)
+		}
+		// the context can be dropped, or folded into an already tracked context
+		if _, ok := cr.contextsByKey[contextKey]; !ok && !contextKey.IsZero() {
+			cr.addContext(metricSampleContext, contextKey, taggerKey, metricKey)
 		}
-		cr.countsByMtype[mtype]++
 	}
 
 	cr.taggerBuffer.Reset()
@@ -84,6 +88,41 @@ func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSample
 	return contextKey
 }
 
+func (cr *contextResolver) addContext(metricSampleContext metrics.MetricSampleContext, contextKey ckey.ContextKey, taggerKey, metricKey ckey.TagsKey) {
+	mtype := metricSampleContext.GetMetricType()
+	cr.contextsByKey[contextKey] = &Context{
+		Name:       metricSampleContext.GetName(),
+		taggerTags: cr.tagsCache.Insert(taggerKey, cr.taggerBuffer),
+		metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
+		Host:       metricSampleContext.GetHost(),
+		mtype:      mtype,
+		noIndex:    metricSampleContext.IsNoIndex(),
+	}
+	cr.countsByMtype[mtype]++
+	if cr.limiter != nil {
+		cr.limiter.track(metricSampleContext.GetName())
+	}
+}
+
+// limitContext checks the new context against the limits of the limiter. The keys of the context
+// are returned as is when no limit is exceeded, otherwise the context is either dropped and zero
+// keys are returned, or the keys of the context with its metric tags folded are returned.
+func (cr *contextResolver) limitContext(metricSampleContext metrics.MetricSampleContext, contextKey ckey.ContextKey, taggerKey, metricKey ckey.TagsKey) (ckey.ContextKey, ckey.TagsKey, ckey.TagsKey) {
+	name := metricSampleContext.GetName()
+	if cr.limiter.allow(name) {
+		return contextKey, taggerKey, metricKey
+	}
+
+	cr.limiter.hit(name)
+	if !cr.limiter.fold {
+		return 0, 0, 0
+	}
+	// the folded context is tracked even if it exceeds the limit, the number of folded
+	// contexts being bounded by the folding itself
+	cr.limiter.foldTagValues(cr.metricBuffer)
+	return cr.generateContextKey(metricSampleContext)
+}
+
 func (cr *contextResolver) get(key ckey.ContextKey) (*Context, bool) {
 	ctx, found := cr.contextsByKey[key]
 	return ctx, found
@@ -100,6 +139,9 @@ func (cr *contextResolver) removeKeys(expiredContextKeys []ckey.ContextKey) {
 
 		if context != nil {
 			cr.countsByMtype[context.mtype]--
+			if cr.limiter != nil {
+				cr.limiter.untrack(context.Name)
+			}
 			context.release()
 		}
 	}
@@ -147,9 +189,12 @@ type timestampContextResolver struct {
 	lastSeenByKey map[ckey.ContextKey]float64
 }
 
-func newTimestampContextResolver(cache *tags.Store) *timestampContextResolver {
+func newTimestampContextResolver(cache *tags.Store, limiter *contextLimiter, tagFilters *tagFilterList) *timestampContextResolver {
+	resolver := newContextResolver(cache)
+	resolver.limiter = limiter
+	resolver.tagFilters = tagFilters
 	return &timestampContextResolver{
-		resolver:      newContextResolver(cache),
+		resolver:      resolver,
 		lastSeenByKey: make(map[ckey.ContextKey]float64),
 	}
 }
@@ -165,10 +210,13 @@ func (cr *timestampContextResolver) updateTrackedContext(contextKey ckey.Context
 	return nil
 }
 
-// trackContext returns the contextKey associated with the context of the metricSample and tracks that context
+// trackContext returns the contextKey associated with the context of the metricSample and tracks that context.
+// The zero contextKey is returned if the context is dropped by the limiter.
 func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp float64) ckey.ContextKey {
 	contextKey := cr.resolver.trackContext(metricSampleContext)
-	cr.lastSeenByKey[contextKey] = currentTimestamp
+	if !contextKey.IsZero() {
+		cr.lastSeenByKey[contextKey] = currentTimestamp
+	}
 	return contextKey
 }
 
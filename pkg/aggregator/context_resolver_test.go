@@ -122,7 +122,7 @@ func testExpireContexts(t *testing.T, store *tags.Store) {
 		Tags:       []string{"foo", "bar", "baz"},
 		SampleRate: 1,
 	}
-	contextResolver := newTimestampContextResolver(store)
+	contextResolver := newTimestampContextResolver(store, nil, nil)
 
 	// Track the 2 contexts
 	contextKey1 := contextResolver.trackContext(&mSample1, 4)
@@ -166,7 +166,7 @@ func testExpireContextsWithKeep(t *testing.T, store *tags.Store) {
 		Tags:       []string{"foo", "bar", "baz"},
 		SampleRate: 1,
 	}
-	contextResolver := newTimestampContextResolver(store)
+	contextResolver := newTimestampContextResolver(store, nil, nil)
 
 	// Track the 2 contexts
 	contextKey1 := contextResolver.trackContext(&mSample1, 4)
@@ -310,3 +310,106 @@ func TestOriginTelemetry(t *testing.T) {
 		Points: []metrics.Point{{Ts: ts, Value: 1.0}},
 	}})
 }
+
+func testContextLimiter(t *testing.T, store *tags.Store) {
+	r := newContextResolver(store)
+	r.limiter = newContextLimiter(3, 2, map[string]int{"bar": 1}, false, nil)
+
+	k1 := r.trackContext(&mockSample{"foo", nil, []string{"id:1"}})
+	k2 := r.trackContext(&mockSample{"foo", nil, []string{"id:2"}})
+	assert.False(t, k1.IsZero())
+	assert.False(t, k2.IsZero())
+	// the per-metric limit is reached, new contexts are dropped but existing ones are kept
+	assert.True(t, r.trackContext(&mockSample{"foo", nil, []string{"id:3"}}).IsZero())
+	assert.Equal(t, k1, r.trackContext(&mockSample{"foo", nil, []string{"id:1"}}))
+
+	// the per-metric limit is overridden for bar
+	assert.False(t, r.trackContext(&mockSample{"bar", nil, []string{"id:1"}}).IsZero())
+	assert.True(t, r.trackContext(&mockSample{"bar", nil, []string{"id:2"}}).IsZero())
+
+	// the global limit is reached
+	assert.True(t, r.trackContext(&mockSample{"baz", nil, []string{"id:1"}}).IsZero())
+	assert.Equal(t, 3, r.length())
+
+	// expired contexts are not counted anymore
+	r.removeKeys([]ckey.ContextKey{k1})
+	assert.False(t, r.trackContext(&mockSample{"foo", nil, []string{"id:3"}}).IsZero())
+
+	hits := contextLimitHits.exp().(map[string]map[string]int64)
+	assert.GreaterOrEqual(t, hits["foo"]["drop"], int64(1))
+	assert.GreaterOrEqual(t, hits["bar"]["drop"], int64(1))
+	assert.GreaterOrEqual(t, hits["baz"]["drop"], int64(1))
+}
+
+func TestContextLimiter(t *testing.T) {
+	testWithTagsStore(t, testContextLimiter)
+}
+
+func testContextLimiterFold(t *testing.T, store *tags.Store) {
+	r := newContextResolver(store)
+	r.limiter = newContextLimiter(0, 1, nil, true, []string{"request_id"})
+
+	k1 := r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"env:prod", "request_id:1"}})
+	k2 := r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"env:prod", "request_id:2"}})
+	k3 := r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"env:prod", "request_id:3"}})
+	assert.NotEqual(t, k1, k2)
+	// the offending tags are folded into a single context
+	assert.Equal(t, k2, k3)
+	cx, ok := r.get(k2)
+	require.True(t, ok)
+	assertContext(t, cx, "foo", []string{"pod:a", "env:prod", "request_id:overflow"}, "noop")
+
+	// all the metric tags with a value are folded when no tag is configured
+	r.limiter = newContextLimiter(0, 1, nil, true, nil)
+	r.trackContext(&mockSample{"bar", nil, []string{"env:prod", "request_id:1"}})
+	cx, ok = r.get(r.trackContext(&mockSample{"bar", nil, []string{"canary", "env:prod", "request_id:2"}}))
+	require.True(t, ok)
+	assertContext(t, cx, "bar", []string{"canary", "env:overflow", "request_id:overflow"}, "noop")
+}
+
+func TestContextLimiterFold(t *testing.T) {
+	testWithTagsStore(t, testContextLimiterFold)
+}
+
+func TestNewContextLimiter(t *testing.T) {
+	assert.Nil(t, newContextLimiter(0, 0, map[string]int{"foo": 0}, true, nil))
+	assert.NotNil(t, newContextLimiter(0, 0, map[string]int{"foo": 10}, false, nil))
+}
+
+func testContextLimiterShared(t *testing.T, store *tags.Store) {
+	limiter := newContextLimiter(3, 2, nil, false, nil)
+	r1 := newContextResolver(store)
+	r1.limiter = limiter
+	r2 := newContextResolver(store)
+	r2.limiter = limiter
+
+	// the limits apply to the contexts of all the resolvers sharing the limiter
+	k1 := r1.trackContext(&mockSample{"foo", nil, []string{"id:1"}})
+	assert.False(t, k1.IsZero())
+	assert.False(t, r2.trackContext(&mockSample{"foo", nil, []string{"id:2"}}).IsZero())
+	assert.True(t, r2.trackContext(&mockSample{"foo", nil, []string{"id:3"}}).IsZero())
+	assert.False(t, r1.trackContext(&mockSample{"bar", nil, []string{"id:1"}}).IsZero())
+	assert.True(t, r2.trackContext(&mockSample{"bar", nil, []string{"id:2"}}).IsZero())
+
+	r1.removeKeys([]ckey.ContextKey{k1})
+	assert.False(t, r2.trackContext(&mockSample{"foo", nil, []string{"id:3"}}).IsZero())
+}
+
+func TestContextLimiterShared(t *testing.T) {
+	testWithTagsStore(t, testContextLimiterShared)
+}
+
+func TestContextLimitHitsStatsAreBounded(t *testing.T) {
+	stats := newContextLimitHitsStats(2)
+	stats.inc("foo", contextLimiterActionDrop)
+	stats.inc("bar", contextLimiterActionDrop)
+	stats.inc("baz", contextLimiterActionDrop)
+	stats.inc("qux", contextLimiterActionFold)
+	stats.inc("foo", contextLimiterActionDrop)
+
+	assert.Equal(t, map[string]map[string]int64{
+		"foo":            {contextLimiterActionDrop: 2},
+		"bar":            {contextLimiterActionDrop: 1},
+		otherMetricsName: {contextLimiterActionDrop: 1, contextLimiterActionFold: 1},
+	}, stats.exp())
+}
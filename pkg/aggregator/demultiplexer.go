@@ -68,6 +68,12 @@ type Demultiplexer interface {
 	// end of line (the time sampler) is putting back the slice in the pool.
 	// Main idea is to reduce the garbage generated by slices allocation.
 	GetMetricSamplePool() *metrics.MetricSamplePool
+	// SetTagFilterRules replaces the rules filtering the tags of the metrics
+	// aggregated by the DogStatsD time samplers.
+	SetTagFilterRules(rules []config.TagFilterRule) error
+	// GetTagFilterRules returns the rules filtering the tags of the metrics
+	// aggregated by the DogStatsD time samplers.
+	GetTagFilterRules() []config.TagFilterRule
 
 	// Senders API, mainly used by collectors/checks
 	// --
@@ -94,6 +94,8 @@ type statsd struct {
 	// every metric to distribute.
 	pipelinesCount int
 	workers        []*timeSamplerWorker
+	// tagFilters filters the tags of the metrics of all the samplers
+	tagFilters *tagFilterList
 	// shared metric sample pool between the dogstatsd server & the time sampler
 	metricSamplePool *metrics.MetricSamplePool
 
@@ -107,6 +109,7 @@ type forwarders struct {
 	orchestrator       forwarder.Forwarder
 	eventPlatform      epforwarder.EventPlatformForwarder
 	containerLifecycle *forwarder.DefaultForwarder
+	remoteWrite        *forwarder.RemoteWriteForwarder
 }
 
 type dataOutputs struct {
@@ -158,10 +161,18 @@ func initAgentDemultiplexer(options AgentDemultiplexerOptions, hostname string)
 	var sharedForwarder forwarder.Forwarder
 	if options.UseNoopForwarder {
 		sharedForwarder = forwarder.NoopForwarder{}
+	} else if config.Datadog.GetBool("forwarder_offline_mode.enabled") {
+		sharedForwarder = buildFileForwarder(options.SharedForwarderOptions)
 	} else {
 		sharedForwarder = forwarder.NewDefaultForwarder(options.SharedForwarderOptions)
 	}
 
+	// Prometheus remote-write forwarder
+	var remoteWriteForwarder *forwarder.RemoteWriteForwarder
+	if !options.UseNoopForwarder && config.Datadog.GetBool("prometheus_remote_write.enabled") {
+		remoteWriteForwarder = buildRemoteWriteForwarder(options.SharedForwarderOptions)
+	}
+
 	if config.Datadog.GetBool("telemetry.enabled") && config.Datadog.GetBool("telemetry.dogstatsd_origin") && !config.Datadog.GetBool("aggregator_use_tags_store") {
 		log.Warn("DogStatsD origin telemetry is not supported when aggregator_use_tags_store is disabled.")
 		config.Datadog.Set("telemetry.dogstatsd_origin", false)
@@ -171,6 +182,7 @@ func initAgentDemultiplexer(options AgentDemultiplexerOptions, hostname string)
 	// ----------------------
 
 	sharedSerializer := serializer.NewSerializer(sharedForwarder, orchestratorForwarder)
+	sharedSerializer.SetRemoteWriteForwarder(remoteWriteForwarder)
 
 	// prepare the embedded aggregator
 	// --
@@ -187,11 +199,14 @@ func initAgentDemultiplexer(options AgentDemultiplexerOptions, hostname string)
 	log.Debug("the Demultiplexer will use", statsdPipelinesCount, "pipelines")
 
 	statsdWorkers := make([]*timeSamplerWorker, statsdPipelinesCount)
+	// the context limits and the tag filters apply to all the pipelines together
+	limiter := newContextLimiterFromConfig()
+	tagFilters := newTagFilterListFromConfig()
 
 	for i := 0; i < statsdPipelinesCount; i++ {
 		// the sampler
 		tagsStore := tags.NewStore(config.Datadog.GetBool("aggregator_use_tags_store"), fmt.Sprintf("timesampler #%d", i))
-		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, agg.hostname)
+		statsdSampler := newTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, agg.hostname, limiter, tagFilters)
 
 		// its worker (process loop + flush/serialization mechanism)
 
@@ -202,7 +217,9 @@ func initAgentDemultiplexer(options AgentDemultiplexerOptions, hostname string)
 	var noAggWorker *noAggregationStreamWorker
 	var noAggSerializer serializer.MetricSerializer
 	if options.EnableNoAggregationPipeline {
-		noAggSerializer = serializer.NewSerializer(sharedForwarder, orchestratorForwarder)
+		s := serializer.NewSerializer(sharedForwarder, orchestratorForwarder)
+		s.SetRemoteWriteForwarder(remoteWriteForwarder)
+		noAggSerializer = s
 		noAggWorker = newNoAggregationStreamWorker(
 			config.Datadog.GetInt("dogstatsd_no_aggregation_pipeline_batch_size"),
 			noAggSerializer,
@@ -227,6 +244,7 @@ func initAgentDemultiplexer(options AgentDemultiplexerOptions, hostname string)
 				shared:        sharedForwarder,
 				orchestrator:  orchestratorForwarder,
 				eventPlatform: eventPlatformForwarder,
+				remoteWrite:   remoteWriteForwarder,
 			},
 
 			sharedSerializer: sharedSerializer,
@@ -239,6 +257,7 @@ func initAgentDemultiplexer(options AgentDemultiplexerOptions, hostname string)
 		statsd: statsd{
 			pipelinesCount:    statsdPipelinesCount,
 			workers:           statsdWorkers,
+			tagFilters:        tagFilters,
 			metricSamplePool:  metricSamplePool,
 			noAggStreamWorker: noAggWorker,
 		},
@@ -247,6 +266,33 @@ func initAgentDemultiplexer(options AgentDemultiplexerOptions, hostname string)
 	return demux
 }
 
+// buildFileForwarder returns the forwarder writing the transactions to local files, or the
+// DefaultForwarder if the offline storage cannot be created.
+func buildFileForwarder(options *forwarder.Options) forwarder.Forwarder {
+	f, err := forwarder.NewFileForwarder(options)
+	if err != nil {
+		log.Errorf("Offline mode disabled: %v", err)
+		return forwarder.NewDefaultForwarder(options)
+	}
+	return f
+}
+
+// buildRemoteWriteForwarder returns the forwarder sending to the Prometheus remote-write
+// endpoints, or nil if they are misconfigured.
+func buildRemoteWriteForwarder(options *forwarder.Options) *forwarder.RemoteWriteForwarder {
+	endpoints, err := config.GetRemoteWriteEndpoints()
+	if err != nil {
+		log.Errorf("Prometheus remote write disabled: %v", err)
+		return nil
+	}
+	f, err := forwarder.NewRemoteWriteForwarder(endpoints, options)
+	if err != nil {
+		log.Errorf("Prometheus remote write disabled: %v", err)
+		return nil
+	}
+	return f
+}
+
 // Options returns options used during the demux initialization.
 func (d *AgentDemultiplexer) Options() AgentDemultiplexerOptions {
 	return d.options
@@ -312,6 +358,15 @@ func (d *AgentDemultiplexer) Run() {
 		} else {
 			log.Debug("not starting the shared forwarder")
 		}
+
+		// Prometheus remote-write forwarder
+		if d.forwarders.remoteWrite != nil {
+			if err := d.forwarders.remoteWrite.Start(); err != nil {
+				log.Errorf("error starting the remote write forwarder: %v", err)
+			}
+		} else {
+			log.Debug("not starting the remote write forwarder")
+		}
 		log.Debug("Forwarders started")
 	}
 
@@ -414,13 +469,19 @@ func (d *AgentDemultiplexer) Stop(flush bool) {
 			d.dataOutputs.forwarders.shared.Stop()
 			d.dataOutputs.forwarders.shared = nil
 		}
+		if d.dataOutputs.forwarders.remoteWrite != nil {
+			d.dataOutputs.forwarders.remoteWrite.Stop()
+			d.dataOutputs.forwarders.remoteWrite = nil
+		}
 	}
 
 	// misc
 
 	d.dataOutputs.sharedSerializer = nil
 	d.senders = nil
+	demultiplexerInstanceMu.Lock()
 	demultiplexerInstance = nil
+	demultiplexerInstanceMu.Unlock()
 }
 
 // ForceFlushToSerializer triggers the execution of a flush from all data of samplers
@@ -597,3 +658,13 @@ func (d *AgentDemultiplexer) Aggregator() *BufferedAggregator {
 func (d *AgentDemultiplexer) GetMetricSamplePool() *metrics.MetricSamplePool {
 	return d.statsd.metricSamplePool
 }
+
+// SetTagFilterRules replaces the rules filtering the tags of the DogStatsD metrics.
+func (d *AgentDemultiplexer) SetTagFilterRules(rules []config.TagFilterRule) error {
+	return d.statsd.tagFilters.setRules(rules)
+}
+
+// GetTagFilterRules returns the rules filtering the tags of the DogStatsD metrics.
+func (d *AgentDemultiplexer) GetTagFilterRules() []config.TagFilterRule {
+	return d.statsd.tagFilters.getRules()
+}
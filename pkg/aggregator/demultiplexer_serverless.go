@@ -27,6 +27,7 @@ type ServerlessDemultiplexer struct {
 	forwarder     *forwarder.SyncForwarder
 	statsdSampler *TimeSampler
 	statsdWorker  *timeSamplerWorker
+	tagFilters    *tagFilterList
 
 	flushLock *sync.Mutex
 
@@ -43,7 +44,8 @@ func InitAndStartServerlessDemultiplexer(domainResolvers map[string]resolver.Dom
 	metricSamplePool := metrics.NewMetricSamplePool(MetricSamplePoolBatchSize)
 	tagsStore := tags.NewStore(config.Datadog.GetBool("aggregator_use_tags_store"), "timesampler")
 
-	statsdSampler := NewTimeSampler(TimeSamplerID(0), bucketSize, tagsStore, "")
+	tagFilters := newTagFilterListFromConfig()
+	statsdSampler := newTimeSampler(TimeSamplerID(0), bucketSize, tagsStore, "", newContextLimiterFromConfig(), tagFilters)
 	flushAndSerializeInParallel := NewFlushAndSerializeInParallel(config.Datadog)
 	statsdWorker := newTimeSamplerWorker(statsdSampler, DefaultFlushInterval, bufferSize, metricSamplePool, flushAndSerializeInParallel, tagsStore)
 
@@ -51,6 +53,7 @@ func InitAndStartServerlessDemultiplexer(domainResolvers map[string]resolver.Dom
 		forwarder:        forwarder,
 		statsdSampler:    statsdSampler,
 		statsdWorker:     statsdWorker,
+		tagFilters:       tagFilters,
 		serializer:       serializer,
 		metricSamplePool: metricSamplePool,
 		flushLock:        &sync.Mutex{},
@@ -59,7 +62,9 @@ func InitAndStartServerlessDemultiplexer(domainResolvers map[string]resolver.Dom
 	}
 
 	// set the global instance
+	demultiplexerInstanceMu.Lock()
 	demultiplexerInstance = demux
+	demultiplexerInstanceMu.Unlock()
 
 	// start routines
 	go demux.Run()
@@ -165,3 +170,13 @@ func (d *ServerlessDemultiplexer) Serializer() serializer.MetricSerializer {
 func (d *ServerlessDemultiplexer) GetMetricSamplePool() *metrics.MetricSamplePool {
 	return d.metricSamplePool
 }
+
+// SetTagFilterRules replaces the rules filtering the tags of the DogStatsD metrics.
+func (d *ServerlessDemultiplexer) SetTagFilterRules(rules []config.TagFilterRule) error {
+	return d.tagFilters.setRules(rules)
+}
+
+// GetTagFilterRules returns the rules filtering the tags of the DogStatsD metrics.
+func (d *ServerlessDemultiplexer) GetTagFilterRules() []config.TagFilterRule {
+	return d.tagFilters.getRules()
+}
@@ -175,6 +175,30 @@ func GetDefaultSender() (Sender, error) {
 	return demultiplexerInstance.GetDefaultSender()
 }
 
+// AggregateSamples sends samples to the first DogStatsD time sampler of the demultiplexer,
+// in batches taken from its metric sample pool.
+// It is used by the components generating metrics outside of the checks and DogStatsD,
+// like the logs processing rules, which should not call it for every sample.
+func AggregateSamples(samples []metrics.MetricSample) error {
+	demultiplexerInstanceMu.Lock()
+	demux := demultiplexerInstance
+	demultiplexerInstanceMu.Unlock()
+
+	if demux == nil {
+		return errors.New("Demultiplexer was not initialized")
+	}
+	for len(samples) > 0 {
+		batch := demux.GetMetricSamplePool().GetBatch()
+		if len(batch) == 0 {
+			batch = make(metrics.MetricSampleBatch, len(samples))
+		}
+		n := copy(batch, samples)
+		demux.AggregateSamples(TimeSamplerID(0), batch[:n])
+		samples = samples[n:]
+	}
+	return nil
+}
+
 // DisableDefaultHostname allows check to override the default hostname that will be injected
 // when no hostname is specified at submission (for metrics, events and service checks).
 func (s *checkSender) DisableDefaultHostname(disable bool) {
@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// tagFilterActionInclude keeps only the listed tags
+	tagFilterActionInclude = "include"
+	// tagFilterActionExclude removes the listed tags
+	tagFilterActionExclude = "exclude"
+)
+
+// tagFilter is a compiled config.TagFilterRule.
+type tagFilter struct {
+	pattern string
+	include bool
+	keys    map[string]struct{}
+}
+
+func newTagFilter(rule config.TagFilterRule) (tagFilter, error) {
+	if rule.MetricName == "" {
+		return tagFilter{}, fmt.Errorf("missing metric_name")
+	}
+	// path.Match only reports malformed patterns when matching
+	if _, err := path.Match(rule.MetricName, ""); err != nil {
+		return tagFilter{}, fmt.Errorf("invalid metric_name %q: %v", rule.MetricName, err)
+	}
+
+	f := tagFilter{
+		pattern: rule.MetricName,
+		keys:    make(map[string]struct{}, len(rule.Tags)),
+	}
+	switch rule.Action {
+	case tagFilterActionInclude:
+		f.include = true
+	case tagFilterActionExclude:
+	default:
+		return tagFilter{}, fmt.Errorf("invalid action %q for metric_name %q, must be %q or %q", rule.Action, rule.MetricName, tagFilterActionInclude, tagFilterActionExclude)
+	}
+	for _, key := range rule.Tags {
+		f.keys[key] = struct{}{}
+	}
+	return f, nil
+}
+
+// match returns whether the filter applies to the metric name.
+func (f *tagFilter) match(metricName string) bool {
+	matched, _ := path.Match(f.pattern, metricName)
+	return matched
+}
+
+// apply filters the tags in place, keeping their hashes. Tags are filtered
+// by key, a tag without value being its own key.
+func (f *tagFilter) apply(tags *tagset.HashingTagsAccumulator) {
+	data, hashes := tags.Get(), tags.Hashes()
+	n := 0
+	for i, tag := range data {
+		key := tag
+		if idx := strings.IndexByte(tag, ':'); idx >= 0 {
+			key = tag[:idx]
+		}
+		if _, listed := f.keys[key]; listed == f.include {
+			data[n] = tag
+			hashes[n] = hashes[i]
+			n++
+		}
+	}
+	tags.Truncate(n)
+}
+
+// tagFilterList filters the tags of the metrics with the first of its rules
+// matching the metric name. The rules can be replaced at runtime, the list
+// being shared by all the DogStatsD time samplers.
+type tagFilterList struct {
+	mu      sync.RWMutex
+	rules   []config.TagFilterRule
+	filters []tagFilter
+}
+
+func newTagFilterList() *tagFilterList {
+	return &tagFilterList{}
+}
+
+// newTagFilterListFromConfig returns the tag filter list configured for DogStatsD. The
+// tags aren't filtered if the configured rules are invalid.
+func newTagFilterListFromConfig() *tagFilterList {
+	l := newTagFilterList()
+	rules, err := config.GetDogstatsdTagFilterRules()
+	if err != nil {
+		// already logged
+		return l
+	}
+	if err := l.setRules(rules); err != nil {
+		log.Errorf("Invalid dogstatsd_tag_filters, the DogStatsD tags won't be filtered: %v", err)
+	}
+	return l
+}
+
+// setRules validates and compiles rules, replacing the current ones. The
+// current rules are left untouched if any of rules is invalid.
+func (l *tagFilterList) setRules(rules []config.TagFilterRule) error {
+	filters := make([]tagFilter, 0, len(rules))
+	for _, rule := range rules {
+		f, err := newTagFilter(rule)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, f)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rules = rules
+	l.filters = filters
+	return nil
+}
+
+// getRules returns the current rules.
+func (l *tagFilterList) getRules() []config.TagFilterRule {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.rules
+}
+
+// filter filters in place the tagger and metric tags of a metric with the
+// first rule matching its name. The tags are left as is when no rule matches.
+func (l *tagFilterList) filter(metricName string, taggerTags, metricTags *tagset.HashingTagsAccumulator) {
+	if l == nil {
+		return
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for i := range l.filters {
+		if l.filters[i].match(metricName) {
+			l.filters[i].apply(taggerTags)
+			l.filters[i].apply(metricTags)
+			return
+		}
+	}
+}
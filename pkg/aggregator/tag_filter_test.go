@@ -0,0 +1,135 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func newTestTagFilterList(t *testing.T, rules ...config.TagFilterRule) *tagFilterList {
+	l := newTagFilterList()
+	require.NoError(t, l.setRules(rules))
+	return l
+}
+
+// filterMetricTags filters tags as metric tags and returns the tags kept, checking their hashes.
+func filterMetricTags(t *testing.T, l *tagFilterList, metricName string, tags []string) []string {
+	metricTags := tagset.NewHashingTagsAccumulatorWithTags(tags)
+	l.filter(metricName, tagset.NewHashingTagsAccumulator(), metricTags)
+	assert.Equal(t, tagset.NewHashingTagsAccumulatorWithTags(metricTags.Get()).Hashes(), metricTags.Hashes())
+	return metricTags.Get()
+}
+
+func TestTagFilterList(t *testing.T) {
+	l := newTestTagFilterList(t,
+		config.TagFilterRule{MetricName: "http.request.*", Action: "include", Tags: []string{"service", "env", "route"}},
+		config.TagFilterRule{MetricName: "kafka.*", Action: "exclude", Tags: []string{"pod_name", "debug"}},
+		config.TagFilterRule{MetricName: "kafka.consumer.lag", Action: "exclude", Tags: []string{"partition"}},
+	)
+
+	for _, tc := range []struct {
+		name       string
+		metricName string
+		tags       []string
+		expected   []string
+	}{
+		{
+			name:       "include",
+			metricName: "http.request.duration",
+			tags:       []string{"service:web", "env:prod", "route:/users", "user_id:42", "canary"},
+			expected:   []string{"service:web", "env:prod", "route:/users"},
+		},
+		{
+			name:       "exclude",
+			metricName: "kafka.consumer.lag",
+			tags:       []string{"topic:orders", "pod_name:consumer-8f7d", "partition:3", "debug"},
+			expected:   []string{"topic:orders", "partition:3"},
+		},
+		{
+			name:       "no match",
+			metricName: "http.response",
+			tags:       []string{"service:web", "user_id:42"},
+			expected:   []string{"service:web", "user_id:42"},
+		},
+		{
+			name:       "no tags",
+			metricName: "http.request.duration",
+			tags:       []string{},
+			expected:   []string{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, filterMetricTags(t, l, tc.metricName, tc.tags))
+		})
+	}
+}
+
+func TestTagFilterListNil(t *testing.T) {
+	var l *tagFilterList
+	assert.Equal(t, []string{"a:b"}, filterMetricTags(t, l, "metric", []string{"a:b"}))
+}
+
+func TestTagFilterListSetRules(t *testing.T) {
+	rules := []config.TagFilterRule{
+		{MetricName: "kafka.*", Action: "exclude", Tags: []string{"pod_name"}},
+	}
+	l := newTestTagFilterList(t, rules...)
+
+	for _, invalid := range []config.TagFilterRule{
+		{Action: "exclude", Tags: []string{"pod_name"}},
+		{MetricName: "kafka.[", Action: "exclude", Tags: []string{"pod_name"}},
+		{MetricName: "kafka.*", Action: "drop", Tags: []string{"pod_name"}},
+	} {
+		assert.Error(t, l.setRules([]config.TagFilterRule{invalid}))
+	}
+	// the rules are left untouched on error
+	assert.Equal(t, rules, l.getRules())
+	assert.Equal(t, []string{"topic:orders"}, filterMetricTags(t, l, "kafka.lag", []string{"topic:orders", "pod_name:a"}))
+
+	// the rules can be replaced
+	require.NoError(t, l.setRules(nil))
+	assert.Empty(t, l.getRules())
+	assert.Equal(t, []string{"topic:orders", "pod_name:a"}, filterMetricTags(t, l, "kafka.lag", []string{"topic:orders", "pod_name:a"}))
+}
+
+func testTagFiltersOriginTags(t *testing.T, store *tags.Store) {
+	r := newContextResolver(store)
+	r.tagFilters = newTestTagFilterList(t,
+		config.TagFilterRule{MetricName: "kafka.*", Action: "exclude", Tags: []string{"pod_name"}},
+		config.TagFilterRule{MetricName: "http.*", Action: "include", Tags: []string{"service", "kube_namespace"}},
+	)
+
+	// the tags detected from the origin of the metrics are filtered as well
+	k1 := r.trackContext(&mockSample{"kafka.consumer.lag", []string{"kube_namespace:kafka", "pod_name:consumer-8f7d"}, []string{"topic:orders"}})
+	k2 := r.trackContext(&mockSample{"kafka.consumer.lag", []string{"kube_namespace:kafka", "pod_name:consumer-5b2c"}, []string{"topic:orders"}})
+	assert.Equal(t, k1, k2)
+	cx, ok := r.get(k1)
+	require.True(t, ok)
+	assertContext(t, cx, "kafka.consumer.lag", []string{"kube_namespace:kafka", "topic:orders"}, "noop")
+
+	cx, ok = r.get(r.trackContext(&mockSample{"http.latency", []string{"kube_namespace:web", "pod_name:web-1"}, []string{"service:web", "user_id:42"}}))
+	require.True(t, ok)
+	assertContext(t, cx, "http.latency", []string{"kube_namespace:web", "service:web"}, "noop")
+
+	// the tags of the metrics matching no rule are kept
+	cx, ok = r.get(r.trackContext(&mockSample{"redis.hits", []string{"pod_name:redis-0"}, []string{"db:0"}}))
+	require.True(t, ok)
+	assertContext(t, cx, "redis.hits", []string{"pod_name:redis-0", "db:0"}, "noop")
+}
+
+func TestTagFiltersOriginTags(t *testing.T) {
+	testWithTagsStore(t, testTagFiltersOriginTags)
+}
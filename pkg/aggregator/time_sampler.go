@@ -41,8 +41,16 @@ type TimeSampler struct {
 	hostname string
 }
 
-// NewTimeSampler returns a newly initialized TimeSampler
+// NewTimeSampler returns a newly initialized TimeSampler, limiting its contexts and filtering
+// their tags as configured
 func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, hostname string) *TimeSampler {
+	return newTimeSampler(id, interval, cache, hostname, newContextLimiterFromConfig(), newTagFilterListFromConfig())
+}
+
+// newTimeSampler returns a newly initialized TimeSampler, limiting its contexts with the
+// given limiter and filtering their tags with the given tag filters, which can both be
+// shared with other TimeSamplers, or nil.
+func newTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, hostname string, limiter *contextLimiter, tagFilters *tagFilterList) *TimeSampler {
 	if interval == 0 {
 		interval = bucketSize
 	}
@@ -51,7 +59,7 @@ func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, hostnam
 
 	s := &TimeSampler{
 		interval:                    interval,
-		contextResolver:             newTimestampContextResolver(cache),
+		contextResolver:             newTimestampContextResolver(cache, limiter, tagFilters),
 		metricsByTimestamp:          map[int64]metrics.ContextMetrics{},
 		counterLastSampledByContext: map[ckey.ContextKey]float64{},
 		sketchMap:                   make(sketchMap),
@@ -78,6 +86,10 @@ func (s *TimeSampler) sample(metricSample *metrics.MetricSample, timestamp float
 
 	// Keep track of the context
 	contextKey := s.contextResolver.trackContext(metricSample, timestamp)
+	if contextKey.IsZero() {
+		// dropped by the context limiter
+		return
+	}
 	bucketStart := s.calculateBucketStart(timestamp)
 
 	switch metricSample.Mtype {
@@ -0,0 +1,219 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package executable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	nagiosOutputFormat = "nagios"
+	jsonOutputFormat   = "json"
+
+	// defaultTimeout is the default timeout of the Nagios plugins, in seconds
+	defaultTimeout = 10
+)
+
+type checkConfig struct {
+	Command          []string `yaml:"command"`
+	Timeout          int      `yaml:"timeout"`
+	OutputFormat     string   `yaml:"output_format"`
+	ServiceCheckName string   `yaml:"service_check_name"`
+	MetricPrefix     string   `yaml:"metric_prefix"`
+}
+
+// parse reads the configuration from init_config, overridden by the instance configuration
+func (c *checkConfig) parse(checkName string, instance integration.Data, initConfig integration.Data) error {
+	if err := yaml.Unmarshal(initConfig, c); err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(instance, c); err != nil {
+		return err
+	}
+
+	if len(c.Command) == 0 || c.Command[0] == "" {
+		return errors.New("instance config `command` must not be empty")
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	switch c.OutputFormat {
+	case "":
+		c.OutputFormat = nagiosOutputFormat
+	case nagiosOutputFormat, jsonOutputFormat:
+	default:
+		return fmt.Errorf("invalid output format '%s', expected '%s' or '%s'", c.OutputFormat, nagiosOutputFormat, jsonOutputFormat)
+	}
+	if c.ServiceCheckName == "" {
+		c.ServiceCheckName = checkName
+	}
+	if c.MetricPrefix == "" {
+		c.MetricPrefix = checkName
+	}
+	return nil
+}
+
+// ExecutableCheck runs an executable, such as a Nagios plugin, and submits its results
+type ExecutableCheck struct {
+	core.CheckBase
+	config checkConfig
+}
+
+func newExecutableCheck(name string) *ExecutableCheck {
+	return &ExecutableCheck{
+		CheckBase: core.NewCheckBase(name),
+	}
+}
+
+// Configure configures the executable check
+func (c *ExecutableCheck) Configure(integrationConfigDigest uint64, data integration.Data, initConfig integration.Data, source string) error {
+	// Make sure check id is different for each different config
+	// Must be called before CommonConfigure that uses checkID
+	c.BuildID(integrationConfigDigest, data, initConfig)
+
+	if err := c.CommonConfigure(integrationConfigDigest, initConfig, data, source); err != nil {
+		return err
+	}
+	return c.config.parse(c.String(), data, initConfig)
+}
+
+// Run runs the executable and submits the service check matching its exit code, along with the
+// metrics and events found in its output
+func (c *ExecutableCheck) Run() error {
+	sender, err := c.GetSender()
+	if err != nil {
+		return err
+	}
+	defer sender.Commit()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.config.Timeout)*time.Second)
+	defer cancel()
+
+	stdout, stderr, exitCode, err := runCommand(ctx, c.config.Command)
+	if len(stderr) > 0 {
+		log.Debugf("%s: '%s' stderr: %s", c.ID(), c.config.Command[0], stderr)
+	}
+	if err != nil {
+		sender.ServiceCheck(c.config.ServiceCheckName, metrics.ServiceCheckUnknown, "", nil, err.Error())
+		return err
+	}
+
+	status := exitCodeStatus(exitCode)
+	if c.config.OutputFormat == jsonOutputFormat {
+		return c.submitJSONOutput(sender, status, stdout)
+	}
+	c.submitNagiosOutput(sender, status, stdout, stderr)
+	return nil
+}
+
+// exitCodeStatus maps the exit code of a Nagios plugin to a service check status
+func exitCodeStatus(exitCode int) metrics.ServiceCheckStatus {
+	status, err := metrics.GetServiceCheckStatus(exitCode)
+	if err != nil {
+		return metrics.ServiceCheckUnknown
+	}
+	return status
+}
+
+func (c *ExecutableCheck) submitNagiosOutput(sender aggregator.Sender, status metrics.ServiceCheckStatus, stdout []byte, stderr []byte) {
+	text, rawPerfData := parseNagiosOutput(string(stdout))
+	if text == "" {
+		// plugins failing early may only write to stderr
+		text = strings.TrimSpace(string(stderr))
+	}
+
+	values, errs := parsePerfData(rawPerfData)
+	for _, err := range errs {
+		_ = c.Warnf("%s: %v", c.ID(), err)
+	}
+	for _, value := range values {
+		name := perfDataMetricName(c.config.MetricPrefix, value)
+		if name == "" {
+			_ = c.Warnf("%s: cannot build a metric name from the performance data label '%s'", c.ID(), value.label)
+			continue
+		}
+		v, _ := value.baseValue()
+		if value.isCounter() {
+			sender.MonotonicCount(name, v, "", nil)
+		} else {
+			sender.Gauge(name, v, "", nil)
+		}
+	}
+
+	sender.ServiceCheck(c.config.ServiceCheckName, status, "", nil, text)
+}
+
+func (c *ExecutableCheck) submitJSONOutput(sender aggregator.Sender, status metrics.ServiceCheckStatus, stdout []byte) error {
+	output, err := parseJSONOutput(stdout)
+	if err != nil {
+		sender.ServiceCheck(c.config.ServiceCheckName, metrics.ServiceCheckUnknown, "", nil, err.Error())
+		return err
+	}
+
+	for _, metric := range output.Metrics {
+		if metric.Name == "" {
+			_ = c.Warnf("%s: ignoring a metric without name", c.ID())
+			continue
+		}
+		tags := append(append([]string{}, output.Tags...), metric.Tags...)
+		switch metric.Type {
+		case "", "gauge":
+			sender.Gauge(metric.Name, metric.Value, "", tags)
+		case "count":
+			sender.Count(metric.Name, metric.Value, "", tags)
+		case "rate":
+			sender.Rate(metric.Name, metric.Value, "", tags)
+		case "monotonic_count":
+			sender.MonotonicCount(metric.Name, metric.Value, "", tags)
+		case "histogram":
+			sender.Histogram(metric.Name, metric.Value, "", tags)
+		default:
+			_ = c.Warnf("%s: ignoring the metric '%s' of unknown type '%s'", c.ID(), metric.Name, metric.Type)
+		}
+	}
+
+	for _, jsonEvent := range output.Events {
+		event := metrics.Event{
+			Title:          jsonEvent.Title,
+			Text:           jsonEvent.Text,
+			Ts:             jsonEvent.Timestamp,
+			Priority:       metrics.EventPriorityNormal,
+			AlertType:      metrics.EventAlertTypeInfo,
+			AggregationKey: jsonEvent.AggregationKey,
+			Tags:           append(append([]string{}, output.Tags...), jsonEvent.Tags...),
+		}
+		if event.Ts == 0 {
+			event.Ts = time.Now().Unix()
+		}
+		if jsonEvent.Priority != "" {
+			if event.Priority, err = metrics.GetEventPriorityFromString(jsonEvent.Priority); err != nil {
+				_ = c.Warnf("%s: %v", c.ID(), err)
+				event.Priority = metrics.EventPriorityNormal
+			}
+		}
+		if jsonEvent.AlertType != "" {
+			if event.AlertType, err = metrics.GetAlertTypeFromString(jsonEvent.AlertType); err != nil {
+				_ = c.Warnf("%s: %v", c.ID(), err)
+			}
+		}
+		sender.Event(event)
+	}
+
+	sender.ServiceCheck(c.config.ServiceCheckName, status, "", output.Tags, output.Message)
+	return nil
+}
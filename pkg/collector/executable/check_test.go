@@ -0,0 +1,149 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+// +build !windows
+
+package executable
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/providers/names"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func writeScript(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "plugin.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+content), 0700))
+	return path
+}
+
+func newTestCheck(t *testing.T, instance string) (*ExecutableCheck, *mocksender.MockSender) {
+	c := newExecutableCheck("check_test")
+	require.NoError(t, c.Configure(integration.FakeConfigHash, []byte(instance), []byte(""), "test"))
+
+	sender := mocksender.NewMockSender(c.ID())
+	sender.SetupAcceptAll()
+	return c, sender
+}
+
+func TestConfigure(t *testing.T) {
+	c := newExecutableCheck("check_test")
+	require.NoError(t, c.Configure(integration.FakeConfigHash, []byte("command: [/bin/true]"), []byte("timeout: 5"), "test"))
+	assert.Equal(t, checkConfig{
+		Command:          []string{"/bin/true"},
+		Timeout:          5,
+		OutputFormat:     nagiosOutputFormat,
+		ServiceCheckName: "check_test",
+		MetricPrefix:     "check_test",
+	}, c.config)
+
+	c = newExecutableCheck("check_test")
+	assert.Error(t, c.Configure(integration.FakeConfigHash, []byte("command: []"), []byte(""), "test"))
+	c = newExecutableCheck("check_test")
+	assert.Error(t, c.Configure(integration.FakeConfigHash, []byte("command: [/bin/true]\noutput_format: xml"), []byte(""), "test"))
+}
+
+func TestRunNagios(t *testing.T) {
+	for _, tc := range []struct {
+		exitCode int
+		status   metrics.ServiceCheckStatus
+	}{
+		{0, metrics.ServiceCheckOK},
+		{1, metrics.ServiceCheckWarning},
+		{2, metrics.ServiceCheckCritical},
+		{3, metrics.ServiceCheckUnknown},
+		{42, metrics.ServiceCheckUnknown},
+	} {
+		script := writeScript(t, "echo 'DISK OK | /boot=68MB;88;93;0;98 '\\''free space'\\''=56%'\nexit "+strconv.Itoa(tc.exitCode)+"\n")
+		c, sender := newTestCheck(t, "command: ["+script+"]\nservice_check_name: disk.status\nmetric_prefix: disk")
+
+		require.NoError(t, c.Run())
+		sender.AssertServiceCheck(t, "disk.status", tc.status, "", nil, "DISK OK")
+		sender.AssertMetric(t, "Gauge", "disk.boot", 68<<20, "", nil)
+		sender.AssertMetric(t, "Gauge", "disk.free_space", 56, "", nil)
+		sender.AssertNumberOfCalls(t, "Commit", 1)
+	}
+}
+
+func TestRunNagiosUnits(t *testing.T) {
+	script := writeScript(t, "echo 'HTTP OK | time=250ms size=2KB requests=1500c temp=21degC'\n")
+	c, sender := newTestCheck(t, "command: ["+script+"]\nmetric_prefix: http")
+
+	require.NoError(t, c.Run())
+	sender.AssertMetric(t, "Gauge", "http.time", 0.25, "", nil)
+	sender.AssertMetric(t, "Gauge", "http.size", 2048, "", nil)
+	sender.AssertMetric(t, "MonotonicCount", "http.requests", 1500, "", nil)
+	sender.AssertNotCalled(t, "Gauge", "http.requests", mock.Anything, mock.Anything, mock.Anything)
+	sender.AssertMetric(t, "Gauge", "http.temp_degc", 21, "", nil)
+}
+
+func TestRunTimeout(t *testing.T) {
+	script := writeScript(t, "sleep 30\n")
+	c, sender := newTestCheck(t, "command: ["+script+"]")
+	c.config.Timeout = 1
+
+	assert.Error(t, c.Run())
+	sender.AssertCalled(t, "ServiceCheck", "check_test", metrics.ServiceCheckUnknown, "", []string(nil), mock.AnythingOfType("string"))
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+}
+
+func TestRunJSON(t *testing.T) {
+	script := writeScript(t, `echo '{"message": "2 jobs failed", "tags": ["queue:default"], "metrics": [{"name": "jobs.failed", "value": 2, "type": "count", "tags": ["job:backup"]}, {"name": "jobs.queued", "value": 7}], "events": [{"title": "Backup failed", "text": "disk full", "alert_type": "error", "timestamp": 1700000000}]}'
+exit 2
+`)
+	c, sender := newTestCheck(t, "command: ["+script+"]\noutput_format: json")
+
+	require.NoError(t, c.Run())
+	sender.AssertServiceCheck(t, "check_test", metrics.ServiceCheckCritical, "", []string{"queue:default"}, "2 jobs failed")
+	sender.AssertMetric(t, "Count", "jobs.failed", 2, "", []string{"queue:default", "job:backup"})
+	sender.AssertMetric(t, "Gauge", "jobs.queued", 7, "", []string{"queue:default"})
+	sender.AssertEvent(t, metrics.Event{
+		Title:     "Backup failed",
+		Text:      "disk full",
+		Ts:        1700000000,
+		Priority:  metrics.EventPriorityNormal,
+		AlertType: metrics.EventAlertTypeError,
+		Tags:      []string{"queue:default"},
+	}, 0)
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+}
+
+func TestRunInvalidJSON(t *testing.T) {
+	script := writeScript(t, "echo 'OK | time=1s'\n")
+	c, sender := newTestCheck(t, "command: ["+script+"]\noutput_format: json")
+
+	assert.Error(t, c.Run())
+	sender.AssertCalled(t, "ServiceCheck", "check_test", metrics.ServiceCheckUnknown, "", []string(nil), mock.AnythingOfType("string"))
+}
+
+func TestLoad(t *testing.T) {
+	loader, err := NewExecutableCheckLoader()
+	require.NoError(t, err)
+	script := writeScript(t, "echo OK\n")
+
+	config := integration.Config{Name: "check_test", Provider: names.File}
+	c, err := loader.Load(config, []byte("command: ["+script+"]"))
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(15)*time.Second, c.Interval())
+
+	_, err = loader.Load(config, []byte("host: localhost"))
+	assert.Error(t, err)
+
+	config.Provider = names.Container
+	_, err = loader.Load(config, []byte("command: ["+script+"]"))
+	assert.Error(t, err)
+}
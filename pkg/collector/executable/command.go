@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package executable
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// maxOutputSize is the maximum size of the standard output and of the standard error of a command
+const maxOutputSize = 1024 * 1024
+
+type limitBuffer struct {
+	max      int
+	buf      *bytes.Buffer
+	exceeded bool
+}
+
+func (b *limitBuffer) Write(p []byte) (n int, err error) {
+	if len(p)+b.buf.Len() > b.max {
+		b.exceeded = true
+		return 0, fmt.Errorf("command output was too long: exceeded %d bytes", b.max)
+	}
+	return b.buf.Write(p)
+}
+
+// runCommand runs the command until it exits or the context is done, in which case the command
+// and the processes it started are killed. A non-zero exit code is not an error.
+func runCommand(ctx context.Context, command []string) (stdout []byte, stderr []byte, exitCode int, err error) {
+	if len(command) == 0 {
+		return nil, nil, 0, errors.New("empty command")
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	stdoutBuf := limitBuffer{buf: &bytes.Buffer{}, max: maxOutputSize}
+	stderrBuf := limitBuffer{buf: &bytes.Buffer{}, max: maxOutputSize}
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+
+	if err := startCommand(cmd); err != nil {
+		return nil, nil, 0, fmt.Errorf("cannot start '%s': %v", command[0], err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		killCommand(cmd)
+		<-done
+		return nil, stderrBuf.buf.Bytes(), 0, fmt.Errorf("'%s' did not complete: %v", command[0], ctx.Err())
+	}
+
+	// the command may exit on the write error, with an exit code hiding the error
+	if stdoutBuf.exceeded || stderrBuf.exceeded {
+		return nil, nil, 0, fmt.Errorf("the output of '%s' exceeded %d bytes", command[0], maxOutputSize)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return stdoutBuf.buf.Bytes(), stderrBuf.buf.Bytes(), exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return nil, stderrBuf.buf.Bytes(), 0, fmt.Errorf("error while running '%s': %v", command[0], err)
+	}
+	return stdoutBuf.buf.Bytes(), stderrBuf.buf.Bytes(), 0, nil
+}
@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+// +build !windows
+
+package executable
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCommand(t *testing.T) {
+	stdout, stderr, exitCode, err := runCommand(context.Background(), []string{"sh", "-c", "echo out; echo err >&2; exit 2"})
+	require.NoError(t, err)
+	assert.Equal(t, "out\n", string(stdout))
+	assert.Equal(t, "err\n", string(stderr))
+	assert.Equal(t, 2, exitCode)
+
+	_, _, _, err = runCommand(context.Background(), []string{"/does/not/exist"})
+	assert.Error(t, err)
+
+	_, _, _, err = runCommand(context.Background(), []string{"sh", "-c", "head -c 2000000 /dev/zero"})
+	assert.Error(t, err)
+}
+
+func TestRunCommandTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	// the sleep started by the shell keeps the output open until it's killed as well
+	_, _, _, err := runCommand(ctx, []string{"sh", "-c", "sleep 30; echo done"})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
@@ -0,0 +1,21 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build windows
+// +build windows
+
+package executable
+
+import (
+	"os/exec"
+)
+
+func startCommand(cmd *exec.Cmd) error {
+	return cmd.Start()
+}
+
+func killCommand(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package executable implements a check loader running executables, such as Nagios plugins,
+// whose exit code is submitted as a service check and whose output contains metrics.
+package executable
+
+import (
+	"errors"
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/providers/names"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/loaders"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// ExecutableCheckLoader is a specific loader for the checks running an executable
+type ExecutableCheckLoader struct{}
+
+// NewExecutableCheckLoader creates a loader for executable checks
+func NewExecutableCheckLoader() (*ExecutableCheckLoader, error) {
+	return &ExecutableCheckLoader{}, nil
+}
+
+// Name returns the executable loader name
+func (el *ExecutableCheckLoader) Name() string {
+	return "executable"
+}
+
+// Load returns an executable check
+func (el *ExecutableCheckLoader) Load(config integration.Config, instance integration.Data) (check.Check, error) {
+	var c check.Check
+
+	if !isExecutableInstance(instance) {
+		return c, errors.New("check is not an executable check: the instance has no `command`")
+	}
+	// the commands are only run from the configuration files of the host, never from the
+	// configurations found in container labels or in the orchestrator
+	if config.Provider != names.File {
+		return c, fmt.Errorf("executable checks can only be configured in files, not by the %s provider", config.Provider)
+	}
+
+	c = newExecutableCheck(config.Name)
+	if err := c.Configure(config.FastDigest(), instance, config.InitConfig, config.Source); err != nil {
+		log.Errorf("executable.loader: could not configure check %s: %s", c, err)
+		return c, fmt.Errorf("Could not configure check %s: %s", c, err)
+	}
+
+	return c, nil
+}
+
+func (el *ExecutableCheckLoader) String() string {
+	return "Executable Check Loader"
+}
+
+func isExecutableInstance(instance integration.Data) bool {
+	var rawInstance map[string]interface{}
+	if err := yaml.Unmarshal(instance, &rawInstance); err != nil {
+		return false
+	}
+	_, found := rawInstance["command"]
+	return found
+}
+
+func init() {
+	factory := func() (check.Loader, error) {
+		return NewExecutableCheckLoader()
+	}
+
+	loaders.RegisterLoader(40, factory)
+}
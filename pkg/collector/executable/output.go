@@ -0,0 +1,219 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package executable
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// perfData is a value of the performance data of a Nagios plugin
+type perfData struct {
+	label string
+	value float64
+	unit  string
+}
+
+// counterUnit is the unit of measure of the continuous counters
+const counterUnit = "c"
+
+var (
+	perfDataValuePattern = regexp.MustCompile(`^([-+]?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][-+]?[0-9]+)?)([a-zA-Z%]*)$`)
+	invalidMetricChars   = regexp.MustCompile(`[^a-z0-9_.]+`)
+
+	// perfDataUnitFactors converts the values of the known units of measure, in lower case,
+	// to their base unit: seconds for the times and bytes for the sizes.
+	perfDataUnitFactors = map[string]float64{
+		"":          1,
+		"%":         1,
+		counterUnit: 1,
+		"s":         1,
+		"ms":        1e-3,
+		"us":        1e-6,
+		"b":         1,
+		"kb":        1 << 10,
+		"mb":        1 << 20,
+		"gb":        1 << 30,
+		"tb":        1 << 40,
+	}
+)
+
+// baseValue returns the value converted to the base unit of its unit of measure, and
+// whether the unit of measure is known. The values of unknown units are left as is.
+func (p perfData) baseValue() (float64, bool) {
+	factor, known := perfDataUnitFactors[strings.ToLower(p.unit)]
+	if !known {
+		return p.value, false
+	}
+	return p.value * factor, true
+}
+
+// isCounter returns whether the value is a continuous counter.
+func (p perfData) isCounter() bool {
+	return p.unit == counterUnit
+}
+
+// parseNagiosOutput splits the output of a Nagios plugin into its text and its performance data.
+// The performance data follow a '|' on the first line and on one of the long text lines, in which
+// case all the lines that follow are performance data:
+//
+//	TEXT OUTPUT | OPTIONAL PERFDATA
+//	LONG TEXT LINE 1
+//	LONG TEXT LINE 2 | PERFDATA LINE 2
+//	PERFDATA LINE 3
+func parseNagiosOutput(output string) (text string, perfData string) {
+	var texts, perfDatas []string
+	inPerfData := false
+	for i, line := range strings.Split(strings.TrimRight(output, "\r\n"), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if inPerfData {
+			perfDatas = append(perfDatas, line)
+			continue
+		}
+		lineText, linePerfData, found := strings.Cut(line, "|")
+		texts = append(texts, strings.TrimSpace(lineText))
+		if found {
+			perfDatas = append(perfDatas, linePerfData)
+			// only the performance data of the first line is followed by more text
+			inPerfData = i > 0
+		}
+	}
+	return strings.TrimSpace(strings.Join(texts, "\n")), strings.Join(perfDatas, " ")
+}
+
+// parsePerfData parses the performance data of a Nagios plugin, made of space separated
+// 'label'=value[UOM];[warn];[crit];[min];[max] values. The undetermined values ('U') are
+// ignored and the invalid values are returned as errors.
+func parsePerfData(s string) ([]perfData, []error) {
+	var values []perfData
+	var errs []error
+	for {
+		s = strings.TrimLeft(s, " \t\r\n")
+		if s == "" {
+			return values, errs
+		}
+
+		var label string
+		if s[0] == '\'' {
+			// quoted label, where '' is an escaped quote
+			var b strings.Builder
+			i := 1
+			for ; i < len(s); i++ {
+				if s[i] == '\'' {
+					if i+1 < len(s) && s[i+1] == '\'' {
+						b.WriteByte('\'')
+						i++
+						continue
+					}
+					break
+				}
+				b.WriteByte(s[i])
+			}
+			label = b.String()
+			s = strings.TrimPrefix(s[i:], "'")
+			if !strings.HasPrefix(s, "=") {
+				errs = append(errs, fmt.Errorf("invalid performance data for '%s': missing value", label))
+				s = skipField(s)
+				continue
+			}
+			s = s[1:]
+		} else {
+			end := strings.IndexAny(s, "= \t\r\n")
+			if end == -1 || s[end] != '=' {
+				errs = append(errs, fmt.Errorf("invalid performance data '%s': missing value", firstField(s)))
+				s = skipField(s)
+				continue
+			}
+			label, s = s[:end], s[end+1:]
+		}
+
+		field := firstField(s)
+		s = s[len(field):]
+		valueWithUnit, _, _ := strings.Cut(field, ";")
+		if valueWithUnit == "U" {
+			continue
+		}
+		// some plugins use the decimal separator of their locale
+		match := perfDataValuePattern.FindStringSubmatch(strings.Replace(valueWithUnit, ",", ".", 1))
+		if match == nil {
+			errs = append(errs, fmt.Errorf("invalid performance data value for '%s': '%s'", label, valueWithUnit))
+			continue
+		}
+		value, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid performance data value for '%s': %v", label, err))
+			continue
+		}
+		values = append(values, perfData{label: label, value: value, unit: match[2]})
+	}
+}
+
+func firstField(s string) string {
+	if end := strings.IndexAny(s, " \t\r\n"); end != -1 {
+		return s[:end]
+	}
+	return s
+}
+
+func skipField(s string) string {
+	return s[len(firstField(s)):]
+}
+
+// perfDataMetricName returns the name of the metric of a performance data value, or an empty
+// string if its label doesn't contain any valid character. The unknown units of measure are
+// appended to the name, so that the values of different units aren't mixed.
+func perfDataMetricName(prefix string, value perfData) string {
+	name := sanitizeMetricName(value.label)
+	if name == "" {
+		return ""
+	}
+	if _, known := value.baseValue(); !known {
+		if unit := sanitizeMetricName(value.unit); unit != "" {
+			name += "_" + unit
+		}
+	}
+	return prefix + "." + name
+}
+
+func sanitizeMetricName(s string) string {
+	return strings.Trim(invalidMetricChars.ReplaceAllString(strings.ToLower(s), "_"), "_.")
+}
+
+// jsonOutput is the output of the executables using the JSON output format
+type jsonOutput struct {
+	Message string       `json:"message"`
+	Tags    []string     `json:"tags"`
+	Metrics []jsonMetric `json:"metrics"`
+	Events  []jsonEvent  `json:"events"`
+}
+
+type jsonMetric struct {
+	Name  string   `json:"name"`
+	Value float64  `json:"value"`
+	Type  string   `json:"type"`
+	Tags  []string `json:"tags"`
+}
+
+type jsonEvent struct {
+	Title          string   `json:"title"`
+	Text           string   `json:"text"`
+	Timestamp      int64    `json:"timestamp"`
+	AlertType      string   `json:"alert_type"`
+	Priority       string   `json:"priority"`
+	AggregationKey string   `json:"aggregation_key"`
+	Tags           []string `json:"tags"`
+}
+
+func parseJSONOutput(output []byte) (jsonOutput, error) {
+	var parsed jsonOutput
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return parsed, fmt.Errorf("invalid JSON output: %v", err)
+	}
+	return parsed, nil
+}
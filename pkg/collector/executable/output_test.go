@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package executable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNagiosOutput(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		output           string
+		expectedText     string
+		expectedPerfData string
+	}{
+		{"text only", "DISK OK\n", "DISK OK", ""},
+		{"perf data", "DISK OK - free space: / 3326 MB (56%); | /=2643MB;5948;5958;0;5968\n", "DISK OK - free space: / 3326 MB (56%);", " /=2643MB;5948;5958;0;5968"},
+		{
+			"long text",
+			"DISK OK - free space: / 3326 MB (56%); | /=2643MB;5948;5958;0;5968\n/ 15272 MB (77%);\n/boot 68 MB (69%); | /boot=68MB;88;93;0;98\n/home=69357MB;253404;253409;0;253414\n",
+			"DISK OK - free space: / 3326 MB (56%);\n/ 15272 MB (77%);\n/boot 68 MB (69%);",
+			" /=2643MB;5948;5958;0;5968  /boot=68MB;88;93;0;98 /home=69357MB;253404;253409;0;253414",
+		},
+		{"windows line endings", "OK | time=1s\r\n", "OK", " time=1s"},
+		{"empty", "", "", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			text, perfData := parseNagiosOutput(tc.output)
+			assert.Equal(t, tc.expectedText, text)
+			assert.Equal(t, tc.expectedPerfData, perfData)
+		})
+	}
+}
+
+func TestParsePerfData(t *testing.T) {
+	values, errs := parsePerfData("/=2643MB;5948;5958;0;5968 'free space'=56% 'it''s'=1.5e3 time=0,25s;1;2 load1=U invalid=abc missing 'quoted'")
+	assert.Equal(t, []perfData{
+		{label: "/", value: 2643, unit: "MB"},
+		{label: "free space", value: 56, unit: "%"},
+		{label: "it's", value: 1500},
+		{label: "time", value: 0.25, unit: "s"},
+	}, values)
+	require.Len(t, errs, 3)
+	assert.Contains(t, errs[0].Error(), "'invalid'")
+	assert.Contains(t, errs[1].Error(), "'missing'")
+	assert.Contains(t, errs[2].Error(), "'quoted'")
+
+	values, errs = parsePerfData("  ")
+	assert.Empty(t, values)
+	assert.Empty(t, errs)
+}
+
+func TestPerfDataMetricName(t *testing.T) {
+	assert.Equal(t, "check_disk.boot", perfDataMetricName("check_disk", perfData{label: "/boot", unit: "MB"}))
+	assert.Equal(t, "check_disk.free_space", perfDataMetricName("check_disk", perfData{label: "Free Space", unit: "%"}))
+	assert.Equal(t, "check_disk.var_log", perfDataMetricName("check_disk", perfData{label: "/var/log"}))
+	assert.Equal(t, "", perfDataMetricName("check_disk", perfData{label: "/"}))
+	// the unknown units are appended to the name
+	assert.Equal(t, "check_temp.cpu_degc", perfDataMetricName("check_temp", perfData{label: "cpu", unit: "degC"}))
+}
+
+func TestPerfDataBaseValue(t *testing.T) {
+	for _, tc := range []struct {
+		value         perfData
+		expected      float64
+		expectedKnown bool
+	}{
+		{perfData{value: 42}, 42, true},
+		{perfData{value: 56, unit: "%"}, 56, true},
+		{perfData{value: 1500, unit: "c"}, 1500, true},
+		{perfData{value: 2, unit: "s"}, 2, true},
+		{perfData{value: 250, unit: "ms"}, 0.25, true},
+		{perfData{value: 500, unit: "us"}, 0.0005, true},
+		{perfData{value: 512, unit: "B"}, 512, true},
+		{perfData{value: 2, unit: "KB"}, 2048, true},
+		{perfData{value: 2, unit: "kB"}, 2048, true},
+		{perfData{value: 3, unit: "MB"}, 3 << 20, true},
+		{perfData{value: 4, unit: "GB"}, 4 << 30, true},
+		{perfData{value: 5, unit: "TB"}, 5 << 40, true},
+		{perfData{value: 21, unit: "degC"}, 21, false},
+	} {
+		value, known := tc.value.baseValue()
+		assert.InDelta(t, tc.expected, value, 1e-9, "%v%s", tc.value.value, tc.value.unit)
+		assert.Equal(t, tc.expectedKnown, known, "%v%s", tc.value.value, tc.value.unit)
+	}
+
+	assert.True(t, perfData{value: 1, unit: "c"}.isCounter())
+	assert.False(t, perfData{value: 1, unit: "s"}.isCounter())
+}
+
+func TestParseJSONOutput(t *testing.T) {
+	output, err := parseJSONOutput([]byte(`{"message": "ok", "tags": ["env:prod"], "metrics": [{"name": "app.requests", "value": 12, "type": "count", "tags": ["code:200"]}], "events": [{"title": "deploy", "text": "v2", "alert_type": "success"}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, jsonOutput{
+		Message: "ok",
+		Tags:    []string{"env:prod"},
+		Metrics: []jsonMetric{{Name: "app.requests", Value: 12, Type: "count", Tags: []string{"code:200"}}},
+		Events:  []jsonEvent{{Title: "deploy", Text: "v2", AlertType: "success"}},
+	}, output)
+
+	_, err = parseJSONOutput([]byte("OK | time=1s"))
+	assert.Error(t, err)
+}
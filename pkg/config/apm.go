@@ -37,6 +37,12 @@ func setupAPM(config Config) {
 	config.SetKnown("apm_config.obfuscation.remove_stack_traces")
 	config.SetKnown("apm_config.obfuscation.redis.enabled")
 	config.SetKnown("apm_config.obfuscation.memcached.enabled")
+	config.SetKnown("apm_config.obfuscation.graphql.enabled")
+	config.SetKnown("apm_config.obfuscation.graphql.keep_variables")
+	config.SetKnown("apm_config.obfuscation.dynamodb.enabled")
+	config.SetKnown("apm_config.obfuscation.dynamodb.keep_values")
+	config.SetKnown("apm_config.obfuscation.kafka.enabled")
+	config.SetKnown("apm_config.obfuscation.kafka.keep_message_keys")
 	config.SetKnown("apm_config.filter_tags.require")
 	config.SetKnown("apm_config.filter_tags.reject")
 	config.SetKnown("apm_config.extra_sample_rate")
@@ -53,6 +59,8 @@ func setupAPM(config Config) {
 	config.SetKnown("apm_config.watchdog_check_delay")
 	config.SetKnown("apm_config.sync_flushing")
 	config.SetKnown("apm_config.features")
+	config.SetKnown("apm_config.tail_sampling.policies")
+	config.SetKnown("apm_config.span_metrics")
 
 	bindVectorOptions(config, Traces)
 
@@ -79,6 +87,20 @@ func setupAPM(config Config) {
 	config.BindEnv("apm_config.enable_rare_sampler", "DD_APM_ENABLE_RARE_SAMPLER")
 	config.BindEnv("apm_config.disable_rare_sampler", "DD_APM_DISABLE_RARE_SAMPLER") //Deprecated
 	config.BindEnv("apm_config.max_remote_traces_per_second", "DD_APM_MAX_REMOTE_TPS")
+	config.BindEnv("apm_config.stats_extra_dimensions", "DD_APM_STATS_EXTRA_DIMENSIONS")
+	config.BindEnv("apm_config.stats_extra_dimensions_max_cardinality", "DD_APM_STATS_EXTRA_DIMENSIONS_MAX_CARDINALITY")
+	config.BindEnv("apm_config.tail_sampling.enabled", "DD_APM_TAIL_SAMPLING_ENABLED")
+	config.BindEnv("apm_config.tail_sampling.decision_wait", "DD_APM_TAIL_SAMPLING_DECISION_WAIT")
+	config.BindEnv("apm_config.tail_sampling.max_spans", "DD_APM_TAIL_SAMPLING_MAX_SPANS")
+	config.BindEnv("apm_config.zipkin_receiver_enabled", "DD_APM_ZIPKIN_RECEIVER_ENABLED")
+	config.BindEnv("apm_config.jaeger_receiver_enabled", "DD_APM_JAEGER_RECEIVER_ENABLED")
+	config.BindEnv("apm_config.capture.enabled", "DD_APM_CAPTURE_ENABLED")
+	config.BindEnv("apm_config.capture.dir", "DD_APM_CAPTURE_DIR")
+	config.BindEnv("apm_config.capture.max_file_size", "DD_APM_CAPTURE_MAX_FILE_SIZE")
+	config.BindEnv("apm_config.capture.max_files", "DD_APM_CAPTURE_MAX_FILES")
+	config.BindEnv("apm_config.sampler_state.enabled", "DD_APM_SAMPLER_STATE_ENABLED")
+	config.BindEnv("apm_config.sampler_state.path", "DD_APM_SAMPLER_STATE_PATH")
+	config.BindEnv("apm_config.sampler_state.max_age", "DD_APM_SAMPLER_STATE_MAX_AGE")
 
 	config.BindEnv("apm_config.max_memory", "DD_APM_MAX_MEMORY")
 	config.BindEnv("apm_config.max_cpu_percent", "DD_APM_MAX_CPU_PERCENT")
@@ -121,6 +143,8 @@ func setupAPM(config Config) {
 
 	config.SetEnvKeyTransformer("apm_config.filter_tags.require", parseKVList("apm_config.filter_tags.require"))
 
+	config.SetEnvKeyTransformer("apm_config.stats_extra_dimensions", parseKVList("apm_config.stats_extra_dimensions"))
+
 	config.SetEnvKeyTransformer("apm_config.filter_tags.reject", parseKVList("apm_config.filter_tags.reject"))
 
 	config.SetEnvKeyTransformer("apm_config.replace_tags", func(in string) interface{} {
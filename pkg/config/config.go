@@ -197,6 +197,34 @@ type MetricMapping struct {
 	Tags      map[string]string `mapstructure:"tags" json:"tags"`
 }
 
+// TagFilterRule represents a rule filtering the tags of the DogStatsD metrics
+// matching a glob pattern
+type TagFilterRule struct {
+	MetricName string   `mapstructure:"metric_name" json:"metric_name"`
+	Action     string   `mapstructure:"action" json:"action"`
+	Tags       []string `mapstructure:"tags" json:"tags"`
+}
+
+// MetricRoute represents a rule restricting the series and sketches sent with some API keys
+type MetricRoute struct {
+	Name            string   `mapstructure:"name" json:"name"`
+	APIKeys         []string `mapstructure:"api_keys" json:"api_keys"`
+	IncludePrefixes []string `mapstructure:"include_prefixes" json:"include_prefixes"`
+	IncludeTags     []string `mapstructure:"include_tags" json:"include_tags"`
+	ExcludePrefixes []string `mapstructure:"exclude_prefixes" json:"exclude_prefixes"`
+	ExcludeTags     []string `mapstructure:"exclude_tags" json:"exclude_tags"`
+}
+
+// RemoteWriteEndpoint represents a Prometheus remote-write endpoint the series
+// and sketches are also sent to
+type RemoteWriteEndpoint struct {
+	URL         string            `mapstructure:"url" json:"url"`
+	Headers     map[string]string `mapstructure:"headers" json:"headers"`
+	BearerToken string            `mapstructure:"bearer_token" json:"bearer_token"`
+	Username    string            `mapstructure:"username" json:"username"`
+	Password    string            `mapstructure:"password" json:"password"`
+}
+
 // Endpoint represent a datadog endpoint
 type Endpoint struct {
 	Site   string `mapstructure:"site" json:"site"`
@@ -475,6 +503,14 @@ func InitConfig(config Config) {
 
 	// Forwarder
 	config.BindEnvAndSetDefault("additional_endpoints", map[string][]string{})
+	config.BindEnv("metric_routes")
+	config.SetEnvKeyTransformer("metric_routes", func(in string) interface{} {
+		var routes []MetricRoute
+		if err := json.Unmarshal([]byte(in), &routes); err != nil {
+			log.Errorf(`"metric_routes" can not be parsed: %v`, err)
+		}
+		return routes
+	})
 	config.BindEnvAndSetDefault("forwarder_timeout", 20)
 	config.BindEnv("forwarder_retry_queue_max_size")                                                     // Deprecated in favor of `forwarder_retry_queue_payloads_max_size`
 	config.BindEnv("forwarder_retry_queue_payloads_max_size")                                            // Default value is defined inside `NewOptions` in pkg/forwarder/forwarder.go
@@ -496,12 +532,38 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("forwarder_storage_max_size_in_bytes", 0)                // 0 means disabled. This is a BETA feature.
 	config.BindEnvAndSetDefault("forwarder_storage_max_disk_ratio", 0.80)                // Do not store transactions on disk when the disk usage exceeds 80% of the disk capacity. Use 80% as some applications do not behave well when the disk space is very small.
 	config.BindEnvAndSetDefault("forwarder_retry_queue_capacity_time_interval_sec", 900) // 15 mins
+	// Encryption of the transactions stored on disk, with base64 encoded AES-256 keys
+	config.BindEnvAndSetDefault("forwarder_storage_encryption_key", "")
+	config.BindEnvAndSetDefault("forwarder_storage_encryption_key_file", "")
+	config.BindEnvAndSetDefault("forwarder_storage_previous_encryption_keys", []string{})
+	config.BindEnvAndSetDefault("forwarder_storage_previous_encryption_key_files", []string{})
 
 	// Forwarder channels buffer size
 	config.BindEnvAndSetDefault("forwarder_high_prio_buffer_size", 100)
 	config.BindEnvAndSetDefault("forwarder_low_prio_buffer_size", 100)
 	config.BindEnvAndSetDefault("forwarder_requeue_buffer_size", 100)
 
+	// Forwarder offline mode: transactions written to local files instead of being sent
+	config.BindEnvAndSetDefault("forwarder_offline_mode.enabled", false)
+	config.BindEnvAndSetDefault("forwarder_offline_mode.storage_path", "")
+	config.BindEnvAndSetDefault("forwarder_offline_mode.file_max_size_in_bytes", 10*1024*1024)
+	config.BindEnvAndSetDefault("forwarder_offline_mode.file_rotation_interval", 3600)              // in seconds
+	config.BindEnvAndSetDefault("forwarder_offline_mode.storage_max_size_in_bytes", 1024*1024*1024) // 0 means unlimited
+
+	// Prometheus remote-write output
+	config.BindEnvAndSetDefault("prometheus_remote_write.enabled", false)
+	config.BindEnv("prometheus_remote_write.endpoints")
+	config.SetEnvKeyTransformer("prometheus_remote_write.endpoints", func(in string) interface{} {
+		var endpoints []RemoteWriteEndpoint
+		if err := json.Unmarshal([]byte(in), &endpoints); err != nil {
+			log.Errorf(`"prometheus_remote_write.endpoints" can not be parsed: %v`, err)
+		}
+		return endpoints
+	})
+	config.BindEnvAndSetDefault("prometheus_remote_write.send_sketches", false)
+	config.BindEnvAndSetDefault("prometheus_remote_write.sketch_quantiles", []string{"0.5", "0.75", "0.9", "0.95", "0.99"})
+	config.BindEnvAndSetDefault("prometheus_remote_write.max_series_per_payload", 2000)
+
 	// Dogstatsd
 	config.BindEnvAndSetDefault("use_dogstatsd", true)
 	config.BindEnvAndSetDefault("dogstatsd_port", 8125)    // Notice: 0 means UDP port closed
@@ -521,7 +583,13 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("dogstatsd_queue_size", 1024)
 
 	config.BindEnvAndSetDefault("dogstatsd_non_local_traffic", false)
-	config.BindEnvAndSetDefault("dogstatsd_socket", "") // Notice: empty means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_socket", "")        // Notice: empty means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_stream_socket", "") // Notice: empty means feature disabled
+	// Options are: newline, length_prefixed
+	config.BindEnvAndSetDefault("dogstatsd_stream_socket_framing", "length_prefixed")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_port", 0) // Notice: 0 means TCP port closed
+	// Options are: newline, length_prefixed
+	config.BindEnvAndSetDefault("dogstatsd_tcp_framing", "newline")
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_autoadjust", false)
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_count", 1)
 	config.BindEnvAndSetDefault("dogstatsd_stats_port", 5000)
@@ -531,6 +599,13 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("dogstatsd_expiry_seconds", 300)
 	// Control how long we keep dogstatsd contexts in memory.
 	config.BindEnvAndSetDefault("dogstatsd_context_expiry_seconds", 20)
+	// Limit the number of dogstatsd contexts in memory, globally and by metric name. 0 means no limit.
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.global_limit", 0)
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.metric_limit", 0)
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.metric_limits", map[string]int{})
+	// Options are: drop, fold
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.action", "drop")
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.fold_tags", []string{})
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection", false) // Only supported for socket traffic
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection_client", false)
 	config.BindEnvAndSetDefault("dogstatsd_origin_optout_enabled", true)
@@ -575,6 +650,15 @@ func InitConfig(config Config) {
 		return mappings
 	})
 
+	config.BindEnv("dogstatsd_tag_filters")
+	config.SetEnvKeyTransformer("dogstatsd_tag_filters", func(in string) interface{} {
+		var rules []TagFilterRule
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"dogstatsd_tag_filters" can not be parsed: %v`, err)
+		}
+		return rules
+	})
+
 	config.BindEnvAndSetDefault("statsd_forward_host", "")
 	config.BindEnvAndSetDefault("statsd_forward_port", 0)
 	config.BindEnvAndSetDefault("statsd_metric_namespace", "")
@@ -935,6 +1019,16 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("logs_config.docker_path_override", "")
 
 	config.BindEnvAndSetDefault("logs_config.auditor_ttl", DefaultAuditorTTL) // in hours
+	// Payloads that can't be sent because the destinations are unreachable are buffered on disk,
+	// up to disk_buffer_max_size_in_bytes (0 means disabled) and for at most disk_buffer_max_age hours.
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_path", "") // defaults to <run_path>/disk_buffer
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_max_size_in_bytes", 0)
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_max_age", 24) // in hours
+	// Send logs to an OpenTelemetry collector over OTLP, in addition to the Datadog intake.
+	config.BindEnvAndSetDefault("logs_config.otlp_endpoint", "")
+	config.BindEnvAndSetDefault("logs_config.otlp_protocol", "http") // http or grpc
+	config.BindEnvAndSetDefault("logs_config.otlp_headers", map[string]string{})
+	config.BindEnvAndSetDefault("logs_config.otlp_is_reliable", true)
 	// Timeout in milliseonds used when performing agreggation operations,
 	// including multi-line log processing rules and chunked line reaggregation.
 	// It may be useful to increase it when logs writing is slowed down, that
@@ -952,6 +1046,10 @@ func InitConfig(config Config) {
 	// more disk I/O at the wildcard log paths
 	config.BindEnvAndSetDefault("logs_config.file_wildcard_selection_mode", "by_name")
 
+	// When enabled, the unread content of the rotated siblings of a tailed file (`.1`, `.gz`, `.<timestamp>`)
+	// is drained before tailing the new file, e.g. when a file rotated several times between two scans.
+	config.BindEnvAndSetDefault("logs_config.drain_rotated_files", false)
+
 	// The cardinality of tags to send for checks and dogstatsd respectively.
 	// Choices are: low, orchestrator, high.
 	// WARNING: sending orchestrator, or high tags for dogstatsd metrics may create more metrics
@@ -2016,6 +2114,54 @@ func getDogstatsdMappingProfilesConfig(config Config) ([]MappingProfile, error)
 	return mappings, nil
 }
 
+// GetDogstatsdTagFilterRules returns the rules used to filter the tags of the DogStatsD metrics
+func GetDogstatsdTagFilterRules() ([]TagFilterRule, error) {
+	return getDogstatsdTagFilterRulesConfig(Datadog)
+}
+
+func getDogstatsdTagFilterRulesConfig(config Config) ([]TagFilterRule, error) {
+	var rules []TagFilterRule
+	if config.IsSet("dogstatsd_tag_filters") {
+		err := config.UnmarshalKey("dogstatsd_tag_filters", &rules)
+		if err != nil {
+			return []TagFilterRule{}, log.Errorf("Could not parse dogstatsd_tag_filters: %v", err)
+		}
+	}
+	return rules, nil
+}
+
+// GetMetricRoutes returns the rules restricting the series and sketches sent with some API keys
+func GetMetricRoutes() ([]MetricRoute, error) {
+	return getMetricRoutesConfig(Datadog)
+}
+
+func getMetricRoutesConfig(config Config) ([]MetricRoute, error) {
+	var routes []MetricRoute
+	if config.IsSet("metric_routes") {
+		err := config.UnmarshalKey("metric_routes", &routes)
+		if err != nil {
+			return []MetricRoute{}, log.Errorf("Could not parse metric_routes: %v", err)
+		}
+	}
+	return routes, nil
+}
+
+// GetRemoteWriteEndpoints returns the Prometheus remote-write endpoints
+func GetRemoteWriteEndpoints() ([]RemoteWriteEndpoint, error) {
+	return getRemoteWriteEndpointsConfig(Datadog)
+}
+
+func getRemoteWriteEndpointsConfig(config Config) ([]RemoteWriteEndpoint, error) {
+	var endpoints []RemoteWriteEndpoint
+	if config.IsSet("prometheus_remote_write.endpoints") {
+		err := config.UnmarshalKey("prometheus_remote_write.endpoints", &endpoints)
+		if err != nil {
+			return []RemoteWriteEndpoint{}, log.Errorf("Could not parse prometheus_remote_write.endpoints: %v", err)
+		}
+	}
+	return endpoints, nil
+}
+
 // IsCLCRunner returns whether the Agent is in cluster check runner mode
 func IsCLCRunner() bool {
 	if !Datadog.GetBool("clc_runner_enabled") {
@@ -1094,6 +1094,123 @@ func TestDogstatsdMappingProfilesEnv(t *testing.T) {
 	assert.Equal(t, mappings, expected)
 }
 
+func TestDogstatsdTagFilterRules(t *testing.T) {
+	datadogYaml := `
+dogstatsd_tag_filters:
+  - metric_name: "http.request.*"
+    action: include
+    tags: ["service", "env", "route"]
+  - metric_name: "kafka.*"
+    action: exclude
+    tags: ["pod_name"]
+`
+	testConfig := setupConfFromYAML(datadogYaml)
+
+	rules, err := getDogstatsdTagFilterRulesConfig(testConfig)
+
+	expectedRules := []TagFilterRule{
+		{MetricName: "http.request.*", Action: "include", Tags: []string{"service", "env", "route"}},
+		{MetricName: "kafka.*", Action: "exclude", Tags: []string{"pod_name"}},
+	}
+
+	assert.Nil(t, err)
+	assert.EqualValues(t, expectedRules, rules)
+}
+
+func TestDogstatsdTagFilterRulesError(t *testing.T) {
+	datadogYaml := `
+dogstatsd_tag_filters:
+  - abc
+`
+	testConfig := setupConfFromYAML(datadogYaml)
+	rules, err := getDogstatsdTagFilterRulesConfig(testConfig)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Could not parse dogstatsd_tag_filters")
+	assert.Empty(t, rules)
+}
+
+func TestDogstatsdTagFilterRulesEnv(t *testing.T) {
+	t.Setenv("DD_DOGSTATSD_TAG_FILTERS", `[{"metric_name":"kafka.*","action":"exclude","tags":["pod_name"]}]`)
+	expected := []TagFilterRule{
+		{MetricName: "kafka.*", Action: "exclude", Tags: []string{"pod_name"}},
+	}
+	rules, _ := GetDogstatsdTagFilterRules()
+	assert.Equal(t, expected, rules)
+}
+
+func TestMetricRoutes(t *testing.T) {
+	datadogYaml := `
+metric_routes:
+  - name: team-a
+    api_keys: ["key-a"]
+    include_prefixes: ["team_a."]
+    include_tags: ["team:a"]
+  - name: secondary
+    api_keys: ["key-b", "key-c"]
+    exclude_prefixes: ["debug."]
+    exclude_tags: ["env:dev"]
+`
+	testConfig := setupConfFromYAML(datadogYaml)
+
+	routes, err := getMetricRoutesConfig(testConfig)
+
+	expectedRoutes := []MetricRoute{
+		{Name: "team-a", APIKeys: []string{"key-a"}, IncludePrefixes: []string{"team_a."}, IncludeTags: []string{"team:a"}},
+		{Name: "secondary", APIKeys: []string{"key-b", "key-c"}, ExcludePrefixes: []string{"debug."}, ExcludeTags: []string{"env:dev"}},
+	}
+
+	assert.Nil(t, err)
+	assert.EqualValues(t, expectedRoutes, routes)
+}
+
+func TestMetricRoutesEnv(t *testing.T) {
+	t.Setenv("DD_METRIC_ROUTES", `[{"name":"team-a","api_keys":["key-a"],"include_prefixes":["team_a."]}]`)
+	expected := []MetricRoute{
+		{Name: "team-a", APIKeys: []string{"key-a"}, IncludePrefixes: []string{"team_a."}},
+	}
+	routes, _ := GetMetricRoutes()
+	assert.Equal(t, expected, routes)
+}
+
+func TestRemoteWriteEndpoints(t *testing.T) {
+	datadogYaml := `
+prometheus_remote_write:
+  enabled: true
+  endpoints:
+    - url: https://mimir.example.com/api/v1/push
+      headers:
+        x-scope-orgid: team-a
+      bearer_token: secret
+    - url: https://prometheus.example.com/api/v1/write
+      username: agent
+      password: pass
+`
+	testConfig := setupConfFromYAML(datadogYaml)
+
+	endpoints, err := getRemoteWriteEndpointsConfig(testConfig)
+
+	expectedEndpoints := []RemoteWriteEndpoint{
+		{URL: "https://mimir.example.com/api/v1/push", Headers: map[string]string{"x-scope-orgid": "team-a"}, BearerToken: "secret"},
+		{URL: "https://prometheus.example.com/api/v1/write", Username: "agent", Password: "pass"},
+	}
+
+	assert.Nil(t, err)
+	assert.EqualValues(t, expectedEndpoints, endpoints)
+	assert.True(t, testConfig.GetBool("prometheus_remote_write.enabled"))
+	assert.False(t, testConfig.GetBool("prometheus_remote_write.send_sketches"))
+	assert.Equal(t, 2000, testConfig.GetInt("prometheus_remote_write.max_series_per_payload"))
+}
+
+func TestRemoteWriteEndpointsEnv(t *testing.T) {
+	t.Setenv("DD_PROMETHEUS_REMOTE_WRITE_ENDPOINTS", `[{"url":"https://mimir.example.com/api/v1/push","headers":{"X-Scope-OrgID":"team-a"}}]`)
+	expected := []RemoteWriteEndpoint{
+		{URL: "https://mimir.example.com/api/v1/push", Headers: map[string]string{"X-Scope-OrgID": "team-a"}},
+	}
+	endpoints, _ := GetRemoteWriteEndpoints()
+	assert.Equal(t, expected, endpoints)
+}
+
 func TestGetValidHostAliasesWithConfig(t *testing.T) {
 	config := setupConfFromYAML(`host_aliases: ["foo", "-bar"]`)
 	assert.EqualValues(t, getValidHostAliasesWithConfig(config), []string{"foo"})
@@ -45,7 +45,7 @@ func NewNamedPipeListener(pipeName string, packetOut chan packets.Packets,
 	return newNamedPipeListener(
 		pipeName,
 		bufferSize,
-		packets.NewPacketManagerFromConfig(packetOut, sharedPacketPoolManager),
+		packets.NewPacketManagerFromConfig(packetOut, sharedPacketPoolManager, packets.NamedPipe),
 		capture)
 }
 
@@ -99,10 +99,12 @@ func (l *namedPipeConnections) handleConnections() {
 		case conn := <-l.newConn:
 			connections[conn] = struct{}{}
 			l.activeConnCount.Inc()
+			namedPipeTelemetry.onConnectionOpened()
 		case conn := <-l.connToClose:
 			conn.Close()
 			delete(connections, conn)
 			l.activeConnCount.Dec()
+			namedPipeTelemetry.onConnectionClosed()
 			if requestStop && len(connections) == 0 {
 				stop = true
 			}
@@ -125,7 +125,7 @@ func newNamedPipeListenerTest(t *testing.T) namedPipeListenerTest {
 	pool := packets.NewPool(maxPipeMessageCount)
 	poolManager := packets.NewPoolManager(pool)
 	packetOut := make(chan packets.Packets, maxPipeMessageCount)
-	packetManager := packets.NewPacketManager(10, maxPipeMessageCount, 10*time.Millisecond, packetOut, poolManager)
+	packetManager := packets.NewPacketManager(10, maxPipeMessageCount, 10*time.Millisecond, packetOut, poolManager, packets.NamedPipe)
 
 	listener, err := newNamedPipeListener(
 		pipeName,
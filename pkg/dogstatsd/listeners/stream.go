@@ -0,0 +1,264 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// streamFraming is the way messages are delimited on a stream connection.
+type streamFraming int
+
+const (
+	// newlineFraming delimits messages with a '\n'.
+	newlineFraming streamFraming = iota
+	// lengthPrefixedFraming prefixes each frame with its length, as a 32-bit little-endian
+	// integer. A frame can hold several messages separated with a '\n'.
+	lengthPrefixedFraming
+)
+
+// lengthPrefixSize is the size of the prefix of the length-prefixed frames.
+const lengthPrefixSize = 4
+
+// parseStreamFraming returns the framing matching the value of a framing setting.
+func parseStreamFraming(framing string) (streamFraming, error) {
+	switch framing {
+	case "newline":
+		return newlineFraming, nil
+	case "length_prefixed":
+		return lengthPrefixedFraming, nil
+	}
+	return 0, fmt.Errorf("unknown framing %q, valid values are newline and length_prefixed", framing)
+}
+
+// streamListener is the base of the stream-oriented listeners. It accepts the connections of
+// a net.Listener and reads the messages of each of them in its own goroutine, assembling them
+// into packets with the origin of the connection.
+type streamListener struct {
+	// name is used in the logs, listenerType in the telemetry.
+	name         string
+	listenerType string
+	listener     net.Listener
+	framing      streamFraming
+	telemetry    *listenerTelemetry
+	// origin returns the origin of a connection. It is nil when origin detection is disabled.
+	origin        func(conn net.Conn) (string, error)
+	packetManager *packets.PacketManager
+
+	mu      sync.Mutex
+	conns   map[net.Conn]struct{}
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+func newStreamListener(name, listenerType string, listener net.Listener, framing streamFraming,
+	packetManager *packets.PacketManager, telemetry *listenerTelemetry) *streamListener {
+	return &streamListener{
+		name:          name,
+		listenerType:  listenerType,
+		listener:      listener,
+		framing:       framing,
+		telemetry:     telemetry,
+		packetManager: packetManager,
+		conns:         make(map[net.Conn]struct{}),
+	}
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *streamListener) Listen() {
+	log.Infof("dogstatsd-%s: starting to listen on %s", l.name, l.listener.Addr())
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				log.Debugf("dogstatsd-%s: stop listening", l.name)
+				return
+			}
+			log.Errorf("dogstatsd-%s: error accepting connection: %v", l.name, err)
+			continue
+		}
+		if !l.track(conn) {
+			conn.Close()
+			return
+		}
+		go l.handleConnection(conn)
+	}
+}
+
+// track registers a new connection, it returns false if the listener is stopped.
+func (l *streamListener) track(conn net.Conn) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stopped {
+		return false
+	}
+	l.conns[conn] = struct{}{}
+	l.wg.Add(1)
+	l.telemetry.onConnectionOpened()
+	return true
+}
+
+func (l *streamListener) untrack(conn net.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.conns, conn)
+	l.wg.Done()
+	l.telemetry.onConnectionClosed()
+}
+
+func (l *streamListener) handleConnection(conn net.Conn) {
+	defer l.untrack(conn)
+	defer conn.Close()
+	log.Debugf("dogstatsd-%s: new client connected from %s", l.name, conn.RemoteAddr())
+
+	origin := packets.NoOrigin
+	if l.origin != nil {
+		var err error
+		if origin, err = l.origin(conn); err != nil {
+			log.Warnf("dogstatsd-%s: error processing origin, data will not be tagged : %v", l.name, err)
+			l.telemetry.onOriginDetectionError()
+		}
+	}
+
+	// each connection has its own assembler so that the packets hold the origin of the connection
+	assembler := l.packetManager.CreateAssembler(origin)
+	defer func() {
+		assembler.Flush()
+		assembler.Close()
+	}()
+
+	var err error
+	buffer := l.packetManager.CreateBuffer()
+	switch l.framing {
+	case lengthPrefixedFraming:
+		err = l.readLengthPrefixed(conn, assembler, buffer)
+	default:
+		err = l.readNewlines(conn, assembler, buffer)
+	}
+
+	switch {
+	case err == nil:
+		log.Debugf("dogstatsd-%s: client disconnected from %s", l.name, conn.RemoteAddr())
+	case errors.Is(err, net.ErrClosed):
+		log.Debugf("dogstatsd-%s: stop listening a client from %s", l.name, conn.RemoteAddr())
+	default:
+		log.Errorf("dogstatsd-%s: error reading from %s, closing the connection: %v", l.name, conn.RemoteAddr(), err)
+		l.telemetry.onReadError()
+	}
+}
+
+// readNewlines reads the messages of conn delimited with a '\n' until the connection is
+// closed. The messages bigger than the buffer are dropped.
+func (l *streamListener) readNewlines(conn net.Conn, assembler *packets.Assembler, buffer []byte) error {
+	var t1, t2 time.Time
+	start := 0
+	// discard is true while reading the end of a message bigger than the buffer
+	discard := false
+	for {
+		n, err := conn.Read(buffer[start:])
+		t1 = time.Now()
+		end := start + n
+
+		if discard {
+			if i := bytes.IndexByte(buffer[:end], '\n'); i >= 0 {
+				end = copy(buffer, buffer[i+1:end])
+				discard = false
+			} else {
+				end = 0
+			}
+		}
+		if i := bytes.LastIndexByte(buffer[:end], '\n'); i >= 0 {
+			// at least one message is complete, the '\n' following the last one is dropped
+			l.telemetry.onReadSuccess(i + 1)
+			if i > 0 {
+				// assembler merges multiple messages together and sends them when its buffer is full
+				assembler.AddMessage(buffer[:i])
+			}
+			end = copy(buffer, buffer[i+1:end])
+		} else if end == len(buffer) {
+			log.Debugf("dogstatsd-%s: dropping a message bigger than the buffer size of %d bytes", l.name, len(buffer))
+			l.telemetry.onReadError()
+			discard = true
+			end = 0
+		}
+		start = end
+
+		if err == io.EOF {
+			if end > 0 {
+				// the last message of the stream is not required to end with a '\n'
+				l.telemetry.onReadSuccess(end)
+				assembler.AddMessage(buffer[:end])
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		t2 = time.Now()
+		tlmListener.Observe(float64(t2.Sub(t1).Nanoseconds()), l.listenerType)
+	}
+}
+
+// readLengthPrefixed reads the length-prefixed frames of conn until the connection is closed.
+// A frame bigger than the buffer is an error: the rest of the stream can't be trusted.
+func (l *streamListener) readLengthPrefixed(conn net.Conn, assembler *packets.Assembler, buffer []byte) error {
+	var t1, t2 time.Time
+	var prefix [lengthPrefixSize]byte
+	reader := bufio.NewReaderSize(conn, len(buffer)+lengthPrefixSize)
+	for {
+		if _, err := io.ReadFull(reader, prefix[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		size := int(binary.LittleEndian.Uint32(prefix[:]))
+		if size > len(buffer) {
+			return fmt.Errorf("frame of %d bytes is bigger than the buffer size of %d bytes", size, len(buffer))
+		}
+		if _, err := io.ReadFull(reader, buffer[:size]); err != nil {
+			return err
+		}
+		t1 = time.Now()
+
+		l.telemetry.onReadSuccess(lengthPrefixSize + size)
+		if message := bytes.TrimSuffix(buffer[:size], []byte{'\n'}); len(message) > 0 {
+			// assembler merges multiple messages together and sends them when its buffer is full
+			assembler.AddMessage(message)
+		}
+
+		t2 = time.Now()
+		tlmListener.Observe(float64(t2.Sub(t1).Nanoseconds()), l.listenerType)
+	}
+}
+
+// Stop closes the listener and the connections, and stops listening
+func (l *streamListener) Stop() {
+	l.listener.Close()
+
+	l.mu.Lock()
+	l.stopped = true
+	for conn := range l.conns {
+		// interrupts the pending reads of the connection
+		conn.Close()
+	}
+	l.mu.Unlock()
+
+	// wait for the connections to flush their messages
+	l.wg.Wait()
+	l.packetManager.Close()
+}
@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/replay"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var tcpTelemetry = newListenerTelemetry("tcp", "TCP")
+
+// TCPListener implements the StatsdListener interface for TCP protocol.
+// It listens to a given TCP address and sends back packets ready to be
+// processed, the messages being framed with a '\n' or a length prefix.
+// Origin detection is not implemented for TCP.
+type TCPListener struct {
+	*streamListener
+	trafficCapture replay.Component // Currently ignored
+}
+
+// NewTCPListener returns an idle TCP Statsd listener
+func NewTCPListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager, capture replay.Component) (*TCPListener, error) {
+	var url string
+
+	if config.Datadog.GetBool("dogstatsd_non_local_traffic") == true {
+		// Listen to all network interfaces
+		url = fmt.Sprintf(":%d", config.Datadog.GetInt("dogstatsd_tcp_port"))
+	} else {
+		url = net.JoinHostPort(config.GetBindHost(), config.Datadog.GetString("dogstatsd_tcp_port"))
+	}
+
+	framing, err := parseStreamFraming(config.Datadog.GetString("dogstatsd_tcp_framing"))
+	if err != nil {
+		return nil, fmt.Errorf("dogstatsd-tcp: %s", err)
+	}
+
+	listener, err := net.Listen("tcp", url)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+
+	packetManager := packets.NewPacketManagerFromConfig(packetOut, sharedPacketPoolManager, packets.TCP)
+	tcpListener := &TCPListener{
+		streamListener: newStreamListener("tcp", "tcp", listener, framing, packetManager, tcpTelemetry),
+		trafficCapture: capture,
+	}
+	log.Debugf("dogstatsd-tcp: %s successfully initialized", listener.Addr())
+	return tcpListener, nil
+}
@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/packets"
+)
+
+var (
+	packetPoolTCP        = packets.NewPool(config.Datadog.GetInt("dogstatsd_buffer_size"))
+	packetPoolManagerTCP = packets.NewPoolManager(packetPoolTCP)
+)
+
+func newTestTCPListener(t *testing.T, framing string) (*TCPListener, chan packets.Packets) {
+	config.Datadog.SetDefault("dogstatsd_tcp_port", 0)
+	config.Datadog.SetDefault("dogstatsd_tcp_framing", framing)
+	config.Datadog.SetDefault("dogstatsd_non_local_traffic", false)
+	packetOut := make(chan packets.Packets, 16)
+	s, err := NewTCPListener(packetOut, packetPoolManagerTCP, nil)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+	go s.Listen()
+	return s, packetOut
+}
+
+// lengthPrefixed returns the frame holding message in the length-prefixed framing.
+func lengthPrefixed(message string) []byte {
+	frame := make([]byte, lengthPrefixSize, lengthPrefixSize+len(message))
+	binary.LittleEndian.PutUint32(frame, uint32(len(message)))
+	return append(frame, message...)
+}
+
+// readStreamMessages returns the n next messages sent to packetOut, along with
+// the last packet read.
+func readStreamMessages(t *testing.T, packetOut chan packets.Packets, n int) ([]string, *packets.Packet) {
+	var (
+		messages []string
+		last     *packets.Packet
+	)
+	for len(messages) < n {
+		select {
+		case pkts := <-packetOut:
+			for _, packet := range pkts {
+				messages = append(messages, strings.Split(string(packet.Contents), "\n")...)
+				last = packet
+			}
+		case <-time.After(2 * time.Second):
+			require.FailNow(t, "Timeout on receive channel", "got %v", messages)
+		}
+	}
+	return messages, last
+}
+
+func TestTCPListenerNewlineFraming(t *testing.T) {
+	s, packetOut := newTestTCPListener(t, "newline")
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	// messages can be split across writes, the last one doesn't require a '\n'
+	for _, chunk := range []string{"daemon:666|g\nfoo:1", "|c\nbar:2|c"} {
+		_, err = conn.Write([]byte(chunk))
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+	}
+	conn.Close()
+
+	messages, packet := readStreamMessages(t, packetOut, 3)
+	assert.Equal(t, []string{"daemon:666|g", "foo:1|c", "bar:2|c"}, messages)
+	assert.Equal(t, packets.TCP, packet.Source)
+	assert.Equal(t, packets.NoOrigin, packet.Origin)
+}
+
+func TestTCPListenerNewlineFramingTooBigMessage(t *testing.T) {
+	s, packetOut := newTestTCPListener(t, "newline")
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	tooBig := strings.Repeat("1", config.Datadog.GetInt("dogstatsd_buffer_size")+3)
+	_, err = conn.Write([]byte("foo:1|c\n" + tooBig + "\nbar:2|c\n"))
+	require.NoError(t, err)
+
+	messages, _ := readStreamMessages(t, packetOut, 2)
+	assert.Equal(t, []string{"foo:1|c", "bar:2|c"}, messages)
+}
+
+func TestTCPListenerLengthPrefixedFraming(t *testing.T) {
+	s, packetOut := newTestTCPListener(t, "length_prefixed")
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	var payload bytes.Buffer
+	payload.Write(lengthPrefixed("daemon:666|g\nfoo:1|c\n"))
+	payload.Write(lengthPrefixed("bar:2|c"))
+	_, err = conn.Write(payload.Bytes())
+	require.NoError(t, err)
+	conn.Close()
+
+	messages, packet := readStreamMessages(t, packetOut, 3)
+	assert.Equal(t, []string{"daemon:666|g", "foo:1|c", "bar:2|c"}, messages)
+	assert.Equal(t, packets.TCP, packet.Source)
+}
+
+func TestTCPListenerLengthPrefixedFramingTooBigFrame(t *testing.T) {
+	s, packetOut := newTestTCPListener(t, "length_prefixed")
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	var payload bytes.Buffer
+	payload.Write(lengthPrefixed("foo:1|c"))
+	payload.Write(lengthPrefixed(strings.Repeat("1", config.Datadog.GetInt("dogstatsd_buffer_size")+1)))
+	payload.Write(lengthPrefixed("bar:2|c"))
+	_, err = conn.Write(payload.Bytes())
+	require.NoError(t, err)
+
+	// the messages preceding the frame are kept, the connection is closed
+	messages, _ := readStreamMessages(t, packetOut, 1)
+	assert.Equal(t, []string{"foo:1|c"}, messages)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
+
+func TestTCPListenerStop(t *testing.T) {
+	s, _ := newTestTCPListener(t, "newline")
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	read := tcpTelemetry.bytes.Value()
+	_, err = conn.Write([]byte("foo:1|c\n"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return tcpTelemetry.bytes.Value() > read }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(1), tcpTelemetry.connections.Value())
+
+	// stopping closes the connections
+	s.Stop()
+	assert.Equal(t, int64(0), tcpTelemetry.connections.Value())
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	_, err = net.Dial("tcp", s.listener.Addr().String())
+	assert.Error(t, err)
+}
+
+func TestNewTCPListenerInvalidFraming(t *testing.T) {
+	config.Datadog.SetDefault("dogstatsd_tcp_port", 0)
+	config.Datadog.SetDefault("dogstatsd_tcp_framing", "crlf")
+	defer config.Datadog.SetDefault("dogstatsd_tcp_framing", "newline")
+	s, err := NewTCPListener(nil, packetPoolManagerTCP, nil)
+	assert.Nil(t, s)
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"expvar"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+type listenerTelemetry struct {
+	packetReadingErrors   *expvar.Int
+	packets               *expvar.Int
+	bytes                 *expvar.Int
+	originDetectionErrors *expvar.Int
+	connections           *expvar.Int
+	expvars               *expvar.Map
+	tlmPackets            telemetry.Counter
+	tlmPacketsBytes       telemetry.Counter
+	tlmOriginDetectionErr telemetry.Counter
+	tlmConnections        telemetry.Gauge
+}
+
+func newListenerTelemetry(metricName string, name string) *listenerTelemetry {
+	expvars := expvar.NewMap("dogstatsd-" + metricName)
+	packetReadingErrors := &expvar.Int{}
+	packets := &expvar.Int{}
+	bytes := &expvar.Int{}
+	originDetectionErrors := &expvar.Int{}
+	connections := &expvar.Int{}
+
+	tlmPackets := telemetry.NewCounter("dogstatsd", metricName+"_packets",
+		[]string{"state"}, fmt.Sprintf("Dogstatsd %s packets count", name))
+	tlmPacketsBytes := telemetry.NewCounter("dogstatsd", metricName+"_packets_bytes",
+		nil, fmt.Sprintf("Dogstatsd %s packets bytes count", name))
+	tlmOriginDetectionErr := telemetry.NewCounter("dogstatsd", metricName+"_origin_detection_error",
+		nil, fmt.Sprintf("Dogstatsd %s origin detection error count", name))
+	tlmConnections := telemetry.NewGauge("dogstatsd", metricName+"_connections",
+		nil, fmt.Sprintf("Dogstatsd %s active connections count", name))
+	expvars.Set("PacketReadingErrors", packetReadingErrors)
+	expvars.Set("Packets", packets)
+	expvars.Set("Bytes", bytes)
+	expvars.Set("OriginDetectionErrors", originDetectionErrors)
+	expvars.Set("Connections", connections)
+
+	return &listenerTelemetry{
+		expvars:               expvars,
+		packetReadingErrors:   packetReadingErrors,
+		tlmPackets:            tlmPackets,
+		packets:               packets,
+		bytes:                 bytes,
+		tlmPacketsBytes:       tlmPacketsBytes,
+		originDetectionErrors: originDetectionErrors,
+		tlmOriginDetectionErr: tlmOriginDetectionErr,
+		connections:           connections,
+		tlmConnections:        tlmConnections,
+	}
+}
+
+func (t *listenerTelemetry) onReadSuccess(n int) {
+	t.packets.Add(1)
+	t.tlmPackets.Inc("ok")
+	t.bytes.Add(int64(n))
+	t.tlmPacketsBytes.Add(float64(n))
+}
+
+func (t *listenerTelemetry) onReadError() {
+	t.packets.Add(1)
+	t.packetReadingErrors.Add(1)
+	t.tlmPackets.Inc("error")
+}
+
+func (t *listenerTelemetry) onOriginDetectionError() {
+	t.originDetectionErrors.Add(1)
+	t.tlmOriginDetectionErr.Inc()
+}
+
+func (t *listenerTelemetry) onConnectionOpened() {
+	t.connections.Add(1)
+	t.tlmConnections.Inc()
+}
+
+func (t *listenerTelemetry) onConnectionClosed() {
+	t.connections.Add(-1)
+	t.tlmConnections.Dec()
+}
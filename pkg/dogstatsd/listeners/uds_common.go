@@ -60,17 +60,8 @@ func NewUDSListener(packetOut chan packets.Packets, sharedPacketPoolManager *pac
 	if addrErr != nil {
 		return nil, fmt.Errorf("dogstatsd-uds: can't ResolveUnixAddr: %v", addrErr)
 	}
-	fileInfo, err := os.Stat(socketPath)
-	// Socket file already exists
-	if err == nil {
-		// Make sure it's a UNIX socket
-		if fileInfo.Mode()&os.ModeSocket == 0 {
-			return nil, fmt.Errorf("dogstatsd-uds: cannot reuse %s socket path: path already exists and is not a UNIX socket", socketPath)
-		}
-		err = os.Remove(socketPath)
-		if err != nil {
-			return nil, fmt.Errorf("dogstatsd-uds: cannot remove stale UNIX socket: %v", err)
-		}
+	if err := removeStaleUDSSocket(socketPath); err != nil {
+		return nil, fmt.Errorf("dogstatsd-uds: %v", err)
 	}
 
 	conn, err := net.ListenUnixgram("unixgram", address)
@@ -139,6 +130,24 @@ func NewUDSListener(packetOut chan packets.Packets, sharedPacketPoolManager *pac
 	return listener, nil
 }
 
+// removeStaleUDSSocket removes the UNIX socket left at socketPath by a previous
+// run, so that the path can be bound again.
+func removeStaleUDSSocket(socketPath string) error {
+	fileInfo, err := os.Stat(socketPath)
+	// Socket file already exists
+	if err == nil {
+		// Make sure it's a UNIX socket
+		if fileInfo.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("cannot reuse %s socket path: path already exists and is not a UNIX socket", socketPath)
+		}
+		err = os.Remove(socketPath)
+		if err != nil {
+			return fmt.Errorf("cannot remove stale UNIX socket: %v", err)
+		}
+	}
+	return nil
+}
+
 // Listen runs the intake loop. Should be called in its own goroutine
 func (l *UDSListener) Listen() {
 	t1 := time.Now()
@@ -87,6 +87,41 @@ func processUDSOrigin(ancillary []byte) (int, string, error) {
 	return int(pid), entity, nil
 }
 
+// processUDSPeerOrigin reads the credentials of the peer of a stream connection
+// to determine its origin, it returns an integer with the peer PID, a string
+// identifying the source, and an error if any.
+// The credentials are the ones of the peer when the connection was established,
+// see SO_PEERCRED.
+func processUDSPeerOrigin(conn *net.UnixConn) (int, string, error) {
+	rawconn, err := conn.SyscallConn()
+	if err != nil {
+		return 0, packets.NoOrigin, err
+	}
+	var cred *unix.Ucred
+	var credErr error
+	err = rawconn.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return 0, packets.NoOrigin, err
+	}
+	if credErr != nil {
+		return 0, packets.NoOrigin, credErr
+	}
+
+	if cred.Pid == 0 {
+		return 0, packets.NoOrigin, fmt.Errorf("matched PID for the process is 0, it belongs " +
+			"probably to another namespace. Is the agent in host PID mode?")
+	}
+
+	entity, err := getEntityForPID(cred.Pid, false)
+	if err != nil {
+		return int(cred.Pid), packets.NoOrigin, err
+	}
+
+	return int(cred.Pid), entity, nil
+}
+
 // getEntityForPID returns the container entity name and caches the value for future lookups
 // As the result is cached and the lookup is really fast (parsing local files), it can be
 // called from the intake goroutine.
@@ -14,6 +14,8 @@
 package listeners
 
 import (
+	"net"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -51,3 +53,23 @@ func TestUDSPassCred(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, enabled, 1)
 }
+
+func TestUDSPeerCred(t *testing.T) {
+	dir := t.TempDir()
+	socketPath := filepath.Join(dir, "dsd.socket")
+
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	require.Nil(t, err)
+	defer l.Close()
+
+	client, err := net.Dial("unix", socketPath)
+	require.Nil(t, err)
+	defer client.Close()
+	conn, err := l.AcceptUnix()
+	require.Nil(t, err)
+	defer conn.Close()
+
+	// The peer is the test process itself
+	pid, _, _ := processUDSPeerOrigin(conn)
+	assert.Equal(t, os.Getpid(), pid)
+}
@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/replay"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var udsStreamTelemetry = newListenerTelemetry("uds_stream", "UDS stream")
+
+// UDSStreamListener implements the StatsdListener interface for Unix Domain
+// Socket stream protocol. It listens to a given socket path and sends back
+// packets ready to be processed, the messages being framed with a '\n' or a
+// length prefix.
+// Origin detection relies on the credentials of the peer of each connection.
+type UDSStreamListener struct {
+	*streamListener
+	trafficCapture  replay.Component // Currently ignored
+	OriginDetection bool
+}
+
+// NewUDSStreamListener returns an idle UDS stream Statsd listener
+func NewUDSStreamListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager, capture replay.Component) (*UDSStreamListener, error) {
+	socketPath := config.Datadog.GetString("dogstatsd_stream_socket")
+	originDetection := config.Datadog.GetBool("dogstatsd_origin_detection")
+
+	framing, err := parseStreamFraming(config.Datadog.GetString("dogstatsd_stream_socket_framing"))
+	if err != nil {
+		return nil, fmt.Errorf("dogstatsd-uds-stream: %s", err)
+	}
+
+	address, addrErr := net.ResolveUnixAddr("unix", socketPath)
+	if addrErr != nil {
+		return nil, fmt.Errorf("dogstatsd-uds-stream: can't ResolveUnixAddr: %v", addrErr)
+	}
+	if err := removeStaleUDSSocket(socketPath); err != nil {
+		return nil, fmt.Errorf("dogstatsd-uds-stream: %v", err)
+	}
+
+	// the socket file is removed when the listener is closed
+	listener, err := net.ListenUnix("unix", address)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+	err = os.Chmod(socketPath, 0722)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("can't set the socket at write only: %s", err)
+	}
+
+	packetManager := packets.NewPacketManagerFromConfig(packetOut, sharedPacketPoolManager, packets.UDSStream)
+	udsListener := &UDSStreamListener{
+		streamListener:  newStreamListener("uds-stream", "uds_stream", listener, framing, packetManager, udsStreamTelemetry),
+		trafficCapture:  capture,
+		OriginDetection: originDetection,
+	}
+	if originDetection {
+		log.Debugf("dogstatsd-uds-stream: enabling origin detection on %s", listener.Addr())
+		udsListener.origin = func(conn net.Conn) (string, error) {
+			_, origin, err := processUDSPeerOrigin(conn.(*net.UnixConn))
+			return origin, err
+		}
+	}
+
+	log.Debugf("dogstatsd-uds-stream: %s successfully initialized", listener.Addr())
+	return udsListener, nil
+}
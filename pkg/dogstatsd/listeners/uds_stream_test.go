@@ -0,0 +1,92 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+// +build !windows
+
+// UDS won't work in windows
+
+package listeners
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/packets"
+)
+
+func newTestUDSStreamListener(t *testing.T, socketPath string, framing string) (*UDSStreamListener, chan packets.Packets) {
+	config.Datadog.SetDefault("dogstatsd_stream_socket", socketPath)
+	config.Datadog.SetDefault("dogstatsd_stream_socket_framing", framing)
+	packetOut := make(chan packets.Packets, 16)
+	s, err := NewUDSStreamListener(packetOut, packetPoolManagerUDS, nil)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+	go s.Listen()
+	return s, packetOut
+}
+
+func TestNewUDSStreamListener(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "dsd.socket")
+
+	t.Run("fail_file_exists", func(t *testing.T) {
+		_, err := os.Create(socketPath)
+		require.NoError(t, err)
+		defer os.Remove(socketPath)
+		config.Datadog.SetDefault("dogstatsd_stream_socket", socketPath)
+		_, err = NewUDSStreamListener(nil, packetPoolManagerUDS, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("socket_exists", func(t *testing.T) {
+		address, err := net.ResolveUnixAddr("unix", socketPath)
+		require.NoError(t, err)
+		l, err := net.ListenUnix("unix", address)
+		require.NoError(t, err)
+		// keep the socket file around, as if a previous run had crashed
+		l.SetUnlinkOnClose(false)
+		l.Close()
+
+		s, _ := newTestUDSStreamListener(t, socketPath, "length_prefixed")
+		fi, err := os.Stat(socketPath)
+		require.NoError(t, err)
+		assert.Equal(t, "Srwx-w--w-", fi.Mode().String())
+
+		// the socket file is removed on stop
+		s.Stop()
+		_, err = os.Stat(socketPath)
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestUDSStreamListenerReceive(t *testing.T) {
+	for framing, payload := range map[string][]byte{
+		"newline":         []byte("daemon:666|g\nfoo:1|c\n"),
+		"length_prefixed": append(lengthPrefixed("daemon:666|g\n"), lengthPrefixed("foo:1|c")...),
+	} {
+		t.Run(framing, func(t *testing.T) {
+			socketPath := filepath.Join(t.TempDir(), "dsd.socket")
+			s, packetOut := newTestUDSStreamListener(t, socketPath, framing)
+			defer s.Stop()
+
+			conn, err := net.Dial("unix", socketPath)
+			require.NoError(t, err)
+			_, err = conn.Write(payload)
+			require.NoError(t, err)
+			conn.Close()
+
+			messages, packet := readStreamMessages(t, packetOut, 2)
+			assert.Equal(t, []string{"daemon:666|g", "foo:1|c"}, messages)
+			assert.Equal(t, packets.UDSStream, packet.Source)
+			assert.Equal(t, packets.NoOrigin, packet.Origin)
+		})
+	}
+}
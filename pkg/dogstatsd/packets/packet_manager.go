@@ -2,8 +2,6 @@
 // under the Apache License Version 2.0.
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-present Datadog, Inc.
-//go:build windows
-// +build windows
 
 package packets
 
@@ -18,15 +16,19 @@ type PacketManager struct {
 	PacketsBuffer   *Buffer
 	PacketAssembler *Assembler
 	bufferSize      int
+
+	flushTimeout            time.Duration
+	sharedPacketPoolManager *PoolManager
+	sourceType              SourceType
 }
 
 // NewPacketManagerFromConfig creates a PacketManager from the relevant config settings.
-func NewPacketManagerFromConfig(packetOut chan Packets, sharedPacketPoolManager *PoolManager) *PacketManager {
+func NewPacketManagerFromConfig(packetOut chan Packets, sharedPacketPoolManager *PoolManager, sourceType SourceType) *PacketManager {
 	bufferSize := config.Datadog.GetInt("dogstatsd_buffer_size")
 	packetsBufferSize := config.Datadog.GetInt("dogstatsd_packet_buffer_size")
 	flushTimeout := config.Datadog.GetDuration("dogstatsd_packet_buffer_flush_timeout")
 
-	return NewPacketManager(bufferSize, packetsBufferSize, flushTimeout, packetOut, sharedPacketPoolManager)
+	return NewPacketManager(bufferSize, packetsBufferSize, flushTimeout, packetOut, sharedPacketPoolManager, sourceType)
 }
 
 // NewPacketManager instantiates a PacketManager
@@ -35,17 +37,30 @@ func NewPacketManager(
 	packetsBufferSize int,
 	flushTimeout time.Duration,
 	packetOut chan Packets,
-	sharedPacketPoolManager *PoolManager) *PacketManager {
+	sharedPacketPoolManager *PoolManager,
+	sourceType SourceType) *PacketManager {
 
 	packetsBuffer := NewBuffer(uint(packetsBufferSize), flushTimeout, packetOut)
 
 	return &PacketManager{
-		bufferSize:      bufferSize,
-		PacketsBuffer:   packetsBuffer,
-		PacketAssembler: NewAssembler(flushTimeout, packetsBuffer, sharedPacketPoolManager, NamedPipe),
+		bufferSize:              bufferSize,
+		PacketsBuffer:           packetsBuffer,
+		PacketAssembler:         NewAssembler(flushTimeout, packetsBuffer, sharedPacketPoolManager, sourceType),
+		flushTimeout:            flushTimeout,
+		sharedPacketPoolManager: sharedPacketPoolManager,
+		sourceType:              sourceType,
 	}
 }
 
+// CreateAssembler creates a new assembler pushing its packets to the packets buffer, with the
+// given origin. It is meant for the listeners reading from several connections, the origin
+// being the one of the connection. The assembler must be flushed and closed by the caller.
+func (l *PacketManager) CreateAssembler(origin string) *Assembler {
+	assembler := NewAssembler(l.flushTimeout, l.PacketsBuffer, l.sharedPacketPoolManager, l.sourceType)
+	assembler.origin = origin
+	return assembler
+}
+
 // CreateBuffer creates a new buffer
 func (l *PacketManager) CreateBuffer() []byte {
 	return make([]byte, l.bufferSize)
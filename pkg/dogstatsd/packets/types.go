@@ -15,6 +15,10 @@ const (
 	UDS
 	// NamedPipe Windows named pipe listner
 	NamedPipe
+	// TCP listener
+	TCP
+	// UDSStream stream-oriented UDS listener
+	UDSStream
 )
 
 // Packet represents a statsd packet ready to process,
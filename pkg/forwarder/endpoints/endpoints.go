@@ -54,4 +54,8 @@ var (
 	OrchestratorEndpoint = transaction.Endpoint{Route: "/api/v2/orch", Name: "orchestrator"}
 	// OrchestratorManifestEndpoint is a v2 endpoint used to send orchestrator manifests
 	OrchestratorManifestEndpoint = transaction.Endpoint{Route: "/api/v2/orchmanif", Name: "orchmanifest"}
+
+	// PrometheusRemoteWriteEndpoint is the endpoint used to send Prometheus remote-write payloads, the
+	// route being part of the configured url
+	PrometheusRemoteWriteEndpoint = transaction.Endpoint{Route: "", Name: "prometheus_remote_write"}
 )
@@ -0,0 +1,329 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/endpoints"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/internal/retry"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	offlineRotationCheckInterval   = time.Minute
+	defaultOfflineRotationInterval = time.Hour
+)
+
+// FileForwarder is a Forwarder writing the transactions to local files instead of
+// sending them, for hosts without access to the intake. The files are rotated by size
+// and age, and can be uploaded later from another host with UploadOfflineTransactions.
+type FileForwarder struct {
+	defaultForwarder *DefaultForwarder
+	storagePath      string
+	rotationInterval time.Duration
+	writers          map[string]*offlineFileWriter // by domain, including the alternate ones
+	internalState    *atomic.Uint32
+	stopRotation     chan struct{}
+	rotationDone     chan struct{}
+	m                sync.Mutex // To control Start/Stop races and the writes
+}
+
+// NewFileForwarder returns a new FileForwarder writing in OfflineStoragePath a folder per
+// configured domain.
+func NewFileForwarder(options *Options) (*FileForwarder, error) {
+	f := &FileForwarder{
+		// The DefaultForwarder is only used to create the transactions, as the SyncForwarder does.
+		defaultForwarder: NewDefaultForwarder(options),
+		storagePath:      OfflineStoragePath(),
+		rotationInterval: time.Duration(config.Datadog.GetInt("forwarder_offline_mode.file_rotation_interval")) * time.Second,
+		writers:          map[string]*offlineFileWriter{},
+		internalState:    atomic.NewUint32(Stopped),
+	}
+	if f.rotationInterval <= 0 {
+		log.Warnf("'forwarder_offline_mode.file_rotation_interval' must be positive, defaulting to %v", defaultOfflineRotationInterval)
+		f.rotationInterval = defaultOfflineRotationInterval
+	}
+	fileMaxSize := config.Datadog.GetInt64("forwarder_offline_mode.file_max_size_in_bytes")
+	storageMaxSize := config.Datadog.GetInt64("forwarder_offline_mode.storage_max_size_in_bytes")
+
+	for domain, resolver := range options.DomainResolvers {
+		// The domains without API key are dropped by NewDefaultForwarder.
+		if len(resolver.GetAPIKeys()) == 0 {
+			continue
+		}
+		writer, err := newOfflineFileWriter(
+			offlineDomainFolder(f.storagePath, domain),
+			retry.NewHTTPTransactionsSerializer(resolver),
+			fileMaxSize,
+			f.rotationInterval,
+			storageMaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create the offline storage of the domain '%s': %v", domain, err)
+		}
+		f.writers[resolver.GetBaseDomain()] = writer
+		for _, v := range resolver.GetAlternateDomains() {
+			f.writers[v] = writer
+		}
+	}
+	return f, nil
+}
+
+// Start starts rotating the files by age.
+func (f *FileForwarder) Start() error {
+	// Lock so we can't stop a Forwarder while is starting
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.internalState.Load() == Started {
+		return fmt.Errorf("the file forwarder is already started")
+	}
+
+	f.stopRotation = make(chan struct{})
+	f.rotationDone = make(chan struct{})
+	go f.rotateFiles(f.stopRotation, f.rotationDone)
+
+	log.Infof("Offline mode enabled: the transactions are written to %s instead of being sent", f.storagePath)
+	f.internalState.Store(Started)
+	return nil
+}
+
+// Stop completes the files being written.
+func (f *FileForwarder) Stop() {
+	log.Infof("stopping the file forwarder")
+	f.m.Lock()
+	if f.internalState.Load() == Stopped {
+		f.m.Unlock()
+		log.Warnf("the file forwarder is already stopped")
+		return
+	}
+	f.internalState.Store(Stopped)
+	close(f.stopRotation)
+	f.m.Unlock()
+
+	// Wait outside of the lock as the rotation takes it.
+	<-f.rotationDone
+
+	f.m.Lock()
+	defer f.m.Unlock()
+	for _, writer := range f.uniqueWriters() {
+		if err := writer.closeFile(); err != nil {
+			log.Errorf("Cannot complete the offline transactions file: %v", err)
+		}
+	}
+}
+
+// State returns the internal state of the forwarder (Started or Stopped)
+func (f *FileForwarder) State() uint32 {
+	// Lock so we can't start/stop a Forwarder while getting its state
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	return f.internalState.Load()
+}
+
+func (f *FileForwarder) rotateFiles(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	checkInterval := offlineRotationCheckInterval
+	if f.rotationInterval < checkInterval {
+		checkInterval = f.rotationInterval
+	}
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			f.m.Lock()
+			for _, writer := range f.uniqueWriters() {
+				if err := writer.rotateIfOutdated(); err != nil {
+					log.Errorf("Cannot complete the offline transactions file: %v", err)
+				}
+			}
+			f.m.Unlock()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// uniqueWriters returns the writers without the duplicates of the alternate domains.
+func (f *FileForwarder) uniqueWriters() []*offlineFileWriter {
+	seen := make(map[*offlineFileWriter]struct{}, len(f.writers))
+	writers := make([]*offlineFileWriter, 0, len(f.writers))
+	for _, writer := range f.writers {
+		if _, found := seen[writer]; !found {
+			seen[writer] = struct{}{}
+			writers = append(writers, writer)
+		}
+	}
+	return writers
+}
+
+func (f *FileForwarder) writeHTTPTransactions(transactions []*transaction.HTTPTransaction) error {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.internalState.Load() == Stopped {
+		return fmt.Errorf("the file forwarder is not started")
+	}
+
+	var lastErr error
+	errorCount := 0
+	for _, t := range transactions {
+		// Like for the retry queue, the transactions containing the API key in their payload are not stored.
+		if !t.StorableOnDisk {
+			log.Debugf("Dropping a %s transaction in offline mode as it cannot be stored on disk", t.Endpoint.Name)
+			continue
+		}
+		writer, found := f.writers[t.Domain]
+		if !found {
+			lastErr = fmt.Errorf("no offline storage for the domain %s", t.Domain)
+			errorCount++
+			continue
+		}
+		if err := writer.write(t); err != nil {
+			lastErr = err
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("cannot write %d transaction(s) to the offline storage: %v", errorCount, lastErr)
+	}
+	log.Debugf("FileForwarder has written %d transactions", len(transactions))
+	return nil
+}
+
+// submitProcessLikePayload writes the transactions, the returned channel being closed
+// as no response is received.
+func (f *FileForwarder) submitProcessLikePayload(ep transaction.Endpoint, payload transaction.BytesPayloads, extra http.Header, retryable bool) (chan Response, error) {
+	transactions := f.defaultForwarder.createHTTPTransactions(ep, payload, extra)
+	for _, t := range transactions {
+		t.Retryable = retryable
+	}
+	results := make(chan Response)
+	close(results)
+	return results, f.writeHTTPTransactions(transactions)
+}
+
+// SubmitV1Series will write timeserie for the v1 endpoint (this will be remove once
+// the backend handles v2 endpoints).
+func (f *FileForwarder) SubmitV1Series(payload transaction.BytesPayloads, extra http.Header) error {
+	transactions := f.defaultForwarder.createHTTPTransactions(endpoints.V1SeriesEndpoint, payload, extra)
+	return f.writeHTTPTransactions(transactions)
+}
+
+// SubmitSeries will write timeseries for the v2 endpoint
+func (f *FileForwarder) SubmitSeries(payload transaction.BytesPayloads, extra http.Header) error {
+	transactions := f.defaultForwarder.createHTTPTransactions(endpoints.SeriesEndpoint, payload, extra)
+	return f.writeHTTPTransactions(transactions)
+}
+
+// SubmitV1Intake will write payloads for the universal `/intake/` endpoint used by Agent v.5
+func (f *FileForwarder) SubmitV1Intake(payload transaction.BytesPayloads, extra http.Header) error {
+	transactions := f.defaultForwarder.createHTTPTransactions(endpoints.V1IntakeEndpoint, payload, extra)
+	return f.writeV1IntakeTransactions(transactions)
+}
+
+func (f *FileForwarder) writeV1IntakeTransactions(transactions []*transaction.HTTPTransaction) error {
+	// the intake endpoint requires the Content-Type header to be set
+	for _, t := range transactions {
+		t.Headers.Set("Content-Type", "application/json")
+	}
+	return f.writeHTTPTransactions(transactions)
+}
+
+// SubmitV1CheckRuns will write service checks for the v1 endpoint (this will be removed once
+// the backend handles v2 endpoints).
+func (f *FileForwarder) SubmitV1CheckRuns(payload transaction.BytesPayloads, extra http.Header) error {
+	transactions := f.defaultForwarder.createHTTPTransactions(endpoints.V1CheckRunsEndpoint, payload, extra)
+	return f.writeHTTPTransactions(transactions)
+}
+
+// SubmitSketchSeries will write sketches
+func (f *FileForwarder) SubmitSketchSeries(payload transaction.BytesPayloads, extra http.Header) error {
+	transactions := f.defaultForwarder.createHTTPTransactions(endpoints.SketchSeriesEndpoint, payload, extra)
+	return f.writeHTTPTransactions(transactions)
+}
+
+// SubmitHostMetadata drops the host metadata as it contains the API key.
+func (f *FileForwarder) SubmitHostMetadata(payload transaction.BytesPayloads, extra http.Header) error {
+	transactions := f.defaultForwarder.createAdvancedHTTPTransactions(endpoints.V1IntakeEndpoint, payload, extra, transaction.TransactionPriorityHigh, false)
+	return f.writeV1IntakeTransactions(transactions)
+}
+
+// SubmitMetadata will write a metadata type payload.
+func (f *FileForwarder) SubmitMetadata(payload transaction.BytesPayloads, extra http.Header) error {
+	transactions := f.defaultForwarder.createHTTPTransactions(endpoints.V1MetadataEndpoint, payload, extra)
+	return f.writeHTTPTransactions(transactions)
+}
+
+// SubmitAgentChecksMetadata drops the agentchecks metadata as it contains the API key.
+func (f *FileForwarder) SubmitAgentChecksMetadata(payload transaction.BytesPayloads, extra http.Header) error {
+	transactions := f.defaultForwarder.createAdvancedHTTPTransactions(endpoints.V1IntakeEndpoint, payload, extra, transaction.TransactionPriorityNormal, false)
+	return f.writeV1IntakeTransactions(transactions)
+}
+
+// SubmitProcessChecks writes process checks
+func (f *FileForwarder) SubmitProcessChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.ProcessesEndpoint, payload, extra, true)
+}
+
+// SubmitProcessDiscoveryChecks writes process discovery checks
+func (f *FileForwarder) SubmitProcessDiscoveryChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.ProcessDiscoveryEndpoint, payload, extra, true)
+}
+
+// SubmitProcessEventChecks writes process events checks
+func (f *FileForwarder) SubmitProcessEventChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.ProcessLifecycleEndpoint, payload, extra, true)
+}
+
+// SubmitRTProcessChecks writes real time process checks
+func (f *FileForwarder) SubmitRTProcessChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.RtProcessesEndpoint, payload, extra, false)
+}
+
+// SubmitContainerChecks writes container checks
+func (f *FileForwarder) SubmitContainerChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.ContainerEndpoint, payload, extra, true)
+}
+
+// SubmitRTContainerChecks writes real time container checks
+func (f *FileForwarder) SubmitRTContainerChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.RtContainerEndpoint, payload, extra, false)
+}
+
+// SubmitConnectionChecks writes connection checks
+func (f *FileForwarder) SubmitConnectionChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.ConnectionsEndpoint, payload, extra, true)
+}
+
+// SubmitOrchestratorChecks writes orchestrator checks
+func (f *FileForwarder) SubmitOrchestratorChecks(payload transaction.BytesPayloads, extra http.Header, payloadType int) (chan Response, error) {
+	bumpOrchestratorPayload(payloadType)
+
+	endpoint := endpoints.OrchestratorEndpoint
+	if config.Datadog.IsSet("orchestrator_explorer.use_legacy_endpoint") {
+		endpoint = endpoints.LegacyOrchestratorEndpoint
+	}
+
+	return f.submitProcessLikePayload(endpoint, payload, extra, true)
+}
+
+// SubmitOrchestratorManifests writes orchestrator manifests
+func (f *FileForwarder) SubmitOrchestratorManifests(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
+	transactionsOrchestratorManifest.Add(1)
+	return f.submitProcessLikePayload(endpoints.OrchestratorManifestEndpoint, payload, extra, true)
+}
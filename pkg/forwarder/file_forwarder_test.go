@@ -0,0 +1,293 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/resolver"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/endpoints"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/internal/retry"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+)
+
+type offlineRequest struct {
+	path   string
+	apiKey string
+	body   string
+}
+
+// newOfflineIntake returns a server recording the requests, failing the ones for which fail returns true.
+func newOfflineIntake(requests chan<- offlineRequest, fail func() bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		requests <- offlineRequest{r.URL.Path, r.Header.Get(apiHTTPHeaderKey), string(body)}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+}
+
+func newOfflineOptions(domain string) *Options {
+	return NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(map[string][]string{domain: {"api_key1", "api_key2"}}))
+}
+
+func offlineFiles(t *testing.T, folder string) []string {
+	files, err := filepath.Glob(filepath.Join(folder, "*"))
+	require.NoError(t, err)
+	return files
+}
+
+func newOfflinePayloads(contents ...string) transaction.BytesPayloads {
+	var payloads []*[]byte
+	for _, content := range contents {
+		c := []byte(content)
+		payloads = append(payloads, &c)
+	}
+	return transaction.NewBytesPayloadsWithoutMetaData(payloads)
+}
+
+func TestFileForwarderEndToEnd(t *testing.T) {
+	storagePath := t.TempDir()
+	config.Datadog.Set("forwarder_offline_mode.storage_path", storagePath)
+	defer config.Datadog.Set("forwarder_offline_mode.storage_path", "")
+
+	requests := make(chan offlineRequest, 10)
+	ts := newOfflineIntake(requests, func() bool { return false })
+	defer ts.Close()
+
+	f, err := NewFileForwarder(newOfflineOptions(ts.URL))
+	require.NoError(t, err)
+	assert.Error(t, f.SubmitSeries(newOfflinePayloads("series"), nil))
+
+	require.NoError(t, f.Start())
+	assert.Error(t, f.Start())
+	require.NoError(t, f.SubmitSeries(newOfflinePayloads("series1", "series2"), nil))
+	require.NoError(t, f.SubmitV1CheckRuns(newOfflinePayloads("checks"), nil))
+	// the host metadata contains the API key and is not written
+	require.NoError(t, f.SubmitHostMetadata(newOfflinePayloads("host"), nil))
+	responses, err := f.SubmitProcessChecks(newOfflinePayloads("processes"), nil)
+	require.NoError(t, err)
+	_, open := <-responses
+	assert.False(t, open)
+
+	folder := offlineDomainFolder(storagePath, ts.URL)
+	files := offlineFiles(t, folder)
+	require.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0], offlineTransactionsExtension+offlineTmpExtension), files[0])
+
+	f.Stop()
+	files = offlineFiles(t, folder)
+	require.Len(t, files, 1)
+	assert.Equal(t, offlineTransactionsExtension, filepath.Ext(files[0]))
+	assert.Empty(t, requests)
+
+	// the API keys are not written
+	content, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "api_key1")
+
+	sent, err := UploadOfflineTransactions(context.Background(), newOfflineOptions(ts.URL), storagePath, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 8, sent)
+	assert.Empty(t, offlineFiles(t, folder))
+
+	close(requests)
+	received := map[offlineRequest]int{}
+	for r := range requests {
+		received[r]++
+	}
+	assert.Equal(t, map[offlineRequest]int{
+		{endpoints.SeriesEndpoint.Route, "api_key1", "series1"}:      1,
+		{endpoints.SeriesEndpoint.Route, "api_key2", "series1"}:      1,
+		{endpoints.SeriesEndpoint.Route, "api_key1", "series2"}:      1,
+		{endpoints.SeriesEndpoint.Route, "api_key2", "series2"}:      1,
+		{endpoints.V1CheckRunsEndpoint.Route, "api_key1", "checks"}:  1,
+		{endpoints.V1CheckRunsEndpoint.Route, "api_key2", "checks"}:  1,
+		{endpoints.ProcessesEndpoint.Route, "api_key1", "processes"}: 1,
+		{endpoints.ProcessesEndpoint.Route, "api_key2", "processes"}: 1,
+	}, received)
+}
+
+func TestUploadOfflineTransactionsResume(t *testing.T) {
+	storagePath := t.TempDir()
+	config.Datadog.Set("forwarder_offline_mode.storage_path", storagePath)
+	defer config.Datadog.Set("forwarder_offline_mode.storage_path", "")
+
+	requests := make(chan offlineRequest, 10)
+	requestCount := atomic.NewInt32(0)
+	ts := newOfflineIntake(requests, func() bool { return requestCount.Inc() == 2 })
+	defer ts.Close()
+
+	f, err := NewFileForwarder(NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(map[string][]string{ts.URL: {"api_key1"}})))
+	require.NoError(t, err)
+	require.NoError(t, f.Start())
+	require.NoError(t, f.SubmitSeries(newOfflinePayloads("series1", "series2", "series3"), nil))
+	f.Stop()
+
+	options := func() *Options {
+		return NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(map[string][]string{ts.URL: {"api_key1"}}))
+	}
+	// the second request fails: the file is rewritten with the transactions not sent
+	sent, err := UploadOfflineTransactions(context.Background(), options(), storagePath, time.Second)
+	assert.Error(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, offlineFiles(t, offlineDomainFolder(storagePath, ts.URL)), 1)
+
+	sent, err = UploadOfflineTransactions(context.Background(), options(), storagePath, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	assert.Empty(t, offlineFiles(t, offlineDomainFolder(storagePath, ts.URL)))
+
+	close(requests)
+	var bodies []string
+	for r := range requests {
+		bodies = append(bodies, r.body)
+	}
+	assert.Equal(t, []string{"series1", "series2", "series3"}, bodies)
+}
+
+func TestUploadOfflineTransactionsOtherAPIKeys(t *testing.T) {
+	storagePath := t.TempDir()
+	config.Datadog.Set("forwarder_offline_mode.storage_path", storagePath)
+	defer config.Datadog.Set("forwarder_offline_mode.storage_path", "")
+
+	requests := make(chan offlineRequest, 10)
+	ts := newOfflineIntake(requests, func() bool { return false })
+	defer ts.Close()
+
+	f, err := NewFileForwarder(newOfflineOptions(ts.URL))
+	require.NoError(t, err)
+	require.NoError(t, f.Start())
+	require.NoError(t, f.SubmitSeries(newOfflinePayloads("series"), nil))
+	f.Stop()
+
+	// the placeholder of the second API key cannot be restored: the file is kept
+	options := NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(map[string][]string{ts.URL: {"api_key1"}}))
+	sent, err := UploadOfflineTransactions(context.Background(), options, storagePath, time.Second)
+	assert.Error(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Len(t, offlineFiles(t, offlineDomainFolder(storagePath, ts.URL)), 1)
+}
+
+func newTestOfflineFileWriter(t *testing.T, folder string, fileMaxSize int64, rotationInterval time.Duration, storageMaxSize int64) (*offlineFileWriter, *transaction.HTTPTransaction) {
+	r := resolver.NewSingleDomainResolver("http://localhost:1234", []string{"api_key1"})
+	w, err := newOfflineFileWriter(folder, retry.NewHTTPTransactionsSerializer(r), fileMaxSize, rotationInterval, storageMaxSize)
+	require.NoError(t, err)
+
+	tr := transaction.NewHTTPTransaction()
+	tr.Domain = "http://localhost:1234"
+	tr.Endpoint = endpoints.SeriesEndpoint
+	payload := []byte("series")
+	tr.Payload = transaction.NewBytesPayloadWithoutMetaData(payload)
+	tr.Headers.Set(apiHTTPHeaderKey, "api_key1")
+	return w, tr
+}
+
+func TestOfflineFileWriterRotation(t *testing.T) {
+	folder := t.TempDir()
+
+	// the size of a file with a single transaction
+	w, tr := newTestOfflineFileWriter(t, folder, 1024, time.Hour, 0)
+	require.NoError(t, w.write(tr))
+	require.NoError(t, w.closeFile())
+	files := offlineFiles(t, folder)
+	require.Len(t, files, 1)
+	info, err := os.Stat(files[0])
+	require.NoError(t, err)
+	recordSize := info.Size()
+	require.NoError(t, os.Remove(files[0]))
+
+	// by size
+	w, tr = newTestOfflineFileWriter(t, folder, 2*recordSize, time.Hour, 0)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, w.write(tr))
+	}
+	completed, err := listOfflineFiles(folder)
+	require.NoError(t, err)
+	assert.Len(t, completed, 2)
+	assert.Len(t, offlineFiles(t, folder), 3)
+
+	// by age
+	require.NoError(t, w.rotateIfOutdated())
+	assert.Len(t, offlineFiles(t, folder), 3)
+	w.rotationInterval = time.Nanosecond
+	require.NoError(t, w.rotateIfOutdated())
+	completed, err = listOfflineFiles(folder)
+	require.NoError(t, err)
+	assert.Len(t, completed, 3)
+	assert.Len(t, offlineFiles(t, folder), 3)
+
+	var records int
+	for _, file := range completed {
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		records += len(splitOfflineRecords(content))
+	}
+	assert.Equal(t, 5, records)
+}
+
+func TestOfflineFileWriterStorageMaxSize(t *testing.T) {
+	folder := t.TempDir()
+	w, tr := newTestOfflineFileWriter(t, folder, 1, time.Hour, 0)
+	require.NoError(t, w.write(tr))
+	require.NoError(t, w.closeFile())
+	files := offlineFiles(t, folder)
+	require.Len(t, files, 1)
+	info, err := os.Stat(files[0])
+	require.NoError(t, err)
+	recordSize := info.Size()
+
+	// a transaction per file, with at most 3 files
+	w, tr = newTestOfflineFileWriter(t, folder, recordSize, time.Hour, 3*recordSize)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, w.write(tr))
+	}
+	require.NoError(t, w.closeFile())
+
+	completed, err := listOfflineFiles(folder)
+	require.NoError(t, err)
+	require.Len(t, completed, 3)
+	// the oldest file was removed
+	assert.NotContains(t, completed, files[0])
+}
+
+func TestOfflineFileWriterCompletesTmpFiles(t *testing.T) {
+	folder := t.TempDir()
+	w, tr := newTestOfflineFileWriter(t, folder, 1024, time.Hour, 0)
+	require.NoError(t, w.write(tr))
+	require.NoError(t, w.write(tr))
+	// simulate a crash while writing the second transaction
+	require.NoError(t, w.file.Truncate(w.fileSize-2))
+	require.NoError(t, w.file.Close())
+
+	completed, err := listOfflineFiles(folder)
+	require.NoError(t, err)
+	assert.Empty(t, completed)
+
+	newTestOfflineFileWriter(t, folder, 1024, time.Hour, 0)
+	completed, err = listOfflineFiles(folder)
+	require.NoError(t, err)
+	require.Len(t, completed, 1)
+	content, err := os.ReadFile(completed[0])
+	require.NoError(t, err)
+	assert.Len(t, splitOfflineRecords(content), 1)
+}
@@ -226,17 +226,23 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 	var optionalRemovalPolicy *retry.FileRemovalPolicy
 	storageMaxSize := config.Datadog.GetInt64("forwarder_storage_max_size_in_bytes")
 	var diskUsageLimit *retry.DiskUsageLimit
+	var storageEncryption *retry.StorageEncryption
+	var err error
 
 	// Disk Persistence is a core-only feature for now.
 	if storageMaxSize == 0 {
 		log.Infof("Retry queue storage on disk is disabled")
-	} else if agentName != "" {
+	} else if agentName == "" {
+		log.Infof("Retry queue storage on disk is disabled because the feature is unavailable for this process.")
+	} else if storageEncryption, err = buildStorageEncryption(); err != nil {
+		// Never fall back to storing the transactions unencrypted.
+		log.Errorf("Retry queue storage on disk is disabled because of the encryption settings: %v", err)
+	} else {
 		storagePath := config.Datadog.GetString("forwarder_storage_path")
 		if storagePath == "" {
 			storagePath = path.Join(config.Datadog.GetString("run_path"), "transactions_to_retry")
 		}
 		outdatedFileInDays := config.Datadog.GetInt("forwarder_outdated_file_in_days")
-		var err error
 
 		storagePath = path.Join(storagePath, agentName)
 		optionalRemovalPolicy, err = retry.NewFileRemovalPolicy(storagePath, outdatedFileInDays, retry.FileRemovalPolicyTelemetry{})
@@ -252,9 +258,6 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 
 		diskRatio := config.Datadog.GetFloat64("forwarder_storage_max_disk_ratio")
 		diskUsageLimit = retry.NewDiskUsageLimit(storagePath, filesystem.NewDisk(), storageMaxSize, diskRatio)
-
-	} else {
-		log.Infof("Retry queue storage on disk is disabled because the feature is unavailable for this process.")
 	}
 
 	flushToDiskMemRatio := config.Datadog.GetFloat64("forwarder_flush_to_disk_mem_ratio")
@@ -282,6 +285,7 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 				flushToDiskMemRatio,
 				domainFolderPath,
 				diskUsageLimit,
+				storageEncryption,
 				transactionContainerSort,
 				resolver,
 				pointCountTelemetry)
@@ -429,6 +433,9 @@ func (f *DefaultForwarder) createAdvancedHTTPTransactions(endpoint transaction.E
 	for _, payload := range payloads {
 		for domain, dr := range f.domainResolvers {
 			for _, apiKey := range dr.GetAPIKeys() {
+				if !payload.GetDestinations().Accept(apiKey) {
+					continue
+				}
 				t := transaction.NewHTTPTransaction()
 				t.Domain, _ = dr.Resolve(endpoint)
 				t.Endpoint = endpoint
@@ -197,6 +197,29 @@ func TestCreateHTTPTransactionsWithMultipleDomains(t *testing.T) {
 	assert.Equal(t, txBar[0].Headers.Get("DD-Api-Key"), "api-key-3")
 }
 
+func TestCreateHTTPTransactionsWithDestinations(t *testing.T) {
+	forwarder := NewDefaultForwarder(NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(keysWithMultipleDomains)))
+	endpoint := transaction.Endpoint{Route: "/api/foo", Name: "foo"}
+	p1 := []byte("A payload")
+	p2 := []byte("Another payload")
+	routed := transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&p1})
+	routed.SetDestinations(transaction.NewDestinations([]string{"api-key-3"}))
+	others := transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&p2})
+	others.SetDestinations(transaction.NewDestinationsExcept([]string{"api-key-3"}))
+
+	transactions := forwarder.createHTTPTransactions(endpoint, routed, nil)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "datadog.bar", transactions[0].Domain)
+	assert.Equal(t, "api-key-3", transactions[0].Headers.Get("DD-Api-Key"))
+
+	transactions = forwarder.createHTTPTransactions(endpoint, others, nil)
+	require.Len(t, transactions, 2)
+	for _, tx := range transactions {
+		assert.Equal(t, testVersionDomain, tx.Domain)
+		assert.NotEqual(t, "api-key-3", tx.Headers.Get("DD-Api-Key"))
+	}
+}
+
 func TestCreateHTTPTransactionsWithDifferentResolvers(t *testing.T) {
 	resolvers := resolver.NewSingleDomainResolvers(keysWithMultipleDomains)
 	additionalResolver := resolver.NewMultiDomainResolver("datadog.vector", []string{"api-key-4"})
@@ -23,6 +23,7 @@ const retryFileFormat = "2006_01_02__15_04_05_"
 
 type onDiskRetryQueue struct {
 	serializer          *HTTPTransactionsSerializer
+	encryption          *StorageEncryption
 	storagePath         string
 	diskUsageLimit      *DiskUsageLimit
 	filenames           []string
@@ -33,6 +34,7 @@ type onDiskRetryQueue struct {
 
 func newOnDiskRetryQueue(
 	serializer *HTTPTransactionsSerializer,
+	encryption *StorageEncryption,
 	storagePath string,
 	diskUsageLimit *DiskUsageLimit,
 	telemetry onDiskRetryQueueTelemetry,
@@ -44,6 +46,7 @@ func newOnDiskRetryQueue(
 
 	storage := &onDiskRetryQueue{
 		serializer:          serializer,
+		encryption:          encryption,
 		storagePath:         storagePath,
 		diskUsageLimit:      diskUsageLimit,
 		telemetry:           telemetry,
@@ -79,6 +82,9 @@ func (s *onDiskRetryQueue) Store(transactions []transaction.Transaction) error {
 	if err != nil {
 		return err
 	}
+	if bytes, err = s.encryption.encrypt(bytes); err != nil {
+		return err
+	}
 	bufferSize := int64(len(bytes))
 
 	if err := s.makeRoomFor(bufferSize); err != nil {
@@ -116,7 +122,7 @@ func (s *onDiskRetryQueue) ExtractLast() ([]transaction.Transaction, error) {
 	s.telemetry.addDeserializeCount()
 	index := len(s.filenames) - 1
 	path := s.filenames[index]
-	bytes, err := os.ReadFile(path)
+	bytes, err := s.readFile(path)
 
 	// Remove the file even in case of a read failure.
 	if errRemoveFile := s.removeFileAt(index); errRemoveFile != nil {
@@ -163,7 +169,7 @@ func (s *onDiskRetryQueue) makeRoomFor(bufferSize int64) error {
 		filename := s.filenames[index]
 		log.Errorf("Maximum disk space for retry transactions is reached. Removing %s", filename)
 
-		bytes, err := os.ReadFile(filename)
+		bytes, err := s.readFile(filename)
 		if err != nil {
 			log.Errorf("Cannot read the file %v: %v", filename, err)
 		} else if transactions, _, errDeserialize := s.serializer.Deserialize(bytes); errDeserialize == nil {
@@ -185,6 +191,15 @@ func (s *onDiskRetryQueue) makeRoomFor(bufferSize int64) error {
 	return nil
 }
 
+// readFile returns the decrypted content of a retry file.
+func (s *onDiskRetryQueue) readFile(path string) ([]byte, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.encryption.decrypt(bytes)
+}
+
 func (s *onDiskRetryQueue) onPointDropped(count int) {
 	s.telemetry.addPointDroppedCount(count)
 	s.pointCountTelemetry.OnPointDropped(count)
@@ -9,6 +9,8 @@
 package retry
 
 import (
+	"bytes"
+	"os"
 	"strconv"
 	"testing"
 
@@ -102,6 +104,49 @@ func TestOnDiskRetryQueueReloadExistingRetryFiles(t *testing.T) {
 	a.Equal([]string{"endpoint1", "endpoint2"}, getEndpointsFromTransactions(transactions))
 }
 
+func TestOnDiskRetryQueueEncryption(t *testing.T) {
+	a := assert.New(t)
+	path := t.TempDir()
+	oldKey := bytes.Repeat([]byte{1}, StorageEncryptionKeySize)
+	newKey := bytes.Repeat([]byte{2}, StorageEncryptionKeySize)
+
+	// a file written without encryption, and one encrypted with the old key
+	retryQueue := newTestOnDiskRetryQueue(a, path, 1000)
+	a.NoError(retryQueue.Store(createHTTPTransactionCollectionTests("endpoint1")))
+	oldEncryption, err := NewStorageEncryption(oldKey, nil)
+	a.NoError(err)
+	retryQueue = newTestEncryptedOnDiskRetryQueue(a, path, 1000, oldEncryption)
+	a.NoError(retryQueue.Store(createHTTPTransactionCollectionTests("endpoint2")))
+
+	// the key is rotated, the old one still being used to read the existing files
+	newEncryption, err := NewStorageEncryption(newKey, [][]byte{oldKey})
+	a.NoError(err)
+	retryQueue = newTestEncryptedOnDiskRetryQueue(a, path, 1000, newEncryption)
+	a.NoError(retryQueue.Store(createHTTPTransactionCollectionTests("endpoint3")))
+	a.Equal(3, retryQueue.getFilesCount())
+
+	content, err := os.ReadFile(retryQueue.filenames[2])
+	a.NoError(err)
+	a.NotContains(string(content), "endpoint3")
+
+	var endpoints []string
+	for i := 0; i < 3; i++ {
+		transactions, err := retryQueue.ExtractLast()
+		a.NoError(err)
+		endpoints = append(endpoints, getEndpointsFromTransactions(transactions)...)
+	}
+	a.ElementsMatch([]string{"endpoint1", "endpoint2", "endpoint3"}, endpoints)
+
+	// the files encrypted with an unknown key cannot be read
+	a.NoError(retryQueue.Store(createHTTPTransactionCollectionTests("endpoint4")))
+	newEncryption, err = NewStorageEncryption(bytes.Repeat([]byte{3}, StorageEncryptionKeySize), nil)
+	a.NoError(err)
+	retryQueue = newTestEncryptedOnDiskRetryQueue(a, path, 1000, newEncryption)
+	_, err = retryQueue.ExtractLast()
+	a.Error(err)
+	a.Equal(0, retryQueue.getFilesCount())
+}
+
 func createHTTPTransactionCollectionTests(endpoints ...string) []transaction.Transaction {
 	var transactions []transaction.Transaction
 
@@ -126,6 +171,10 @@ func getEndpointsFromTransactions(transactions []transaction.Transaction) []stri
 }
 
 func newTestOnDiskRetryQueue(a *assert.Assertions, path string, maxSizeInBytes int64) *onDiskRetryQueue {
+	return newTestEncryptedOnDiskRetryQueue(a, path, maxSizeInBytes, nil)
+}
+
+func newTestEncryptedOnDiskRetryQueue(a *assert.Assertions, path string, maxSizeInBytes int64, encryption *StorageEncryption) *onDiskRetryQueue {
 	telemetry := newOnDiskRetryQueueTelemetry("domain")
 	disk := diskUsageRetrieverMock{
 		diskUsage: &filesystem.DiskUsage{
@@ -133,7 +182,7 @@ func newTestOnDiskRetryQueue(a *assert.Assertions, path string, maxSizeInBytes i
 			Total:     10000,
 		}}
 	diskUsageLimit := NewDiskUsageLimit("", disk, maxSizeInBytes, 1)
-	storage, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domainName, nil)), path, diskUsageLimit, telemetry, NewPointCountTelemetryMock())
+	storage, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domainName, nil)), encryption, path, diskUsageLimit, telemetry, NewPointCountTelemetryMock())
 	a.NoError(err)
 	return storage
 }
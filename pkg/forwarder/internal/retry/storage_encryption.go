@@ -0,0 +1,133 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package retry
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// StorageEncryptionKeySize is the size of the keys used to encrypt the retry files (AES-256).
+const StorageEncryptionKeySize = 32
+
+// An encrypted retry file is made of a header and of the transactions sealed with AES-256-GCM:
+//
+//	magic (5 bytes) | version (1 byte) | key ID (8 bytes) | nonce (12 bytes) | ciphertext and tag
+//
+// The header is authenticated as additional data. A serialized HttpTransactionProtoCollection
+// cannot start with the magic, so that the files written without encryption remain readable.
+var encryptedFileMagic = []byte("DDENC")
+
+const (
+	encryptedFileVersion = 1
+	storageKeyIDSize     = 8
+	encryptedHeaderSize  = 5 + 1 + storageKeyIDSize
+)
+
+type storageKeyID [storageKeyIDSize]byte
+
+// StorageEncryption encrypts the transactions stored on disk with authenticated encryption.
+// New files are encrypted with the current key, the previous keys being only used to
+// decrypt the files written before a key rotation.
+// A nil *StorageEncryption stores the transactions unencrypted.
+type StorageEncryption struct {
+	currentKeyID storageKeyID
+	aeads        map[storageKeyID]cipher.AEAD
+}
+
+// NewStorageEncryption returns a new StorageEncryption encrypting with currentKey and
+// decrypting with currentKey or previousKeys.
+func NewStorageEncryption(currentKey []byte, previousKeys [][]byte) (*StorageEncryption, error) {
+	e := &StorageEncryption{aeads: make(map[storageKeyID]cipher.AEAD)}
+	for i, key := range append([][]byte{currentKey}, previousKeys...) {
+		if len(key) != StorageEncryptionKeySize {
+			return nil, fmt.Errorf("invalid encryption key size: %d bytes instead of %d", len(key), StorageEncryptionKeySize)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		id := getStorageKeyID(key)
+		if i == 0 {
+			e.currentKeyID = id
+		}
+		e.aeads[id] = aead
+	}
+	return e, nil
+}
+
+// getStorageKeyID identifies a key in the encrypted files without revealing it.
+func getStorageKeyID(key []byte) storageKeyID {
+	var id storageKeyID
+	hash := sha256.Sum256(key)
+	copy(id[:], hash[:])
+	return id
+}
+
+func (e *StorageEncryption) encrypt(plaintext []byte) ([]byte, error) {
+	if e == nil {
+		return plaintext, nil
+	}
+	aead := e.aeads[e.currentKeyID]
+
+	header := make([]byte, 0, encryptedHeaderSize)
+	header = append(header, encryptedFileMagic...)
+	header = append(header, encryptedFileVersion)
+	header = append(header, e.currentKeyID[:]...)
+
+	out := make([]byte, encryptedHeaderSize+aead.NonceSize(), encryptedHeaderSize+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	copy(out, header)
+	nonce := out[encryptedHeaderSize:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, nonce, plaintext, header), nil
+}
+
+// decrypt returns the content of a retry file, the files written without encryption
+// being returned unchanged.
+func (e *StorageEncryption) decrypt(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, encryptedFileMagic) {
+		return data, nil
+	}
+	if e == nil {
+		return nil, errors.New("the file is encrypted but no encryption key is configured")
+	}
+	if len(data) < encryptedHeaderSize {
+		return nil, errors.New("the encrypted file is truncated")
+	}
+	if version := data[len(encryptedFileMagic)]; version != encryptedFileVersion {
+		return nil, fmt.Errorf("unsupported encrypted file version %d", version)
+	}
+
+	var id storageKeyID
+	copy(id[:], data[len(encryptedFileMagic)+1:encryptedHeaderSize])
+	aead, found := e.aeads[id]
+	if !found {
+		return nil, errors.New("the file is encrypted with an unknown key")
+	}
+	if len(data) < encryptedHeaderSize+aead.NonceSize() {
+		return nil, errors.New("the encrypted file is truncated")
+	}
+
+	header := data[:encryptedHeaderSize]
+	nonce := data[encryptedHeaderSize : encryptedHeaderSize+aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, data[encryptedHeaderSize+aead.NonceSize():], header)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt the file: %v", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,113 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package retry
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config/resolver"
+)
+
+func TestStorageEncryption(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, StorageEncryptionKeySize)
+	e, err := NewStorageEncryption(key, nil)
+	require.NoError(t, err)
+
+	plaintext := []byte("transactions")
+	encrypted, err := e.encrypt(plaintext)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(encrypted, encryptedFileMagic))
+	assert.NotContains(t, string(encrypted), string(plaintext))
+
+	// a new nonce is used for each file
+	encryptedAgain, err := e.encrypt(plaintext)
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted, encryptedAgain)
+
+	decrypted, err := e.decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, decrypted)
+
+	// the header and the content are authenticated
+	for _, i := range []int{len(encryptedFileMagic) + 1, len(encrypted) - 1} {
+		tampered := append([]byte{}, encrypted...)
+		tampered[i] ^= 1
+		_, err = e.decrypt(tampered)
+		assert.Error(t, err)
+	}
+	_, err = e.decrypt(encrypted[:encryptedHeaderSize+1])
+	assert.Error(t, err)
+}
+
+func TestStorageEncryptionKeyRotation(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, StorageEncryptionKeySize)
+	newKey := bytes.Repeat([]byte{2}, StorageEncryptionKeySize)
+
+	oldEncryption, err := NewStorageEncryption(oldKey, nil)
+	require.NoError(t, err)
+	encryptedWithOldKey, err := oldEncryption.encrypt([]byte("old"))
+	require.NoError(t, err)
+
+	e, err := NewStorageEncryption(newKey, [][]byte{oldKey})
+	require.NoError(t, err)
+	decrypted, err := e.decrypt(encryptedWithOldKey)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("old"), decrypted)
+
+	// new files are encrypted with the new key only
+	encrypted, err := e.encrypt([]byte("new"))
+	require.NoError(t, err)
+	_, err = oldEncryption.decrypt(encrypted)
+	assert.Error(t, err)
+
+	withoutOldKey, err := NewStorageEncryption(newKey, nil)
+	require.NoError(t, err)
+	_, err = withoutOldKey.decrypt(encryptedWithOldKey)
+	assert.Error(t, err)
+}
+
+func TestStorageEncryptionUnencryptedFiles(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, StorageEncryptionKeySize)
+	e, err := NewStorageEncryption(key, nil)
+	require.NoError(t, err)
+
+	serializer := NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domainName, nil))
+	for _, tr := range createHTTPTransactionCollectionTests("endpoint") {
+		require.NoError(t, tr.SerializeTo(serializer))
+	}
+	plaintext, err := serializer.GetBytesAndReset()
+	require.NoError(t, err)
+
+	// the files written before enabling the encryption remain readable
+	decrypted, err := e.decrypt(plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, decrypted)
+
+	// without encryption, nothing is changed but the encrypted files cannot be read
+	var noEncryption *StorageEncryption
+	data, err := noEncryption.encrypt(plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, data)
+	encrypted, err := e.encrypt(plaintext)
+	require.NoError(t, err)
+	_, err = noEncryption.decrypt(encrypted)
+	assert.Error(t, err)
+}
+
+func TestNewStorageEncryptionInvalidKey(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, StorageEncryptionKeySize)
+	_, err := NewStorageEncryption(key[:16], nil)
+	assert.Error(t, err)
+	_, err = NewStorageEncryption(key, [][]byte{key[:16]})
+	assert.Error(t, err)
+}
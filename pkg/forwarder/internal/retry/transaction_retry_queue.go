@@ -48,6 +48,7 @@ func BuildTransactionRetryQueue(
 	flushToStorageRatio float64,
 	optionalDomainFolderPath string,
 	optionalDiskUsageLimit *DiskUsageLimit,
+	optionalEncryption *StorageEncryption,
 	dropPrioritySorter TransactionPrioritySorter,
 	resolver resolver.DomainResolver,
 	pointCountTelemetry *PointCountTelemetry) *TransactionRetryQueue {
@@ -57,7 +58,7 @@ func BuildTransactionRetryQueue(
 
 	if optionalDomainFolderPath != "" && optionalDiskUsageLimit != nil {
 		serializer := NewHTTPTransactionsSerializer(resolver)
-		storage, err = newOnDiskRetryQueue(serializer, optionalDomainFolderPath, optionalDiskUsageLimit, newOnDiskRetryQueueTelemetry(resolver.GetBaseDomain()), pointCountTelemetry)
+		storage, err = newOnDiskRetryQueue(serializer, optionalEncryption, optionalDomainFolderPath, optionalDiskUsageLimit, newOnDiskRetryQueueTelemetry(resolver.GetBaseDomain()), pointCountTelemetry)
 
 		// If the storage on disk cannot be used, log the error and continue.
 		// Returning `nil, err` would mean not using `TransactionRetryQueue` and so not using `forwarder_retry_queue_payloads_max_size` config.
@@ -156,6 +156,7 @@ func newOnDiskRetryQueueTest(t *testing.T, a *assert.Assertions) *onDiskRetryQue
 	diskUsageLimit := NewDiskUsageLimit("", disk, 1000, 1)
 	q, err := newOnDiskRetryQueue(
 		NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver("", nil)),
+		nil,
 		path,
 		diskUsageLimit,
 		newOnDiskRetryQueueTelemetry("domain"),
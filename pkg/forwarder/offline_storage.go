@@ -0,0 +1,236 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/internal/retry"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// The files written in offline mode are a sequence of records, each record being a
+// serialized transaction prefixed by its size as a big endian uint32. The file being
+// written has the `.offline.tmp` extension, and is renamed once complete.
+const (
+	offlineTransactionsExtension = ".offline"
+	offlineTmpExtension          = ".tmp"
+	offlineFileFormat            = "2006_01_02__15_04_05.000000000_"
+	offlineRecordHeaderSize      = 4
+)
+
+// OfflineStoragePath returns the folder where the transactions are written in offline mode.
+func OfflineStoragePath() string {
+	storagePath := config.Datadog.GetString("forwarder_offline_mode.storage_path")
+	if storagePath == "" {
+		storagePath = path.Join(config.Datadog.GetString("run_path"), "offline_transactions")
+	}
+	return storagePath
+}
+
+// offlineDomainFolder returns the folder of the transactions of a configured domain. Like for
+// the retry queue, the md5 of the domain is used as it can contain characters invalid in a path.
+// The configured domain is used rather than the base domain of its resolver, which contains the
+// Agent version, for the files to be uploaded by any version of the Agent.
+func offlineDomainFolder(storagePath string, domain string) string {
+	return path.Join(storagePath, fmt.Sprintf("%x", md5.Sum([]byte(domain))))
+}
+
+// offlineFileWriter writes the transactions of a domain to size and time rotated files.
+// The API keys are replaced by placeholders and the domain is not stored, see
+// retry.HTTPTransactionsSerializer.
+type offlineFileWriter struct {
+	folderPath       string
+	serializer       *retry.HTTPTransactionsSerializer
+	fileMaxSize      int64
+	rotationInterval time.Duration
+	storageMaxSize   int64
+
+	file          *os.File
+	fileSize      int64
+	fileCreatedAt time.Time
+}
+
+func newOfflineFileWriter(
+	folderPath string,
+	serializer *retry.HTTPTransactionsSerializer,
+	fileMaxSize int64,
+	rotationInterval time.Duration,
+	storageMaxSize int64) (*offlineFileWriter, error) {
+
+	if err := os.MkdirAll(folderPath, 0700); err != nil {
+		return nil, err
+	}
+
+	// Complete the files left by a previous run, their last record being ignored if truncated.
+	tmpFiles, err := filepath.Glob(path.Join(folderPath, "*"+offlineTransactionsExtension+offlineTmpExtension))
+	if err != nil {
+		return nil, err
+	}
+	for _, tmpFile := range tmpFiles {
+		if err := os.Rename(tmpFile, strings.TrimSuffix(tmpFile, offlineTmpExtension)); err != nil {
+			log.Errorf("Cannot complete the offline transactions file %s: %v", tmpFile, err)
+		}
+	}
+
+	return &offlineFileWriter{
+		folderPath:       folderPath,
+		serializer:       serializer,
+		fileMaxSize:      fileMaxSize,
+		rotationInterval: rotationInterval,
+		storageMaxSize:   storageMaxSize,
+	}, nil
+}
+
+// write appends a transaction to the current file, starting a new file if needed.
+func (w *offlineFileWriter) write(t *transaction.HTTPTransaction) error {
+	// Reset the serializer in case a transaction was serialized
+	// but `GetBytesAndReset` was not called because of an error.
+	_, _ = w.serializer.GetBytesAndReset()
+
+	if err := t.SerializeTo(w.serializer); err != nil {
+		return err
+	}
+	bytes, err := w.serializer.GetBytesAndReset()
+	if err != nil {
+		return err
+	}
+	record := appendOfflineRecord(nil, bytes)
+
+	if w.file != nil && w.fileSize+int64(len(record)) > w.fileMaxSize {
+		if err := w.closeFile(); err != nil {
+			return err
+		}
+	}
+	if w.file == nil {
+		if err := w.createFile(); err != nil {
+			return err
+		}
+	}
+
+	n, err := w.file.Write(record)
+	w.fileSize += int64(n)
+	return err
+}
+
+// rotateIfOutdated completes the current file if it was created more than
+// rotationInterval ago.
+func (w *offlineFileWriter) rotateIfOutdated() error {
+	if w.file == nil || time.Since(w.fileCreatedAt) < w.rotationInterval {
+		return nil
+	}
+	return w.closeFile()
+}
+
+func (w *offlineFileWriter) createFile() error {
+	w.makeRoom()
+
+	filename := time.Now().UTC().Format(offlineFileFormat)
+	file, err := os.CreateTemp(w.folderPath, filename+"*"+offlineTransactionsExtension+offlineTmpExtension)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.fileSize = 0
+	w.fileCreatedAt = time.Now()
+	return nil
+}
+
+// closeFile closes the current file and renames it so that it can be uploaded.
+func (w *offlineFileWriter) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	filename := w.file.Name()
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return err
+	}
+	return os.Rename(filename, strings.TrimSuffix(filename, offlineTmpExtension))
+}
+
+// makeRoom removes the oldest complete files until a new file fits in storageMaxSize.
+// The folder is listed every time as the files can be moved away while the Agent runs.
+func (w *offlineFileWriter) makeRoom() {
+	if w.storageMaxSize <= 0 {
+		return
+	}
+	filenames, err := listOfflineFiles(w.folderPath)
+	if err != nil {
+		log.Errorf("Cannot list the offline transactions files: %v", err)
+		return
+	}
+
+	sizes := make([]int64, len(filenames))
+	totalSize := int64(0)
+	for i, filename := range filenames {
+		if info, err := os.Stat(filename); err == nil {
+			sizes[i] = info.Size()
+			totalSize += sizes[i]
+		}
+	}
+	for i := 0; i < len(filenames) && totalSize+w.fileMaxSize > w.storageMaxSize; i++ {
+		log.Errorf("Maximum disk space for offline transactions is reached. Removing %s", filenames[i])
+		if err := os.Remove(filenames[i]); err != nil && !os.IsNotExist(err) {
+			log.Errorf("Cannot remove the offline transactions file %s: %v", filenames[i], err)
+			continue
+		}
+		totalSize -= sizes[i]
+	}
+}
+
+// listOfflineFiles returns the complete files of a folder, oldest first.
+func listOfflineFiles(folderPath string) ([]string, error) {
+	entries, err := os.ReadDir(folderPath)
+	if err != nil {
+		return nil, err
+	}
+	var filenames []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && filepath.Ext(entry.Name()) == offlineTransactionsExtension {
+			filenames = append(filenames, path.Join(folderPath, entry.Name()))
+		}
+	}
+	// The file names start with their UTC creation time, with a nanosecond precision.
+	sort.Strings(filenames)
+	return filenames, nil
+}
+
+// appendOfflineRecord appends to buf a serialized transaction prefixed by its size.
+func appendOfflineRecord(buf []byte, record []byte) []byte {
+	var header [offlineRecordHeaderSize]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(record)))
+	buf = append(buf, header[:]...)
+	return append(buf, record...)
+}
+
+// splitOfflineRecords returns the serialized transactions of a file. A truncated last record,
+// left by a crash of the Agent, is ignored.
+func splitOfflineRecords(content []byte) [][]byte {
+	var records [][]byte
+	for len(content) > 0 {
+		if len(content) < offlineRecordHeaderSize || int(binary.BigEndian.Uint32(content)) > len(content)-offlineRecordHeaderSize {
+			log.Warnf("Ignoring a truncated offline transaction of %d bytes", len(content))
+			break
+		}
+		size := int(binary.BigEndian.Uint32(content))
+		content = content[offlineRecordHeaderSize:]
+		records = append(records, content[:size])
+		content = content[size:]
+	}
+	return records
+}
@@ -0,0 +1,145 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/forwarder/internal/retry"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	utilhttp "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// UploadOfflineTransactions sends to the intake the transactions written by a FileForwarder
+// in storagePath, for the domains of options. As the API keys are stored as placeholders, the
+// API keys of each domain must be the ones of the host where the transactions were written.
+//
+// Each file is removed once its transactions are sent. When a transaction cannot be sent, the
+// file is rewritten with the transactions not sent yet and the upload stops, the next call
+// resuming it. It returns the number of transactions sent.
+func UploadOfflineTransactions(ctx context.Context, options *Options, storagePath string, timeout time.Duration) (int, error) {
+	// NewDefaultForwarder sets the base domain of the resolvers.
+	_ = NewDefaultForwarder(options)
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: utilhttp.CreateHTTPTransport(),
+	}
+
+	sentCount := 0
+	knownFolders := map[string]struct{}{}
+	for domain, resolver := range options.DomainResolvers {
+		if len(resolver.GetAPIKeys()) == 0 {
+			continue
+		}
+		folderPath := offlineDomainFolder(storagePath, domain)
+		knownFolders[folderPath] = struct{}{}
+
+		filenames, err := listOfflineFiles(folderPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return sentCount, err
+		}
+		serializer := retry.NewHTTPTransactionsSerializer(resolver)
+		for _, filename := range filenames {
+			count, err := uploadOfflineFile(ctx, client, serializer, filename)
+			sentCount += count
+			if err != nil {
+				return sentCount, err
+			}
+			log.Infof("Uploaded %d transaction(s) from %s to %s", count, filename, domain)
+		}
+	}
+
+	if entries, err := os.ReadDir(storagePath); err == nil {
+		for _, entry := range entries {
+			folderPath := path.Join(storagePath, entry.Name())
+			if _, found := knownFolders[folderPath]; entry.IsDir() && !found {
+				log.Warnf("Ignoring %s, which contains the transactions of a domain which is not configured", folderPath)
+			}
+		}
+	}
+	return sentCount, nil
+}
+
+// uploadOfflineFile sends the transactions of a file and removes it, or rewrites it with
+// the transactions not sent on a failure.
+func uploadOfflineFile(ctx context.Context, client *http.Client, serializer *retry.HTTPTransactionsSerializer, filename string) (int, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return 0, err
+	}
+
+	sentCount := 0
+	records := splitOfflineRecords(content)
+	for i, record := range records {
+		if err := sendOfflineRecord(ctx, client, serializer, record); err != nil {
+			if errRewrite := rewriteOfflineFile(filename, records[i:]); errRewrite != nil {
+				log.Errorf("Cannot rewrite %s, the transactions already sent will be sent again: %v", filename, errRewrite)
+			}
+			return sentCount, fmt.Errorf("cannot upload %s: %v", filename, err)
+		}
+		sentCount++
+	}
+	return sentCount, os.Remove(filename)
+}
+
+func sendOfflineRecord(ctx context.Context, client *http.Client, serializer *retry.HTTPTransactionsSerializer, record []byte) error {
+	transactions, errorCount, err := serializer.Deserialize(record)
+	if err != nil {
+		return err
+	}
+	if errorCount > 0 {
+		// The API keys of the domain are not the ones of the host which wrote the transaction.
+		return fmt.Errorf("cannot restore the transaction, check that the API keys are the ones of the host in offline mode")
+	}
+
+	for _, t := range transactions {
+		var statusCode int
+		if httpTransaction, ok := t.(*transaction.HTTPTransaction); ok {
+			// Report every failure, and not only the ones of the retryable transactions.
+			httpTransaction.Retryable = true
+			httpTransaction.CompletionHandler = func(_ *transaction.HTTPTransaction, code int, _ []byte, _ error) {
+				statusCode = code
+			}
+		}
+		if err := t.Process(ctx, client); err != nil {
+			return err
+		}
+		// Process does not report the canceled transactions.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// The transactions rejected with a 403 are not retried by the forwarder, but the file
+		// is kept here as the other transactions would be rejected too.
+		if statusCode == http.StatusForbidden {
+			return fmt.Errorf("the API key of the transaction is invalid")
+		}
+	}
+	return nil
+}
+
+// rewriteOfflineFile replaces the content of a file by records, the file being renamed
+// over to not lose its content on a failure.
+func rewriteOfflineFile(filename string, records [][]byte) error {
+	var content []byte
+	for _, record := range records {
+		content = appendOfflineRecord(content, record)
+	}
+	tmpFilename := filename + offlineTmpExtension
+	if err := os.WriteFile(tmpFilename, content, 0600); err != nil {
+		_ = os.Remove(tmpFilename)
+		return err
+	}
+	return os.Rename(tmpFilename, filename)
+}
@@ -0,0 +1,241 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/endpoints"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/internal/retry"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+const remoteWriteVersion = "0.1.0"
+
+// remoteWriteEndpoint is a validated config.RemoteWriteEndpoint.
+type remoteWriteEndpoint struct {
+	url     string
+	headers http.Header
+}
+
+func newRemoteWriteEndpoint(endpoint config.RemoteWriteEndpoint) (remoteWriteEndpoint, error) {
+	u, err := url.Parse(endpoint.URL)
+	if err != nil {
+		return remoteWriteEndpoint{}, fmt.Errorf("invalid remote write url: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return remoteWriteEndpoint{}, fmt.Errorf("invalid remote write url %q: the scheme must be http or https", u.Redacted())
+	}
+	if endpoint.BearerToken != "" && endpoint.Username != "" {
+		return remoteWriteEndpoint{}, fmt.Errorf("invalid remote write endpoint %q: bearer_token and username are mutually exclusive", u.Redacted())
+	}
+
+	headers := make(http.Header)
+	for key, value := range endpoint.Headers {
+		headers.Set(key, value)
+	}
+	if endpoint.BearerToken != "" {
+		headers.Set("Authorization", "Bearer "+endpoint.BearerToken)
+	}
+	if endpoint.Username != "" {
+		credentials := endpoint.Username + ":" + endpoint.Password
+		headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+	headers.Set("Content-Type", "application/x-protobuf")
+	headers.Set("Content-Encoding", "snappy")
+	headers.Set("X-Prometheus-Remote-Write-Version", remoteWriteVersion)
+	headers.Set(useragentHTTPHeaderKey, fmt.Sprintf("datadog-agent/%s", version.AgentVersion))
+
+	return remoteWriteEndpoint{url: endpoint.URL, headers: headers}, nil
+}
+
+// RemoteWriteForwarder sends Prometheus remote-write payloads to a set of endpoints.
+// Like the DefaultForwarder, each endpoint has its own workers and retry queue, the
+// failed transactions being retried in memory.
+type RemoteWriteForwarder struct {
+	// NumberOfWorkers Number of concurrent HTTP request made to each endpoint.
+	NumberOfWorkers int
+
+	endpoints        []remoteWriteEndpoint
+	domainForwarders map[string]*domainForwarder
+	internalState    *atomic.Uint32
+	m                sync.Mutex // To control Start/Stop races
+}
+
+// NewRemoteWriteForwarder returns a new RemoteWriteForwarder sending to endpoints.
+// The number of workers and the size of the retry queues are taken from options.
+func NewRemoteWriteForwarder(remoteWriteEndpoints []config.RemoteWriteEndpoint, options *Options) (*RemoteWriteForwarder, error) {
+	if len(remoteWriteEndpoints) == 0 {
+		return nil, fmt.Errorf("no remote write endpoint configured")
+	}
+
+	f := &RemoteWriteForwarder{
+		NumberOfWorkers:  options.NumberOfWorkers,
+		domainForwarders: map[string]*domainForwarder{},
+		internalState:    atomic.NewUint32(Stopped),
+	}
+
+	flushToDiskMemRatio := config.Datadog.GetFloat64("forwarder_flush_to_disk_mem_ratio")
+	domainForwarderSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: true}
+	transactionContainerSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: false}
+
+	for _, e := range remoteWriteEndpoints {
+		endpoint, err := newRemoteWriteEndpoint(e)
+		if err != nil {
+			return nil, err
+		}
+		f.endpoints = append(f.endpoints, endpoint)
+
+		// endpoints with the same url share their workers and retry queue
+		if _, found := f.domainForwarders[endpoint.url]; found {
+			continue
+		}
+		pointCountTelemetry := retry.NewPointCountTelemetry(endpoint.url, telemetry.GetStatsTelemetryProvider())
+		transactionContainer := retry.NewTransactionRetryQueue(
+			transactionContainerSort,
+			nil,
+			options.RetryQueuePayloadsTotalMaxSize,
+			flushToDiskMemRatio,
+			retry.NewTransactionRetryQueueTelemetry(endpoint.url),
+			pointCountTelemetry)
+		f.domainForwarders[endpoint.url] = newDomainForwarder(
+			endpoint.url,
+			transactionContainer,
+			options.NumberOfWorkers,
+			options.ConnectionResetInterval,
+			domainForwarderSort,
+			pointCountTelemetry)
+	}
+
+	return f, nil
+}
+
+// Start initialize and runs the forwarder.
+func (f *RemoteWriteForwarder) Start() error {
+	// Lock so we can't stop a Forwarder while is starting
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.internalState.Load() == Started {
+		return fmt.Errorf("the remote write forwarder is already started")
+	}
+
+	for _, df := range f.domainForwarders {
+		_ = df.Start()
+	}
+
+	log.Infof("Remote write forwarder started, sending to %v endpoint(s) with %v worker(s) each: %s",
+		len(f.domainForwarders), f.NumberOfWorkers, f.redactedURLs())
+
+	f.internalState.Store(Started)
+	return nil
+}
+
+// Stop all the component of a forwarder and free resources
+func (f *RemoteWriteForwarder) Stop() {
+	log.Infof("stopping the remote write forwarder")
+	// Lock so we can't start a Forwarder while is stopping
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.internalState.Load() == Stopped {
+		log.Warnf("the remote write forwarder is already stopped")
+		return
+	}
+
+	f.internalState.Store(Stopped)
+
+	purgeTimeout := config.Datadog.GetDuration("forwarder_stop_timeout") * time.Second
+	if purgeTimeout > 0 {
+		var wg sync.WaitGroup
+
+		for _, df := range f.domainForwarders {
+			wg.Add(1)
+			go func(df *domainForwarder) {
+				df.Stop(true)
+				wg.Done()
+			}(df)
+		}
+
+		donePurging := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(donePurging)
+		}()
+
+		select {
+		case <-donePurging:
+		case <-time.After(purgeTimeout):
+			log.Warnf("Timeout emptying new transactions before stopping the remote write forwarder %v", purgeTimeout)
+		}
+	} else {
+		for _, df := range f.domainForwarders {
+			df.Stop(false)
+		}
+	}
+}
+
+// State returns the internal state of the forwarder (Started or Stopped)
+func (f *RemoteWriteForwarder) State() uint32 {
+	// Lock so we can't start/stop a Forwarder while getting its state
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	return f.internalState.Load()
+}
+
+// SubmitRemoteWrite sends snappy-compressed Prometheus remote-write payloads to every endpoint.
+func (f *RemoteWriteForwarder) SubmitRemoteWrite(payloads transaction.BytesPayloads) error {
+	if f.internalState.Load() == Stopped {
+		return fmt.Errorf("the remote write forwarder is not started")
+	}
+
+	endpoint := endpoints.PrometheusRemoteWriteEndpoint
+	for _, payload := range payloads {
+		for _, e := range f.endpoints {
+			t := transaction.NewHTTPTransaction()
+			t.Domain = e.url
+			t.Endpoint = endpoint
+			t.Payload = payload
+			t.Priority = transaction.TransactionPriorityNormal
+			t.StorableOnDisk = false
+			for key := range e.headers {
+				t.Headers.Set(key, e.headers.Get(key))
+			}
+
+			tlmTxInputCount.Inc(e.url, endpoint.Name)
+			tlmTxInputBytes.Add(float64(t.GetPayloadSize()), e.url, endpoint.Name)
+			transactionsInputCountByEndpoint.Add(endpoint.Name, 1)
+			transactionsInputBytesByEndpoint.Add(endpoint.Name, int64(t.GetPayloadSize()))
+
+			f.domainForwarders[e.url].sendHTTPTransactions(t)
+		}
+	}
+	return nil
+}
+
+// redactedURLs returns the urls of the endpoints, without their password, to be logged.
+func (f *RemoteWriteForwarder) redactedURLs() string {
+	urls := make([]string, 0, len(f.domainForwarders))
+	for u := range f.domainForwarders {
+		if parsed, err := url.Parse(u); err == nil {
+			urls = append(urls, parsed.Redacted())
+		}
+	}
+	return strings.Join(urls, " ; ")
+}
@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+)
+
+func TestNewRemoteWriteEndpoint(t *testing.T) {
+	for _, invalid := range []config.RemoteWriteEndpoint{
+		{URL: ""},
+		{URL: "mimir:9009/api/v1/push"},
+		{URL: "ftp://mimir/api/v1/push"},
+		{URL: "https://mimir/api/v1/push", BearerToken: "token", Username: "user"},
+	} {
+		_, err := newRemoteWriteEndpoint(invalid)
+		assert.Error(t, err, invalid.URL)
+	}
+
+	endpoint, err := newRemoteWriteEndpoint(config.RemoteWriteEndpoint{
+		URL:         "https://mimir/api/v1/push",
+		Headers:     map[string]string{"x-scope-orgid": "team-a"},
+		BearerToken: "token",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://mimir/api/v1/push", endpoint.url)
+	assert.Equal(t, "team-a", endpoint.headers.Get("X-Scope-OrgID"))
+	assert.Equal(t, "Bearer token", endpoint.headers.Get("Authorization"))
+	assert.Equal(t, "snappy", endpoint.headers.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", endpoint.headers.Get("Content-Type"))
+	assert.Equal(t, "0.1.0", endpoint.headers.Get("X-Prometheus-Remote-Write-Version"))
+
+	endpoint, err = newRemoteWriteEndpoint(config.RemoteWriteEndpoint{
+		URL:      "http://prometheus:9090/api/v1/write",
+		Username: "user",
+		Password: "pass",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Basic dXNlcjpwYXNz", endpoint.headers.Get("Authorization"))
+}
+
+func TestNewRemoteWriteForwarderNoEndpoint(t *testing.T) {
+	_, err := NewRemoteWriteForwarder(nil, &Options{NumberOfWorkers: 1})
+	assert.Error(t, err)
+}
+
+func TestRemoteWriteForwarderEndtoEnd(t *testing.T) {
+	type request struct {
+		path          string
+		authorization string
+		body          string
+	}
+	requests := make(chan request, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "0.1.0", r.Header.Get("X-Prometheus-Remote-Write-Version"))
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		requests <- request{r.URL.Path, r.Header.Get("Authorization"), string(body)}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	f, err := NewRemoteWriteForwarder([]config.RemoteWriteEndpoint{
+		{URL: ts.URL + "/mimir/api/v1/push", BearerToken: "token"},
+		{URL: ts.URL + "/prometheus/api/v1/write", Username: "user", Password: "pass"},
+	}, &Options{NumberOfWorkers: 1, RetryQueuePayloadsTotalMaxSize: 1024 * 1024})
+	require.NoError(t, err)
+
+	payload := []byte("payload")
+	assert.Error(t, f.SubmitRemoteWrite(transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&payload})))
+
+	require.NoError(t, f.Start())
+	defer f.Stop()
+	assert.Error(t, f.Start())
+
+	require.NoError(t, f.SubmitRemoteWrite(transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&payload})))
+
+	var received []request
+	for len(received) < 2 {
+		select {
+		case r := <-requests:
+			received = append(received, r)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timeout waiting for the remote write requests")
+		}
+	}
+	sort.Slice(received, func(i, j int) bool { return received[i].path < received[j].path })
+	assert.Equal(t, []request{
+		{"/mimir/api/v1/push", "Bearer token", "payload"},
+		{"/prometheus/api/v1/write", "Basic dXNlcjpwYXNz", "payload"},
+	}, received)
+}
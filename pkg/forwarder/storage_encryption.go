@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/internal/retry"
+)
+
+// buildStorageEncryption returns the encryption of the retry files, or nil when no key is
+// configured. The keys are base64 encoded, either set in the configuration, where they can
+// be retrieved from the secrets backend, or read from a file.
+func buildStorageEncryption() (*retry.StorageEncryption, error) {
+	currentKeys, err := decodeStorageEncryptionKeys(
+		[]string{config.Datadog.GetString("forwarder_storage_encryption_key")},
+		[]string{config.Datadog.GetString("forwarder_storage_encryption_key_file")})
+	if err != nil {
+		return nil, err
+	}
+	previousKeys, err := decodeStorageEncryptionKeys(
+		config.Datadog.GetStringSlice("forwarder_storage_previous_encryption_keys"),
+		config.Datadog.GetStringSlice("forwarder_storage_previous_encryption_key_files"))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(currentKeys) > 1:
+		return nil, fmt.Errorf("'forwarder_storage_encryption_key' and 'forwarder_storage_encryption_key_file' are mutually exclusive")
+	case len(currentKeys) == 0 && len(previousKeys) > 0:
+		return nil, fmt.Errorf("previous encryption keys are set without 'forwarder_storage_encryption_key' or 'forwarder_storage_encryption_key_file'")
+	case len(currentKeys) == 0:
+		return nil, nil
+	}
+	return retry.NewStorageEncryption(currentKeys[0], previousKeys)
+}
+
+// decodeStorageEncryptionKeys returns the decoded encodedKeys followed by the keys read from
+// keyFiles, the empty values being ignored.
+func decodeStorageEncryptionKeys(encodedKeys []string, keyFiles []string) ([][]byte, error) {
+	var keysToDecode []string
+	for _, key := range encodedKeys {
+		if key != "" {
+			keysToDecode = append(keysToDecode, key)
+		}
+	}
+	for _, path := range keyFiles {
+		if path == "" {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read the encryption key file: %v", err)
+		}
+		keysToDecode = append(keysToDecode, string(content))
+	}
+
+	keys := make([][]byte, 0, len(keysToDecode))
+	for _, encodedKey := range keysToDecode {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+		if err != nil {
+			return nil, fmt.Errorf("the encryption keys must be base64 encoded: %v", err)
+		}
+		if len(key) != retry.StorageEncryptionKeySize {
+			return nil, fmt.Errorf("the encryption keys must be %d bytes long, got %d", retry.StorageEncryptionKeySize, len(key))
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestBuildStorageEncryption(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	previousKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+	keyFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(key+"\n"), 0600))
+
+	settings := []string{
+		"forwarder_storage_encryption_key",
+		"forwarder_storage_encryption_key_file",
+		"forwarder_storage_previous_encryption_keys",
+		"forwarder_storage_previous_encryption_key_files",
+	}
+	reset := func() {
+		config.Datadog.Set(settings[0], "")
+		config.Datadog.Set(settings[1], "")
+		config.Datadog.Set(settings[2], []string{})
+		config.Datadog.Set(settings[3], []string{})
+	}
+	defer reset()
+
+	for _, tc := range []struct {
+		name          string
+		values        map[string]interface{}
+		expectedNil   bool
+		expectedError bool
+	}{
+		{"no key", nil, true, false},
+		{"key", map[string]interface{}{settings[0]: key}, false, false},
+		{"key file", map[string]interface{}{settings[1]: keyFile}, false, false},
+		{"previous keys", map[string]interface{}{settings[0]: key, settings[2]: []string{previousKey}, settings[3]: []string{keyFile}}, false, false},
+		{"key and key file", map[string]interface{}{settings[0]: key, settings[1]: keyFile}, false, true},
+		{"previous keys only", map[string]interface{}{settings[2]: []string{previousKey}}, false, true},
+		{"invalid base64", map[string]interface{}{settings[0]: "not base64!"}, false, true},
+		{"invalid size", map[string]interface{}{settings[0]: base64.StdEncoding.EncodeToString([]byte("short"))}, false, true},
+		{"missing key file", map[string]interface{}{settings[1]: keyFile + ".missing"}, false, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			reset()
+			for setting, value := range tc.values {
+				config.Datadog.Set(setting, value)
+			}
+			encryption, err := buildStorageEncryption()
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedNil, encryption == nil)
+		})
+	}
+}
@@ -8,8 +8,9 @@ package transaction
 // BytesPayload is a payload stored as bytes.
 // It contains metadata about the payload.
 type BytesPayload struct {
-	content    []byte
-	pointCount int
+	content      []byte
+	pointCount   int
+	destinations *Destinations
 }
 
 // NewBytesPayload creates a new instance of BytesPayload.
@@ -40,9 +41,23 @@ func (p *BytesPayload) GetPointCount() int {
 	return p.pointCount
 }
 
+// GetDestinations returns the destinations of this payload, nil if it is sent
+// to every destination
+func (p *BytesPayload) GetDestinations() *Destinations {
+	return p.destinations
+}
+
 // BytesPayloads is a collection of BytesPayload
 type BytesPayloads []*BytesPayload
 
+// SetDestinations restricts the destinations of the payloads, nil sending them
+// to every destination
+func (payloads BytesPayloads) SetDestinations(destinations *Destinations) {
+	for _, payload := range payloads {
+		payload.destinations = destinations
+	}
+}
+
 // NewBytesPayloadsWithoutMetaData creates BytesPayloads without metadata.
 func NewBytesPayloadsWithoutMetaData(payloads []*[]byte) BytesPayloads {
 	var bytesPayloads BytesPayloads
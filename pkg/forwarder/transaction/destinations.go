@@ -0,0 +1,44 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package transaction
+
+// Destinations restricts the API keys a payload is sent with, and so the
+// organizations receiving it. A nil *Destinations accepts every API key.
+type Destinations struct {
+	apiKeys map[string]struct{}
+	// except is true when apiKeys lists the only API keys rejected
+	except bool
+}
+
+// NewDestinations returns the Destinations accepting only apiKeys.
+func NewDestinations(apiKeys []string) *Destinations {
+	return newDestinations(apiKeys, false)
+}
+
+// NewDestinationsExcept returns the Destinations accepting every API key but apiKeys.
+func NewDestinationsExcept(apiKeys []string) *Destinations {
+	return newDestinations(apiKeys, true)
+}
+
+func newDestinations(apiKeys []string, except bool) *Destinations {
+	d := &Destinations{
+		apiKeys: make(map[string]struct{}, len(apiKeys)),
+		except:  except,
+	}
+	for _, apiKey := range apiKeys {
+		d.apiKeys[apiKey] = struct{}{}
+	}
+	return d
+}
+
+// Accept returns whether a payload must be sent with apiKey.
+func (d *Destinations) Accept(apiKey string) bool {
+	if d == nil {
+		return true
+	}
+	_, listed := d.apiKeys[apiKey]
+	return listed != d.except
+}
@@ -5,23 +5,11 @@
 
 package auditor
 
-import (
-	"encoding/json"
-)
-
 // v2: In the third version of the auditor, we dropped Timestamp and used a generic Offset instead to reinforce the separation of concerns
 // between the auditor and log sources.
 
 func unmarshalRegistryV2(b []byte) (map[string]*RegistryEntry, error) {
-	var r JSONRegistry
-	err := json.Unmarshal(b, &r)
-	if err != nil {
-		return nil, err
-	}
-	registry := make(map[string]*RegistryEntry)
-	for identifier, entry := range r.Registry {
-		newEntry := entry
-		registry[identifier] = &newEntry
-	}
-	return registry, nil
+	// some registries of the third version were written with the inode and the fingerprint
+	// of the fourth version, which are migrated the same way
+	return unmarshalRegistryV3(b)
 }
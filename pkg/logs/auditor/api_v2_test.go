@@ -9,6 +9,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
 )
 
 func TestAuditorUnmarshalRegistryV2(t *testing.T) {
@@ -21,6 +23,13 @@ func TestAuditorUnmarshalRegistryV2(t *testing.T) {
 	        "path2.log": {
 	            "Offset": "2006-01-12T01:01:03.000000001Z",
 	            "LastUpdated": "2006-01-12T01:01:02.000000001Z"
+	        },
+	        "path3.log": {
+	            "Offset": "2048",
+	            "LastUpdated": "2006-01-12T01:01:03.000000001Z",
+	            "TailingMode": "beginning",
+	            "Inode": 12,
+	            "Fingerprint": 34
 	        }
 	    },
 	    "Version": 2
@@ -33,4 +42,10 @@ func TestAuditorUnmarshalRegistryV2(t *testing.T) {
 
 	assert.Equal(t, "2006-01-12T01:01:03.000000001Z", r["path2.log"].Offset)
 	assert.Equal(t, 2, r["path2.log"].LastUpdated.Second())
+	assert.Nil(t, r["path2.log"].Fingerprint)
+
+	assert.Equal(t, "2048", r["path3.log"].Offset)
+	assert.Equal(t, "beginning", r["path3.log"].TailingMode)
+	assert.Equal(t, uint64(12), r["path3.log"].Inode)
+	assert.Equal(t, &message.Fingerprint{Checksum: 34, Size: 1024}, r["path3.log"].Fingerprint)
 }
@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package auditor
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// v3: In the fourth version of the auditor, we added the inode and the fingerprint of the file an offset belongs to,
+// the fingerprint being a checksum of the first fingerprintSizeV3 bytes of the file.
+
+// fingerprintSizeV3 is the number of bytes the fingerprints of the fourth version of the auditor were computed on.
+const fingerprintSizeV3 = 1024
+
+type registryEntryV3 struct {
+	LastUpdated        time.Time
+	Offset             string
+	TailingMode        string
+	IngestionTimestamp int64
+	Inode              uint64
+	Fingerprint        uint64
+}
+
+type jsonRegistryV3 struct {
+	Version  int
+	Registry map[string]registryEntryV3
+}
+
+func unmarshalRegistryV3(b []byte) (map[string]*RegistryEntry, error) {
+	var r jsonRegistryV3
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return nil, err
+	}
+	registry := make(map[string]*RegistryEntry)
+	for identifier, entry := range r.Registry {
+		newEntry := &RegistryEntry{
+			LastUpdated:        entry.LastUpdated,
+			Offset:             entry.Offset,
+			TailingMode:        entry.TailingMode,
+			IngestionTimestamp: entry.IngestionTimestamp,
+			Inode:              entry.Inode,
+		}
+		if entry.Fingerprint != 0 {
+			// only the files of at least fingerprintSizeV3 bytes were fingerprinted
+			newEntry.Fingerprint = &message.Fingerprint{Checksum: entry.Fingerprint, Size: fingerprintSizeV3}
+		}
+		registry[identifier] = newEntry
+	}
+	return registry, nil
+}
@@ -0,0 +1,27 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package auditor
+
+import (
+	"encoding/json"
+)
+
+// v4: In the fifth version of the auditor, the fingerprint of a file records the number of bytes its checksum
+// was computed on, so that files shorter than the fingerprint size can be fingerprinted as well.
+
+func unmarshalRegistryV4(b []byte) (map[string]*RegistryEntry, error) {
+	var r JSONRegistry
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return nil, err
+	}
+	registry := make(map[string]*RegistryEntry)
+	for identifier, entry := range r.Registry {
+		newEntry := entry
+		registry[identifier] = &newEntry
+	}
+	return registry, nil
+}
@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package auditor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestAuditorUnmarshalRegistryV4(t *testing.T) {
+	input := `{
+	    "Registry": {
+	        "path1.log": {
+	            "Offset": "1",
+	            "LastUpdated": "2006-01-12T01:01:01.000000001Z"
+	        },
+	        "path2.log": {
+	            "Offset": "12",
+	            "LastUpdated": "2006-01-12T01:01:02.000000001Z",
+	            "TailingMode": "end",
+	            "Inode": 42,
+	            "Fingerprint": {
+	                "Checksum": 1234,
+	                "Size": 12
+	            }
+	        }
+	    },
+	    "Version": 4
+	}`
+	r, err := unmarshalRegistryV4([]byte(input))
+	assert.Nil(t, err)
+
+	assert.Equal(t, "1", r["path1.log"].Offset)
+	assert.Equal(t, 1, r["path1.log"].LastUpdated.Second())
+	assert.Zero(t, r["path1.log"].Inode)
+	assert.Nil(t, r["path1.log"].Fingerprint)
+
+	assert.Equal(t, "12", r["path2.log"].Offset)
+	assert.Equal(t, 2, r["path2.log"].LastUpdated.Second())
+	assert.Equal(t, "end", r["path2.log"].TailingMode)
+	assert.Equal(t, uint64(42), r["path2.log"].Inode)
+	assert.Equal(t, &message.Fingerprint{Checksum: 1234, Size: 12}, r["path2.log"].Fingerprint)
+}
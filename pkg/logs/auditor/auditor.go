@@ -27,12 +27,13 @@ const defaultFlushPeriod = 1 * time.Second
 const defaultCleanupPeriod = 300 * time.Second
 
 // latest version of the API used by the auditor to retrieve the registry from disk.
-const registryAPIVersion = 2
+const registryAPIVersion = 4
 
 // Registry holds a list of offsets.
 type Registry interface {
 	GetOffset(identifier string) string
 	GetTailingMode(identifier string) string
+	GetFileIdentity(identifier string) message.FileIdentity
 }
 
 // A RegistryEntry represents an entry in the registry where we keep track
@@ -42,6 +43,9 @@ type RegistryEntry struct {
 	Offset             string
 	TailingMode        string
 	IngestionTimestamp int64
+	// Inode and Fingerprint identify the file the offset belongs to, see message.FileIdentity.
+	Inode       uint64               `json:",omitempty"`
+	Fingerprint *message.Fingerprint `json:",omitempty"`
 }
 
 // JSONRegistry represents the registry that will be written on disk
@@ -148,6 +152,21 @@ func (a *RegistryAuditor) GetTailingMode(identifier string) string {
 	return entry.TailingMode
 }
 
+// GetFileIdentity returns the identity of the file the last committed offset belongs to
+// for a given identifier, returns a zero identity if it does not exist.
+func (a *RegistryAuditor) GetFileIdentity(identifier string) message.FileIdentity {
+	r := a.readOnlyRegistryCopy()
+	entry, exists := r[identifier]
+	if !exists {
+		return message.FileIdentity{}
+	}
+	identity := message.FileIdentity{Inode: entry.Inode}
+	if entry.Fingerprint != nil {
+		identity.Fingerprint = *entry.Fingerprint
+	}
+	return identity
+}
+
 // run keeps up to date the registry depending on different events
 func (a *RegistryAuditor) run() {
 	cleanUpTicker := time.NewTicker(defaultCleanupPeriod)
@@ -170,7 +189,7 @@ func (a *RegistryAuditor) run() {
 			}
 			// update the registry with new entry
 			for _, msg := range payload.Messages {
-				a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.IngestionTimestamp)
+				a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.FileIdentity, msg.Origin.LogSource.Config.TailingMode, msg.IngestionTimestamp)
 			}
 		case <-cleanUpTicker.C:
 			// remove expired offsets from registry
@@ -223,7 +242,7 @@ func (a *RegistryAuditor) cleanupRegistry() {
 }
 
 // updateRegistry updates the registry entry matching identifier with new the offset and timestamp
-func (a *RegistryAuditor) updateRegistry(identifier string, offset string, tailingMode string, ingestionTimestamp int64) {
+func (a *RegistryAuditor) updateRegistry(identifier string, offset string, fileIdentity message.FileIdentity, tailingMode string, ingestionTimestamp int64) {
 	a.registryMutex.Lock()
 	defer a.registryMutex.Unlock()
 	if identifier == "" {
@@ -241,12 +260,18 @@ func (a *RegistryAuditor) updateRegistry(identifier string, offset string, taili
 		}
 	}
 
-	a.registry[identifier] = &RegistryEntry{
+	entry := &RegistryEntry{
 		LastUpdated:        time.Now().UTC(),
 		Offset:             offset,
 		TailingMode:        tailingMode,
 		IngestionTimestamp: ingestionTimestamp,
+		Inode:              fileIdentity.Inode,
+	}
+	if fileIdentity.Fingerprint.Size > 0 {
+		fingerprint := fileIdentity.Fingerprint
+		entry.Fingerprint = &fingerprint
 	}
+	a.registry[identifier] = entry
 }
 
 // readOnlyRegistryCopy returns a read only copy of the registry
@@ -292,6 +317,10 @@ func (a *RegistryAuditor) unmarshalRegistry(b []byte) (map[string]*RegistryEntry
 	}
 	// ensure backward compatibility
 	switch int(version) {
+	case 4:
+		return unmarshalRegistryV4(b)
+	case 3:
+		return unmarshalRegistryV3(b)
 	case 2:
 		return unmarshalRegistryV2(b)
 	case 1:
@@ -5,10 +5,13 @@
 
 package mock
 
+import "github.com/DataDog/datadog-agent/pkg/logs/message"
+
 // Registry does nothing
 type Registry struct {
-	offset      string
-	tailingMode string
+	offset       string
+	tailingMode  string
+	fileIdentity message.FileIdentity
 }
 
 // NewRegistry returns a new registry.
@@ -35,3 +38,13 @@ func (r *Registry) GetTailingMode(identifier string) string {
 func (r *Registry) SetTailingMode(tailingMode string) {
 	r.tailingMode = tailingMode
 }
+
+// GetFileIdentity returns the file identity.
+func (r *Registry) GetFileIdentity(identifier string) message.FileIdentity {
+	return r.fileIdentity
+}
+
+// SetFileIdentity sets the file identity.
+func (r *Registry) SetFileIdentity(fileIdentity message.FileIdentity) {
+	r.fileIdentity = fileIdentity
+}
@@ -32,6 +32,11 @@ func (a *NullAuditor) GetOffset(identifier string) string { return "" }
 // GetTailingMode returns an empty string.
 func (a *NullAuditor) GetTailingMode(identifier string) string { return "" }
 
+// GetFileIdentity returns a zero identity.
+func (a *NullAuditor) GetFileIdentity(identifier string) message.FileIdentity {
+	return message.FileIdentity{}
+}
+
 // Start starts the NullAuditor main loop.
 func (a *NullAuditor) Start() {
 	go a.run()
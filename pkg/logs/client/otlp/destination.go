@@ -0,0 +1,326 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	protobufContentType = "application/x-protobuf"
+	sendTimeout         = 10 * time.Second
+)
+
+var (
+	errClient = errors.New("client error")
+	errServer = errors.New("server error")
+	tlmSend   = telemetry.NewCounter("logs_client_otlp_destination", "send", []string{"endpoint_host", "error"}, "Payloads sent over OTLP")
+)
+
+// Destination sends the logs of the payloads to an OpenTelemetry collector, as OTLP
+// ExportLogsServiceRequests over HTTP or gRPC. It only supports the payloads of the
+// HTTP pipelines, from which the logs are decoded.
+type Destination struct {
+	endpoint            config.OTLPEndpoint
+	destinationsContext *client.DestinationsContext
+
+	// OTLP/HTTP
+	url        string
+	httpClient *httputils.ResetClient
+
+	// OTLP/gRPC
+	grpcConn   *grpc.ClientConn
+	grpcClient plogotlp.GRPCClient
+
+	// Retry
+	backoff        backoff.Policy
+	nbErrors       int
+	shouldRetry    bool
+	lastRetryError error
+}
+
+// NewDestination returns a new Destination.
+func NewDestination(endpoint config.OTLPEndpoint, destinationsContext *client.DestinationsContext, shouldRetry bool) *Destination {
+	return &Destination{
+		endpoint:            endpoint,
+		destinationsContext: destinationsContext,
+		url:                 buildURL(endpoint),
+		httpClient:          httputils.NewResetClient(endpoint.ConnectionResetInterval, httpClientFactory(sendTimeout)),
+		backoff: backoff.NewPolicy(
+			endpoint.BackoffFactor,
+			endpoint.BackoffBase,
+			endpoint.BackoffMax,
+			endpoint.RecoveryInterval,
+			endpoint.RecoveryReset,
+		),
+		shouldRetry: shouldRetry,
+	}
+}
+
+// Start starts reading the input channel
+func (d *Destination) Start(input chan *message.Payload, output chan *message.Payload, isRetrying chan bool) (stopChan <-chan struct{}) {
+	stop := make(chan struct{})
+	go d.run(input, output, stop, isRetrying)
+	return stop
+}
+
+func (d *Destination) run(input chan *message.Payload, output chan *message.Payload, stopChan chan struct{}, isRetrying chan bool) {
+	for payload := range input {
+		d.sendAndRetry(payload, output, isRetrying)
+	}
+	if d.grpcConn != nil {
+		d.grpcConn.Close()
+	}
+	d.updateRetryState(nil, isRetrying)
+	stopChan <- struct{}{}
+}
+
+func (d *Destination) sendAndRetry(payload *message.Payload, output chan *message.Payload, isRetrying chan bool) {
+	for {
+		if backoffDuration := d.backoff.GetBackoffDuration(d.nbErrors); backoffDuration > 0 {
+			log.Debugf("%s: sleeping for %s before retrying due to %d errors", d.endpoint.Host, backoffDuration, d.nbErrors)
+			d.waitForBackoff(backoffDuration)
+		}
+
+		err := d.send(payload)
+		tlmSend.Inc(d.endpoint.Host, errorToTag(err))
+		if err != nil {
+			metrics.DestinationErrors.Add(1)
+			metrics.TlmDestinationErrors.Inc()
+			log.Warnf("Could not send payload over OTLP: %v", err)
+		}
+
+		if err == context.Canceled {
+			d.updateRetryState(nil, isRetrying)
+			return
+		}
+
+		if d.shouldRetry && d.updateRetryState(err, isRetrying) {
+			continue
+		}
+
+		metrics.LogsSent.Add(int64(len(payload.Messages)))
+		metrics.TlmLogsSent.Add(float64(len(payload.Messages)))
+		output <- payload
+		return
+	}
+}
+
+// send exports the logs of the payload, returning a RetryableError when the export can be retried.
+func (d *Destination) send(payload *message.Payload) error {
+	entries, err := decodePayload(payload)
+	if err != nil {
+		return err
+	}
+	request := plogotlp.NewExportRequestFromLogs(toLogs(entries))
+	if d.endpoint.Transport == config.OTLPOverGRPC {
+		return d.sendGRPC(request)
+	}
+	return d.sendHTTP(request)
+}
+
+func (d *Destination) sendHTTP(request plogotlp.ExportRequest) error {
+	ctx := d.destinationsContext.Context()
+
+	body, err := request.MarshalProto()
+	if err != nil {
+		return err
+	}
+	if d.endpoint.UseCompression {
+		if body, err = compress(body, d.endpoint.CompressionLevel); err != nil {
+			return err
+		}
+	}
+	metrics.EncodedBytesSent.Add(int64(len(body)))
+	metrics.TlmEncodedBytesSent.Add(float64(len(body)))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, value := range d.endpoint.Headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", protobufContentType)
+	if d.endpoint.UseCompression {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return ctx.Err()
+		}
+		// most likely a network or a connect error, the callee should retry.
+		return client.NewRetryableError(err)
+	}
+	defer resp.Body.Close()
+	response, _ := io.ReadAll(resp.Body)
+
+	switch {
+	case resp.StatusCode < http.StatusMultipleChoices:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusBadGateway ||
+		resp.StatusCode == http.StatusServiceUnavailable ||
+		resp.StatusCode == http.StatusGatewayTimeout:
+		// the collector is overloaded or unreachable, these requests are retried as per the OTLP specification
+		log.Warnf("failed to export logs over OTLP. code=%d host=%s response=%s", resp.StatusCode, d.endpoint.Host, string(response))
+		return client.NewRetryableError(errServer)
+	default:
+		log.Warnf("failed to export logs over OTLP. code=%d host=%s response=%s", resp.StatusCode, d.endpoint.Host, string(response))
+		return errClient
+	}
+}
+
+func (d *Destination) sendGRPC(request plogotlp.ExportRequest) error {
+	if d.grpcClient == nil {
+		if err := d.dial(); err != nil {
+			// the connection is attempted again on the next send
+			return client.NewRetryableError(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(d.destinationsContext.Context(), sendTimeout)
+	defer cancel()
+	for key, value := range d.endpoint.Headers {
+		ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+	}
+	var options []grpc.CallOption
+	if d.endpoint.UseCompression {
+		options = append(options, grpc.UseCompressor(grpcgzip.Name))
+	}
+
+	_, err := d.grpcClient.Export(ctx, request, options...)
+	if err == nil {
+		return nil
+	}
+	if d.destinationsContext.Context().Err() == context.Canceled {
+		return context.Canceled
+	}
+	switch status.Code(err) {
+	case codes.Canceled, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted,
+		codes.OutOfRange, codes.Unavailable, codes.DataLoss:
+		return client.NewRetryableError(err)
+	default:
+		return err
+	}
+}
+
+func (d *Destination) dial() error {
+	transportCredentials := insecure.NewCredentials()
+	if d.endpoint.UseSSL {
+		transportCredentials = credentials.NewTLS(&tls.Config{})
+	}
+	conn, err := grpc.Dial(fmt.Sprintf("%s:%d", d.endpoint.Host, d.endpoint.Port), grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return err
+	}
+	d.grpcConn = conn
+	d.grpcClient = plogotlp.NewGRPCClient(conn)
+	return nil
+}
+
+func (d *Destination) updateRetryState(err error, isRetrying chan bool) bool {
+	if _, ok := err.(*client.RetryableError); ok {
+		d.nbErrors = d.backoff.IncError(d.nbErrors)
+		if isRetrying != nil && d.lastRetryError == nil {
+			isRetrying <- true
+		}
+		d.lastRetryError = err
+		return true
+	}
+
+	d.nbErrors = d.backoff.DecError(d.nbErrors)
+	if isRetrying != nil && d.lastRetryError != nil {
+		isRetrying <- false
+	}
+	d.lastRetryError = nil
+	return false
+}
+
+func (d *Destination) waitForBackoff(backoffDuration time.Duration) {
+	ctx, cancel := context.WithTimeout(d.destinationsContext.Context(), backoffDuration)
+	defer cancel()
+	<-ctx.Done()
+}
+
+func errorToTag(err error) string {
+	if err == nil {
+		return "none"
+	} else if _, ok := err.(*client.RetryableError); ok {
+		return "retryable"
+	} else {
+		return "non-retryable"
+	}
+}
+
+func compress(data []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = writer.Write(data); err != nil {
+		writer.Close()
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func httpClientFactory(timeout time.Duration) func() *http.Client {
+	return func() *http.Client {
+		return &http.Client{
+			Timeout: timeout,
+			// reusing core agent HTTP transport to benefit from proxy settings.
+			Transport: httputils.CreateHTTPTransport(),
+		}
+	}
+}
+
+// buildURL builds the url of the OTLP/HTTP logs service of the endpoint.
+func buildURL(endpoint config.OTLPEndpoint) string {
+	scheme := "http"
+	if endpoint.UseSSL {
+		scheme = "https"
+	}
+	u := url.URL{
+		Scheme: scheme,
+		Host:   fmt.Sprintf("%s:%d", endpoint.Host, endpoint.Port),
+		Path:   endpoint.Path,
+	}
+	return u.String()
+}
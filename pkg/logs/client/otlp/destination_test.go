@@ -0,0 +1,255 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/processor"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newTestMessage(t *testing.T, content string, status string, source *sources.LogSource) *message.Message {
+	msg := message.NewMessageWithSource([]byte(content), status, source, time.Now().UnixNano())
+	encoded, err := processor.JSONEncoder.Encode(msg, []byte(content))
+	require.NoError(t, err)
+	msg.Content = encoded
+	return msg
+}
+
+// newTestPayload returns the payload of the messages as built by the HTTP pipelines.
+func newTestPayload(messages ...*message.Message) *message.Payload {
+	var encoded [][]byte
+	for _, msg := range messages {
+		encoded = append(encoded, msg.Content)
+	}
+	return &message.Payload{
+		Messages: messages,
+		Encoded:  append(append([]byte("["), bytes.Join(encoded, []byte(","))...), ']'),
+		Encoding: "identity",
+	}
+}
+
+func TestToLogs(t *testing.T) {
+	web := sources.NewLogSource("web", &config.LogsConfig{Service: "web", Source: "nginx", Tags: []string{"env:prod", "team:a", "team:b", "canary"}})
+	db := sources.NewLogSource("db", &config.LogsConfig{Service: "db", Source: "postgresql"})
+
+	first := message.NewMessageWithSource(nil, message.StatusInfo, web, 0)
+	first.Timestamp = time.Unix(1600000000, 0)
+	first.SetAttribute("http.status_code", 200)
+	first.SetAttribute("latency", 0.5)
+	encoded, err := processor.JSONEncoder.Encode(first, []byte("GET /"))
+	require.NoError(t, err)
+	first.Content = encoded
+	second := newTestMessage(t, "connection lost", message.StatusCritical, db)
+	third := newTestMessage(t, "GET /admin", message.StatusWarning, web)
+
+	entries, err := decodePayload(newTestPayload(first, second, third))
+	require.NoError(t, err)
+	logs := toLogs(entries)
+	require.Equal(t, 2, logs.ResourceLogs().Len())
+	assert.Equal(t, 3, logs.LogRecordCount())
+
+	resource := logs.ResourceLogs().At(0)
+	service, _ := resource.Resource().Attributes().Get(serviceNameAttribute)
+	assert.Equal(t, "web", service.Str())
+	source, _ := resource.Resource().Attributes().Get(sourceAttribute)
+	assert.Equal(t, "nginx", source.Str())
+	_, found := resource.Resource().Attributes().Get(hostNameAttribute)
+	assert.True(t, found)
+	assert.Equal(t, scopeName, resource.ScopeLogs().At(0).Scope().Name())
+
+	records := resource.ScopeLogs().At(0).LogRecords()
+	require.Equal(t, 2, records.Len())
+	record := records.At(0)
+	assert.Equal(t, "GET /", record.Body().Str())
+	assert.Equal(t, "info", record.SeverityText())
+	assert.Equal(t, plog.SeverityNumberInfo, record.SeverityNumber())
+	assert.Equal(t, first.Timestamp.UnixNano(), int64(record.Timestamp()))
+	assert.NotZero(t, record.ObservedTimestamp())
+	assert.Equal(t, map[string]interface{}{
+		"env":              "prod",
+		"team":             []interface{}{"a", "b"},
+		"canary":           "",
+		"http.status_code": int64(200),
+		"latency":          0.5,
+		"status":           "info",
+	}, record.Attributes().AsRaw())
+	assert.Equal(t, "GET /admin", records.At(1).Body().Str())
+	assert.Equal(t, plog.SeverityNumberWarn, records.At(1).SeverityNumber())
+
+	record = logs.ResourceLogs().At(1).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, "connection lost", record.Body().Str())
+	assert.Equal(t, plog.SeverityNumberFatal, record.SeverityNumber())
+}
+
+func TestDecodePayload(t *testing.T) {
+	source := sources.NewLogSource("lambda", &config.LogsConfig{Service: "function"})
+	msg := message.NewMessageWithSource(nil, message.StatusError, source, 0)
+	msg.Lambda = &message.Lambda{ARN: "arn:aws:lambda:us-east-1:123456789012:function:function"}
+	encoded, err := processor.JSONServerlessEncoder.Encode(msg, []byte("timeout"))
+	require.NoError(t, err)
+	compressed, err := compress(append(append([]byte("["), encoded...), ']'), 6)
+	require.NoError(t, err)
+
+	// a payload replayed from the disk buffer does not hold its messages
+	entries, err := decodePayload(&message.Payload{Encoded: compressed, Encoding: "gzip"})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "timeout", entries[0].content)
+	assert.Equal(t, message.StatusError, entries[0].status)
+	assert.Equal(t, "function", entries[0].service)
+
+	_, err = decodePayload(&message.Payload{Encoded: []byte("<13>0 2020-01-01T00:00:00Z host - - - - raw"), Encoding: ""})
+	assert.Error(t, err)
+}
+
+func TestBuildURL(t *testing.T) {
+	assert.Equal(t, "https://collector:4318/v1/logs", buildURL(config.OTLPEndpoint{
+		Endpoint: config.Endpoint{Host: "collector", Port: 4318, UseSSL: true},
+		Path:     "/v1/logs",
+	}))
+	assert.Equal(t, "http://collector:1234/custom", buildURL(config.OTLPEndpoint{
+		Endpoint: config.Endpoint{Host: "collector", Port: 1234},
+		Path:     "/custom",
+	}))
+}
+
+type testCollector struct {
+	sync.Mutex
+	server     *httptest.Server
+	statusCode int
+	requests   []plogotlp.ExportRequest
+	headers    []http.Header
+}
+
+func newTestCollector(t *testing.T, statusCode int) *testCollector {
+	c := &testCollector{statusCode: statusCode}
+	c.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		request := plogotlp.NewExportRequest()
+		assert.NoError(t, request.UnmarshalProto(body))
+
+		c.Lock()
+		defer c.Unlock()
+		c.requests = append(c.requests, request)
+		c.headers = append(c.headers, r.Header)
+		w.WriteHeader(c.statusCode)
+	}))
+	t.Cleanup(c.server.Close)
+	return c
+}
+
+func (c *testCollector) setStatusCode(statusCode int) {
+	c.Lock()
+	defer c.Unlock()
+	c.statusCode = statusCode
+}
+
+func (c *testCollector) requestCount() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.requests)
+}
+
+func (c *testCollector) endpoint() config.OTLPEndpoint {
+	u, _ := url.Parse(c.server.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return config.OTLPEndpoint{
+		Endpoint: config.Endpoint{
+			Host:          u.Hostname(),
+			Port:          port,
+			BackoffFactor: 1,
+			BackoffBase:   1,
+			BackoffMax:    1,
+		},
+		Transport: config.OTLPOverHTTP,
+		Path:      "/v1/logs",
+		Headers:   map[string]string{"X-Scope-OrgID": "logs"},
+	}
+}
+
+func TestDestinationSendsOverHTTP(t *testing.T) {
+	collector := newTestCollector(t, http.StatusOK)
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+	defer destinationsCtx.Stop()
+
+	input := make(chan *message.Payload)
+	output := make(chan *message.Payload)
+	stop := NewDestination(collector.endpoint(), destinationsCtx, true).Start(input, output, nil)
+
+	source := sources.NewLogSource("web", &config.LogsConfig{Service: "web"})
+	payload := newTestPayload(
+		newTestMessage(t, "first", message.StatusInfo, source),
+		newTestMessage(t, "second", message.StatusError, source),
+	)
+	input <- payload
+	assert.Equal(t, payload, <-output)
+	close(input)
+	<-stop
+
+	require.Equal(t, 1, collector.requestCount())
+	assert.Equal(t, 2, collector.requests[0].Logs().LogRecordCount())
+	assert.Equal(t, protobufContentType, collector.headers[0].Get("Content-Type"))
+	assert.Equal(t, "logs", collector.headers[0].Get("X-Scope-OrgID"))
+}
+
+func TestDestinationRetriesOverHTTP(t *testing.T) {
+	collector := newTestCollector(t, http.StatusServiceUnavailable)
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+	defer destinationsCtx.Stop()
+
+	input := make(chan *message.Payload)
+	output := make(chan *message.Payload)
+	isRetrying := make(chan bool, 1)
+	NewDestination(collector.endpoint(), destinationsCtx, true).Start(input, output, isRetrying)
+
+	source := sources.NewLogSource("web", &config.LogsConfig{})
+	payload := newTestPayload(newTestMessage(t, "first", message.StatusInfo, source))
+	input <- payload
+	assert.True(t, <-isRetrying)
+
+	collector.setStatusCode(http.StatusOK)
+	assert.False(t, <-isRetrying)
+	assert.Equal(t, payload, <-output)
+	assert.GreaterOrEqual(t, collector.requestCount(), 2)
+}
+
+func TestDestinationDoesNotRetryClientErrors(t *testing.T) {
+	collector := newTestCollector(t, http.StatusBadRequest)
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+	defer destinationsCtx.Stop()
+
+	input := make(chan *message.Payload)
+	output := make(chan *message.Payload)
+	NewDestination(collector.endpoint(), destinationsCtx, true).Start(input, output, nil)
+
+	source := sources.NewLogSource("web", &config.LogsConfig{})
+	payload := newTestPayload(newTestMessage(t, "first", message.StatusInfo, source))
+	input <- payload
+	assert.Equal(t, payload, <-output)
+	assert.Equal(t, 1, collector.requestCount())
+}
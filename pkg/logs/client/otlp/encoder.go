@@ -0,0 +1,250 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+// scopeName is the name of the instrumentation scope of the log records sent by the agent.
+const scopeName = "datadog-agent/logs"
+
+// Attributes of the OTLP resources and log records.
+const (
+	hostNameAttribute    = "host.name"
+	serviceNameAttribute = "service.name"
+	sourceAttribute      = "datadog.log.source"
+	statusAttribute      = "status"
+)
+
+// statusSeverityMapping maps the statuses of the messages to the OTLP severity numbers.
+var statusSeverityMapping = map[string]plog.SeverityNumber{
+	message.StatusEmergency: plog.SeverityNumberFatal4,
+	message.StatusAlert:     plog.SeverityNumberFatal3,
+	message.StatusCritical:  plog.SeverityNumberFatal,
+	message.StatusError:     plog.SeverityNumberError,
+	message.StatusWarning:   plog.SeverityNumberWarn,
+	message.StatusNotice:    plog.SeverityNumberInfo2,
+	message.StatusInfo:      plog.SeverityNumberInfo,
+	message.StatusDebug:     plog.SeverityNumberDebug,
+}
+
+// logEntry is a log decoded from the JSON encoded content of a payload.
+type logEntry struct {
+	content    string
+	status     string
+	timestamp  time.Time
+	hostname   string
+	service    string
+	source     string
+	tags       []string
+	attributes map[string]interface{}
+}
+
+// resource identifies the OTLP resource a log belongs to.
+type resource struct {
+	hostname string
+	service  string
+	source   string
+}
+
+// decodePayload decodes the logs of a payload built by the HTTP pipelines, where the
+// JSON encoded messages are serialized as an array and possibly compressed with gzip.
+// The logs are decoded from the encoded bytes rather than the messages of the payload,
+// as the payloads replayed from the disk buffer do not hold their messages anymore.
+func decodePayload(payload *message.Payload) ([]logEntry, error) {
+	var reader io.Reader = bytes.NewReader(payload.Encoded)
+	if payload.Encoding == "gzip" {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+	var fields []map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("could not decode the payload: %v", err)
+	}
+	entries := make([]logEntry, 0, len(fields))
+	for _, f := range fields {
+		entries = append(entries, toLogEntry(f))
+	}
+	return entries, nil
+}
+
+// toLogEntry extracts the reserved fields of the JSON encoded message, the remaining ones
+// being the structured attributes of the message.
+func toLogEntry(fields map[string]interface{}) logEntry {
+	entry := logEntry{attributes: make(map[string]interface{})}
+	for key, value := range fields {
+		switch key {
+		case "message":
+			switch content := value.(type) {
+			case string:
+				entry.content = content
+			case map[string]interface{}:
+				// the serverless encoder nests the content alongside the lambda metadata
+				entry.content, _ = content["message"].(string)
+			}
+		case "status":
+			entry.status, _ = value.(string)
+		case "timestamp":
+			if n, ok := value.(json.Number); ok {
+				if millis, err := n.Int64(); err == nil && millis > 0 {
+					entry.timestamp = time.UnixMilli(millis)
+				}
+			}
+		case "hostname":
+			entry.hostname, _ = value.(string)
+		case "service":
+			entry.service, _ = value.(string)
+		case "ddsource":
+			entry.source, _ = value.(string)
+		case "ddtags":
+			if tags, _ := value.(string); tags != "" {
+				entry.tags = strings.Split(tags, ",")
+			}
+		default:
+			entry.attributes[key] = fromJSONNumbers(value)
+		}
+	}
+	return entry
+}
+
+// fromJSONNumbers converts the numbers decoded as json.Number into integers when possible,
+// and floats otherwise.
+func fromJSONNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = fromJSONNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = fromJSONNumbers(item)
+		}
+	}
+	return value
+}
+
+// toLogs converts the logs into OTLP logs, the logs sharing the same hostname,
+// service and source being grouped under the same resource.
+func toLogs(entries []logEntry) plog.Logs {
+	logs := plog.NewLogs()
+	records := make(map[resource]plog.LogRecordSlice)
+	observed := pcommon.NewTimestampFromTime(time.Now())
+	for _, entry := range entries {
+		r := resource{
+			hostname: entry.hostname,
+			service:  entry.service,
+			source:   entry.source,
+		}
+		slice, found := records[r]
+		if !found {
+			slice = newResourceLogs(logs, r)
+			records[r] = slice
+		}
+		record := slice.AppendEmpty()
+		record.SetObservedTimestamp(observed)
+		toLogRecord(entry, record)
+	}
+	return logs
+}
+
+func newResourceLogs(logs plog.Logs, r resource) plog.LogRecordSlice {
+	resourceLogs := logs.ResourceLogs().AppendEmpty()
+	attributes := resourceLogs.Resource().Attributes()
+	attributes.PutStr(hostNameAttribute, r.hostname)
+	if r.service != "" {
+		attributes.PutStr(serviceNameAttribute, r.service)
+	}
+	if r.source != "" {
+		attributes.PutStr(sourceAttribute, r.source)
+	}
+	scopeLogs := resourceLogs.ScopeLogs().AppendEmpty()
+	scopeLogs.Scope().SetName(scopeName)
+	scopeLogs.Scope().SetVersion(version.AgentVersion)
+	return scopeLogs.LogRecords()
+}
+
+// toLogRecord fills the record with the content, the status, the tags and the attributes of the log.
+func toLogRecord(entry logEntry, record plog.LogRecord) {
+	if !entry.timestamp.IsZero() {
+		record.SetTimestamp(pcommon.NewTimestampFromTime(entry.timestamp))
+	}
+
+	record.Body().SetStr(entry.content)
+
+	status := entry.status
+	if status == "" {
+		status = message.StatusInfo
+	}
+	record.SetSeverityText(status)
+	if severity, found := statusSeverityMapping[status]; found {
+		record.SetSeverityNumber(severity)
+	} else {
+		record.SetSeverityNumber(plog.SeverityNumberInfo)
+	}
+
+	attributes := record.Attributes()
+	putTags(attributes, entry.tags)
+	// the attributes of the message are more specific than the tags of its origin
+	for key, value := range entry.attributes {
+		if err := attributes.PutEmpty(key).FromRaw(value); err != nil {
+			attributes.PutStr(key, fmt.Sprint(value))
+		}
+	}
+	attributes.PutStr(statusAttribute, status)
+}
+
+// putTags adds the 'key:value' tags as attributes, a key having several values
+// being added as a slice.
+func putTags(attributes pcommon.Map, tags []string) {
+	var keys []string
+	values := make(map[string][]string, len(tags))
+	for _, tag := range tags {
+		key, value := tag, ""
+		if i := strings.IndexByte(tag, ':'); i >= 0 {
+			key, value = tag[:i], tag[i+1:]
+		}
+		if _, found := values[key]; !found {
+			keys = append(keys, key)
+		}
+		values[key] = append(values[key], value)
+	}
+	for _, key := range keys {
+		if len(values[key]) == 1 {
+			attributes.PutStr(key, values[key][0])
+			continue
+		}
+		slice := attributes.PutEmptySlice(key)
+		for _, value := range values[key] {
+			slice.AppendEmpty().SetStr(value)
+		}
+	}
+}
@@ -178,6 +178,10 @@ func buildTCPEndpoints(logsConfig *LogsConfigKeys) (*Endpoints, error) {
 		additionals[i].ProxyAddress = proxyAddress
 		additionals[i].APIKey = coreConfig.SanitizeAPIKey(additionals[i].APIKey)
 	}
+	if _, defined := logsConfig.otlpEndpoint(); defined {
+		// the OTLP destination decodes the logs from the JSON payloads of the HTTP pipelines
+		log.Warnf("%s is only supported when sending logs over HTTP, logs will not be sent to it", logsConfig.getConfigKey("otlp_endpoint"))
+	}
 	return NewEndpoints(main, additionals, useProto, false), nil
 }
 
@@ -288,7 +292,83 @@ func BuildHTTPEndpointsWithConfig(logsConfig *LogsConfigKeys, endpointPrefix str
 	batchMaxContentSize := logsConfig.batchMaxContentSize()
 	inputChanSize := logsConfig.inputChanSize()
 
-	return NewEndpointsWithBatchSettings(main, additionals, false, true, batchWait, batchMaxConcurrentSend, batchMaxSize, batchMaxContentSize, inputChanSize), nil
+	endpoints := NewEndpointsWithBatchSettings(main, additionals, false, true, batchWait, batchMaxConcurrentSend, batchMaxSize, batchMaxContentSize, inputChanSize)
+	var err error
+	if endpoints.OTLP, err = buildOTLPEndpoint(logsConfig); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// buildOTLPEndpoint returns the OpenTelemetry collector to send logs to, or nil if none is configured.
+// The address is either a URL, or '<HOST>:<PORT>' in which case ssl is enabled unless 'logs_no_ssl' is set.
+func buildOTLPEndpoint(logsConfig *LogsConfigKeys) (*OTLPEndpoint, error) {
+	address, defined := logsConfig.otlpEndpoint()
+	if !defined {
+		return nil, nil
+	}
+
+	isReliable := logsConfig.otlpIsReliable()
+	endpoint := &OTLPEndpoint{
+		Endpoint: Endpoint{
+			UseCompression:          logsConfig.useCompression(),
+			CompressionLevel:        logsConfig.compressionLevel(),
+			IsReliable:              &isReliable,
+			ConnectionResetInterval: logsConfig.connectionResetInterval(),
+			BackoffBase:             logsConfig.senderBackoffBase(),
+			BackoffMax:              logsConfig.senderBackoffMax(),
+			BackoffFactor:           logsConfig.senderBackoffFactor(),
+			RecoveryInterval:        logsConfig.senderRecoveryInterval(),
+			RecoveryReset:           logsConfig.senderRecoveryReset(),
+		},
+		Transport: OTLPTransport(logsConfig.otlpProtocol()),
+		Headers:   logsConfig.otlpHeaders(),
+	}
+
+	defaultPort := 4318
+	switch endpoint.Transport {
+	case OTLPOverHTTP:
+	case OTLPOverGRPC:
+		defaultPort = 4317
+	default:
+		return nil, fmt.Errorf("invalid %s: %q, must be either %q or %q", logsConfig.getConfigKey("otlp_protocol"), endpoint.Transport, OTLPOverHTTP, OTLPOverGRPC)
+	}
+
+	if strings.Contains(address, "://") {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %v", address, err)
+		}
+		switch u.Scheme {
+		case "https":
+			endpoint.UseSSL = true
+		case "http":
+			endpoint.UseSSL = false
+		default:
+			return nil, fmt.Errorf("could not parse %s: unsupported scheme %q", address, u.Scheme)
+		}
+		endpoint.Host = u.Hostname()
+		endpoint.Port = defaultPort
+		if u.Port() != "" {
+			if endpoint.Port, err = strconv.Atoi(u.Port()); err != nil {
+				return nil, fmt.Errorf("could not parse %s: %v", address, err)
+			}
+		}
+		endpoint.Path = strings.TrimSuffix(u.Path, "/")
+	} else {
+		host, port, err := parseAddress(address)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %v", address, err)
+		}
+		endpoint.Host = host
+		endpoint.Port = port
+		endpoint.UseSSL = !logsConfig.logsNoSSL()
+	}
+
+	if endpoint.Transport == OTLPOverHTTP && endpoint.Path == "" {
+		endpoint.Path = "/v1/logs"
+	}
+	return endpoint, nil
 }
 
 // parseAddress returns the host and the port of the address.
@@ -309,6 +389,24 @@ func TaggerWarmupDuration() time.Duration {
 	return defaultLogsConfigKeys().taggerWarmupDuration()
 }
 
+// DiskBufferConfig holds the settings of the disk buffer of the logs senders
+type DiskBufferConfig struct {
+	Path           string
+	MaxSizeInBytes int64
+	MaxAge         time.Duration
+}
+
+// DiskBuffer returns the settings of the disk buffer of the logs senders, which is disabled
+// when MaxSizeInBytes is 0
+func DiskBuffer() DiskBufferConfig {
+	keys := defaultLogsConfigKeys()
+	return DiskBufferConfig{
+		Path:           keys.diskBufferPath(),
+		MaxSizeInBytes: keys.diskBufferMaxSizeInBytes(),
+		MaxAge:         keys.diskBufferMaxAge(),
+	}
+}
+
 // AggregationTimeout is used when performing aggregation operations
 func AggregationTimeout() time.Duration {
 	return defaultLogsConfigKeys().aggregationTimeout()
@@ -7,6 +7,7 @@ package config
 
 import (
 	"encoding/json"
+	"path/filepath"
 	"time"
 
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
@@ -259,6 +260,38 @@ func (l *LogsConfigKeys) aggregationTimeout() time.Duration {
 	return l.getConfig().GetDuration(l.getConfigKey("aggregation_timeout")) * time.Millisecond
 }
 
+func (l *LogsConfigKeys) diskBufferPath() string {
+	if path := l.getConfig().GetString(l.getConfigKey("disk_buffer_path")); path != "" {
+		return path
+	}
+	return filepath.Join(l.getConfig().GetString(l.getConfigKey("run_path")), "disk_buffer")
+}
+
+func (l *LogsConfigKeys) diskBufferMaxSizeInBytes() int64 {
+	return l.getConfig().GetInt64(l.getConfigKey("disk_buffer_max_size_in_bytes"))
+}
+
+func (l *LogsConfigKeys) diskBufferMaxAge() time.Duration {
+	return time.Duration(l.getConfig().GetInt(l.getConfigKey("disk_buffer_max_age"))) * time.Hour
+}
+
+func (l *LogsConfigKeys) otlpEndpoint() (string, bool) {
+	configKey := l.getConfigKey("otlp_endpoint")
+	return l.getConfig().GetString(configKey), l.isSetAndNotEmpty(configKey)
+}
+
+func (l *LogsConfigKeys) otlpProtocol() string {
+	return l.getConfig().GetString(l.getConfigKey("otlp_protocol"))
+}
+
+func (l *LogsConfigKeys) otlpHeaders() map[string]string {
+	return l.getConfig().GetStringMapString(l.getConfigKey("otlp_headers"))
+}
+
+func (l *LogsConfigKeys) otlpIsReliable() bool {
+	return l.getConfig().GetBool(l.getConfigKey("otlp_is_reliable"))
+}
+
 func (l *LogsConfigKeys) useV2API() bool {
 	return l.getConfig().GetBool(l.getConfigKey("use_v2_api"))
 }
@@ -98,6 +98,40 @@ func (e *Endpoint) GetIsReliable() bool {
 	return e.IsReliable == nil || *e.IsReliable
 }
 
+// OTLPTransport is the transport used to send logs over OTLP.
+type OTLPTransport string
+
+// OTLP transports
+const (
+	OTLPOverHTTP OTLPTransport = "http"
+	OTLPOverGRPC OTLPTransport = "grpc"
+)
+
+// OTLPEndpoint holds the parameters to send logs to an OpenTelemetry collector over OTLP.
+type OTLPEndpoint struct {
+	Endpoint
+	Transport OTLPTransport
+	// Path is the path of the logs service when using OTLP/HTTP
+	Path    string
+	Headers map[string]string
+}
+
+// GetStatus returns the endpoint status
+func (e *OTLPEndpoint) GetStatus(prefix string) string {
+	compression := "uncompressed"
+	if e.UseCompression {
+		compression = "compressed"
+	}
+	protocol := "OTLP/HTTP"
+	if e.Transport == OTLPOverGRPC {
+		protocol = "OTLP/gRPC"
+	}
+	if e.UseSSL {
+		protocol += " with TLS"
+	}
+	return fmt.Sprintf("%sSending %s logs in %s to %s on port %d", prefix, compression, protocol, e.Host, e.Port)
+}
+
 // Endpoints holds the main endpoint and additional ones to dualship logs.
 type Endpoints struct {
 	Main                   Endpoint
@@ -109,6 +143,8 @@ type Endpoints struct {
 	BatchMaxSize           int
 	BatchMaxContentSize    int
 	InputChanSize          int
+	// OTLP is the OpenTelemetry collector logs are also sent to, nil if not configured
+	OTLP *OTLPEndpoint
 }
 
 // GetStatus returns the endpoints status, one line per endpoint
@@ -120,6 +156,13 @@ func (e *Endpoints) GetStatus() []string {
 	for _, endpoint := range e.GetUnReliableEndpoints() {
 		result = append(result, endpoint.GetStatus("Unreliable: ", e.UseHTTP))
 	}
+	if e.OTLP != nil {
+		prefix := "Reliable: "
+		if !e.OTLP.GetIsReliable() {
+			prefix = "Unreliable: "
+		}
+		result = append(result, e.OTLP.GetStatus(prefix))
+	}
 	return result
 }
 
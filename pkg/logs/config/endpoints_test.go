@@ -482,6 +482,67 @@ func (suite *EndpointsTestSuite) TestIsReliableDefaultTrue() {
 	suite.Len(endpoints.GetReliableEndpoints(), 3)
 }
 
+func (suite *EndpointsTestSuite) TestOTLPEndpoint() {
+	endpoints, err := BuildEndpoints(HTTPConnectivityFailure, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.Nil(endpoints.OTLP)
+
+	suite.config.Set("logs_config.use_http", true)
+	suite.config.Set("logs_config.otlp_endpoint", "http://collector:4318")
+	suite.config.Set("logs_config.otlp_headers", map[string]string{"x-scope": "logs"})
+	endpoints, err = BuildEndpoints(HTTPConnectivityFailure, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.NotNil(endpoints.OTLP)
+	suite.Equal(OTLPOverHTTP, endpoints.OTLP.Transport)
+	suite.Equal("collector", endpoints.OTLP.Host)
+	suite.Equal(4318, endpoints.OTLP.Port)
+	suite.Equal("/v1/logs", endpoints.OTLP.Path)
+	suite.False(endpoints.OTLP.UseSSL)
+	suite.True(endpoints.OTLP.GetIsReliable())
+	suite.Equal(map[string]string{"x-scope": "logs"}, endpoints.OTLP.Headers)
+	suite.Len(endpoints.Endpoints, 1)
+
+	suite.config.Set("logs_config.otlp_endpoint", "https://collector/custom/logs/")
+	endpoints, err = BuildEndpoints(HTTPConnectivityFailure, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.True(endpoints.OTLP.UseSSL)
+	suite.Equal(4318, endpoints.OTLP.Port)
+	suite.Equal("/custom/logs", endpoints.OTLP.Path)
+
+	suite.config.Set("logs_config.otlp_protocol", "grpc")
+	suite.config.Set("logs_config.otlp_endpoint", "collector:4317")
+	suite.config.Set("logs_config.otlp_is_reliable", false)
+	endpoints, err = BuildEndpoints(HTTPConnectivityFailure, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.Equal(OTLPOverGRPC, endpoints.OTLP.Transport)
+	suite.Equal(4317, endpoints.OTLP.Port)
+	suite.Equal("", endpoints.OTLP.Path)
+	suite.True(endpoints.OTLP.UseSSL)
+	suite.False(endpoints.OTLP.GetIsReliable())
+	suite.Equal([]string{
+		"Reliable: Sending compressed logs in HTTPS to agent-http-intake.logs.datadoghq.com on port 443",
+		"Unreliable: Sending compressed logs in OTLP/gRPC with TLS to collector on port 4317",
+	}, endpoints.GetStatus())
+
+	suite.config.Set("logs_config.otlp_protocol", "thrift")
+	_, err = BuildEndpoints(HTTPConnectivityFailure, "test-track", "test-proto", "test-source")
+	suite.NotNil(err)
+
+	suite.config.Set("logs_config.otlp_protocol", "http")
+	suite.config.Set("logs_config.otlp_endpoint", "collector")
+	_, err = BuildEndpoints(HTTPConnectivityFailure, "test-track", "test-proto", "test-source")
+	suite.NotNil(err)
+
+	// the logs sent over TCP are not sent over OTLP
+	suite.config.Set("logs_config.otlp_endpoint", "collector:4318")
+	suite.config.Set("logs_config.use_http", false)
+	suite.config.Set("logs_config.use_tcp", true)
+	endpoints, err = BuildEndpoints(HTTPConnectivityFailure, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.False(endpoints.UseHTTP)
+	suite.Nil(endpoints.OTLP)
+}
+
 func TestEndpointsTestSuite(t *testing.T) {
 	suite.Run(t, new(EndpointsTestSuite))
 }
@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Field types supported in grok references, e.g. `%{NUMBER:duration:float}`
+const (
+	FieldTypeString = "string"
+	FieldTypeInt    = "int"
+	FieldTypeFloat  = "float"
+)
+
+// maxGrokDepth bounds the recursive expansion of grok patterns referencing other patterns.
+const maxGrokDepth = 16
+
+// grokPatterns is the set of named patterns that can be referenced with `%{NAME}`
+// in extract_fields and log_to_metric processing rules.
+var grokPatterns = map[string]string{
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"INT":               `[+-]?\d+`,
+	"POSINT":            `\b[1-9]\d*\b`,
+	"NONNEGINT":         `\b\d+\b`,
+	"BASE10NUM":         `[+-]?(?:\d+(?:\.\d*)?|\.\d+)`,
+	"NUMBER":            `%{BASE10NUM}`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)`,
+	"IPV6":              `(?:[A-Fa-f0-9]{0,4}:){2,7}[A-Fa-f0-9]{0,4}`,
+	"IP":                `%{IPV6}|%{IPV4}`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z\-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z\-]{0,62})*\.?\b`,
+	"IPORHOST":          `%{IP}|%{HOSTNAME}`,
+	"USER":              `[a-zA-Z0-9._-]+`,
+	"URIPATH":           `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":          `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM":      `%{URIPATH}(?:%{URIPARAM})?`,
+	"HTTPMETHOD":        `GET|HEAD|POST|PUT|DELETE|CONNECT|OPTIONS|TRACE|PATCH`,
+	"LOGLEVEL":          `(?i:trace|debug|info|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?|alert)`,
+	"MONTH":             `\b(?:Jan(?:uary)?|Feb(?:ruary)?|Mar(?:ch)?|Apr(?:il)?|May|Jun(?:e)?|Jul(?:y)?|Aug(?:ust)?|Sep(?:tember)?|Oct(?:ober)?|Nov(?:ember)?|Dec(?:ember)?)\b`,
+	"MONTHDAY":          `(?:0[1-9]|[12]\d|3[01]|[1-9])`,
+	"YEAR":              `\d{4}`,
+	"HOUR":              `(?:2[0123]|[01]?\d)`,
+	"MINUTE":            `[0-5]\d`,
+	"SECOND":            `(?:[0-5]?\d|60)(?:[.,]\d+)?`,
+	"TIME":              `%{HOUR}:%{MINUTE}:%{SECOND}`,
+	"ISO8601_TIMEZONE":  `Z|[+-]%{HOUR}(?::?%{MINUTE})`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-\d{2}-\d{2}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?(?:%{ISO8601_TIMEZONE})?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} [+-]\d{4}`,
+}
+
+// grokReference matches `%{NAME}`, `%{NAME:field}` and `%{NAME:field:type}`.
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::([\w]+))?(?::(string|int|float))?\}`)
+
+// expandGrokPattern replaces grok references in a pattern with the regular expressions
+// they stand for and returns the types declared for the named fields.
+// References without a field name are expanded as non-capturing groups.
+func expandGrokPattern(pattern string) (string, map[string]string, error) {
+	types := make(map[string]string)
+	expanded, err := expandGrokReferences(pattern, types, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	return expanded, types, nil
+}
+
+func expandGrokReferences(pattern string, types map[string]string, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("grok pattern nesting is too deep")
+	}
+	var err error
+	expanded := grokReference.ReplaceAllStringFunc(pattern, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		parts := grokReference.FindStringSubmatch(ref)
+		name, field, fieldType := parts[1], parts[2], parts[3]
+		definition, found := grokPatterns[name]
+		if !found {
+			err = fmt.Errorf("unknown grok pattern %s", name)
+			return ""
+		}
+		var inner string
+		inner, err = expandGrokReferences(definition, nil, depth+1)
+		if field == "" {
+			return "(?:" + inner + ")"
+		}
+		if types != nil && fieldType != "" {
+			types[field] = fieldType
+		}
+		return "(?P<" + field + ">" + inner + ")"
+	})
+	if err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
+
+// compileExtractPattern expands the grok references of an extract_fields pattern
+// and compiles it, making sure it captures at least one named field.
+func compileExtractPattern(pattern string) (*regexp.Regexp, map[string]string, error) {
+	re, types, err := compileGrokPattern(pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+	hasField := false
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			hasField = true
+			break
+		}
+	}
+	if !hasField {
+		return nil, nil, fmt.Errorf("pattern %s does not capture any named field", strings.TrimSpace(pattern))
+	}
+	return re, types, nil
+}
+
+// compileGrokPattern expands the grok references of the pattern and compiles it,
+// the pattern may not capture any field.
+func compileGrokPattern(pattern string) (*regexp.Regexp, map[string]string, error) {
+	expanded, types, err := expandGrokPattern(pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, nil, err
+	}
+	return re, types, nil
+}
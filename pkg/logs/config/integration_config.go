@@ -17,6 +17,7 @@ import (
 const (
 	TCPType           = "tcp"
 	UDPType           = "udp"
+	SyslogType        = "syslog"
 	FileType          = "file"
 	DockerType        = "docker"
 	ContainerdType    = "containerd"
@@ -30,6 +31,9 @@ const (
 	UTF16LE string = "utf-16-le"
 	// SHIFTJIS for Shift JIS (Japanese) encoding
 	SHIFTJIS string = "shift-jis"
+
+	// JSONParser parses structured JSON lines
+	JSONParser = "json"
 )
 
 // LogsConfig represents a log source config, which can be for instance
@@ -39,6 +43,7 @@ type LogsConfig struct {
 
 	Port        int    // Network
 	IdleTimeout string `mapstructure:"idle_timeout" json:"idle_timeout"` // Network
+	Protocol    string // Syslog
 	Path        string // File, Journald
 
 	Encoding     string   `mapstructure:"encoding" json:"encoding"`             // File
@@ -85,6 +90,19 @@ type LogsConfig struct {
 	AutoMultiLine               *bool   `mapstructure:"auto_multi_line_detection" json:"auto_multi_line_detection"`
 	AutoMultiLineSampleSize     int     `mapstructure:"auto_multi_line_sample_size" json:"auto_multi_line_sample_size"`
 	AutoMultiLineMatchThreshold float64 `mapstructure:"auto_multi_line_match_threshold" json:"auto_multi_line_match_threshold"`
+
+	// Parser is the optional parser applied to each line after the source specific parsing.
+	Parser   string
+	JSONKeys JSONKeys `mapstructure:"json_keys" json:"json_keys"`
+}
+
+// JSONKeys defines the keys of a JSON line which are lifted to the
+// message content, status, timestamp and service by the json parser.
+type JSONKeys struct {
+	Message   string `mapstructure:"message" json:"message"`
+	Status    string `mapstructure:"status" json:"status"`
+	Timestamp string `mapstructure:"timestamp" json:"timestamp"`
+	Service   string `mapstructure:"service" json:"service"`
 }
 
 // Dump dumps the contents of this struct to a string, for debugging purposes.
@@ -110,6 +128,10 @@ func (c *LogsConfig) Dump(multiline bool) string {
 	case UDPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+	case SyslogType:
+		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
+		fmt.Fprintf(&b, ws("Protocol: %#v,"), c.Protocol)
+		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
 	case FileType:
 		fmt.Fprintf(&b, ws("Path: %#v,"), c.Path)
 		fmt.Fprintf(&b, ws("Encoding: %#v,"), c.Encoding)
@@ -148,7 +170,9 @@ func (c *LogsConfig) Dump(multiline bool) string {
 		fmt.Fprint(&b, ws("AutoMultiLine: nil,"))
 	}
 	fmt.Fprintf(&b, ws("AutoMultiLineSampleSize: %d,"), c.AutoMultiLineSampleSize)
-	fmt.Fprintf(&b, ws("AutoMultiLineMatchThreshold: %f}"), c.AutoMultiLineMatchThreshold)
+	fmt.Fprintf(&b, ws("AutoMultiLineMatchThreshold: %f,"), c.AutoMultiLineMatchThreshold)
+	fmt.Fprintf(&b, ws("Parser: %#v,"), c.Parser)
+	fmt.Fprintf(&b, ws("JSONKeys: %+v}"), c.JSONKeys)
 	return b.String()
 }
 
@@ -213,6 +237,16 @@ func (c *LogsConfig) Validate() error {
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case c.Type == SyslogType:
+		if c.Port == 0 {
+			return fmt.Errorf("syslog source must have a port")
+		}
+		if c.Protocol != "" && c.Protocol != TCPType && c.Protocol != UDPType {
+			return fmt.Errorf("invalid protocol '%v' for syslog source, must be tcp or udp", c.Protocol)
+		}
+	}
+	if c.Parser != "" && c.Parser != JSONParser {
+		return fmt.Errorf("parser %s is not supported", c.Parser)
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
@@ -8,6 +8,8 @@ package config
 import (
 	"fmt"
 	"regexp"
+
+	"github.com/benbjohnson/clock"
 )
 
 // Processing rule types
@@ -16,8 +18,21 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	ExtractFields  = "extract_fields"
+	Sample         = "sample"
+	RateLimit      = "rate_limit"
+	LogToMetric    = "log_to_metric"
+)
+
+// Metric types of the log_to_metric rules
+const (
+	MetricTypeCount        = "count"
+	MetricTypeDistribution = "distribution"
 )
 
+// LogSizeField is the value_field of the log_to_metric rules recording the size of the logs in bytes.
+const LogSizeField = "log_size"
+
 // ProcessingRule defines an exclusion or a masking rule to
 // be applied on log lines
 type ProcessingRule struct {
@@ -25,16 +40,37 @@ type ProcessingRule struct {
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+	// TagFields lists the fields captured by an extract_fields rule that
+	// should also be added as tags to the message.
+	TagFields []string `mapstructure:"tag_fields" json:"tag_fields"`
+	// SampleRate is the ratio, between 0 and 1, of the matching logs kept by a sample rule.
+	SampleRate float64 `mapstructure:"sample_rate" json:"sample_rate"`
+	// LimitPerSecond is the number of matching logs per second let through by a rate_limit rule,
+	// Burst is the number of logs that can be let through at once and defaults to LimitPerSecond.
+	LimitPerSecond float64 `mapstructure:"limit_per_second" json:"limit_per_second"`
+	Burst          int     `mapstructure:"burst" json:"burst"`
+	// GroupBy lists the attributes or tag names whose values get their own rate_limit budget.
+	GroupBy []string `mapstructure:"group_by" json:"group_by"`
+	// MetricName and MetricType define the metric generated by a log_to_metric rule, a count by default.
+	MetricName string `mapstructure:"metric_name" json:"metric_name"`
+	MetricType string `mapstructure:"metric_type" json:"metric_type"`
+	// ValueField is the captured field or attribute holding the value recorded by a log_to_metric rule,
+	// each matching log counting for 1 when not set.
+	ValueField string `mapstructure:"value_field" json:"value_field"`
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
+	// FieldTypes holds the types declared for the fields of an extract_fields rule.
+	FieldTypes map[string]string
+	// RateLimiter holds the budgets of a rate_limit rule.
+	RateLimiter *RateLimiter
 }
 
 // ValidateProcessingRules validates the rules and raises an error if one is misconfigured.
 // Each processing rule must have:
 // - a valid name
 // - a valid type
-// - a valid pattern that compiles
+// - a valid pattern that compiles, optional for sample, rate_limit and log_to_metric rules
 func ValidateProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
 		if rule.Name == "" {
@@ -44,6 +80,50 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		switch rule.Type {
 		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
 			break
+		case ExtractFields:
+			if rule.Pattern == "" {
+				return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
+			}
+			if _, _, err := compileExtractPattern(rule.Pattern); err != nil {
+				return fmt.Errorf("invalid pattern %s for processing rule: %s: %v", rule.Pattern, rule.Name, err)
+			}
+			continue
+		case Sample:
+			if rule.SampleRate < 0 || rule.SampleRate > 1 {
+				return fmt.Errorf("sample_rate must be between 0 and 1 for processing rule: %s", rule.Name)
+			}
+			if rule.Pattern == "" {
+				continue
+			}
+		case RateLimit:
+			if rule.LimitPerSecond <= 0 {
+				return fmt.Errorf("limit_per_second must be positive for processing rule: %s", rule.Name)
+			}
+			if rule.Burst < 0 {
+				return fmt.Errorf("burst must not be negative for processing rule: %s", rule.Name)
+			}
+			if rule.Pattern == "" {
+				continue
+			}
+		case LogToMetric:
+			if rule.MetricName == "" {
+				return fmt.Errorf("no metric_name provided for processing rule: %s", rule.Name)
+			}
+			switch rule.MetricType {
+			case "", MetricTypeCount, MetricTypeDistribution:
+			default:
+				return fmt.Errorf("metric_type %s is not supported for processing rule: %s", rule.MetricType, rule.Name)
+			}
+			if rule.MetricType == MetricTypeDistribution && rule.ValueField == "" {
+				return fmt.Errorf("no value_field provided for distribution processing rule: %s", rule.Name)
+			}
+			if rule.Pattern == "" {
+				continue
+			}
+			if _, _, err := compileGrokPattern(rule.Pattern); err != nil {
+				return fmt.Errorf("invalid pattern %s for processing rule: %s: %v", rule.Pattern, rule.Name, err)
+			}
+			continue
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
@@ -64,12 +144,38 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		if rule.Type == RateLimit && rule.RateLimiter == nil {
+			rule.RateLimiter = NewRateLimiter(rule.LimitPerSecond, rule.Burst, clock.New())
+		}
+		if rule.Type == ExtractFields {
+			re, types, err := compileExtractPattern(rule.Pattern)
+			if err != nil {
+				return err
+			}
+			rule.Regex = re
+			rule.FieldTypes = types
+			continue
+		}
+		if rule.Type == LogToMetric && rule.Pattern != "" {
+			re, types, err := compileGrokPattern(rule.Pattern)
+			if err != nil {
+				return err
+			}
+			rule.Regex = re
+			rule.FieldTypes = types
+			continue
+		}
+		if (rule.Type == Sample || rule.Type == RateLimit || rule.Type == LogToMetric) && rule.Pattern == "" {
+			// without a pattern, the rule applies to all logs
+			rule.Regex = nil
+			continue
+		}
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, Sample, RateLimit:
 			rule.Regex = re
 		case MaskSequences:
 			rule.Regex = re
@@ -34,3 +34,73 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestCompileExtractFieldsRule(t *testing.T) {
+	rules := []*ProcessingRule{{Name: "access", Type: ExtractFields, Pattern: `%{IPV4:client} %{WORD:method} %{INT:status:int}`}}
+	assert.Nil(t, ValidateProcessingRules(rules))
+	assert.Nil(t, CompileProcessingRules(rules))
+	assert.Equal(t, map[string]string{"status": FieldTypeInt}, rules[0].FieldTypes)
+
+	match := rules[0].Regex.FindStringSubmatch("10.0.0.1 GET 200")
+	assert.Equal(t, []string{"10.0.0.1 GET 200", "10.0.0.1", "GET", "200"}, match)
+	assert.Equal(t, []string{"", "client", "method", "status"}, rules[0].Regex.SubexpNames())
+}
+
+func TestValidateExtractFieldsRule(t *testing.T) {
+	for _, pattern := range []string{
+		`%{UNKNOWN:field}`,
+		`no named capture`,
+		`(?P<field>[)`,
+	} {
+		rules := []*ProcessingRule{{Name: "invalid", Type: ExtractFields, Pattern: pattern}}
+		assert.NotNil(t, ValidateProcessingRules(rules), pattern)
+	}
+
+	rules := []*ProcessingRule{{Name: "named", Type: ExtractFields, Pattern: `latency=(?P<latency>\d+)ms`}}
+	assert.Nil(t, ValidateProcessingRules(rules))
+}
+
+func TestValidateSampleAndRateLimitRules(t *testing.T) {
+	for _, rule := range []*ProcessingRule{
+		{Name: "invalid", Type: Sample, SampleRate: 1.5},
+		{Name: "invalid", Type: Sample, SampleRate: -0.1},
+		{Name: "invalid", Type: Sample, SampleRate: 0.5, Pattern: "[a"},
+		{Name: "invalid", Type: RateLimit},
+		{Name: "invalid", Type: RateLimit, LimitPerSecond: 10, Burst: -1},
+	} {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule)
+	}
+
+	rules := []*ProcessingRule{
+		{Name: "sample", Type: Sample, SampleRate: 0.1},
+		{Name: "limit", Type: RateLimit, LimitPerSecond: 10, Pattern: "DEBUG", GroupBy: []string{"user"}},
+	}
+	assert.Nil(t, ValidateProcessingRules(rules))
+	assert.Nil(t, CompileProcessingRules(rules))
+	assert.Nil(t, rules[0].Regex)
+	assert.True(t, rules[1].Regex.MatchString("DEBUG"))
+}
+
+func TestValidateLogToMetricRules(t *testing.T) {
+	for _, rule := range []*ProcessingRule{
+		{Name: "invalid", Type: LogToMetric},
+		{Name: "invalid", Type: LogToMetric, MetricName: "errors", MetricType: "gauge"},
+		{Name: "invalid", Type: LogToMetric, MetricName: "latency", MetricType: MetricTypeDistribution},
+		{Name: "invalid", Type: LogToMetric, MetricName: "errors", Pattern: "%{UNKNOWN:level}"},
+	} {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule)
+	}
+
+	rules := []*ProcessingRule{
+		{Name: "errors", Type: LogToMetric, MetricName: "logs.errors", Pattern: "ERROR"},
+		{Name: "bytes", Type: LogToMetric, MetricName: "logs.bytes", ValueField: LogSizeField},
+		{Name: "latency", Type: LogToMetric, MetricName: "http.latency", MetricType: MetricTypeDistribution, Pattern: "took %{NUMBER:latency:float}ms", ValueField: "latency"},
+	}
+	assert.Nil(t, ValidateProcessingRules(rules))
+	assert.Nil(t, CompileProcessingRules(rules))
+	assert.True(t, rules[0].Regex.MatchString("ERROR"))
+	assert.Nil(t, rules[1].Regex)
+	assert.True(t, rules[2].Regex.MatchString("took 12.5ms"))
+	assert.Greater(t, rules[2].Regex.SubexpIndex("latency"), 0)
+	assert.Equal(t, FieldTypeFloat, rules[2].FieldTypes["latency"])
+}
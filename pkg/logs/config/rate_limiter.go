@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+// maxRateLimitBuckets bounds the number of budgets kept by a rate_limit rule,
+// the least recently used ones are evicted above this size.
+const maxRateLimitBuckets = 4096
+
+// tokenBucket lets through up to burst logs at once, refilled at the rule rate.
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// RateLimiter keeps a token bucket per key for a rate_limit rule. It is built with
+// the rule, so that its budgets are shared by all the pipelines and released with the rule.
+type RateLimiter struct {
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+	clock   clock.Clock
+	mu      sync.Mutex
+}
+
+// NewRateLimiter returns a limiter letting through limitPerSecond logs per key, and
+// up to burst logs at once, using the given clock.
+func NewRateLimiter(limitPerSecond float64, burst int, clock clock.Clock) *RateLimiter {
+	b := float64(burst)
+	if b == 0 {
+		b = limitPerSecond
+	}
+	if b < 1 {
+		b = 1
+	}
+	return &RateLimiter{
+		rate:    limitPerSecond,
+		burst:   b,
+		buckets: make(map[string]*tokenBucket),
+		clock:   clock,
+	}
+}
+
+// Allow returns true if a log can be let through for the given key.
+func (l *RateLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t := l.clock.Now()
+	bucket, found := l.buckets[key]
+	if !found {
+		if len(l.buckets) >= maxRateLimitBuckets {
+			l.evictOldest()
+		}
+		bucket = &tokenBucket{tokens: l.burst, lastSeen: t}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens += t.Sub(bucket.lastSeen).Seconds() * l.rate
+	if bucket.tokens > l.burst {
+		bucket.tokens = l.burst
+	}
+	bucket.lastSeen = t
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+func (l *RateLimiter) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, bucket := range l.buckets {
+		if oldestKey == "" || bucket.lastSeen.Before(oldest) {
+			oldestKey, oldest = key, bucket.lastSeen
+		}
+	}
+	delete(l.buckets, oldestKey)
+}
@@ -0,0 +1,53 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	clock := clock.NewMock()
+	limiter := NewRateLimiter(2, 0, clock)
+
+	assert.True(t, limiter.Allow("alice"))
+	assert.True(t, limiter.Allow("alice"))
+	assert.False(t, limiter.Allow("alice"))
+	assert.True(t, limiter.Allow("bob"))
+
+	clock.Add(500 * time.Millisecond)
+	assert.True(t, limiter.Allow("alice"))
+	assert.False(t, limiter.Allow("alice"))
+}
+
+func TestRateLimiterEvictsOldestBucket(t *testing.T) {
+	clock := clock.NewMock()
+	limiter := NewRateLimiter(1, 0, clock)
+
+	for i := 0; i < maxRateLimitBuckets; i++ {
+		assert.True(t, limiter.Allow(strconv.Itoa(i)))
+		clock.Add(time.Millisecond)
+	}
+	assert.True(t, limiter.Allow("new"))
+	assert.Len(t, limiter.buckets, maxRateLimitBuckets)
+	assert.NotContains(t, limiter.buckets, "0")
+}
+
+func TestCompileBuildsRateLimiter(t *testing.T) {
+	rule := &ProcessingRule{Type: RateLimit, Name: "noisy", LimitPerSecond: 1}
+	assert.Nil(t, CompileProcessingRules([]*ProcessingRule{rule}))
+	limiter := rule.RateLimiter
+	assert.NotNil(t, limiter)
+
+	// compiling the rule again keeps its budgets
+	assert.Nil(t, CompileProcessingRules([]*ProcessingRule{rule}))
+	assert.Same(t, limiter, rule.RateLimiter)
+}
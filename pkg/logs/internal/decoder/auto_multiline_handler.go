@@ -20,6 +20,10 @@ import (
 
 const autoMultiLineTelemetryMetricName = "datadog.logs_agent.auto_multi_line"
 
+// stackTraceMatchThreshold is the ratio of the assessed lines which must be stack trace
+// lines for the handler to aggregate the stack traces when no pattern was detected.
+const stackTraceMatchThreshold = 0.1
+
 type scoredPattern struct {
 	score  int
 	regexp *regexp.Regexp
@@ -47,6 +51,8 @@ func (d *DetectedPattern) Get() *regexp.Regexp {
 
 // AutoMultilineHandler can attempts to detect a known/commob pattern (a timestamp) in the logs
 // and will switch to a MultiLine handler if one is detected and the thresholds are met.
+// When no pattern meets the thresholds but stack traces are found, it switches to a MultiLine
+// handler aggregating the stack traces lines instead.
 type AutoMultilineHandler struct {
 	multiLineHandler  *MultiLineHandler
 	singleLineHandler *SingleLineHandler
@@ -63,6 +69,7 @@ type AutoMultilineHandler struct {
 	matchTimeout      time.Duration
 	timeoutTimer      *clock.Timer
 	detectedPattern   *DetectedPattern
+	stackTraces       *stackTraceMatcher
 	clk               clock.Clock
 }
 
@@ -100,6 +107,7 @@ func NewAutoMultilineHandler(
 		matchTimeout:    matchTimeout,
 		timeoutTimer:    nil,
 		detectedPattern: detectedPattern,
+		stackTraces:     &stackTraceMatcher{},
 		clk:             clock.New(),
 	}
 
@@ -132,6 +140,11 @@ func (h *AutoMultilineHandler) processAndTry(message *Message) {
 	// Process message before anything else
 	h.singleLineHandler.process(message)
 
+	if message.Structured != nil {
+		// structured messages are never aggregated, they don't help detecting a pattern
+		return
+	}
+
 	for i, scoredPattern := range h.scoredMatches {
 		match := scoredPattern.regexp.Match(message.Content)
 		if match {
@@ -147,6 +160,7 @@ func (h *AutoMultilineHandler) processAndTry(message *Message) {
 			break
 		}
 	}
+	h.stackTraces.isContinuation(message.Content)
 
 	if h.timeoutTimer == nil {
 		h.timeoutTimer = h.clk.Timer(h.matchTimeout)
@@ -166,12 +180,18 @@ func (h *AutoMultilineHandler) processAndTry(message *Message) {
 	if h.linesTested >= h.linesToAssess || timeout {
 		topMatch := h.scoredMatches[0]
 		matchRatio := float64(topMatch.score) / float64(h.linesTested)
+		stackTraceRatio := float64(h.stackTraces.markers) / float64(h.linesTested)
 
 		if matchRatio >= h.matchThreshold {
 			log.Debugf("Pattern %v matched %d lines with a ratio of %f", topMatch.regexp.String(), topMatch.score, matchRatio)
 			telemetry.GetStatsTelemetryProvider().Count(autoMultiLineTelemetryMetricName, 1, []string{"success:true"})
 			h.detectedPattern.Set(topMatch.regexp)
 			h.switchToMultilineHandler(topMatch.regexp)
+		} else if h.stackTraces.markers > 0 && stackTraceRatio >= stackTraceMatchThreshold {
+			log.Debugf("No pattern met the line match threshold: %f during multiline auto detection, found %d stack trace lines with a ratio of %f - aggregating stack traces", h.matchThreshold, h.stackTraces.markers, stackTraceRatio)
+			telemetry.GetStatsTelemetryProvider().Count(autoMultiLineTelemetryMetricName, 1, []string{"success:true"})
+			h.detectedPattern.Set(stackTracePattern)
+			h.switchToStackTraceHandler()
 		} else {
 			log.Debugf("No pattern met the line match threshold: %f during multiline auto detection. Top match was %v with a match ratio of: %f - using single line handler", h.matchThreshold, topMatch.regexp.String(), matchRatio)
 			telemetry.GetStatsTelemetryProvider().Count(autoMultiLineTelemetryMetricName, 1, []string{"success:false"})
@@ -193,6 +213,17 @@ func (h *AutoMultilineHandler) switchToMultilineHandler(r *regexp.Regexp) {
 	h.processFunc = h.multiLineHandler.process
 }
 
+func (h *AutoMultilineHandler) switchToStackTraceHandler() {
+	h.isRunning = false
+	h.singleLineHandler = nil
+
+	h.multiLineHandler = NewStackTraceMultiLineHandler(h.outputFn, h.flushTimeout, h.lineLimit, true)
+	h.source.RegisterInfo(h.multiLineHandler.countInfo)
+	h.source.RegisterInfo(h.multiLineHandler.linesCombinedInfo)
+	h.source.RegisterInfo(h.multiLineHandler.stackTracesInfo)
+	h.processFunc = h.multiLineHandler.process
+}
+
 // Originally referenced from https://github.com/egnyte/ax/blob/master/pkg/heuristic/timestamp.go
 // All line matching rules must only match the beginning of a line, so when adding new expressions
 // make sure to prepend it with `^`
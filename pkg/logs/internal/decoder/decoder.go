@@ -13,6 +13,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/framer"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	jsonparser "github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/json"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/status"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -41,6 +42,8 @@ type Message struct {
 	RawDataLen         int
 	Timestamp          string
 	IngestionTimestamp int64
+	Tags               []string
+	Structured         *parsers.StructuredContent
 }
 
 // NewMessage returns a new output.
@@ -102,6 +105,15 @@ func syncSourceInfo(source *sources.ReplaceableSource, lh *MultiLineHandler) {
 	} else {
 		source.RegisterInfo(lh.linesCombinedInfo)
 	}
+	// And for stackTracesInfo when aggregating stack traces
+	if lh.stackTracesInfo == nil {
+		return
+	}
+	if existingInfo, ok := source.GetInfo(lh.stackTracesInfo.InfoKey()).(*status.KeyedCountInfo); ok {
+		lh.stackTracesInfo = existingInfo
+	} else {
+		source.RegisterInfo(lh.stackTracesInfo)
+	}
 }
 
 // NewDecoderWithFraming initialize a decoder with given endline strategy.
@@ -132,7 +144,12 @@ func NewDecoderWithFraming(source *sources.ReplaceableSource, parser parsers.Par
 				// Save the pattern again for the next rotation
 				detectedPattern.Set(multiLinePattern)
 
-				lh := NewMultiLineHandler(outputFn, multiLinePattern, config.AggregationTimeout(), lineLimit, true)
+				var lh *MultiLineHandler
+				if multiLinePattern == stackTracePattern {
+					lh = NewStackTraceMultiLineHandler(outputFn, config.AggregationTimeout(), lineLimit, true)
+				} else {
+					lh = NewMultiLineHandler(outputFn, multiLinePattern, config.AggregationTimeout(), lineLimit, true)
+				}
 				syncSourceInfo(source, lh)
 				lineHandler = lh
 			} else {
@@ -143,6 +160,10 @@ func NewDecoderWithFraming(source *sources.ReplaceableSource, parser parsers.Par
 		}
 	}
 
+	if source.Config().Parser == config.JSONParser {
+		parser = jsonparser.New(parser, source.Config().JSONKeys)
+	}
+
 	// construct the lineParser, wrapping the parser
 	var lineParser LineParser
 	if parser.SupportsPartialLine() {
@@ -6,6 +6,7 @@
 package decoder
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 	"testing"
@@ -15,6 +16,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 )
 
@@ -251,6 +253,34 @@ func TestMultiLineHandlerSendsRawInvalidMessages(t *testing.T) {
 	assert.Equal(t, "1.third line\\nfourth line", string(output.Content))
 }
 
+func TestMultiLineHandlerDoesNotAggregateStructuredMessages(t *testing.T) {
+	re := regexp.MustCompile("[0-9]+\\.")
+	outputFn, outputChan := lineHandlerChans()
+	h := NewMultiLineHandler(outputFn, re, 10*time.Millisecond, 100, false)
+
+	structured := getDummyMessage("structured")
+	structured.Structured = &parsers.StructuredContent{Service: "web"}
+
+	h.process(getDummyMessage("1.first line"))
+	h.process(getDummyMessage("second line"))
+	h.process(structured)
+	h.process(getDummyMessage("2.third line"))
+
+	var output *Message
+
+	output = <-outputChan
+	assert.Equal(t, "1.first line\\nsecond line", string(output.Content))
+	output = <-outputChan
+	assert.Equal(t, "structured", string(output.Content))
+	assert.Equal(t, "web", output.Structured.Service)
+
+	assertNothingInChannel(t, outputChan)
+	h.flush()
+
+	output = <-outputChan
+	assert.Equal(t, "2.third line", string(output.Content))
+}
+
 func TestAutoMultiLineHandlerStaysSingleLineMode(t *testing.T) {
 
 	outputFn, outputChan := lineHandlerChans()
@@ -349,6 +379,97 @@ func TestAutoMultiLineHandlerHandelsMessageConflictingPatternsNoWinner(t *testin
 	assert.Equal(t, "Jul 12, 2021 12:55:15 PM test message 2", string(output.Content))
 }
 
+func TestAutoMultiLineHandlerSwitchesToStackTraceMode(t *testing.T) {
+	outputFn, outputChan := lineHandlerChans()
+	source := sources.NewReplaceableSource(sources.NewLogSource("config", &config.LogsConfig{}))
+	detectedPattern := &DetectedPattern{}
+	h := NewAutoMultilineHandler(outputFn, 500, 4, 0.75, 10*time.Millisecond, 10*time.Millisecond, source, []*regexp.Regexp{}, detectedPattern)
+
+	// no timestamp, but a stack trace
+	h.process(getDummyMessageWithLF("request failed"))
+	h.process(getDummyMessageWithLF("java.lang.Exception: boom"))
+	h.process(getDummyMessageWithLF("\tat Main.funcd(Main.java:62)"))
+	h.process(getDummyMessageWithLF("\tat Main.funcc(Main.java:60)"))
+	for i := 0; i < 4; i++ {
+		<-outputChan
+	}
+
+	assert.Nil(t, h.singleLineHandler)
+	assert.NotNil(t, h.multiLineHandler)
+	assert.Equal(t, stackTracePattern, detectedPattern.Get())
+
+	h.process(getDummyMessageWithLF("java.lang.Exception: boom"))
+	h.process(getDummyMessageWithLF("\tat Main.funcd(Main.java:62)"))
+	h.process(getDummyMessageWithLF("Caused by: java.lang.NullPointerException"))
+	h.process(getDummyMessageWithLF("\tat Main.funcb(Main.java:58)"))
+	h.process(getDummyMessageWithLF("request failed"))
+	output := <-outputChan
+
+	assert.Equal(t, "java.lang.Exception: boom\\n\tat Main.funcd(Main.java:62)\\nCaused by: java.lang.NullPointerException\\n\tat Main.funcb(Main.java:58)", string(output.Content))
+	assert.Equal(t, int64(1), h.multiLineHandler.stackTracesInfo.Get(javaStackTrace))
+}
+
+func TestAutoMultiLineHandlerIgnoresIndentedLines(t *testing.T) {
+	outputFn, outputChan := lineHandlerChans()
+	source := sources.NewReplaceableSource(sources.NewLogSource("config", &config.LogsConfig{}))
+	detectedPattern := &DetectedPattern{}
+	h := NewAutoMultilineHandler(outputFn, 500, 20, 0.75, 10*time.Millisecond, 10*time.Millisecond, source, []*regexp.Regexp{}, detectedPattern)
+
+	// indented lines without a stack trace, and a single line looking like a frame
+	lines := []string{"loaded configuration:"}
+	for i := 0; i < 9; i++ {
+		lines = append(lines, fmt.Sprintf("  key%d: value", i))
+	}
+	lines = append(lines, "  at startup(took 3s)")
+	for i := 0; i < 9; i++ {
+		lines = append(lines, fmt.Sprintf("    item%d", i))
+	}
+	for _, line := range lines {
+		h.process(getDummyMessageWithLF(line))
+		assert.Equal(t, strings.TrimSpace(line), string((<-outputChan).Content))
+	}
+
+	assert.NotNil(t, h.singleLineHandler)
+	assert.Nil(t, h.multiLineHandler)
+	assert.Nil(t, detectedPattern.Get())
+
+	h.process(getDummyMessageWithLF("  key: value"))
+	assert.Equal(t, "key: value", string((<-outputChan).Content))
+}
+
+func TestStackTraceMultiLineHandler(t *testing.T) {
+	outputFn, outputChan := lineHandlerChans()
+	h := NewStackTraceMultiLineHandler(outputFn, 10*time.Millisecond, 500, false)
+
+	lines := []string{
+		"Traceback (most recent call last):",
+		"  File \"app.py\", line 3, in <module>",
+		"KeyError: 'id'",
+		"panic: boom",
+		"",
+		"goroutine 1 [running]:",
+		"main.main()",
+		"\t/app/main.go:8 +0x1d",
+		"exit status 2",
+	}
+	for _, line := range lines {
+		h.process(getDummyMessageWithLF(line))
+	}
+	h.flush()
+
+	output := <-outputChan
+	assert.Equal(t, "Traceback (most recent call last):\\n  File \"app.py\", line 3, in <module>\\nKeyError: 'id'", string(output.Content))
+	output = <-outputChan
+	assert.Equal(t, "panic: boom\\n\\ngoroutine 1 [running]:\\nmain.main()\\n\t/app/main.go:8 +0x1d", string(output.Content))
+	output = <-outputChan
+	assert.Equal(t, "exit status 2", string(output.Content))
+	assertNothingInChannel(t, outputChan)
+
+	assert.Equal(t, int64(1), h.stackTracesInfo.Get(pythonStackTrace))
+	assert.Equal(t, int64(1), h.stackTracesInfo.Get(goStackTrace))
+	assert.Equal(t, int64(0), h.stackTracesInfo.Get(javaStackTrace))
+}
+
 func TestAutoMultiLineHandlerSwitchesToMultiLineModeWithDelay(t *testing.T) {
 	outputFn, _ := lineHandlerChans()
 	source := sources.NewReplaceableSource(sources.NewLogSource("config", &config.LogsConfig{}))
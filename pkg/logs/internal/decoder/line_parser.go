@@ -58,20 +58,25 @@ func (p *SingleLineParser) process(content []byte, rawDataLen int) {
 	if err != nil {
 		log.Debug(err)
 	}
-	p.outputFn(NewMessage(msg.Content, msg.Status, rawDataLen, msg.Timestamp))
+	output := NewMessage(msg.Content, msg.Status, rawDataLen, msg.Timestamp)
+	output.Tags = msg.Tags
+	output.Structured = msg.Structured
+	p.outputFn(output)
 }
 
 // MultiLineParser makes sure that chunked lines are properly put together.
 type MultiLineParser struct {
-	outputFn     func(*Message)
-	buffer       *bytes.Buffer
-	flushTimeout time.Duration
-	flushTimer   *time.Timer
-	parser       parsers.Parser
-	rawDataLen   int
-	lineLimit    int
-	status       string
-	timestamp    string
+	outputFn      func(*Message)
+	buffer        *bytes.Buffer
+	flushTimeout  time.Duration
+	flushTimer    *time.Timer
+	parser        parsers.Parser
+	contentParser parsers.ContentParser
+	rawDataLen    int
+	lineLimit     int
+	status        string
+	timestamp     string
+	tags          []string
 }
 
 // NewMultiLineParser returns a new MultiLineParser.
@@ -81,7 +86,7 @@ func NewMultiLineParser(
 	parser parsers.Parser,
 	lineLimit int,
 ) *MultiLineParser {
-	return &MultiLineParser{
+	p := &MultiLineParser{
 		outputFn:     outputFn,
 		buffer:       bytes.NewBuffer(nil),
 		flushTimeout: flushTimeout,
@@ -89,6 +94,12 @@ func NewMultiLineParser(
 		lineLimit:    lineLimit,
 		parser:       parser,
 	}
+	if contentParser, ok := parser.(parsers.ContentParser); ok {
+		// the content is parsed once the line is reassembled
+		p.parser = contentParser.Unwrap()
+		p.contentParser = contentParser
+	}
+	return p
 }
 
 func (p *MultiLineParser) flushChan() <-chan time.Time {
@@ -119,6 +130,7 @@ func (p *MultiLineParser) process(content []byte, rawDataLen int) {
 	p.rawDataLen += rawDataLen
 	p.timestamp = msg.Timestamp
 	p.status = msg.Status
+	p.tags = msg.Tags
 	p.buffer.Write(msg.Content)
 
 	if !msg.IsPartial || p.buffer.Len() >= p.lineLimit {
@@ -140,11 +152,19 @@ func (p *MultiLineParser) sendLine() {
 	defer func() {
 		p.buffer.Reset()
 		p.rawDataLen = 0
+		p.tags = nil
 	}()
 
 	content := make([]byte, p.buffer.Len())
 	copy(content, p.buffer.Bytes())
 	if len(content) > 0 || p.rawDataLen > 0 {
-		p.outputFn(NewMessage(content, p.status, p.rawDataLen, p.timestamp))
+		msg := parsers.Message{Content: content, Status: p.status, Timestamp: p.timestamp, Tags: p.tags}
+		if p.contentParser != nil {
+			msg = p.contentParser.ParseContent(msg)
+		}
+		output := NewMessage(msg.Content, msg.Status, p.rawDataLen, msg.Timestamp)
+		output.Tags = msg.Tags
+		output.Structured = msg.Structured
+		p.outputFn(output)
 	}
 }
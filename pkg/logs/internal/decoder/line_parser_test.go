@@ -14,7 +14,11 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	jsonparser "github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/json"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/kubernetes"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
 )
 
 const header = "HEADER"
@@ -155,3 +159,39 @@ func TestMultilineParserLimit(t *testing.T) {
 	assert.Equal(t, "aaaa", string(message.Content))
 	assert.Equal(t, message.RawDataLen, 13)
 }
+
+func TestMultilineParserKeepsStructuredContent(t *testing.T) {
+	p := jsonparser.New(kubernetes.New(), config.JSONKeys{})
+	timeout := 1000 * time.Millisecond
+	contentLenLimit := 256 * 100
+
+	outputFn, outputChan := lineParserChans()
+	lineParser := NewMultiLineParser(outputFn, timeout, p, contentLenLimit)
+
+	lineParser.process([]byte("2018-09-20T11:54:11.753589172Z stdout P plain "), 47)
+	lineParser.process([]byte("2018-09-20T11:54:11.753589172Z stdout F line"), 44)
+	lineParser.process([]byte(`2018-09-20T11:54:12.753589172Z stdout F {"message":"hello","level":"error","user":"bob"}`), 89)
+	// a JSON object split in partial lines is parsed once reassembled, even when
+	// its last part is a JSON object too
+	lineParser.process([]byte(`2018-09-20T11:54:13.753589172Z stdout P {"message":"split","level":"warn","ctx":`), 80)
+	lineParser.process([]byte(`2018-09-20T11:54:13.753589172Z stdout F {"user":"alice"}}`), 57)
+
+	msg := <-outputChan
+	assert.Equal(t, "plain line", string(msg.Content))
+	assert.Nil(t, msg.Structured)
+
+	msg = <-outputChan
+	assert.Equal(t, "hello", string(msg.Content))
+	assert.Equal(t, message.StatusError, msg.Status)
+	if assert.NotNil(t, msg.Structured) {
+		assert.Equal(t, map[string]interface{}{"user": "bob"}, msg.Structured.Attributes)
+	}
+
+	msg = <-outputChan
+	assert.Equal(t, "split", string(msg.Content))
+	assert.Equal(t, message.StatusWarning, msg.Status)
+	assert.Equal(t, 137, msg.RawDataLen)
+	if assert.NotNil(t, msg.Structured) {
+		assert.Equal(t, map[string]interface{}{"ctx": map[string]interface{}{"user": "alice"}}, msg.Structured.Attributes)
+	}
+}
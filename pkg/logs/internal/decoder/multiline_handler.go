@@ -31,10 +31,15 @@ type MultiLineHandler struct {
 	linesLen          int
 	status            string
 	timestamp         string
+	tags              []string
 	countInfo         *status.CountInfo
 	linesCombinedInfo *status.CountInfo
 	telemetryEnabled  bool
 	linesCombined     int
+	// stackTraces is set when the lines are aggregated by stack traces,
+	// in which case newContentRe is not used.
+	stackTraces     *stackTraceMatcher
+	stackTracesInfo *status.KeyedCountInfo
 }
 
 // NewMultiLineHandler returns a new MultiLineHandler.
@@ -52,6 +57,15 @@ func NewMultiLineHandler(outputFn func(*Message), newContentRe *regexp.Regexp, f
 	}
 }
 
+// NewStackTraceMultiLineHandler returns a new MultiLineHandler aggregating the lines of
+// Java, .NET, Python and Go stack traces with the line they follow.
+func NewStackTraceMultiLineHandler(outputFn func(*Message), flushTimeout time.Duration, lineLimit int, telemetryEnabled bool) *MultiLineHandler {
+	h := NewMultiLineHandler(outputFn, stackTracePattern, flushTimeout, lineLimit, telemetryEnabled)
+	h.stackTraces = &stackTraceMatcher{}
+	h.stackTracesInfo = status.NewKeyedCountInfo("Stack Traces")
+	return h
+}
+
 func (h *MultiLineHandler) flushChan() <-chan time.Time {
 	if h.flushTimer != nil && h.buffer.Len() > 0 {
 		return h.flushTimer.C
@@ -76,7 +90,15 @@ func (h *MultiLineHandler) process(message *Message) {
 		}
 	}
 
-	if h.newContentRe.Match(message.Content) {
+	if message.Structured != nil {
+		// structured messages are complete on their own,
+		// send the buffer and forward the message as is
+		h.sendBuffer()
+		h.outputFn(message)
+		return
+	}
+
+	if h.isNewContent(message.Content) {
 		h.countInfo.Add(1)
 		// the current line is part of a new message,
 		// send the buffer
@@ -91,6 +113,7 @@ func (h *MultiLineHandler) process(message *Message) {
 	h.linesLen += message.RawDataLen
 	h.timestamp = message.Timestamp
 	h.status = message.Status
+	h.tags = message.Tags
 	h.linesCombined++
 
 	if h.buffer.Len() > 0 {
@@ -126,6 +149,14 @@ func (h *MultiLineHandler) process(message *Message) {
 	}
 }
 
+// isNewContent returns true if the line is the first line of a new message.
+func (h *MultiLineHandler) isNewContent(content []byte) bool {
+	if h.stackTraces != nil {
+		return !h.stackTraces.isContinuation(content)
+	}
+	return h.newContentRe.Match(content)
+}
+
 // sendBuffer forwards the content stored in the buffer
 // to the output function.
 func (h *MultiLineHandler) sendBuffer() {
@@ -150,6 +181,14 @@ func (h *MultiLineHandler) sendBuffer() {
 			}
 		}
 
-		h.outputFn(NewMessage(content, h.status, h.linesLen, h.timestamp))
+		if h.stackTraces != nil {
+			if kind := h.stackTraces.reset(); kind != "" {
+				h.stackTracesInfo.Add(kind, 1)
+			}
+		}
+
+		output := NewMessage(content, h.status, h.linesLen, h.timestamp)
+		output.Tags = h.tags
+		h.outputFn(output)
 	}
 }
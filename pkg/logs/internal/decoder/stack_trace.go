@@ -0,0 +1,150 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// Kinds of stack traces reported on the status page
+const (
+	javaStackTrace   = "java"
+	dotnetStackTrace = "dotnet"
+	pythonStackTrace = "python"
+	goStackTrace     = "go"
+)
+
+// stackTracePattern is the pattern reported as detected when the lines are aggregated
+// by stack traces rather than by a line-start pattern. It matches the lines which
+// continue a stack trace regardless of the previous lines.
+var stackTracePattern = regexp.MustCompile(`^(\s+\S|at \S+\(|Caused by: |Traceback \(most recent call last\):|goroutine \d+ \[)`)
+
+var (
+	goroutineHeader = regexp.MustCompile(`^goroutine \d+ \[`)
+	// a Go function call is printed on its own line, followed by an indented file location
+	goFunctionCall = regexp.MustCompile(`^[\w./*()\[\]-]+\(.*\)$`)
+	// unlike Java ones, .NET frames list parameter types rather than a file location
+	javaFrameLocation = regexp.MustCompile(`\(([\w$-]+\.(java|kt|scala|groovy|clj):\d+|Native Method|Unknown Source)\)$`)
+	// .NET frames end with the file location when the debug symbols are available
+	dotnetFrameLocation = regexp.MustCompile(`\) in .+:line \d+$`)
+)
+
+var (
+	pythonTracebackHeader = []byte("Traceback (most recent call last):")
+	pythonChainedHeaders  = [][]byte{
+		[]byte("During handling of the above exception, another exception occurred:"),
+		[]byte("The above exception was the direct cause of the following exception:"),
+	}
+	javaCausedBy    = []byte("Caused by: ")
+	javaFramePrefix = []byte("at ")
+	pythonFrame     = []byte(`File "`)
+	goPanicHeaders  = [][]byte{[]byte("panic: "), []byte("fatal error: ")}
+	goCreatedBy     = []byte("created by ")
+)
+
+type stackTraceState int
+
+const (
+	outsideStackTrace stackTraceState = iota
+	// after a Java or .NET frame
+	inJavaStackTrace
+	inPythonTraceback
+	// after the exception line, which may be followed by a chained traceback
+	inPythonException
+	inGoPanic
+)
+
+// stackTraceMatcher recognizes the lines continuing a Java, .NET, Python or Go stack trace,
+// so that they are aggregated with the line they follow.
+type stackTraceMatcher struct {
+	state stackTraceState
+	// kind is the kind of the stack trace found in the lines since the last reset
+	kind string
+	// markers counts the lines that can only be part of a stack trace
+	markers int
+}
+
+// isContinuation returns true if the line continues the message of the previous lines.
+func (m *stackTraceMatcher) isContinuation(line []byte) bool {
+	trimmed := bytes.TrimSpace(line)
+	indented := len(trimmed) > 0 && len(trimmed) < len(line) && (line[0] == ' ' || line[0] == '\t')
+
+	switch {
+	case len(trimmed) == 0:
+		// blank lines separate the parts of Python and Go traces
+		return m.state != outsideStackTrace
+	case bytes.HasPrefix(trimmed, javaFramePrefix) && (bytes.HasSuffix(trimmed, []byte(")")) || dotnetFrameLocation.Match(trimmed)):
+		if javaFrameLocation.Match(trimmed) {
+			m.found(javaStackTrace)
+		} else {
+			m.found(dotnetStackTrace)
+		}
+		if m.state == outsideStackTrace {
+			m.state = inJavaStackTrace
+		}
+		return true
+	case indented && m.state != outsideStackTrace:
+		// indented lines only continue a message once a stack trace started
+		if m.state == inPythonTraceback && bytes.HasPrefix(trimmed, pythonFrame) {
+			m.found(pythonStackTrace)
+		}
+		return true
+	case bytes.HasPrefix(line, javaCausedBy):
+		m.state = inJavaStackTrace
+		m.found(javaStackTrace)
+		return true
+	case bytes.Equal(trimmed, pythonTracebackHeader):
+		m.state = inPythonTraceback
+		m.found(pythonStackTrace)
+		return true
+	case m.state == inPythonTraceback:
+		// the exception line ends the traceback
+		m.state = inPythonException
+		return true
+	case m.state == inPythonException && isPythonChainedHeader(trimmed):
+		m.state = inPythonTraceback
+		return true
+	case goroutineHeader.Match(line):
+		m.state = inGoPanic
+		m.found(goStackTrace)
+		return true
+	case m.state == inGoPanic && (goFunctionCall.Match(trimmed) || bytes.HasPrefix(trimmed, goCreatedBy)):
+		return true
+	}
+
+	m.state = outsideStackTrace
+	for _, header := range goPanicHeaders {
+		if bytes.HasPrefix(line, header) {
+			// a panic starts a new message, followed by the goroutines traces
+			m.state = inGoPanic
+		}
+	}
+	return false
+}
+
+func isPythonChainedHeader(line []byte) bool {
+	for _, header := range pythonChainedHeaders {
+		if bytes.Equal(line, header) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *stackTraceMatcher) found(kind string) {
+	m.markers++
+	if m.kind == "" {
+		m.kind = kind
+	}
+}
+
+// reset returns the kind of the stack trace found since the last reset, if any.
+func (m *stackTraceMatcher) reset() string {
+	kind := m.kind
+	m.kind = ""
+	return kind
+}
@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// aggregate returns the messages formed by the lines, and the kinds of stack traces found in each message
+func aggregate(lines []string) ([]string, []string) {
+	m := &stackTraceMatcher{}
+	var messages, kinds []string
+	var current []string
+	send := func() {
+		if len(current) > 0 {
+			messages = append(messages, strings.Join(current, "\n"))
+			kinds = append(kinds, m.reset())
+		}
+		current = nil
+	}
+	for _, line := range lines {
+		if !m.isContinuation([]byte(line)) {
+			send()
+		}
+		current = append(current, line)
+	}
+	send()
+	return messages, kinds
+}
+
+func TestStackTraceMatcherJava(t *testing.T) {
+	messages, kinds := aggregate([]string{
+		"Exception in thread \"main\" java.lang.IllegalStateException: boom",
+		"\tat com.example.Main.run(Main.java:42)",
+		"\tat com.example.Main.main(Main.java:12)",
+		"Caused by: java.lang.NullPointerException",
+		"\tat com.example.Service.call(Service.java:7)",
+		"\t... 2 more",
+		"next log line",
+	})
+	assert.Len(t, messages, 2)
+	assert.Equal(t, []string{javaStackTrace, ""}, kinds)
+	assert.Equal(t, "next log line", messages[1])
+}
+
+func TestStackTraceMatcherDotnet(t *testing.T) {
+	messages, kinds := aggregate([]string{
+		"System.InvalidOperationException: boom",
+		"   at Example.Service.Call(String name) in /src/Service.cs:line 21",
+		"   at Example.Program.Main()",
+		"next log line",
+	})
+	assert.Len(t, messages, 2)
+	assert.Equal(t, []string{dotnetStackTrace, ""}, kinds)
+}
+
+func TestStackTraceMatcherPython(t *testing.T) {
+	messages, kinds := aggregate([]string{
+		"ERROR request failed",
+		"Traceback (most recent call last):",
+		"  File \"app.py\", line 3, in <module>",
+		"    handle()",
+		"KeyError: 'id'",
+		"",
+		"During handling of the above exception, another exception occurred:",
+		"",
+		"Traceback (most recent call last):",
+		"  File \"app.py\", line 5, in <module>",
+		"ValueError: bad request",
+		"next log line",
+	})
+	assert.Len(t, messages, 2)
+	assert.Equal(t, []string{pythonStackTrace, ""}, kinds)
+	assert.True(t, strings.HasSuffix(messages[0], "ValueError: bad request"))
+}
+
+func TestStackTraceMatcherGo(t *testing.T) {
+	messages, kinds := aggregate([]string{
+		"panic: runtime error: index out of range [1] with length 1",
+		"",
+		"goroutine 1 [running]:",
+		"main.main()",
+		"\t/app/main.go:8 +0x1d",
+		"created by main.start",
+		"\t/app/main.go:3 +0x25",
+		"exit status 2",
+	})
+	assert.Len(t, messages, 2)
+	assert.Equal(t, []string{goStackTrace, ""}, kinds)
+	assert.Equal(t, "exit status 2", messages[1])
+}
+
+func TestStackTraceMatcherKeepsRegularLines(t *testing.T) {
+	messages, kinds := aggregate([]string{
+		"first line",
+		"",
+		"second line",
+		"main.main()",
+	})
+	assert.Equal(t, []string{"first line", "", "second line", "main.main()"}, messages)
+	assert.Equal(t, []string{"", "", "", ""}, kinds)
+}
+
+func TestStackTraceMatcherKeepsIndentedLines(t *testing.T) {
+	messages, kinds := aggregate([]string{
+		"loaded configuration:",
+		"  port: 8080",
+		"\tdebug: true",
+		"next log line",
+		"  host: localhost",
+	})
+	// the indented lines are only continued once a stack trace started
+	assert.Equal(t, []string{"loaded configuration:", "  port: 8080", "\tdebug: true", "next log line", "  host: localhost"}, messages)
+	assert.Equal(t, []string{"", "", "", "", ""}, kinds)
+}
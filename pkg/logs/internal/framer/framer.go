@@ -38,6 +38,10 @@ const (
 	// headers are included in the log frame.  The size in those headers is not
 	// consulted.  The result does not include the trailing newlines.
 	DockerStream
+
+	// Syslog stream format, as described in RFC 6587.  Frames are either
+	// octet-counted or newline-terminated UTF-8 text.
+	SyslogStream
 )
 
 // Framer gets chunks of bytes (via Process(..)) and uses an
@@ -95,6 +99,8 @@ func NewFramer(
 		matcher = &oneByteNewLineMatcher{contentLenLimit}
 	case DockerStream:
 		matcher = &dockerStreamMatcher{contentLenLimit}
+	case SyslogStream:
+		matcher = &syslogMatcher{oneByteNewLineMatcher{contentLenLimit}}
 	default:
 		panic(fmt.Sprintf("unknown framing %d", framing))
 	}
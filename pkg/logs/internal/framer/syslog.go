@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import "strconv"
+
+// maxOctetCountLen is the maximum number of digits accepted in the length
+// prefix of an octet-counted frame.
+const maxOctetCountLen = 10
+
+// syslogMatcher implements FrameMatcher for syslog streams as described in RFC 6587.
+//
+// Frames starting with a digit use octet-counting framing, `MSG-LEN SP SYSLOG-MSG`,
+// where MSG-LEN is the number of bytes of SYSLOG-MSG.  Any other frame uses the
+// non-transparent framing, where messages are terminated by a newline.
+type syslogMatcher struct {
+	newLineMatcher oneByteNewLineMatcher
+}
+
+// FindFrame implements EndLineMatcher#FindFrame.
+func (s *syslogMatcher) FindFrame(buf []byte, seen int) ([]byte, int) {
+	if len(buf) == 0 || buf[0] < '1' || buf[0] > '9' {
+		return s.newLineMatcher.FindFrame(buf, seen)
+	}
+
+	sp := 1
+	for ; sp < len(buf) && sp <= maxOctetCountLen; sp++ {
+		if buf[sp] == ' ' {
+			break
+		}
+		if buf[sp] < '0' || buf[sp] > '9' {
+			// not a length prefix
+			return s.newLineMatcher.FindFrame(buf, seen)
+		}
+	}
+	if sp == len(buf) {
+		// wait for the rest of the length prefix
+		return nil, 0
+	}
+	if sp > maxOctetCountLen {
+		return s.newLineMatcher.FindFrame(buf, seen)
+	}
+
+	msgLen, err := strconv.Atoi(string(buf[:sp]))
+	if err != nil || msgLen > s.newLineMatcher.contentLenLimit {
+		return s.newLineMatcher.FindFrame(buf, seen)
+	}
+	end := sp + 1 + msgLen
+	if end > len(buf) {
+		return nil, 0
+	}
+	return buf[sp+1 : end], end
+}
@@ -17,6 +17,7 @@ import (
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/util/containersorpods"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 )
 
@@ -41,6 +42,11 @@ func (r *fakeRegistry) GetTailingMode(identifier string) string {
 	panic("unused")
 }
 
+// GetFileIdentity implements auditor.Registry#GetFileIdentity.
+func (r *fakeRegistry) GetFileIdentity(identifier string) message.FileIdentity {
+	panic("unused")
+}
+
 func TestUseFile(t *testing.T) {
 	ctrs := containersorpods.LogContainers
 	pods := containersorpods.LogPods
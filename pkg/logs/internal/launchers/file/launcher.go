@@ -7,6 +7,7 @@ package file
 
 import (
 	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/util"
@@ -45,10 +46,16 @@ type Launcher struct {
 	// Feature flag defaulting to false, use `logs_config.validate_pod_container_id`.
 	validatePodContainerID bool
 	scanPeriod             time.Duration
+	// set to true to drain the rotated siblings of the tailed files before tailing them again.
+	// Feature flag defaulting to false, use `logs_config.drain_rotated_files`.
+	drainRotatedFiles bool
+	// drains holds the rotated files remaining to be drained, by scan key, for the files
+	// currently tailed by a drain tailer.
+	drains map[string]*rotationDrain
 }
 
 // NewLauncher returns a new launcher.
-func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePodContainerID bool, scanPeriod time.Duration, wildcardMode string) *Launcher {
+func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePodContainerID bool, scanPeriod time.Duration, wildcardMode string, drainRotatedFiles bool) *Launcher {
 
 	var wildcardStrategy fileprovider.WildcardSelectionStrategy
 	switch wildcardMode {
@@ -69,6 +76,8 @@ func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePo
 		stop:                   make(chan struct{}),
 		validatePodContainerID: validatePodContainerID,
 		scanPeriod:             scanPeriod,
+		drainRotatedFiles:      drainRotatedFiles,
+		drains:                 make(map[string]*rotationDrain),
 	}
 }
 
@@ -113,6 +122,7 @@ func (s *Launcher) cleanup() {
 	for scanKey, tailer := range s.tailers {
 		stopper.Add(tailer)
 		delete(s.tailers, scanKey)
+		delete(s.drains, scanKey)
 	}
 	stopper.Stop()
 }
@@ -142,6 +152,14 @@ func (s *Launcher) scan() {
 		// tailer is tailing the file for the new container).
 		scanKey := file.GetScanKey()
 		tailer, isTailed := s.tailers[scanKey]
+		if _, isDrained := s.drains[scanKey]; isTailed && isDrained {
+			// the file is not tailed yet, its rotated files are being drained
+			if tailer.IsFinished() && !s.advanceDrain(file, tailer) {
+				continue
+			}
+			filesTailed[scanKey] = true
+			continue
+		}
 		if isTailed && tailer.IsFinished() {
 			// skip this tailer as it must be stopped
 			continue
@@ -271,6 +289,10 @@ func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode) bool
 	var offset int64
 	var whence int
 	mode := s.handleTailingModeChange(tailer.Identifier(), m)
+	if s.drainRotatedFiles && mode != config.ForceBeginning && mode != config.ForceEnd && s.startDrainAfterRestart(file, tailer) {
+		return true
+	}
+	mode = s.handleFileIdentityChange(file, tailer.Identifier(), mode)
 
 	offset, whence, err := Position(s.registry, tailer.Identifier(), mode)
 	if err != nil {
@@ -310,10 +332,38 @@ func (s *Launcher) handleTailingModeChange(tailerID string, currentTailingMode c
 	return currentTailingMode
 }
 
+// handleFileIdentityChange determines the tailing behaviour when the file found at the path of a tailer is not
+// the file its registered offset belongs to anymore, comparing their inodes and fingerprints. If the file has
+// been truncated and written again, it is tailed from the beginning so that no lines are lost. If it has been
+// replaced by another file, the registered offset is ignored and the file is tailed as a new one.
+func (s *Launcher) handleFileIdentityChange(file *tailer.File, tailerID string, mode config.TailingMode) config.TailingMode {
+	if mode == config.ForceBeginning || mode == config.ForceEnd {
+		return mode
+	}
+	offset, err := strconv.ParseInt(s.registry.GetOffset(tailerID), 10, 64)
+	if err != nil {
+		return mode
+	}
+	switch resumptionOf(file.Path, offset, s.registry.GetFileIdentity(tailerID)) {
+	case restartFromBeginning:
+		log.Infof("%s has been rewritten since its offset was registered, tailing it from the beginning", file.Path)
+		return config.ForceBeginning
+	case tailAsNewFile:
+		log.Infof("%s has been replaced since its offset was registered, ignoring the offset", file.Path)
+		if mode == config.End {
+			return config.ForceEnd
+		}
+		return config.ForceBeginning
+	default:
+		return mode
+	}
+}
+
 // stopTailer stops the tailer
 func (s *Launcher) stopTailer(scanKey string, tailer *tailer.Tailer) {
 	go tailer.Stop()
 	delete(s.tailers, scanKey)
+	delete(s.drains, scanKey)
 }
 
 // restartTailer safely stops tailer and starts a new one
@@ -321,6 +371,9 @@ func (s *Launcher) stopTailer(scanKey string, tailer *tailer.Tailer) {
 func (s *Launcher) restartTailerAfterFileRotation(tailer *tailer.Tailer, file *tailer.File) bool {
 	log.Info("Log rotation happened to ", file.Path)
 	tailer.StopAfterFileRotation()
+	if s.drainRotatedFiles && s.startDrainAfterRotation(file, tailer) {
+		return true
+	}
 	tailer = s.createRotatedTailer(tailer, file, tailer.GetDetectedPattern())
 	// force reading file from beginning since it has been log-rotated
 	err := tailer.StartFromBeginning()
@@ -9,12 +9,14 @@
 package file
 
 import (
+	"compress/gzip"
 	"fmt"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
 	auditor "github.com/DataDog/datadog-agent/pkg/logs/auditor/mock"
@@ -67,7 +69,7 @@ func (suite *LauncherTestSuite) SetupTest() {
 	suite.openFilesLimit = 100
 	suite.source = sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Identifier: suite.configID, Path: suite.testPath})
 	sleepDuration := 20 * time.Millisecond
-	suite.s = NewLauncher(suite.openFilesLimit, sleepDuration, false, 10*time.Second, "by_name")
+	suite.s = NewLauncher(suite.openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", false)
 	suite.s.pipelineProvider = suite.pipelineProvider
 	suite.s.registry = auditor.NewRegistry()
 	suite.s.activeSources = append(suite.s.activeSources, suite.source)
@@ -222,7 +224,7 @@ func TestLauncherScanStartNewTailer(t *testing.T) {
 		path = fmt.Sprintf("%s/*.log", testDir)
 		openFilesLimit := 2
 		sleepDuration := 20 * time.Millisecond
-		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name")
+		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", false)
 		launcher.pipelineProvider = mock.NewMockProvider()
 		launcher.registry = auditor.NewRegistry()
 		outputChan := launcher.pipelineProvider.NextPipelineChan()
@@ -260,7 +262,7 @@ func TestLauncherWithConcurrentContainerTailer(t *testing.T) {
 	// create launcher
 	openFilesLimit := 3
 	sleepDuration := 20 * time.Millisecond
-	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name")
+	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", false)
 	launcher.pipelineProvider = mock.NewMockProvider()
 	launcher.registry = auditor.NewRegistry()
 	outputChan := launcher.pipelineProvider.NextPipelineChan()
@@ -307,7 +309,7 @@ func TestLauncherTailFromTheBeginning(t *testing.T) {
 	// create launcher
 	openFilesLimit := 3
 	sleepDuration := 20 * time.Millisecond
-	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name")
+	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", false)
 	launcher.pipelineProvider = mock.NewMockProvider()
 	launcher.registry = auditor.NewRegistry()
 	outputChan := launcher.pipelineProvider.NextPipelineChan()
@@ -373,7 +375,7 @@ func TestLauncherScanWithTooManyFiles(t *testing.T) {
 	path = fmt.Sprintf("%s/*.log", testDir)
 	openFilesLimit := 2
 	sleepDuration := 20 * time.Millisecond
-	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name")
+	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", false)
 	launcher.pipelineProvider = mock.NewMockProvider()
 	launcher.registry = auditor.NewRegistry()
 	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
@@ -402,7 +404,7 @@ func TestLauncherUpdatesSourceForExistingTailer(t *testing.T) {
 	os.Create(path)
 	openFilesLimit := 2
 	sleepDuration := 20 * time.Millisecond
-	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name")
+	launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", false)
 	launcher.pipelineProvider = mock.NewMockProvider()
 	launcher.registry = auditor.NewRegistry()
 
@@ -511,7 +513,7 @@ func TestLauncherScanRecentFilesWithNewFiles(t *testing.T) {
 
 	createLauncher := func() *Launcher {
 		sleepDuration := 20 * time.Millisecond
-		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_modification_time")
+		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_modification_time", false)
 		launcher.pipelineProvider = mock.NewMockProvider()
 		launcher.registry = auditor.NewRegistry()
 		logDirectory := fmt.Sprintf("%s/*.log", testDir)
@@ -571,7 +573,7 @@ func TestLauncherFileRotation(t *testing.T) {
 
 	createLauncher := func() *Launcher {
 		sleepDuration := 20 * time.Millisecond
-		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name")
+		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", false)
 		launcher.pipelineProvider = mock.NewMockProvider()
 		launcher.registry = auditor.NewRegistry()
 		logDirectory := fmt.Sprintf("%s/*.log", testDir)
@@ -629,7 +631,7 @@ func TestLauncherFileDetectionSingleScan(t *testing.T) {
 
 	createLauncher := func() *Launcher {
 		sleepDuration := 20 * time.Millisecond
-		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name")
+		launcher := NewLauncher(openFilesLimit, sleepDuration, false, 10*time.Second, "by_name", false)
 		launcher.pipelineProvider = mock.NewMockProvider()
 		launcher.registry = auditor.NewRegistry()
 		logDirectory := fmt.Sprintf("%s/*.log", testDir)
@@ -659,6 +661,183 @@ func TestLauncherFileDetectionSingleScan(t *testing.T) {
 	assert.Contains(t, launcher.tailers, path("b.log"))
 }
 
+func TestLauncherDrainsRotatedFilesAfterRestart(t *testing.T) {
+	testDir := t.TempDir()
+	path := fmt.Sprintf("%s/app.log", testDir)
+	now := time.Now()
+
+	// the agent stopped while tailing app.log, which has been rotated twice since then
+	require.NoError(t, os.WriteFile(path+".2", []byte("first\nsecond\n"), 0644))
+	require.NoError(t, os.Chtimes(path+".2", now.Add(-2*time.Minute), now.Add(-2*time.Minute)))
+	writeCompressedFile(t, path+".1.gz", "third\n")
+	require.NoError(t, os.Chtimes(path+".1.gz", now.Add(-time.Minute), now.Add(-time.Minute)))
+	require.NoError(t, os.WriteFile(path, []byte("fourth\n"), 0644))
+
+	registeredIdentity, err := filetailer.ComputeFileIdentity(path+".2", filetailer.FingerprintSize)
+	require.NoError(t, err)
+	registry := auditor.NewRegistry()
+	registry.SetOffset("6")
+	registry.SetFileIdentity(registeredIdentity)
+
+	launcher := NewLauncher(100, 20*time.Millisecond, false, 10*time.Second, "by_name", true)
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.registry = registry
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+	launcher.activeSources = append(launcher.activeSources, source)
+	status.InitStatus(util.CreateSources([]*sources.LogSource{source}))
+	defer status.Clear()
+	defer launcher.cleanup()
+
+	launcher.scan()
+	msg := <-outputChan
+	assert.Equal(t, "second", string(msg.Content))
+	assert.Equal(t, "13", msg.Origin.Offset)
+	assert.Equal(t, registeredIdentity, msg.Origin.FileIdentity)
+	assert.Equal(t, "file:"+path, msg.Origin.Identifier)
+
+	scanAfterDrain(t, launcher, path, source)
+	msg = <-outputChan
+	assert.Equal(t, "third", string(msg.Content))
+	assert.Equal(t, "6", msg.Origin.Offset)
+
+	scanAfterDrain(t, launcher, path, source)
+	msg = <-outputChan
+	assert.Equal(t, "fourth", string(msg.Content))
+	assert.Equal(t, "7", msg.Origin.Offset)
+	assert.Empty(t, launcher.drains)
+}
+
+func TestLauncherDrainsFilesRotatedTwiceBetweenScans(t *testing.T) {
+	testDir := t.TempDir()
+	path := fmt.Sprintf("%s/app.log", testDir)
+	now := time.Now()
+	require.NoError(t, os.WriteFile(path, []byte("one\n"), 0644))
+
+	launcher := NewLauncher(100, 20*time.Millisecond, false, 10*time.Second, "by_name", true)
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.registry = auditor.NewRegistry()
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+	launcher.activeSources = append(launcher.activeSources, source)
+	status.InitStatus(util.CreateSources([]*sources.LogSource{source}))
+	defer status.Clear()
+	defer launcher.cleanup()
+
+	launcher.scan()
+	msg := <-outputChan
+	assert.Equal(t, "one", string(msg.Content))
+
+	// rotate the file twice
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, os.WriteFile(path, []byte("two\n"), 0644))
+	require.NoError(t, os.Rename(path+".1", path+".2"))
+	require.NoError(t, os.Chtimes(path+".2", now.Add(-2*time.Minute), now.Add(-2*time.Minute)))
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, os.Chtimes(path+".1", now.Add(-time.Minute), now.Add(-time.Minute)))
+	require.NoError(t, os.WriteFile(path, []byte("three\n"), 0644))
+
+	launcher.scan()
+	msg = <-outputChan
+	assert.Equal(t, "two", string(msg.Content))
+
+	scanAfterDrain(t, launcher, path, source)
+	msg = <-outputChan
+	assert.Equal(t, "three", string(msg.Content))
+	assert.Empty(t, launcher.drains)
+}
+
+func TestResumptionOf(t *testing.T) {
+	testDir := t.TempDir()
+	path := fmt.Sprintf("%s/app.log", testDir)
+	require.NoError(t, os.WriteFile(path, []byte("hello world\n"), 0644))
+	registered, err := filetailer.ComputeFileIdentity(path, filetailer.FingerprintSize)
+	require.NoError(t, err)
+
+	// nothing is known about the file
+	assert.Equal(t, resumeFromOffset, resumptionOf(path, 12, message.FileIdentity{}))
+	// same file
+	assert.Equal(t, resumeFromOffset, resumptionOf(path, 12, registered))
+
+	// the file grew
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("hello again\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, resumeFromOffset, resumptionOf(path, 12, registered))
+
+	// the file was truncated then written again with the same first line
+	require.NoError(t, os.Truncate(path, 12))
+	assert.Equal(t, restartFromBeginning, resumptionOf(path, 24, registered))
+
+	// the file was truncated then written again by copytruncate
+	require.NoError(t, os.WriteFile(path, []byte("good bye world\n"), 0644))
+	assert.Equal(t, restartFromBeginning, resumptionOf(path, 12, registered))
+
+	// the file was replaced by another one
+	current, err := filetailer.ComputeFileIdentity(path, 0)
+	require.NoError(t, err)
+	replaced := message.FileIdentity{Inode: current.Inode + 1, Fingerprint: registered.Fingerprint}
+	assert.Equal(t, tailAsNewFile, resumptionOf(path, 12, replaced))
+
+	// the file was empty when registered, only its inode is known
+	assert.Equal(t, resumeFromOffset, resumptionOf(path, 0, current))
+	assert.Equal(t, tailAsNewFile, resumptionOf(path, 0, message.FileIdentity{Inode: current.Inode + 1}))
+}
+
+func TestLauncherTailsRewrittenFileFromBeginningAfterRestart(t *testing.T) {
+	testDir := t.TempDir()
+	path := fmt.Sprintf("%s/app.log", testDir)
+	require.NoError(t, os.WriteFile(path, []byte("first\nsecond\n"), 0644))
+	registeredIdentity, err := filetailer.ComputeFileIdentity(path, filetailer.FingerprintSize)
+	require.NoError(t, err)
+
+	// the file was truncated by copytruncate while the agent was stopped, then written again
+	require.NoError(t, os.WriteFile(path, []byte("third\nfourth\nfifth\n"), 0644))
+	registry := auditor.NewRegistry()
+	registry.SetOffset("13")
+	registry.SetFileIdentity(registeredIdentity)
+
+	launcher := NewLauncher(100, 20*time.Millisecond, false, 10*time.Second, "by_name", false)
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.registry = registry
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+	launcher.activeSources = append(launcher.activeSources, source)
+	status.InitStatus(util.CreateSources([]*sources.LogSource{source}))
+	defer status.Clear()
+	defer launcher.cleanup()
+
+	launcher.scan()
+	msg := <-outputChan
+	assert.Equal(t, "third", string(msg.Content))
+	assert.Equal(t, "6", msg.Origin.Offset)
+	// the tailer is only stopped once all its messages are consumed
+	msg = <-outputChan
+	assert.Equal(t, "fourth", string(msg.Content))
+	msg = <-outputChan
+	assert.Equal(t, "fifth", string(msg.Content))
+}
+
+// scanAfterDrain waits for the current drain tailer of the file to finish before scanning.
+func scanAfterDrain(t *testing.T, launcher *Launcher, path string, source *sources.LogSource) {
+	tailer := launcher.tailers[getScanKey(path, source)]
+	require.NotNil(t, tailer)
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+	launcher.scan()
+}
+
+func writeCompressedFile(t *testing.T, path string, content string) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	w := gzip.NewWriter(f)
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+}
+
 func getScanKey(path string, source *sources.LogSource) string {
 	return filetailer.NewFile(path, source, false).GetScanKey()
 }
@@ -7,10 +7,13 @@ package file
 
 import (
 	"io"
+	"os"
 	"strconv"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	tailer "github.com/DataDog/datadog-agent/pkg/logs/internal/tailers/file"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
 )
 
 // Position returns the position from where logs should be collected.
@@ -47,3 +50,51 @@ func Position(registry auditor.Registry, identifier string, mode config.TailingM
 	}
 	return offset, whence, err
 }
+
+// resumption tells how a file should be tailed when an offset has been registered for it.
+type resumption int
+
+const (
+	// resumeFromOffset tails the file from its registered offset, the offset belonging to it.
+	resumeFromOffset resumption = iota
+	// restartFromBeginning tails the file from its beginning, the file having been truncated
+	// and written again since the offset was registered, e.g. by copytruncate.
+	restartFromBeginning
+	// tailAsNewFile ignores the registered offset, the file having been replaced by another one.
+	tailAsNewFile
+)
+
+// resumptionOf compares the file at path with the identity of the file its registered offset belongs to.
+func resumptionOf(path string, offset int64, registered message.FileIdentity) resumption {
+	if registered.IsZero() {
+		// nothing is known about the file the offset belongs to
+		return resumeFromOffset
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return resumeFromOffset
+	}
+	current, err := tailer.ComputeFileIdentity(path, registered.Fingerprint.Size)
+	if err != nil {
+		return resumeFromOffset
+	}
+	sameInode := registered.Inode != 0 && registered.Inode == current.Inode
+
+	switch {
+	case registered.Fingerprint.Size > 0 && registered.Fingerprint != current.Fingerprint:
+		// the content of the file changed, it was rewritten if it is still the same inode
+		if sameInode {
+			return restartFromBeginning
+		}
+		return tailAsNewFile
+	case registered.Fingerprint.Size == 0 && registered.Inode != 0 && current.Inode != 0 && !sameInode:
+		// the file was empty when the offset was registered, only the inodes can be compared
+		return tailAsNewFile
+	case fi.Size() < offset:
+		// the file starts with the same content (possibly reported with another inode, e.g. by an
+		// overlay filesystem) but is shorter than the offset, it was truncated then written again
+		return restartFromBeginning
+	default:
+		return resumeFromOffset
+	}
+}
@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	tailer "github.com/DataDog/datadog-agent/pkg/logs/internal/tailers/file"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// rotatedSuffix matches the suffixes added to the name of a file by logrotate or
+// the kubelet when rotating it, e.g. `.1`, `.1.gz`, `-20230102` or `.20230102-150405.gz`.
+var rotatedSuffix = regexp.MustCompile(`^(\.gz|[.-]\d[\d._T:-]*?(\.gz)?)$`)
+
+// rotatedFile is a rotated sibling of a tailed file.
+type rotatedFile struct {
+	path     string
+	modTime  time.Time
+	identity message.FileIdentity
+}
+
+// rotationDrain holds the rotated files of a tailed file that remain to be drained
+// before tailing the file itself.
+type rotationDrain struct {
+	// previous is the tailer the drain tailers are created from, so that they write
+	// to the same channel.
+	previous *tailer.Tailer
+	pattern  *regexp.Regexp
+	files    []*rotatedFile
+}
+
+// findRotatedFiles returns the rotated siblings of the file at path, the oldest first, their
+// fingerprint being computed on fingerprintSize bytes to be compared with an existing identity.
+func findRotatedFiles(path string, fingerprintSize int64) []*rotatedFile {
+	dir, name := filepath.Split(path)
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		log.Debugf("Could not list the rotated files of %s: %v", path, err)
+		return nil
+	}
+
+	var files []*rotatedFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), name) || !rotatedSuffix.MatchString(entry.Name()[len(name):]) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		rotatedPath := filepath.Join(dir, entry.Name())
+		identity, err := tailer.ComputeFileIdentity(rotatedPath, fingerprintSize)
+		if err != nil {
+			log.Debugf("Could not identify rotated file %s: %v", rotatedPath, err)
+			continue
+		}
+		files = append(files, &rotatedFile{path: rotatedPath, modTime: info.ModTime(), identity: identity})
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	return files
+}
+
+// indexOf returns the index of the last rotated file matching identity, -1 if none does.
+func indexOf(files []*rotatedFile, identity message.FileIdentity) int {
+	for i := len(files) - 1; i >= 0; i-- {
+		if files[i].identity.Matches(identity) {
+			return i
+		}
+	}
+	return -1
+}
+
+// startDrainAfterRestart drains the rotated file the registered offset of the file belongs to,
+// and the ones rotated after it, when the file has been rotated while the agent was stopped.
+// Returns true if the file is being drained, in which case it will be tailed from the beginning
+// once done.
+func (s *Launcher) startDrainAfterRestart(file *tailer.File, t *tailer.Tailer) bool {
+	registered := s.registry.GetFileIdentity(t.Identifier())
+	if registered.IsZero() {
+		return false
+	}
+	offset, err := strconv.ParseInt(s.registry.GetOffset(t.Identifier()), 10, 64)
+	if err != nil {
+		return false
+	}
+	if current, err := tailer.ComputeFileIdentity(file.Path, registered.Fingerprint.Size); err == nil && registered.Matches(current) {
+		return false
+	}
+
+	files := findRotatedFiles(file.Path, registered.Fingerprint.Size)
+	i := indexOf(files, registered)
+	if i < 0 {
+		log.Debugf("Could not find the rotated file the registered offset of %s belongs to", file.Path)
+		return false
+	}
+	log.Infof("%s has been rotated since its offset was registered, draining %d rotated files first", file.Path, len(files)-i)
+	return s.startDrain(file, &rotationDrain{previous: t, files: files[i:]}, offset)
+}
+
+// startDrainAfterRotation drains the files rotated after the one the previous tailer was tailing,
+// when the file has been rotated more than once between two scans.
+// Returns true if the file is being drained, in which case it will be tailed from the beginning
+// once done.
+func (s *Launcher) startDrainAfterRotation(file *tailer.File, previous *tailer.Tailer) bool {
+	identity := previous.FileIdentity()
+	files := findRotatedFiles(file.Path, identity.Fingerprint.Size)
+	i := indexOf(files, identity)
+	if i < 0 {
+		return false
+	}
+	var newer []*rotatedFile
+	for _, f := range files[i+1:] {
+		// skip the copies of the file still being read by the previous tailer, e.g. once compressed
+		if !f.identity.Matches(identity) {
+			newer = append(newer, f)
+		}
+	}
+	if len(newer) == 0 {
+		return false
+	}
+	log.Infof("%s has been rotated %d times since the last scan, draining the rotated files first", file.Path, len(newer)+1)
+	return s.startDrain(file, &rotationDrain{previous: previous, pattern: previous.GetDetectedPattern(), files: newer}, 0)
+}
+
+// startDrain starts a tailer draining the next rotated file from offset.
+func (s *Launcher) startDrain(file *tailer.File, drain *rotationDrain, offset int64) bool {
+	for len(drain.files) > 0 {
+		next := drain.files[0]
+		drain.files = drain.files[1:]
+		t := drain.previous.NewDrainTailer(file, next.path, decoder.NewDecoderFromSourceWithPattern(file.Source, drain.pattern))
+		if err := t.Start(offset, io.SeekStart); err != nil {
+			log.Warnf("Could not drain rotated file %s: %v", next.path, err)
+			offset = 0
+			continue
+		}
+		s.tailers[file.GetScanKey()] = t
+		s.drains[file.GetScanKey()] = drain
+		return true
+	}
+	return false
+}
+
+// advanceDrain replaces the finished drain tailer of the file with a tailer draining the next
+// rotated file, or tailing the file from the beginning if there are none left.
+// Returns false if the file could not be tailed.
+func (s *Launcher) advanceDrain(file *tailer.File, finished *tailer.Tailer) bool {
+	scanKey := file.GetScanKey()
+	drain := s.drains[scanKey]
+	delete(s.drains, scanKey)
+	delete(s.tailers, scanKey)
+	finished.Stop()
+
+	if s.startDrain(file, drain, 0) {
+		return true
+	}
+	t := drain.previous.NewRotatedTailer(file, decoder.NewDecoderFromSourceWithPattern(file.Source, drain.pattern))
+	if err := t.StartFromBeginning(); err != nil {
+		log.Warn(err)
+		return false
+	}
+	s.tailers[scanKey] = t
+	return true
+}
@@ -20,6 +20,7 @@ type Launcher struct {
 	frameSize        int
 	tcpSources       chan *sources.LogSource
 	udpSources       chan *sources.LogSource
+	syslogSources    chan *sources.LogSource
 	listeners        []startstop.StartStoppable
 	stop             chan struct{}
 }
@@ -37,6 +38,7 @@ func (l *Launcher) Start(sourceProvider launchers.SourceProvider, pipelineProvid
 	l.pipelineProvider = pipelineProvider
 	l.tcpSources = sourceProvider.GetAddedForType(config.TCPType)
 	l.udpSources = sourceProvider.GetAddedForType(config.UDPType)
+	l.syslogSources = sourceProvider.GetAddedForType(config.SyslogType)
 	go l.run()
 }
 
@@ -52,6 +54,16 @@ func (l *Launcher) run() {
 			listener := NewUDPListener(l.pipelineProvider, source, l.frameSize)
 			listener.Start()
 			l.listeners = append(l.listeners, listener)
+		case source := <-l.syslogSources:
+			// syslog is received over UDP unless configured otherwise
+			var listener startstop.StartStoppable
+			if source.Config.Protocol == config.TCPType {
+				listener = NewTCPListener(l.pipelineProvider, source, l.frameSize)
+			} else {
+				listener = NewUDPListener(l.pipelineProvider, source, l.frameSize)
+			}
+			listener.Start()
+			l.listeners = append(l.listeners, listener)
 		case <-l.stop:
 			return
 		}
@@ -24,6 +24,25 @@ var (
 	// TlmLogsProcessed is the total number of processed logs.
 	TlmLogsProcessed = telemetry.NewCounter("logs", "processed",
 		nil, "Total number of processed logs")
+	// LogsSampledOut is the total number of logs dropped by sample processing rules.
+	LogsSampledOut = expvar.Int{}
+	// LogsRateLimited is the total number of logs dropped by rate_limit processing rules.
+	LogsRateLimited = expvar.Int{}
+	// TlmLogsDroppedByRule is the total number of logs dropped by sample and rate_limit processing rules.
+	TlmLogsDroppedByRule = telemetry.NewCounter("logs", "dropped_by_rule",
+		[]string{"rule_type", "rule_name"}, "Total number of logs dropped by sample and rate_limit processing rules")
+	// LogsMetricsGenerated is the total number of metric samples generated by log_to_metric processing rules.
+	LogsMetricsGenerated = expvar.Int{}
+	// TlmLogsMetricsGenerated is the total number of metric samples generated by log_to_metric processing rules.
+	TlmLogsMetricsGenerated = telemetry.NewCounter("logs", "metrics_generated",
+		[]string{"rule_name", "metric_name"}, "Total number of metric samples generated by log_to_metric processing rules")
+	// LogsMetricsDropped is the total number of metric samples generated by log_to_metric processing rules
+	// dropped because the aggregator could not keep up.
+	LogsMetricsDropped = expvar.Int{}
+	// TlmLogsMetricsDropped is the total number of metric samples generated by log_to_metric processing rules
+	// dropped because the aggregator could not keep up.
+	TlmLogsMetricsDropped = telemetry.NewCounter("logs", "metrics_dropped",
+		nil, "Total number of metric samples generated by log_to_metric processing rules dropped because the aggregator could not keep up")
 
 	// LogsSent is the total number of sent logs.
 	LogsSent = expvar.Int{}
@@ -67,6 +86,10 @@ func init() {
 	LogsExpvars = expvar.NewMap("logs-agent")
 	LogsExpvars.Set("LogsDecoded", &LogsDecoded)
 	LogsExpvars.Set("LogsProcessed", &LogsProcessed)
+	LogsExpvars.Set("LogsSampledOut", &LogsSampledOut)
+	LogsExpvars.Set("LogsRateLimited", &LogsRateLimited)
+	LogsExpvars.Set("LogsMetricsGenerated", &LogsMetricsGenerated)
+	LogsExpvars.Set("LogsMetricsDropped", &LogsMetricsDropped)
 	LogsExpvars.Set("LogsSent", &LogsSent)
 	LogsExpvars.Set("DestinationErrors", &DestinationErrors)
 	LogsExpvars.Set("DestinationLogsDropped", &DestinationLogsDropped)
@@ -10,6 +10,12 @@
 // before further processing and aggregation of log messages.
 package parsers
 
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
 // Message represents a message parsed from a single line of log data
 type Message struct {
 	// Content is the message content.  If this is nil then the message
@@ -28,6 +34,44 @@ type Message struct {
 	// supports partial lines, then this is true only for the message returned
 	// from the last parsed line in a multi-line message.
 	IsPartial bool
+
+	// Tags are the tags parsed from the message metadata, if any.
+	Tags []string
+
+	// Structured holds the fields lifted from a structured line, such as a JSON
+	// object.  It is nil for unstructured lines.  It is only set on complete
+	// lines, once the partial lines are reassembled (see ContentParser).
+	Structured *StructuredContent
+}
+
+// StructuredContent represents the fields of a structured line which are
+// sent alongside the message content.
+type StructuredContent struct {
+	// Service is the service parsed from the line, if any.
+	Service string
+
+	// Timestamp is the time at which the event was logged, if any.  Unlike
+	// Message.Timestamp, it is not used to track the position in the source.
+	Timestamp time.Time
+
+	// Attributes are the remaining fields of the line.
+	Attributes map[string]interface{}
+}
+
+// ApplyTo sets the structured fields on a message, it does nothing if s is nil.
+func (s *StructuredContent) ApplyTo(msg *message.Message) {
+	if s == nil {
+		return
+	}
+	if s.Service != "" {
+		msg.Origin.SetService(s.Service)
+	}
+	if !s.Timestamp.IsZero() {
+		msg.Timestamp = s.Timestamp.UTC()
+	}
+	for key, value := range s.Attributes {
+		msg.SetAttribute(key, value)
+	}
 }
 
 // Parser parses messages, given as a raw byte sequence, into content and metadata.
@@ -40,3 +84,17 @@ type Parser interface {
 	// IsPartial: true
 	SupportsPartialLine() bool
 }
+
+// ContentParser is a Parser parsing the content of the lines returned by another
+// parser, such as JSON objects. The lines split in partial messages are
+// reassembled from the messages returned by the wrapped parser before their
+// content is parsed.
+type ContentParser interface {
+	Parser
+
+	// Unwrap returns the parser of the lines.
+	Unwrap() Parser
+
+	// ParseContent parses the content of a complete line.
+	ParseContent(Message) Message
+}
@@ -0,0 +1,36 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestStructuredContentApplyTo(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	msg := message.NewMessageWithSource([]byte("hello"), message.StatusInfo, source, 0)
+
+	var nilContent *StructuredContent
+	nilContent.ApplyTo(msg)
+	assert.Nil(t, msg.Attributes)
+
+	ts := time.Date(2019, 6, 6, 16, 35, 55, 0, time.FixedZone("", 3600))
+	(&StructuredContent{
+		Service:    "web",
+		Timestamp:  ts,
+		Attributes: map[string]interface{}{"duration": 12},
+	}).ApplyTo(msg)
+	assert.Equal(t, "web", msg.Origin.Service())
+	assert.Equal(t, ts.UTC(), msg.Timestamp)
+	assert.Equal(t, map[string]interface{}{"duration": 12}, msg.Attributes)
+}
@@ -0,0 +1,187 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package json implements a Parser lifting the well-known fields of
+// JSON-formatted log lines.
+package json
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// Default keys of the lifted fields
+const (
+	defaultMessageKey   = "message"
+	defaultStatusKey    = "level"
+	defaultTimestampKey = "timestamp"
+	defaultServiceKey   = "service"
+)
+
+// epochMillisThreshold is the value above which a numeric timestamp is
+// considered to be expressed in milliseconds rather than seconds.
+const epochMillisThreshold = 1e11
+
+// statuses maps the usual level names to statuses.
+var statuses = map[string]string{
+	"emerg":       message.StatusEmergency,
+	"emergency":   message.StatusEmergency,
+	"panic":       message.StatusEmergency,
+	"alert":       message.StatusAlert,
+	"crit":        message.StatusCritical,
+	"critical":    message.StatusCritical,
+	"fatal":       message.StatusCritical,
+	"err":         message.StatusError,
+	"error":       message.StatusError,
+	"warn":        message.StatusWarning,
+	"warning":     message.StatusWarning,
+	"notice":      message.StatusNotice,
+	"info":        message.StatusInfo,
+	"information": message.StatusInfo,
+	"debug":       message.StatusDebug,
+	"trace":       message.StatusDebug,
+}
+
+// New returns a parser which parses the lines returned by the given parser as
+// JSON objects.
+//
+// For example, with the default keys:
+//
+//	`{"message":"request handled","level":"warn","timestamp":"2019-06-06T16:35:55Z","duration":12}`
+//
+// returns:
+//
+//	parsers.Message {
+//	    Content: []byte("request handled"),
+//	    Status: "warn",
+//	    Structured: &parsers.StructuredContent{
+//	        Timestamp: 2019-06-06 16:35:55 +0000 UTC,
+//	        Attributes: {"duration": 12},
+//	    },
+//	}
+//
+// Lines which are not JSON objects are returned as parsed by the given parser.
+// Partial lines are returned as is, their content being parsed once reassembled
+// (see parsers.ContentParser).
+func New(parser parsers.Parser, keys config.JSONKeys) parsers.Parser {
+	return &jsonFormat{
+		parser:       parser,
+		messageKey:   withDefault(keys.Message, defaultMessageKey),
+		statusKey:    withDefault(keys.Status, defaultStatusKey),
+		timestampKey: withDefault(keys.Timestamp, defaultTimestampKey),
+		serviceKey:   withDefault(keys.Service, defaultServiceKey),
+	}
+}
+
+type jsonFormat struct {
+	parser       parsers.Parser
+	messageKey   string
+	statusKey    string
+	timestampKey string
+	serviceKey   string
+}
+
+// Parse implements Parser#Parse
+func (p *jsonFormat) Parse(data []byte) (parsers.Message, error) {
+	msg, err := p.parser.Parse(data)
+	if err != nil || msg.IsPartial {
+		return msg, err
+	}
+	return p.ParseContent(msg), nil
+}
+
+// Unwrap implements ContentParser#Unwrap
+func (p *jsonFormat) Unwrap() parsers.Parser {
+	return p.parser
+}
+
+// ParseContent implements ContentParser#ParseContent
+func (p *jsonFormat) ParseContent(msg parsers.Message) parsers.Message {
+	content := bytes.TrimSpace(msg.Content)
+	if len(content) == 0 || content[0] != '{' {
+		return msg
+	}
+	decoder := stdjson.NewDecoder(bytes.NewReader(content))
+	// keep numbers as they were written, the encoder will write them back unchanged
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil || decoder.More() {
+		return msg
+	}
+
+	structured := &parsers.StructuredContent{}
+	if status, ok := fields[p.statusKey].(string); ok {
+		if s, found := statuses[strings.ToLower(status)]; found {
+			msg.Status = s
+			delete(fields, p.statusKey)
+		}
+	}
+	if ts, ok := parseTimestamp(fields[p.timestampKey]); ok {
+		structured.Timestamp = ts
+		delete(fields, p.timestampKey)
+	}
+	if service, ok := fields[p.serviceKey].(string); ok && service != "" {
+		structured.Service = service
+		delete(fields, p.serviceKey)
+	}
+	// without a message field, the whole line is kept as content
+	// and the other fields are not duplicated in the attributes
+	if text, ok := fields[p.messageKey].(string); ok {
+		msg.Content = []byte(text)
+		delete(fields, p.messageKey)
+		if len(fields) > 0 {
+			structured.Attributes = fields
+		}
+	}
+	msg.Structured = structured
+	return msg
+}
+
+// SupportsPartialLine implements Parser#SupportsPartialLine
+func (p *jsonFormat) SupportsPartialLine() bool {
+	return p.parser.SupportsPartialLine()
+}
+
+// parseTimestamp parses either an RFC 3339 date or a number of seconds or
+// milliseconds since the epoch.
+func parseTimestamp(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case string:
+		ts, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return ts, true
+	case stdjson.Number:
+		if i, err := v.Int64(); err == nil && i > 0 {
+			if i > epochMillisThreshold {
+				return time.Unix(0, i*int64(time.Millisecond)), true
+			}
+			return time.Unix(i, 0), true
+		}
+		f, err := v.Float64()
+		if err != nil || f <= 0 {
+			return time.Time{}, false
+		}
+		if f > epochMillisThreshold {
+			return time.Unix(0, int64(f*float64(time.Millisecond))), true
+		}
+		return time.Unix(0, int64(f*float64(time.Second))), true
+	}
+	return time.Time{}, false
+}
+
+func withDefault(key, defaultKey string) string {
+	if key == "" {
+		return defaultKey
+	}
+	return key
+}
@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package json
+
+import (
+	stdjson "encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/noop"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestJSONParserWithDefaultKeys(t *testing.T) {
+	parser := New(noop.New(), config.JSONKeys{})
+	msg, err := parser.Parse([]byte(`{"message":"request handled","level":"WARN","timestamp":"2019-06-06T16:35:55.930852911Z","service":"web","duration":12,"http":{"status":200}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("request handled"), msg.Content)
+	assert.Equal(t, message.StatusWarning, msg.Status)
+	assert.Equal(t, &parsers.StructuredContent{
+		Service:   "web",
+		Timestamp: time.Date(2019, 6, 6, 16, 35, 55, 930852911, time.UTC),
+		Attributes: map[string]interface{}{
+			"duration": stdjson.Number("12"),
+			"http":     map[string]interface{}{"status": stdjson.Number("200")},
+		},
+	}, msg.Structured)
+}
+
+func TestJSONParserWithCustomKeys(t *testing.T) {
+	parser := New(noop.New(), config.JSONKeys{Message: "msg", Status: "severity", Timestamp: "ts", Service: "app"})
+	msg, err := parser.Parse([]byte(`{"msg":"done","severity":"error","ts":1559838955123,"app":"worker","level":"info"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("done"), msg.Content)
+	assert.Equal(t, message.StatusError, msg.Status)
+	assert.Equal(t, "worker", msg.Structured.Service)
+	assert.Equal(t, time.Unix(1559838955, 123000000), msg.Structured.Timestamp)
+	assert.Equal(t, map[string]interface{}{"level": "info"}, msg.Structured.Attributes)
+
+	msg, err = parser.Parse([]byte(`{"msg":"done","ts":1559838955}`))
+	assert.Nil(t, err)
+	assert.Equal(t, time.Unix(1559838955, 0), msg.Structured.Timestamp)
+	assert.Nil(t, msg.Structured.Attributes)
+}
+
+func TestJSONParserWithoutMessageKey(t *testing.T) {
+	parser := New(noop.New(), config.JSONKeys{})
+	line := `{"level":"debug","event":"cache miss"}`
+	msg, err := parser.Parse([]byte(line))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte(line), msg.Content)
+	assert.Equal(t, message.StatusDebug, msg.Status)
+	assert.NotNil(t, msg.Structured)
+	assert.Nil(t, msg.Structured.Attributes)
+}
+
+func TestJSONParserKeepsUnknownStatus(t *testing.T) {
+	parser := New(noop.New(), config.JSONKeys{})
+	msg, err := parser.Parse([]byte(`{"message":"hello","level":"verbose"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "", msg.Status)
+	assert.Equal(t, map[string]interface{}{"level": "verbose"}, msg.Structured.Attributes)
+}
+
+func TestJSONParserShouldIgnoreNonJSONLines(t *testing.T) {
+	parser := New(noop.New(), config.JSONKeys{})
+	for _, line := range []string{
+		"plain text line",
+		"    at com.example.Foo.bar(Foo.java:42)",
+		`{"message":"truncated`,
+		`{"message":"first"} {"message":"second"}`,
+		`["not", "an", "object"]`,
+	} {
+		msg, err := parser.Parse([]byte(line))
+		assert.Nil(t, err)
+		assert.Equal(t, []byte(line), msg.Content)
+		assert.Nil(t, msg.Structured, line)
+	}
+}
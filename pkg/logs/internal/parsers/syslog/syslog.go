@@ -0,0 +1,298 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package syslog implements a parser for syslog messages following either
+// RFC 5424 or the BSD format described in RFC 3164.
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// nilValue is the value used by RFC 5424 for empty header fields.
+const nilValue = "-"
+
+// rfc3164TimestampLen is the length of a `Mmm dd hh:mm:ss` timestamp.
+const rfc3164TimestampLen = 15
+
+// severityStatuses maps syslog severities, from 0 to 7, to statuses.
+var severityStatuses = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// facilities holds the names of syslog facilities, indexed by their code.
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var errInvalidPriority = errors.New("cannot parse the syslog priority")
+
+// New creates a new parser that parses syslog messages.
+//
+// RFC 5424 messages follow the pattern
+// '<PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG',
+// for example `<165>1 2003-10-11T22:14:15.003Z host app 1234 ID47 [id key="val"] message`.
+//
+// RFC 3164 messages follow the pattern '<PRI>TIMESTAMP HOSTNAME TAG: MSG',
+// for example `<34>Oct 11 22:14:15 host su[123]: message`.
+//
+// The syslog header fields are returned as tags and the severity is mapped to the status.
+func New() parsers.Parser {
+	return &syslogFormat{}
+}
+
+type syslogFormat struct{}
+
+// Parse implements Parser#Parse
+func (p *syslogFormat) Parse(msg []byte) (parsers.Message, error) {
+	return parseSyslog(msg)
+}
+
+// SupportsPartialLine implements Parser#SupportsPartialLine
+func (p *syslogFormat) SupportsPartialLine() bool {
+	return false
+}
+
+func parseSyslog(msg []byte) (parsers.Message, error) {
+	facility, severity, rest, err := parsePriority(msg)
+	if err != nil {
+		return parsers.Message{
+			Content: msg,
+			Status:  message.StatusInfo,
+		}, err
+	}
+	tags := []string{"syslog.facility:" + facilityName(facility)}
+	if len(rest) > 1 && rest[0] >= '1' && rest[0] <= '9' && (rest[1] == ' ' || (len(rest) > 2 && rest[1] >= '0' && rest[1] <= '9' && rest[2] == ' ')) {
+		parsed, err := parseRFC5424(rest, severityStatuses[severity], tags)
+		if err != nil {
+			// forward the malformed line intact
+			return parsers.Message{
+				Content: msg,
+				Status:  severityStatuses[severity],
+				Tags:    tags,
+			}, err
+		}
+		return parsed, nil
+	}
+	return parseRFC3164(rest, severityStatuses[severity], tags), nil
+}
+
+// parsePriority parses the `<PRI>` header and returns the facility, the severity
+// and the remainder of the message.
+func parsePriority(msg []byte) (int, int, []byte, error) {
+	if len(msg) < 3 || msg[0] != '<' {
+		return 0, 0, nil, errInvalidPriority
+	}
+	end := bytes.IndexByte(msg[:min(len(msg), 5)], '>')
+	if end < 2 {
+		return 0, 0, nil, errInvalidPriority
+	}
+	pri, err := strconv.Atoi(string(msg[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, 0, nil, errInvalidPriority
+	}
+	return pri / 8, pri % 8, msg[end+1:], nil
+}
+
+// parseRFC5424 parses what follows the priority of an RFC 5424 message.
+func parseRFC5424(msg []byte, status string, tags []string) (parsers.Message, error) {
+	// VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID
+	fields := make([]string, 6)
+	for i := range fields {
+		var field []byte
+		field, msg = nextField(msg)
+		if field == nil {
+			return parsers.Message{}, errors.New("cannot parse the syslog header")
+		}
+		fields[i] = string(field)
+	}
+	timestamp := ""
+	if fields[1] != nilValue {
+		if ts, err := time.Parse(time.RFC3339Nano, fields[1]); err == nil {
+			timestamp = ts.UTC().Format(config.DateFormat)
+		}
+	}
+	tags = appendHeaderTag(tags, "syslog.hostname", fields[2])
+	tags = appendHeaderTag(tags, "syslog.appname", fields[3])
+	tags = appendHeaderTag(tags, "syslog.procid", fields[4])
+	tags = appendHeaderTag(tags, "syslog.msgid", fields[5])
+
+	tags, msg = parseStructuredData(msg, tags)
+	// a UTF-8 encoded message may be prefixed with a byte order mark
+	msg = bytes.TrimPrefix(msg, []byte("\xef\xbb\xbf"))
+
+	return parsers.Message{
+		Content:   msg,
+		Status:    status,
+		Timestamp: timestamp,
+		Tags:      tags,
+	}, nil
+}
+
+// parseStructuredData parses the STRUCTURED-DATA part of an RFC 5424 message,
+// each `[id key="value"]` parameter is returned as an `id.key:value` tag.
+func parseStructuredData(msg []byte, tags []string) ([]string, []byte) {
+	if len(msg) > 0 && msg[0] == '-' {
+		return tags, trimSeparator(msg[1:])
+	}
+	for len(msg) > 0 && msg[0] == '[' {
+		end := findElementEnd(msg)
+		if end < 0 {
+			return tags, msg
+		}
+		tags = appendStructuredDataTags(tags, msg[1:end])
+		msg = msg[end+1:]
+	}
+	return tags, trimSeparator(msg)
+}
+
+// findElementEnd returns the index of the `]` closing the element starting
+// at the beginning of msg, taking escaped characters into account.
+func findElementEnd(msg []byte) int {
+	inValue := false
+	for i := 1; i < len(msg); i++ {
+		switch msg[i] {
+		case '\\':
+			i++
+		case '"':
+			inValue = !inValue
+		case ']':
+			if !inValue {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func appendStructuredDataTags(tags []string, element []byte) []string {
+	id, params := nextField(element)
+	if id == nil {
+		id, params = element, nil
+	}
+	for len(params) > 0 {
+		eq := bytes.IndexByte(params, '=')
+		if eq < 0 || eq+1 >= len(params) || params[eq+1] != '"' {
+			break
+		}
+		name := string(bytes.TrimSpace(params[:eq]))
+		value, end := unquoteParamValue(params[eq+2:])
+		if end < 0 {
+			break
+		}
+		tags = append(tags, string(id)+"."+name+":"+value)
+		params = params[eq+2+end+1:]
+	}
+	return tags
+}
+
+// unquoteParamValue reads a parameter value up to its closing quote and
+// returns the unescaped value and the index of the closing quote.
+func unquoteParamValue(params []byte) (string, int) {
+	var value []byte
+	for i := 0; i < len(params); i++ {
+		switch params[i] {
+		case '\\':
+			if i+1 < len(params) {
+				i++
+				value = append(value, params[i])
+			}
+		case '"':
+			return string(value), i
+		default:
+			value = append(value, params[i])
+		}
+	}
+	return "", -1
+}
+
+// parseRFC3164 parses what follows the priority of an RFC 3164 message. As this
+// format is loosely specified, any part that can't be recognized is kept in the content.
+func parseRFC3164(msg []byte, status string, tags []string) parsers.Message {
+	if len(msg) > rfc3164TimestampLen && msg[rfc3164TimestampLen] == ' ' {
+		if _, err := time.Parse(time.Stamp, string(msg[:rfc3164TimestampLen])); err == nil {
+			msg = msg[rfc3164TimestampLen+1:]
+			if hostname, rest := nextField(msg); hostname != nil && !isTag(hostname) {
+				tags = appendHeaderTag(tags, "syslog.hostname", string(hostname))
+				msg = rest
+			}
+		}
+	}
+	// TAG[PID]: MSG
+	if colon := bytes.Index(msg, []byte(": ")); colon > 0 && bytes.IndexByte(msg[:colon], ' ') < 0 {
+		tag := msg[:colon]
+		if open := bytes.IndexByte(tag, '['); open > 0 && tag[len(tag)-1] == ']' {
+			tags = appendHeaderTag(tags, "syslog.procid", string(tag[open+1:len(tag)-1]))
+			tag = tag[:open]
+		}
+		tags = appendHeaderTag(tags, "syslog.appname", string(tag))
+		msg = msg[colon+2:]
+	}
+	return parsers.Message{
+		Content: msg,
+		Status:  status,
+		Tags:    tags,
+	}
+}
+
+// isTag returns true when the field looks like a `TAG:` rather than a hostname,
+// which happens when the sender omits its hostname.
+func isTag(field []byte) bool {
+	return len(field) > 0 && field[len(field)-1] == ':'
+}
+
+// nextField returns the content up to the next space and the remainder of msg,
+// or nil when there is no space left.
+func nextField(msg []byte) ([]byte, []byte) {
+	idx := bytes.IndexByte(msg, ' ')
+	if idx < 0 {
+		return nil, msg
+	}
+	return msg[:idx], msg[idx+1:]
+}
+
+func trimSeparator(msg []byte) []byte {
+	if len(msg) > 0 && msg[0] == ' ' {
+		return msg[1:]
+	}
+	return msg
+}
+
+func appendHeaderTag(tags []string, name, value string) []string {
+	if value == "" || value == nilValue {
+		return tags
+	}
+	return append(tags, name+":"+value)
+}
+
+func facilityName(facility int) string {
+	if facility < len(facilities) {
+		return facilities[facility]
+	}
+	return strconv.Itoa(facility)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
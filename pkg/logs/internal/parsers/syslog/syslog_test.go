@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestSyslogParserRFC5424(t *testing.T) {
+	msg, err := New().Parse([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="Application"] An application event`))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("An application event"), msg.Content)
+	assert.Equal(t, message.StatusNotice, msg.Status)
+	assert.Equal(t, "2003-10-11T22:14:15.003000000Z", msg.Timestamp)
+	assert.Equal(t, []string{
+		"syslog.facility:local4",
+		"syslog.hostname:mymachine.example.com",
+		"syslog.appname:evntslog",
+		"syslog.procid:1234",
+		"syslog.msgid:ID47",
+		"exampleSDID@32473.iut:3",
+		"exampleSDID@32473.eventSource:Application",
+	}, msg.Tags)
+}
+
+func TestSyslogParserRFC5424NilValues(t *testing.T) {
+	msg, err := New().Parse([]byte("<34>1 - - su - - - 'su root' failed\xef\xbb\xbf"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("'su root' failed\xef\xbb\xbf"), msg.Content)
+	assert.Equal(t, message.StatusCritical, msg.Status)
+	assert.Equal(t, "", msg.Timestamp)
+	assert.Equal(t, []string{"syslog.facility:auth", "syslog.appname:su"}, msg.Tags)
+
+	msg, err = New().Parse([]byte("<13>1 2023-01-02T03:04:05Z host app - - [sd key=\"a \\\"quoted\\\" ] value\"][other] \xef\xbb\xbfmessage"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("message"), msg.Content)
+	assert.Equal(t, []string{
+		"syslog.facility:user",
+		"syslog.hostname:host",
+		"syslog.appname:app",
+		`sd.key:a "quoted" ] value`,
+	}, msg.Tags)
+}
+
+func TestSyslogParserRFC3164(t *testing.T) {
+	msg, err := New().Parse([]byte("<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("'su root' failed for lonvick on /dev/pts/8"), msg.Content)
+	assert.Equal(t, message.StatusCritical, msg.Status)
+	assert.Equal(t, []string{
+		"syslog.facility:auth",
+		"syslog.hostname:mymachine",
+		"syslog.procid:123",
+		"syslog.appname:su",
+	}, msg.Tags)
+
+	msg, err = New().Parse([]byte("<14>Oct  1 02:04:05 kernel: something happened"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("something happened"), msg.Content)
+	assert.Equal(t, message.StatusInfo, msg.Status)
+	assert.Equal(t, []string{"syslog.facility:user", "syslog.appname:kernel"}, msg.Tags)
+
+	msg, err = New().Parse([]byte("<191>free form message"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("free form message"), msg.Content)
+	assert.Equal(t, message.StatusDebug, msg.Status)
+	assert.Equal(t, []string{"syslog.facility:local7"}, msg.Tags)
+}
+
+func TestSyslogParserShouldFailWithInvalidPriority(t *testing.T) {
+	for _, line := range []string{"", "no priority", "<>1 - - - - - -", "<192>1 - - - - - - msg", "<abc>msg"} {
+		msg, err := New().Parse([]byte(line))
+		assert.NotNil(t, err, line)
+		assert.Equal(t, []byte(line), msg.Content)
+		assert.Equal(t, message.StatusInfo, msg.Status)
+	}
+}
+
+func TestSyslogParserShouldKeepMalformedRFC5424Lines(t *testing.T) {
+	line := "<165>1 2003-10-11T22:14:15.003Z mymachine.example.com"
+	msg, err := New().Parse([]byte(line))
+	assert.NotNil(t, err)
+	assert.Equal(t, []byte(line), msg.Content)
+	assert.Equal(t, message.StatusNotice, msg.Status)
+	assert.Equal(t, []string{"syslog.facility:local4"}, msg.Tags)
+}
@@ -229,3 +229,38 @@ func TestEncoderToValidUTF8(t *testing.T) {
 	assert.Equal(t, "a���z", toValidUtf8([]byte("a\xed\xa0\x80z")))
 	assert.Equal(t, "a����z", toValidUtf8([]byte("a\xf0\x8f\xbf\xbfz")))
 }
+
+func TestJsonEncoderWithAttributes(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{Service: "Service"})
+	msg := newMessage([]byte("message"), source, message.StatusInfo)
+	msg.SetAttribute("status_code", int64(502))
+	msg.SetAttribute("service", "overridden")
+
+	jsonMessage, err := JSONEncoder.Encode(msg, []byte("redacted"))
+	assert.Nil(t, err)
+
+	log := make(map[string]interface{})
+	err = json.Unmarshal(jsonMessage, &log)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "redacted", log["message"])
+	assert.Equal(t, "Service", log["service"])
+	assert.Equal(t, float64(502), log["status_code"])
+}
+
+func TestProtoEncoderWithAttributes(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	msg := newMessage([]byte("message"), source, message.StatusInfo)
+	msg.Origin.SetTags([]string{"status_code:502"})
+	msg.SetAttribute("status_code", int64(502))
+	msg.SetAttribute("request_id", "c5a1e7")
+
+	proto, err := ProtoEncoder.Encode(msg, []byte("redacted"))
+	assert.Nil(t, err)
+
+	log := &pb.Log{}
+	err = log.Unmarshal(proto)
+	assert.Nil(t, err)
+	// only the fields listed in tag_fields, already added to the origin, are sent as tags
+	assert.Equal(t, []string{"status_code:502"}, log.Tags)
+}
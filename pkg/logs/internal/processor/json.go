@@ -37,7 +37,7 @@ func (j *jsonEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte,
 	if !msg.Timestamp.IsZero() {
 		ts = msg.Timestamp
 	}
-	return json.Marshal(jsonPayload{
+	payload := jsonPayload{
 		Message:   toValidUtf8(redactedMsg),
 		Status:    msg.GetStatus(),
 		Timestamp: ts.UnixNano() / nanoToMillis,
@@ -45,5 +45,26 @@ func (j *jsonEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte,
 		Service:   msg.Origin.Service(),
 		Source:    msg.Origin.Source(),
 		Tags:      msg.Origin.TagsToString(),
-	})
+	}
+	if len(msg.Attributes) == 0 {
+		return json.Marshal(payload)
+	}
+	return json.Marshal(withAttributes(payload, msg.Attributes))
+}
+
+// withAttributes returns the payload as a flat map holding the structured attributes
+// of the message, the reserved fields of the payload take precedence.
+func withAttributes(payload jsonPayload, attributes map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]interface{}, len(attributes)+7)
+	for key, value := range attributes {
+		fields[key] = value
+	}
+	fields["message"] = payload.Message
+	fields["status"] = payload.Status
+	fields["timestamp"] = payload.Timestamp
+	fields["hostname"] = payload.Hostname
+	fields["service"] = payload.Service
+	fields["ddsource"] = payload.Source
+	fields["ddtags"] = payload.Tags
+	return fields
 }
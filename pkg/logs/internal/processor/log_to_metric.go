@@ -0,0 +1,181 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	coreMetrics "github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Sizes of the buffer of the samples waiting to be sent to the aggregator, and of the batches sent to it.
+const (
+	metricSubmitterBufferSize = 1024
+	metricBatchSize           = 32
+)
+
+// submitSample queues the samples generated by the log_to_metric rules,
+// it is overridden in tests.
+var submitSample = newMetricSubmitter(aggregator.AggregateSamples).submit
+
+// metricSubmitter sends the samples generated by the log_to_metric rules to the aggregator
+// in batches from its own goroutine, so that the processors never wait for the aggregator.
+type metricSubmitter struct {
+	samples   chan coreMetrics.MetricSample
+	aggregate func([]coreMetrics.MetricSample) error
+	startOnce sync.Once
+	errorOnce sync.Once
+}
+
+func newMetricSubmitter(aggregate func([]coreMetrics.MetricSample) error) *metricSubmitter {
+	return &metricSubmitter{
+		samples:   make(chan coreMetrics.MetricSample, metricSubmitterBufferSize),
+		aggregate: aggregate,
+	}
+}
+
+// submit queues the sample, it returns false if the sample was dropped because the buffer is full.
+func (s *metricSubmitter) submit(sample coreMetrics.MetricSample) bool {
+	s.startOnce.Do(func() { go s.run() })
+	select {
+	case s.samples <- sample:
+		return true
+	default:
+		return false
+	}
+}
+
+// run sends the queued samples, batching the ones which are already queued.
+func (s *metricSubmitter) run() {
+	batch := make([]coreMetrics.MetricSample, 0, metricBatchSize)
+	for sample := range s.samples {
+		batch = append(batch, sample)
+	drain:
+		for len(batch) < metricBatchSize {
+			select {
+			case sample := <-s.samples:
+				batch = append(batch, sample)
+			default:
+				break drain
+			}
+		}
+		if err := s.aggregate(batch); err != nil {
+			// avoid flooding the logs when the aggregator is not available
+			s.errorOnce.Do(func() {
+				log.Warnf("Could not submit the metrics generated by log_to_metric processing rules: %v", err)
+			})
+		}
+		batch = batch[:0]
+	}
+}
+
+// logToMetric generates the metric of a log_to_metric rule from a matching message,
+// tagged with the tags of the message and the fields listed in tag_fields.
+func logToMetric(msg *message.Message, rule *config.ProcessingRule, content []byte) {
+	var fields map[string]string
+	if rule.Regex != nil {
+		match := rule.Regex.FindSubmatch(content)
+		if match == nil {
+			return
+		}
+		for i, name := range rule.Regex.SubexpNames() {
+			if name == "" || len(match[i]) == 0 {
+				continue
+			}
+			if fields == nil {
+				fields = make(map[string]string)
+			}
+			fields[name] = string(match[i])
+		}
+	}
+
+	value, ok := metricValue(msg, rule, fields, content)
+	if !ok {
+		log.Debugf("Could not get the value of the metric %s from the field %s of a log matching processing rule %s", rule.MetricName, rule.ValueField, rule.Name)
+		return
+	}
+
+	tags := metricTags(msg)
+	for _, name := range rule.TagFields {
+		if v, found := fields[name]; found {
+			tags = append(tags, name+":"+v)
+		} else if v, found := msg.Attributes[name]; found {
+			tags = append(tags, name+":"+fmt.Sprint(v))
+		}
+	}
+
+	mtype := coreMetrics.CountType
+	if rule.MetricType == config.MetricTypeDistribution {
+		mtype = coreMetrics.DistributionType
+	}
+	submitted := submitSample(coreMetrics.MetricSample{
+		Name:       rule.MetricName,
+		Value:      value,
+		Mtype:      mtype,
+		Tags:       tags,
+		Host:       msg.GetHostname(),
+		SampleRate: 1,
+		Timestamp:  float64(time.Now().UnixNano()) / float64(time.Second),
+	})
+	if !submitted {
+		metrics.LogsMetricsDropped.Add(1)
+		metrics.TlmLogsMetricsDropped.Inc()
+		return
+	}
+
+	metrics.LogsMetricsGenerated.Add(1)
+	metrics.TlmLogsMetricsGenerated.Inc(rule.Name, rule.MetricName)
+	msg.Origin.LogSource.RecordGeneratedMetric(rule.MetricName)
+}
+
+// metricValue returns the value recorded by a log_to_metric rule: the size of the log,
+// the captured field or the attribute named by value_field, or 1 when value_field isn't set.
+func metricValue(msg *message.Message, rule *config.ProcessingRule, fields map[string]string, content []byte) (float64, bool) {
+	switch rule.ValueField {
+	case "":
+		return 1, true
+	case config.LogSizeField:
+		return float64(len(content)), true
+	}
+	if v, found := fields[rule.ValueField]; found {
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	switch v := msg.Attributes[rule.ValueField].(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// metricTags returns the tags of the message, along with its service and source.
+func metricTags(msg *message.Message) []string {
+	originTags := msg.Origin.Tags()
+	tags := make([]string, 0, len(originTags)+4)
+	tags = append(tags, originTags...)
+	if service := msg.Origin.Service(); service != "" {
+		tags = append(tags, "service:"+service)
+	}
+	if source := msg.Origin.Source(); source != "" {
+		tags = append(tags, "source:"+source)
+	}
+	return tags
+}
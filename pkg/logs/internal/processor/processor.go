@@ -7,6 +7,7 @@ package processor
 
 import (
 	"context"
+	"strconv"
 	"sync"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -121,7 +122,59 @@ func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 			}
 		case config.MaskSequences:
 			content = rule.Regex.ReplaceAll(content, rule.Placeholder)
+		case config.ExtractFields:
+			extractFields(msg, rule, content)
+		case config.Sample:
+			if (rule.Regex == nil || rule.Regex.Match(content)) && !sample(rule) {
+				recordDroppedByRule(msg, rule)
+				return false, nil
+			}
+		case config.RateLimit:
+			if (rule.Regex == nil || rule.Regex.Match(content)) && !rateLimit(msg, rule) {
+				recordDroppedByRule(msg, rule)
+				return false, nil
+			}
+		case config.LogToMetric:
+			logToMetric(msg, rule, content)
 		}
 	}
 	return true, content
 }
+
+// extractFields attaches the fields captured by an extract_fields rule
+// to the message as attributes, and as tags when requested.
+func extractFields(msg *message.Message, rule *config.ProcessingRule, content []byte) {
+	match := rule.Regex.FindSubmatch(content)
+	if match == nil {
+		return
+	}
+	for i, name := range rule.Regex.SubexpNames() {
+		if name == "" || len(match[i]) == 0 {
+			continue
+		}
+		value := string(match[i])
+		msg.SetAttribute(name, convertField(value, rule.FieldTypes[name]))
+		for _, tagField := range rule.TagFields {
+			if tagField == name {
+				msg.Origin.AddTags(name + ":" + value)
+				break
+			}
+		}
+	}
+}
+
+// convertField converts a captured value to the type declared in the pattern,
+// falling back to the raw string when it can't be converted.
+func convertField(value string, fieldType string) interface{} {
+	switch fieldType {
+	case config.FieldTypeInt:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case config.FieldTypeFloat:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
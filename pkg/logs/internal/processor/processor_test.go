@@ -8,12 +8,15 @@ package processor
 import (
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	coreMetrics "github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
 func TestExclusion(t *testing.T) {
@@ -130,6 +133,201 @@ func TestMask(t *testing.T) {
 	assert.Equal(t, []byte("New data added to data_values= on prod"), redactedMessage)
 }
 
+func TestExtractFields(t *testing.T) {
+	p := &Processor{}
+
+	rule := &config.ProcessingRule{
+		Type:      config.ExtractFields,
+		Name:      "nginx",
+		Pattern:   `%{HTTPMETHOD:method} %{URIPATHPARAM:path} %{INT:status:int} %{NUMBER:latency:float}`,
+		TagFields: []string{"status"},
+	}
+	assert.Nil(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	source := sources.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}}}
+
+	msg := newMessage([]byte("GET /checkout?id=1 502 0.254"), &source, "")
+	shouldProcess, redactedMessage := p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.Equal(t, []byte("GET /checkout?id=1 502 0.254"), redactedMessage)
+	assert.Equal(t, map[string]interface{}{
+		"method":  "GET",
+		"path":    "/checkout?id=1",
+		"status":  int64(502),
+		"latency": 0.254,
+	}, msg.Attributes)
+	assert.Equal(t, []string{"status:502"}, msg.Origin.Tags())
+
+	msg = newMessage([]byte("not an access log"), &source, "")
+	shouldProcess, _ = p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.Nil(t, msg.Attributes)
+	assert.Empty(t, msg.Origin.Tags())
+}
+
+func TestExtractFieldsAfterMask(t *testing.T) {
+	mask := newProcessingRule(config.MaskSequences, "[masked]", `user=\w+`)
+	extract := &config.ProcessingRule{Type: config.ExtractFields, Name: "user", Pattern: `login (?P<user>\S+)`}
+	assert.Nil(t, config.CompileProcessingRules([]*config.ProcessingRule{extract}))
+	p := &Processor{processingRules: []*config.ProcessingRule{mask, extract}}
+
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	msg := newMessage([]byte("login user=bob"), source, "")
+	shouldProcess, _ := p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.Equal(t, "[masked]", msg.Attributes["user"])
+}
+
+func TestSample(t *testing.T) {
+	rule := &config.ProcessingRule{Type: config.Sample, Name: "debug_sampling", Pattern: "DEBUG", SampleRate: 0}
+	assert.Nil(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	p := &Processor{processingRules: []*config.ProcessingRule{rule}}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+
+	shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("DEBUG connecting"), source, ""))
+	assert.False(t, shouldProcess)
+	shouldProcess, _ = p.applyRedactingRules(newMessage([]byte("ERROR connection refused"), source, ""))
+	assert.True(t, shouldProcess)
+	assert.Equal(t, int64(1), source.DroppedLogs.Get("debug_sampling"))
+
+	rule.SampleRate = 1
+	shouldProcess, _ = p.applyRedactingRules(newMessage([]byte("DEBUG connecting"), source, ""))
+	assert.True(t, shouldProcess)
+	assert.Equal(t, int64(1), source.DroppedLogs.Get("debug_sampling"))
+}
+
+func TestRateLimit(t *testing.T) {
+	clock := clock.NewMock()
+	rule := &config.ProcessingRule{Type: config.RateLimit, Name: "noisy", LimitPerSecond: 2, GroupBy: []string{"user"}}
+	rule.RateLimiter = config.NewRateLimiter(rule.LimitPerSecond, rule.Burst, clock)
+	assert.Nil(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	p := &Processor{}
+	parent := sources.NewLogSource("parent", &config.LogsConfig{})
+	source := sources.NewLogSource("child", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}})
+	source.ParentSource = parent
+
+	process := func(user string) bool {
+		msg := newMessage([]byte("hello"), source, "")
+		msg.SetAttribute("user", user)
+		shouldProcess, _ := p.applyRedactingRules(msg)
+		return shouldProcess
+	}
+
+	assert.True(t, process("alice"))
+	assert.True(t, process("alice"))
+	assert.False(t, process("alice"))
+	// every group has its own budget
+	assert.True(t, process("bob"))
+
+	clock.Add(500 * time.Millisecond)
+	assert.True(t, process("alice"))
+	assert.False(t, process("alice"))
+
+	assert.Equal(t, int64(2), source.DroppedLogs.Get("noisy"))
+	assert.Equal(t, int64(2), parent.DroppedLogs.Get("noisy"))
+}
+
+func TestLogToMetric(t *testing.T) {
+	var samples []coreMetrics.MetricSample
+	defer func(submit func(coreMetrics.MetricSample) bool) { submitSample = submit }(submitSample)
+	submitSample = func(sample coreMetrics.MetricSample) bool {
+		samples = append(samples, sample)
+		return true
+	}
+
+	errorRule := &config.ProcessingRule{Type: config.LogToMetric, Name: "errors", MetricName: "app.errors", Pattern: `ERROR (?P<code>\w+)`, TagFields: []string{"code"}}
+	bytesRule := &config.ProcessingRule{Type: config.LogToMetric, Name: "bytes", MetricName: "app.bytes", ValueField: config.LogSizeField}
+	latencyRule := &config.ProcessingRule{Type: config.LogToMetric, Name: "latency", MetricName: "app.latency", MetricType: config.MetricTypeDistribution, Pattern: `took %{NUMBER:latency}ms`, ValueField: "latency"}
+	rules := []*config.ProcessingRule{errorRule, bytesRule, latencyRule}
+	assert.Nil(t, config.CompileProcessingRules(rules))
+	p := &Processor{processingRules: rules}
+	source := sources.NewLogSource("", &config.LogsConfig{Service: "web", Tags: []string{"env:prod"}})
+
+	shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("ERROR E42 request took 12.5ms"), source, ""))
+	assert.True(t, shouldProcess)
+	shouldProcess, _ = p.applyRedactingRules(newMessage([]byte("INFO ok"), source, ""))
+	assert.True(t, shouldProcess)
+
+	assert.Len(t, samples, 4)
+	assert.Equal(t, "app.errors", samples[0].Name)
+	assert.Equal(t, coreMetrics.CountType, samples[0].Mtype)
+	assert.Equal(t, float64(1), samples[0].Value)
+	assert.ElementsMatch(t, []string{"env:prod", "service:web", "code:E42"}, samples[0].Tags)
+	assert.Equal(t, "app.bytes", samples[1].Name)
+	assert.Equal(t, float64(29), samples[1].Value)
+	assert.Equal(t, "app.latency", samples[2].Name)
+	assert.Equal(t, coreMetrics.DistributionType, samples[2].Mtype)
+	assert.Equal(t, 12.5, samples[2].Value)
+	assert.Equal(t, "app.bytes", samples[3].Name)
+	assert.Equal(t, float64(7), samples[3].Value)
+
+	assert.Equal(t, int64(1), source.GeneratedMetrics.Get("app.errors"))
+	assert.Equal(t, int64(2), source.GeneratedMetrics.Get("app.bytes"))
+	assert.Equal(t, int64(1), source.GeneratedMetrics.Get("app.latency"))
+}
+
+func TestLogToMetricValueFromAttribute(t *testing.T) {
+	var samples []coreMetrics.MetricSample
+	defer func(submit func(coreMetrics.MetricSample) bool) { submitSample = submit }(submitSample)
+	submitSample = func(sample coreMetrics.MetricSample) bool {
+		samples = append(samples, sample)
+		return true
+	}
+
+	extract := &config.ProcessingRule{Type: config.ExtractFields, Name: "extract", Pattern: `status=%{INT:status:int}`}
+	rule := &config.ProcessingRule{Type: config.LogToMetric, Name: "status", MetricName: "app.status", MetricType: config.MetricTypeDistribution, ValueField: "status"}
+	rules := []*config.ProcessingRule{extract, rule}
+	assert.Nil(t, config.CompileProcessingRules(rules))
+	p := &Processor{processingRules: rules}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+
+	p.applyRedactingRules(newMessage([]byte("status=503"), source, ""))
+	// logs without the value are skipped
+	p.applyRedactingRules(newMessage([]byte("no status"), source, ""))
+
+	assert.Len(t, samples, 1)
+	assert.Equal(t, float64(503), samples[0].Value)
+	assert.Equal(t, int64(1), source.GeneratedMetrics.Get("app.status"))
+}
+
+func TestMetricSubmitterBatchesSamples(t *testing.T) {
+	batches := make(chan []coreMetrics.MetricSample, 3)
+	s := newMetricSubmitter(func(samples []coreMetrics.MetricSample) error {
+		batches <- append([]coreMetrics.MetricSample(nil), samples...)
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, s.submit(coreMetrics.MetricSample{Name: "app.errors", Value: float64(i)}))
+	}
+	var received []coreMetrics.MetricSample
+	for len(received) < 3 {
+		batch := <-batches
+		assert.LessOrEqual(t, len(batch), metricBatchSize)
+		received = append(received, batch...)
+	}
+	for i, sample := range received {
+		assert.Equal(t, float64(i), sample.Value)
+	}
+}
+
+func TestMetricSubmitterDropsSamplesWhenFull(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	s := newMetricSubmitter(func(samples []coreMetrics.MetricSample) error {
+		<-block
+		return nil
+	})
+
+	// the submitter holds at most a batch being sent and a full buffer
+	dropped := 0
+	for i := 0; i < metricSubmitterBufferSize+metricBatchSize+1; i++ {
+		if !s.submit(coreMetrics.MetricSample{Name: "app.errors", Value: 1}) {
+			dropped++
+		}
+	}
+	assert.NotZero(t, dropped)
+}
+
 func TestTruncate(t *testing.T) {
 	p := &Processor{}
 
@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// sample returns true if the message should be kept by the sample rule.
+func sample(rule *config.ProcessingRule) bool {
+	return rand.Float64() < rule.SampleRate
+}
+
+// rateLimit returns true if the message should be kept by the rate_limit rule,
+// each source, and each combination of the group_by values, having its own budget.
+func rateLimit(msg *message.Message, rule *config.ProcessingRule) bool {
+	return rule.RateLimiter.Allow(rateLimitKey(msg, rule.GroupBy))
+}
+
+func rateLimitKey(msg *message.Message, groupBy []string) string {
+	var b strings.Builder
+	b.WriteString(msg.Origin.LogSource.Name)
+	if len(groupBy) == 0 {
+		return b.String()
+	}
+	tags := msg.Origin.Tags()
+	for _, name := range groupBy {
+		b.WriteByte('|')
+		b.WriteString(groupValue(msg, tags, name))
+	}
+	return b.String()
+}
+
+// groupValue returns the value of the given attribute, or of the tag with the given name.
+func groupValue(msg *message.Message, tags []string, name string) string {
+	if value, found := msg.Attributes[name]; found {
+		return fmt.Sprint(value)
+	}
+	prefix := name + ":"
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, prefix) {
+			return tag[len(prefix):]
+		}
+	}
+	return ""
+}
+
+// recordDroppedByRule reports a log dropped by a sample or rate_limit rule.
+func recordDroppedByRule(msg *message.Message, rule *config.ProcessingRule) {
+	switch rule.Type {
+	case config.Sample:
+		metrics.LogsSampledOut.Add(1)
+	case config.RateLimit:
+		metrics.LogsRateLimited.Add(1)
+	}
+	metrics.TlmLogsDroppedByRule.Inc(rule.Type, rule.Name)
+	msg.Origin.LogSource.RecordDroppedLog(rule.Name)
+}
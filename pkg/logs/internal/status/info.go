@@ -7,6 +7,7 @@ package status
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"go.uber.org/atomic"
@@ -113,3 +114,49 @@ func (m *MappedInfo) Info() []string {
 	}
 	return info
 }
+
+// KeyedCountInfo records counts grouped by key
+type KeyedCountInfo struct {
+	key    string
+	counts map[string]int64
+	lock   sync.Mutex
+}
+
+// NewKeyedCountInfo creates a new KeyedCountInfo instance
+func NewKeyedCountInfo(key string) *KeyedCountInfo {
+	return &KeyedCountInfo{
+		key:    key,
+		counts: make(map[string]int64),
+	}
+}
+
+// Add a new value to the count of the given key
+func (c *KeyedCountInfo) Add(key string, v int64) {
+	defer c.lock.Unlock()
+	c.lock.Lock()
+	c.counts[key] += v
+}
+
+// Get the count of the given key
+func (c *KeyedCountInfo) Get(key string) int64 {
+	defer c.lock.Unlock()
+	c.lock.Lock()
+	return c.counts[key]
+}
+
+// InfoKey returns the key
+func (c *KeyedCountInfo) InfoKey() string {
+	return c.key
+}
+
+// Info returns the info, sorted by key
+func (c *KeyedCountInfo) Info() []string {
+	defer c.lock.Unlock()
+	c.lock.Lock()
+	info := make([]string, 0, len(c.counts))
+	for k, v := range c.counts {
+		info = append(info, fmt.Sprintf("%s: %d", k, v))
+	}
+	sort.Strings(info)
+	return info
+}
@@ -317,7 +317,9 @@ func (t *Tailer) forwardMessages() {
 			t.setLastSince(output.Timestamp)
 			origin.Identifier = t.Identifier()
 			origin.SetTags(t.tagProvider.GetTags())
-			t.outputChan <- message.NewMessage(output.Content, origin, output.Status, output.IngestionTimestamp)
+			msg := message.NewMessage(output.Content, origin, output.Status, output.IngestionTimestamp)
+			output.Structured.ApplyTo(msg)
+			t.outputChan <- msg
 		}
 	}
 }
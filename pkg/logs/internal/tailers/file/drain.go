@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"compress/gzip"
+	"io"
+	"path/filepath"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// setupDrain sets up a tailer created with NewDrainTailer, the offset being
+// relative to the uncompressed content of the rotated file.
+func (t *Tailer) setupDrain(offset int64) error {
+	fullpath, err := filepath.Abs(t.file.Path)
+	if err != nil {
+		return err
+	}
+	t.fullpath = fullpath
+
+	// the messages are tagged as coming from the tailed file
+	t.tags = t.buildTailerTags()
+
+	log.Info("Draining", t.drainPath, "for tailer key", t.file.GetScanKey(), "from offset", offset)
+	f, err := filesystem.OpenShared(t.drainPath)
+	if err != nil {
+		return err
+	}
+
+	identity, err := fileIdentity(f, isCompressed(t.drainPath), FingerprintSize)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	t.setFileIdentity(identity)
+
+	if isCompressed(t.drainPath) {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		// a compressed file can't be seeked, skip the content already read instead
+		offset, _ = io.CopyN(io.Discard, gz, offset)
+		t.reader = gz
+	} else {
+		offset, _ = f.Seek(offset, io.SeekStart)
+		t.reader = f
+	}
+
+	t.osFile = f
+	t.lastReadOffset.Store(offset)
+	t.decodedOffset.Store(offset)
+
+	return nil
+}
+
+// readDrain reads the content of the rotated file, returning io.EOF once it has all
+// been read since nothing is written to a rotated file anymore.
+func (t *Tailer) readDrain() (int, error) {
+	inBuf := make([]byte, 4096)
+	n, err := t.reader.Read(inBuf)
+	if n > 0 {
+		t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+		t.lastReadOffset.Add(int64(n))
+		return n, nil
+	}
+	if err == nil {
+		return 0, nil
+	}
+	if err != io.EOF {
+		// e.g. a file being compressed, there is nothing more to read from it
+		log.Warnf("Stopped draining %s: %v", t.drainPath, err)
+	}
+	return 0, io.EOF
+}
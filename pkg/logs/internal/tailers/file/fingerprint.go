@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"compress/gzip"
+	"hash/crc64"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+)
+
+// FingerprintSize is the maximum number of bytes at the beginning of a file used to fingerprint it.
+const FingerprintSize = 1024
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// fingerprint returns a checksum of the first size bytes read from r, or of the bytes
+// available when there are less.
+func fingerprint(r io.Reader, size int64) message.Fingerprint {
+	buf := make([]byte, size)
+	n, err := io.ReadFull(r, buf)
+	if n == 0 || (err != nil && err != io.ErrUnexpectedEOF) {
+		return message.Fingerprint{}
+	}
+	return message.Fingerprint{Checksum: crc64.Checksum(buf[:n], crc64Table), Size: int64(n)}
+}
+
+// isCompressed returns true if the file at path is gzip-compressed.
+func isCompressed(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+// ComputeFileIdentity returns the identity of the file at path, its fingerprint being
+// computed on its first fingerprintSize bytes at most. The fingerprint of a compressed
+// file is computed on its uncompressed content so that it matches the fingerprint of the
+// file it was compressed from.
+func ComputeFileIdentity(path string, fingerprintSize int64) (message.FileIdentity, error) {
+	f, err := filesystem.OpenShared(path)
+	if err != nil {
+		return message.FileIdentity{}, err
+	}
+	defer f.Close()
+	return fileIdentity(f, isCompressed(path), fingerprintSize)
+}
+
+func fileIdentity(f *os.File, compressed bool, fingerprintSize int64) (message.FileIdentity, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return message.FileIdentity{}, err
+	}
+	identity := message.FileIdentity{Inode: inode(fi)}
+	if !compressed {
+		identity.Fingerprint = fingerprint(io.NewSectionReader(f, 0, fingerprintSize), fingerprintSize)
+		return identity, nil
+	}
+	gz, err := gzip.NewReader(io.NewSectionReader(f, 0, fi.Size()))
+	if err != nil {
+		return message.FileIdentity{}, err
+	}
+	defer gz.Close()
+	identity.Fingerprint = fingerprint(gz, fingerprintSize)
+	return identity, nil
+}
+
+// FileIdentity returns the identity of the file read by the tailer.
+func (t *Tailer) FileIdentity() message.FileIdentity {
+	t.fingerprintLock.Lock()
+	defer t.fingerprintLock.Unlock()
+	return message.FileIdentity{Inode: t.inode, Fingerprint: t.fingerprint}
+}
+
+func (t *Tailer) setFileIdentity(identity message.FileIdentity) {
+	t.fingerprintLock.Lock()
+	defer t.fingerprintLock.Unlock()
+	t.inode = identity.Inode
+	t.fingerprint = identity.Fingerprint
+}
+
+// refreshFingerprint fingerprints the file again once more bytes than the ones
+// covered by its fingerprint have been read, until FingerprintSize is reached. The
+// fingerprint is left unchanged if the file no longer starts with the fingerprinted
+// bytes, so that the rotation of the file can be detected.
+func (t *Tailer) refreshFingerprint(offset int64) {
+	t.fingerprintLock.Lock()
+	defer t.fingerprintLock.Unlock()
+	if t.fingerprint.Size >= FingerprintSize || offset <= t.fingerprint.Size {
+		return
+	}
+	f := t.osFile
+	if f == nil {
+		// the file is not kept open on Windows
+		var err error
+		if f, err = filesystem.OpenShared(t.fullpath); err != nil {
+			return
+		}
+		defer f.Close()
+	}
+	if t.fingerprint.Size > 0 && fingerprint(io.NewSectionReader(f, 0, t.fingerprint.Size), t.fingerprint.Size) != t.fingerprint {
+		return
+	}
+	t.fingerprint = fingerprint(io.NewSectionReader(f, 0, FingerprintSize), FingerprintSize)
+}
+
+// hasSameContent returns whether the file f starts with the bytes fingerprinted by the tailer,
+// known being false when the tailer has no fingerprint to compare with.
+func (t *Tailer) hasSameContent(f *os.File) (same bool, known bool) {
+	current := t.FileIdentity().Fingerprint
+	if current.Size == 0 {
+		return false, false
+	}
+	return fingerprint(io.NewSectionReader(f, 0, current.Size), current.Size) == current, true
+}
@@ -0,0 +1,18 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build windows
+// +build windows
+
+package file
+
+import (
+	"os"
+)
+
+// inode returns 0 as files are only identified by their fingerprint on Windows.
+func inode(fi os.FileInfo) uint64 {
+	return 0
+}
@@ -10,6 +10,7 @@ package file
 
 import (
 	"os"
+	"syscall"
 
 	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -21,6 +22,10 @@ import (
 // - renamed and recreated
 // - removed and recreated
 // - truncated
+//
+// A file whose content no longer starts with the fingerprinted bytes is considered as
+// rotated as well, while a file reported with another inode is not when it has the same
+// fingerprint, size and modification time as the opened file, which is still linked.
 func (t *Tailer) DidRotate() (bool, error) {
 	f, err := filesystem.OpenShared(t.osFile.Name())
 	if err != nil {
@@ -43,12 +48,36 @@ func (t *Tailer) DidRotate() (bool, error) {
 
 	recreated := !os.SameFile(fi1, fi2)
 	truncated := fileSize < lastReadOffset
+	rewritten := false
+
+	if sameContent, known := t.hasSameContent(f); known {
+		if recreated && sameContent && t.FileIdentity().Fingerprint.Size == FingerprintSize && !truncated && isSameFile(fi1, fi2) {
+			// the file starts with the same content, it is the same file reported with another
+			// inode, e.g. by an overlay filesystem
+			log.Debugf("File %s reported with another inode has the same fingerprint, f1: %+v, f2: %+v", t.file.Path, fi1, fi2)
+			recreated = false
+		}
+		// the file has been truncated and written again since the last scan, e.g. by copytruncate
+		rewritten = !recreated && !truncated && !sameContent
+	}
 
 	if recreated {
 		log.Debugf("File rotation detected due to recreation, f1: %+v, f2: %+v", fi1, fi2)
 	} else if truncated {
 		log.Debugf("File rotation detected due to size change, lastReadOffset=%d, fileSize=%d", lastReadOffset, fileSize)
+	} else if rewritten {
+		log.Debugf("File rotation detected due to fingerprint change, lastReadOffset=%d, fileSize=%d", lastReadOffset, fileSize)
 	}
 
-	return recreated || truncated, nil
+	return recreated || truncated || rewritten, nil
+}
+
+// isSameFile returns true if the file at the path, reported with another inode than the
+// opened file, is the opened file.
+func isSameFile(current, opened os.FileInfo) bool {
+	if stat, ok := opened.Sys().(*syscall.Stat_t); ok && stat.Nlink == 0 {
+		// the opened file has been removed, the file at the path is a new one
+		return false
+	}
+	return current.Size() == opened.Size() && current.ModTime().Equal(opened.ModTime())
 }
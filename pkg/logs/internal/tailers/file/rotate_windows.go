@@ -16,7 +16,8 @@ import (
 // DidRotate returns true if the file has been log-rotated.
 //
 // On Windows, log rotation is identified by the file size being smaller
-// than the last offset read.
+// than the last offset read, or by the file no longer starting with the
+// fingerprinted bytes.
 func (t *Tailer) DidRotate() (bool, error) {
 	f, err := filesystem.OpenShared(t.fullpath)
 	if err != nil {
@@ -43,5 +44,10 @@ func (t *Tailer) DidRotate() (bool, error) {
 		return true, nil
 	}
 
+	if sameContent, known := t.hasSameContent(f); known && !sameContent {
+		log.Debugf("File rotation detected due to fingerprint change, lastReadOffset=%d, fileSize=%d", offset, sz)
+		return true, nil
+	}
+
 	return false, nil
 }
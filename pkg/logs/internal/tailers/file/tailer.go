@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"sync"
 	"time"
 
 	"go.uber.org/atomic"
@@ -57,6 +58,18 @@ type Tailer struct {
 	// is platform-specific.
 	osFile *os.File
 
+	// inode and fingerprint identify the file being read, see message.FileIdentity.
+	// The fingerprint is computed again as data is read as long as the file is
+	// shorter than FingerprintSize.
+	inode           uint64
+	fingerprint     message.Fingerprint
+	fingerprintLock sync.Mutex
+
+	// drainPath is the path of the rotated file read in place of file.Path by a tailer
+	// created with NewDrainTailer, reader being the reader of its (uncompressed) content.
+	drainPath string
+	reader    io.Reader
+
 	// tags are the tags to be attached to each log message, excluding tags provided
 	// by the tag provider.
 	tags []string
@@ -171,9 +184,23 @@ func (t *Tailer) Identifier() string {
 	return fmt.Sprintf("file:%s", t.file.Path)
 }
 
+// NewDrainTailer creates a new tailer reading the rotated file at rotatedPath until
+// its end, writing messages to the same channel as this one. The messages are attributed
+// to file, so that the offsets in the rotated file are committed under its identifier.
+func (t *Tailer) NewDrainTailer(file *File, rotatedPath string, decoder *decoder.Decoder) *Tailer {
+	tailer := NewTailer(t.outputChan, file, t.sleepDuration, decoder)
+	tailer.drainPath = rotatedPath
+	return tailer
+}
+
 // Start begins the tailer's operation in a dedicated goroutine.
 func (t *Tailer) Start(offset int64, whence int) error {
-	err := t.setup(offset, whence)
+	var err error
+	if t.drainPath != "" {
+		err = t.setupDrain(offset)
+	} else {
+		err = t.setup(offset, whence)
+	}
 	if err != nil {
 		t.file.Source.Status().Error(err)
 		return err
@@ -236,8 +263,12 @@ func (t *Tailer) readForever() {
 		log.Info("Closed", t.file.Path, "for tailer key", t.file.GetScanKey(), "read", t.Source().BytesRead.Get(), "bytes and", t.decoder.GetLineCount(), "lines")
 	}()
 
+	read := t.read
+	if t.drainPath != "" {
+		read = t.readDrain
+	}
 	for {
-		n, err := t.read()
+		n, err := read()
 		if err != nil {
 			return
 		}
@@ -290,20 +321,26 @@ func (t *Tailer) forwardMessages() {
 			identifier = ""
 		}
 		t.decodedOffset.Store(offset)
+		if t.drainPath == "" {
+			t.refreshFingerprint(offset)
+		}
 		origin := message.NewOrigin(t.file.Source.UnderlyingSource())
 		origin.Identifier = identifier
 		origin.Offset = strconv.FormatInt(offset, 10)
+		origin.FileIdentity = t.FileIdentity()
 		origin.SetTags(append(t.tags, t.tagProvider.GetTags()...))
 		// Ignore empty lines once the registry offset is updated
 		if len(output.Content) == 0 {
 			continue
 		}
+		msg := message.NewMessage(output.Content, origin, output.Status, output.IngestionTimestamp)
+		output.Structured.ApplyTo(msg)
 		// Make the write to the output chan cancellable to be able to stop the tailer
 		// after a file rotation when it is stuck on it.
 		// We don't return directly to keep the same shutdown sequence that in the
 		// normal case.
 		select {
-		case t.outputChan <- message.NewMessage(output.Content, origin, output.Status, output.IngestionTimestamp):
+		case t.outputChan <- msg:
 		case <-t.forwardContext.Done():
 		}
 	}
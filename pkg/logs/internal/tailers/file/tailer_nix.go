@@ -34,6 +34,13 @@ func (t *Tailer) setup(offset int64, whence int) error {
 		return err
 	}
 
+	identity, err := fileIdentity(f, false, FingerprintSize)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	t.setFileIdentity(identity)
+
 	t.osFile = f
 	ret, _ := f.Seek(offset, whence)
 	t.lastReadOffset.Store(ret)
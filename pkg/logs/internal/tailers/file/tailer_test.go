@@ -9,15 +9,19 @@
 package file
 
 import (
+	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
@@ -328,6 +332,142 @@ func (suite *TailerTestSuite) TestMutliLineAutoDetect() {
 	suite.Equal(suite.tailer.GetDetectedPattern(), expectedRegex)
 }
 
+func (suite *TailerTestSuite) TestDrainCompressedRotatedFile() {
+	lines := []string{"hello world\n", "hello again\n", "good bye\n"}
+
+	rotatedPath := suite.testPath + ".1.gz"
+	writeCompressedFile(suite.T(), rotatedPath, lines[0]+lines[1]+lines[2])
+
+	suite.tailer.StartFromBeginning()
+	drainTailer := suite.tailer.NewDrainTailer(suite.tailer.file, rotatedPath, decoder.NewDecoderFromSource(suite.source))
+	suite.Nil(drainTailer.Start(int64(len(lines[0])), io.SeekStart))
+
+	msg := <-suite.outputChan
+	suite.Equal("hello again", string(msg.Content))
+	suite.Equal(len(lines[0])+len(lines[1]), toInt(msg.Origin.Offset))
+	suite.Equal(suite.tailer.Identifier(), msg.Origin.Identifier)
+	suite.Equal(drainTailer.FileIdentity(), msg.Origin.FileIdentity)
+	suite.Contains(msg.Origin.Tags(), "filename:tailer.log")
+
+	msg = <-suite.outputChan
+	suite.Equal("good bye", string(msg.Content))
+	suite.Equal(len(lines[0])+len(lines[1])+len(lines[2]), toInt(msg.Origin.Offset))
+
+	// the tailer stops by itself at the end of the rotated file
+	suite.Eventually(drainTailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+	drainTailer.Stop()
+}
+
+func (suite *TailerTestSuite) TestFileIdentity() {
+	line := "a log line\n"
+	lineCount := FingerprintSize/len(line) + 1
+	content := strings.Repeat(line, lineCount)
+
+	_, err := suite.testFile.WriteString(line)
+	suite.Nil(err)
+	suite.tailer.StartFromBeginning()
+	<-suite.outputChan
+	identity := suite.tailer.FileIdentity()
+	suite.NotZero(identity.Inode)
+	// the file is shorter than the fingerprint size
+	suite.Equal(int64(len(line)), identity.Fingerprint.Size)
+
+	_, err = suite.testFile.WriteString(content[len(line):])
+	suite.Nil(err)
+	for i := 1; i < lineCount; i++ {
+		<-suite.outputChan
+	}
+	suite.Equal(int64(FingerprintSize), suite.tailer.FileIdentity().Fingerprint.Size)
+	suite.NotEqual(identity.Fingerprint.Checksum, suite.tailer.FileIdentity().Fingerprint.Checksum)
+	suite.Equal(identity.Inode, suite.tailer.FileIdentity().Inode)
+
+	// a compressed copy of the file has the same fingerprint
+	rotatedPath := suite.testPath + ".1.gz"
+	writeCompressedFile(suite.T(), rotatedPath, content)
+	rotatedIdentity, err := ComputeFileIdentity(rotatedPath, FingerprintSize)
+	suite.Nil(err)
+	suite.Equal(suite.tailer.FileIdentity().Fingerprint, rotatedIdentity.Fingerprint)
+	suite.NotEqual(identity.Inode, rotatedIdentity.Inode)
+	suite.True(rotatedIdentity.Matches(suite.tailer.FileIdentity()))
+
+	// as well as the beginning of the file, on the number of bytes of the first fingerprint
+	rotatedIdentity, err = ComputeFileIdentity(rotatedPath, identity.Fingerprint.Size)
+	suite.Nil(err)
+	suite.Equal(identity.Fingerprint, rotatedIdentity.Fingerprint)
+}
+
+func (suite *TailerTestSuite) TestDidRotateWhenFileIsRewritten() {
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+	suite.tailer.StartFromBeginning()
+	<-suite.outputChan
+
+	// the file is truncated then written again with more bytes than read so far
+	suite.Nil(suite.testFile.Truncate(0))
+	_, err = suite.testFile.WriteAt([]byte("good bye world\n"), 0)
+	suite.Nil(err)
+
+	didRotate, err := suite.tailer.DidRotate()
+	suite.Nil(err)
+	suite.True(didRotate)
+}
+
+func (suite *TailerTestSuite) TestDidRotateWhenFileIsRecreatedWithSameContent() {
+	lineCount := FingerprintSize/10 + 1
+	content := strings.Repeat("a log line\n", lineCount)
+	_, err := suite.testFile.WriteString(content)
+	suite.Nil(err)
+	suite.tailer.StartFromBeginning()
+	for i := 0; i < lineCount; i++ {
+		<-suite.outputChan
+	}
+
+	// a new file starting with the same content
+	copyPath := suite.testPath + ".copy"
+	suite.Nil(os.WriteFile(copyPath, []byte(content+"another log line\n"), 0644))
+	suite.Nil(os.Rename(copyPath, suite.testPath))
+
+	didRotate, err := suite.tailer.DidRotate()
+	suite.Nil(err)
+	suite.True(didRotate)
+}
+
+func TestIsSameFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.log")
+	require.NoError(t, os.WriteFile(path, []byte("a log line\n"), 0644))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	current, err := os.Stat(path)
+	require.NoError(t, err)
+	opened, err := f.Stat()
+	require.NoError(t, err)
+	assert.True(t, isSameFile(current, opened))
+
+	// a file with another size
+	require.NoError(t, os.WriteFile(path+".new", []byte("another log line\n"), 0644))
+	current, err = os.Stat(path + ".new")
+	require.NoError(t, err)
+	assert.False(t, isSameFile(current, opened))
+
+	// the opened file has been removed
+	require.NoError(t, os.Remove(path))
+	opened, err = f.Stat()
+	require.NoError(t, err)
+	assert.False(t, isSameFile(opened, opened))
+}
+
+func writeCompressedFile(t *testing.T, path string, content string) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	w := gzip.NewWriter(f)
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+}
+
 func toInt(str string) int {
 	if value, err := strconv.ParseInt(str, 10, 64); err == nil {
 		return int(value)
@@ -35,6 +35,13 @@ func (t *Tailer) setup(offset int64, whence int) error {
 	if err != nil {
 		return err
 	}
+	identity, err := fileIdentity(f, false, FingerprintSize)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	t.setFileIdentity(identity)
+
 	filePos, _ := f.Seek(offset, whence)
 	f.Close()
 
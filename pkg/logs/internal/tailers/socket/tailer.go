@@ -11,8 +11,11 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/framer"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/noop"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/syslog"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 )
@@ -36,12 +39,21 @@ func NewTailer(source *sources.LogSource, conn net.Conn, outputChan chan *messag
 		Conn:       conn,
 		outputChan: outputChan,
 		read:       read,
-		decoder:    decoder.InitializeDecoder(sources.NewReplaceableSource(source), noop.New()),
+		decoder:    newDecoder(source),
 		stop:       make(chan struct{}, 1),
 		done:       make(chan struct{}, 1),
 	}
 }
 
+// newDecoder returns a decoder parsing syslog messages for syslog sources,
+// and plain lines otherwise.
+func newDecoder(source *sources.LogSource) *decoder.Decoder {
+	if source.Config.Type == config.SyslogType {
+		return decoder.NewDecoderWithFraming(sources.NewReplaceableSource(source), syslog.New(), framer.SyslogStream, nil)
+	}
+	return decoder.InitializeDecoder(sources.NewReplaceableSource(source), noop.New())
+}
+
 // Start prepares the tailer to read and decode data from the connection
 func (t *Tailer) Start() {
 	go t.forwardMessages()
@@ -64,7 +76,16 @@ func (t *Tailer) forwardMessages() {
 	}()
 	for output := range t.decoder.OutputChan {
 		if len(output.Content) > 0 {
-			t.outputChan <- message.NewMessageWithSource(output.Content, message.StatusInfo, t.source, output.IngestionTimestamp)
+			status := output.Status
+			if status == "" {
+				status = message.StatusInfo
+			}
+			msg := message.NewMessageWithSource(output.Content, status, t.source, output.IngestionTimestamp)
+			if len(output.Tags) > 0 {
+				msg.Origin.SetTags(output.Tags)
+			}
+			output.Structured.ApplyTo(msg)
+			t.outputChan <- msg
 		}
 	}
 }
@@ -58,6 +58,31 @@ func TestReadShouldFailWithError(t *testing.T) {
 	tailer.Stop()
 }
 
+func TestReadAndForwardSyslogMessages(t *testing.T) {
+	msgChan := make(chan *message.Message)
+	r, w := net.Pipe()
+	tailer := NewTailer(sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType}), r, msgChan, read)
+	tailer.Start()
+
+	var msg *message.Message
+
+	// octet-counted frame
+	w.Write([]byte("30 <11>1 - host app - - - failure"))
+	msg = <-msgChan
+	assert.Equal(t, "failure", string(msg.Content))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, []string{"syslog.facility:user", "syslog.hostname:host", "syslog.appname:app"}, msg.Origin.Tags())
+
+	// newline-terminated frame
+	w.Write([]byte("<38>Oct 11 22:14:15 host sshd[42]: accepted\n"))
+	msg = <-msgChan
+	assert.Equal(t, "accepted", string(msg.Content))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	assert.Equal(t, []string{"syslog.facility:auth", "syslog.hostname:host", "syslog.procid:42", "syslog.appname:sshd"}, msg.Origin.Tags())
+
+	tailer.Stop()
+}
+
 func read(tailer *Tailer) ([]byte, error) {
 	inBuf := make([]byte, 4096)
 	n, err := tailer.Conn.Read(inBuf)
@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package message
+
+// FileIdentity identifies the content of a file independently of its path, so that
+// an offset can still be attributed to the right file after it has been rotated.
+type FileIdentity struct {
+	// Inode is the inode of the file, zero when unknown (e.g. on Windows).
+	Inode uint64
+	// Fingerprint is a checksum of the first bytes of the file, empty when the file
+	// was empty.
+	Fingerprint Fingerprint
+}
+
+// Fingerprint is a checksum of the first bytes of a file.
+type Fingerprint struct {
+	// Checksum is the CRC-64 checksum of the first Size bytes of the file.
+	Checksum uint64
+	// Size is the number of bytes covered by the checksum. It is lower than the
+	// size used by the tailer when the file was shorter when it was fingerprinted.
+	Size int64
+}
+
+// IsZero returns true if nothing is known about the file.
+func (i FileIdentity) IsZero() bool {
+	return i.Inode == 0 && i.Fingerprint.Size == 0
+}
+
+// Matches returns true if both identities designate the same file. The fingerprints
+// are compared when both are known since they survive compression and copies,
+// otherwise the inodes are.
+func (i FileIdentity) Matches(other FileIdentity) bool {
+	if i.Fingerprint.Size > 0 && other.Fingerprint.Size > 0 {
+		return i.Fingerprint == other.Fingerprint
+	}
+	return i.Inode != 0 && i.Inode == other.Inode
+}
@@ -0,0 +1,30 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package message
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileIdentityMatches(t *testing.T) {
+	fingerprint := Fingerprint{Checksum: 42, Size: 1024}
+
+	assert.True(t, FileIdentity{}.IsZero())
+	assert.False(t, FileIdentity{Inode: 1}.IsZero())
+	assert.False(t, FileIdentity{Fingerprint: fingerprint}.IsZero())
+
+	// the fingerprints are compared first
+	assert.True(t, FileIdentity{Inode: 1, Fingerprint: fingerprint}.Matches(FileIdentity{Inode: 2, Fingerprint: fingerprint}))
+	assert.False(t, FileIdentity{Inode: 1, Fingerprint: fingerprint}.Matches(FileIdentity{Inode: 1, Fingerprint: Fingerprint{Checksum: 43, Size: 1024}}))
+	assert.False(t, FileIdentity{Inode: 1, Fingerprint: fingerprint}.Matches(FileIdentity{Inode: 1, Fingerprint: Fingerprint{Checksum: 42, Size: 12}}))
+
+	// then the inodes
+	assert.True(t, FileIdentity{Inode: 1, Fingerprint: fingerprint}.Matches(FileIdentity{Inode: 1}))
+	assert.False(t, FileIdentity{Inode: 1}.Matches(FileIdentity{Inode: 2}))
+	assert.False(t, FileIdentity{}.Matches(FileIdentity{}))
+}
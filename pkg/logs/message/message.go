@@ -37,6 +37,9 @@ type Message struct {
 	// Optional.
 	// Used in the Serverless Agent
 	Lambda *Lambda
+	// Optional.
+	// Structured attributes extracted from the content, sent alongside the message
+	Attributes map[string]interface{}
 }
 
 // Lambda is a struct storing information about the Lambda function and function execution.
@@ -84,6 +87,14 @@ func (m *Message) GetStatus() string {
 	return m.status
 }
 
+// SetAttribute sets a structured attribute on the message.
+func (m *Message) SetAttribute(key string, value interface{}) {
+	if m.Attributes == nil {
+		m.Attributes = make(map[string]interface{})
+	}
+	m.Attributes[key] = value
+}
+
 // GetLatency returns the latency delta from ingestion time until now
 func (m *Message) GetLatency() int64 {
 	return time.Now().UnixNano() - m.IngestionTimestamp
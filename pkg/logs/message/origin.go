@@ -16,9 +16,11 @@ type Origin struct {
 	Identifier string
 	LogSource  *sources.LogSource
 	Offset     string
-	service    string
-	source     string
-	tags       []string
+	// FileIdentity identifies the file the offset belongs to, it is only set by file tailers.
+	FileIdentity FileIdentity
+	service      string
+	source       string
+	tags         []string
 }
 
 // NewOrigin returns a new Origin
@@ -90,6 +92,15 @@ func (o *Origin) SetTags(tags []string) {
 	o.tags = tags
 }
 
+// AddTags appends tags to the origin.
+func (o *Origin) AddTags(tags ...string) {
+	// the current slice may share its backing array with other origins,
+	// always copy it before appending.
+	newTags := make([]string, 0, len(o.tags)+len(tags))
+	newTags = append(newTags, o.tags...)
+	o.tags = append(newTags, tags...)
+}
+
 // SetSource sets the source of the origin.
 func (o *Origin) SetSource(source string) {
 	o.source = source
@@ -11,6 +11,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/otlp"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/tcp"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
@@ -35,7 +36,8 @@ func NewPipeline(outputChan chan *message.Payload,
 	destinationsContext *client.DestinationsContext,
 	diagnosticMessageReceiver diagnostic.MessageReceiver,
 	serverless bool,
-	pipelineID int) *Pipeline {
+	pipelineID int,
+	diskBuffer *sender.DiskBuffer) *Pipeline {
 
 	mainDestinations := getDestinations(endpoints, destinationsContext, pipelineID)
 
@@ -57,7 +59,7 @@ func NewPipeline(outputChan chan *message.Payload,
 	}
 
 	strategy := getStrategy(strategyInput, senderInput, flushChan, endpoints, serverless, pipelineID)
-	logsSender = sender.NewSender(senderInput, outputChan, mainDestinations, config.DestinationPayloadChanSize)
+	logsSender = sender.NewSenderWithDiskBuffer(senderInput, outputChan, mainDestinations, config.DestinationPayloadChanSize, diskBuffer)
 
 	inputChan := make(chan *message.Message, config.ChanSize)
 	processor := processor.New(inputChan, strategyInput, processingRules, encoder, diagnosticMessageReceiver)
@@ -95,6 +97,14 @@ func getDestinations(endpoints *config.Endpoints, destinationsContext *client.De
 	reliable := []client.Destination{}
 	additionals := []client.Destination{}
 
+	if endpoints.OTLP != nil {
+		if endpoints.OTLP.GetIsReliable() {
+			reliable = append(reliable, otlp.NewDestination(*endpoints.OTLP, destinationsContext, true))
+		} else {
+			additionals = append(additionals, otlp.NewDestination(*endpoints.OTLP, destinationsContext, false))
+		}
+	}
+
 	if endpoints.UseHTTP {
 		for i, endpoint := range endpoints.GetReliableEndpoints() {
 			telemetryName := fmt.Sprintf("logs_%d_reliable_%d", pipelineID, i)
@@ -16,6 +16,8 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/DataDog/datadog-agent/pkg/util/startstop"
 )
 
@@ -78,13 +80,28 @@ func (p *provider) Start() {
 	// This requires the auditor to be started before.
 	p.outputChan = p.auditor.Channel()
 
+	diskBuffer := p.newDiskBuffer()
 	for i := 0; i < p.numberOfPipelines; i++ {
-		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, i)
+		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, i, diskBuffer)
 		pipeline.Start()
 		p.pipelines = append(p.pipelines, pipeline)
 	}
 }
 
+// newDiskBuffer returns the disk buffer shared by the pipelines, or nil when it is disabled.
+func (p *provider) newDiskBuffer() *sender.DiskBuffer {
+	cfg := config.DiskBuffer()
+	if p.serverless || cfg.MaxSizeInBytes <= 0 {
+		return nil
+	}
+	diskBuffer, err := sender.NewDiskBuffer(cfg.Path, cfg.MaxSizeInBytes, cfg.MaxAge)
+	if err != nil {
+		log.Errorf("Could not create the logs disk buffer in %s, payloads will not be buffered on disk: %v", cfg.Path, err)
+		return nil
+	}
+	return diskBuffer
+}
+
 // Stop stops all pipelines in parallel,
 // this call blocks until all pipelines are stopped
 func (p *provider) Stop() {
@@ -0,0 +1,264 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const diskBufferExtension = ".payload"
+const diskBufferFormatVersion = 1
+
+var (
+	tlmDiskBufferStored   = telemetry.NewCounter("logs_sender", "disk_buffer_stored", nil, "Payloads stored in the disk buffer")
+	tlmDiskBufferReplayed = telemetry.NewCounter("logs_sender", "disk_buffer_replayed", nil, "Payloads of the disk buffer sent to a destination")
+	tlmDiskBufferDropped  = telemetry.NewCounter("logs_sender", "disk_buffer_dropped", []string{"reason"}, "Payloads dropped from the disk buffer")
+	tlmDiskBufferSize     = telemetry.NewGauge("logs_sender", "disk_buffer_size", nil, "Size in bytes of the disk buffer")
+)
+
+var errDiskBufferFull = errors.New("the disk buffer is full")
+
+// DiskBuffer stores on disk the encoded payloads that no reliable destination can accept,
+// until a destination recovers. Payloads are persisted before being reported to the auditor,
+// and removed only once a destination has sent them, so that the auditor offsets stay
+// consistent with what has been sent or buffered across agent restarts.
+// It is safe to share a DiskBuffer between senders.
+type DiskBuffer struct {
+	path           string
+	maxSizeInBytes int64
+	maxAge         time.Duration
+
+	mu                 sync.Mutex
+	files              []bufferedFile // oldest first
+	inFlight           map[*message.Payload]bufferedFile
+	currentSizeInBytes int64
+	lastID             int64
+}
+
+type bufferedFile struct {
+	path    string
+	size    int64
+	created time.Time
+}
+
+// NewDiskBuffer returns a DiskBuffer storing up to maxSizeInBytes bytes of payloads,
+// for at most maxAge, in the given directory. Payloads left by a previous run are reloaded.
+func NewDiskBuffer(path string, maxSizeInBytes int64, maxAge time.Duration) (*DiskBuffer, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+	b := &DiskBuffer{
+		path:           path,
+		maxSizeInBytes: maxSizeInBytes,
+		maxAge:         maxAge,
+		inFlight:       make(map[*message.Payload]bufferedFile),
+	}
+	if err := b.reloadExistingFiles(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *DiskBuffer) reloadExistingFiles() error {
+	paths, err := filepath.Glob(filepath.Join(b.path, "*"+diskBufferExtension))
+	if err != nil {
+		return err
+	}
+	// file names start with a fixed width timestamp so that they sort by creation
+	sort.Strings(paths)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Warnf("Cannot stat the buffered logs payload %s: %v", path, err)
+			continue
+		}
+		b.files = append(b.files, bufferedFile{path: path, size: info.Size(), created: info.ModTime()})
+		b.currentSizeInBytes += info.Size()
+	}
+	if len(b.files) > 0 {
+		log.Infof("Reloaded %d buffered logs payloads (%d bytes) from %s", len(b.files), b.currentSizeInBytes, b.path)
+	}
+	tlmDiskBufferSize.Set(float64(b.currentSizeInBytes))
+	return nil
+}
+
+// Store persists the payload, it returns an error when the buffer is full.
+func (b *DiskBuffer) Store(payload *message.Payload) error {
+	data := encodeBufferedPayload(payload)
+	size := int64(len(data))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.dropExpired()
+	if b.currentSizeInBytes+size > b.maxSizeInBytes {
+		return errDiskBufferFull
+	}
+
+	now := time.Now()
+	id := now.UnixNano()
+	if id <= b.lastID {
+		id = b.lastID + 1
+	}
+	b.lastID = id
+	path := filepath.Join(b.path, fmt.Sprintf("%020d%s", id, diskBufferExtension))
+	if err := writeFileSync(path, data); err != nil {
+		return err
+	}
+
+	b.files = append(b.files, bufferedFile{path: path, size: size, created: now})
+	b.currentSizeInBytes += size
+	tlmDiskBufferStored.Inc()
+	tlmDiskBufferSize.Set(float64(b.currentSizeInBytes))
+	return nil
+}
+
+// IsEmpty returns true if there is no payload waiting to be sent.
+func (b *DiskBuffer) IsEmpty() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.files) == 0
+}
+
+// Pop returns the oldest buffered payload, or nil if there is none. The payload must then be
+// either acknowledged once sent with Ack, or given back with PushBack.
+func (b *DiskBuffer) Pop() (*message.Payload, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.dropExpired()
+	if len(b.files) == 0 {
+		return nil, nil
+	}
+	file := b.files[0]
+	b.files = b.files[1:]
+
+	data, err := os.ReadFile(file.path)
+	if err == nil {
+		var payload *message.Payload
+		if payload, err = decodeBufferedPayload(data); err == nil {
+			b.inFlight[payload] = file
+			return payload, nil
+		}
+	}
+	b.remove(file, "corrupted")
+	return nil, fmt.Errorf("cannot read the buffered logs payload %s: %v", file.path, err)
+}
+
+// PushBack gives back a payload returned by Pop that could not be sent.
+func (b *DiskBuffer) PushBack(payload *message.Payload) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if file, found := b.inFlight[payload]; found {
+		delete(b.inFlight, payload)
+		b.files = append([]bufferedFile{file}, b.files...)
+	}
+}
+
+// Ack removes from the disk a payload returned by Pop once it has been sent,
+// it does nothing for payloads which were not returned by Pop.
+func (b *DiskBuffer) Ack(payload *message.Payload) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if file, found := b.inFlight[payload]; found {
+		delete(b.inFlight, payload)
+		b.remove(file, "")
+		tlmDiskBufferReplayed.Inc()
+	}
+}
+
+// dropExpired removes the payloads older than the max age, it must be called with the lock held.
+func (b *DiskBuffer) dropExpired() {
+	if b.maxAge <= 0 {
+		return
+	}
+	deadline := time.Now().Add(-b.maxAge)
+	for len(b.files) > 0 && b.files[0].created.Before(deadline) {
+		b.remove(b.files[0], "expired")
+		b.files = b.files[1:]
+	}
+}
+
+// remove deletes the file of a payload, it must be called with the lock held.
+func (b *DiskBuffer) remove(file bufferedFile, dropReason string) {
+	if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Cannot remove the buffered logs payload %s: %v", file.path, err)
+	}
+	b.currentSizeInBytes -= file.size
+	tlmDiskBufferSize.Set(float64(b.currentSizeInBytes))
+	if dropReason != "" {
+		log.Warnf("Dropping the buffered logs payload %s: %s", file.path, dropReason)
+		tlmDiskBufferDropped.Inc(dropReason)
+	}
+}
+
+// writeFileSync writes the data to a temporary file which is renamed once synced,
+// so that a crash never leaves a partial payload behind.
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
+
+// encodeBufferedPayload serializes the payload as:
+// version (1 byte) | encoding length (4 bytes) | encoding | unencoded size (8 bytes) | encoded payload
+// The messages are not kept as their offsets have already been reported to the auditor.
+func encodeBufferedPayload(payload *message.Payload) []byte {
+	data := make([]byte, 1+4+len(payload.Encoding)+8+len(payload.Encoded))
+	data[0] = diskBufferFormatVersion
+	binary.BigEndian.PutUint32(data[1:], uint32(len(payload.Encoding)))
+	offset := 5 + copy(data[5:], payload.Encoding)
+	binary.BigEndian.PutUint64(data[offset:], uint64(payload.UnencodedSize))
+	copy(data[offset+8:], payload.Encoded)
+	return data
+}
+
+func decodeBufferedPayload(data []byte) (*message.Payload, error) {
+	if len(data) < 5 || data[0] != diskBufferFormatVersion {
+		return nil, errors.New("unsupported format")
+	}
+	encodingLen := int(binary.BigEndian.Uint32(data[1:5]))
+	data = data[5:]
+	if len(data) < encodingLen+8 {
+		return nil, errors.New("truncated payload")
+	}
+	encoding := string(data[:encodingLen])
+	data = data[encodingLen:]
+	unencodedSize := int(binary.BigEndian.Uint64(data[:8]))
+	return &message.Payload{
+		Encoded:       data[8:],
+		Encoding:      encoding,
+		UnencodedSize: unencodedSize,
+	}, nil
+}
@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newBufferedPayload(content string) *message.Payload {
+	return &message.Payload{Encoded: []byte(content), Encoding: "gzip", UnencodedSize: len(content) * 2}
+}
+
+func TestDiskBufferStoreAndPop(t *testing.T) {
+	b, err := NewDiskBuffer(t.TempDir(), 1024, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, b.IsEmpty())
+
+	require.NoError(t, b.Store(newBufferedPayload("first")))
+	require.NoError(t, b.Store(newBufferedPayload("second")))
+	assert.False(t, b.IsEmpty())
+
+	payload, err := b.Pop()
+	require.NoError(t, err)
+	assert.Equal(t, newBufferedPayload("first"), payload)
+
+	// a payload given back is the next one returned
+	b.PushBack(payload)
+	payload, err = b.Pop()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("first"), payload.Encoded)
+	b.Ack(payload)
+
+	payload, err = b.Pop()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), payload.Encoded)
+	b.Ack(payload)
+
+	payload, err = b.Pop()
+	assert.NoError(t, err)
+	assert.Nil(t, payload)
+	assert.Equal(t, int64(0), b.currentSizeInBytes)
+}
+
+func TestDiskBufferReloadsUnacknowledgedPayloads(t *testing.T) {
+	path := t.TempDir()
+	b, err := NewDiskBuffer(path, 1024, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, b.Store(newBufferedPayload("sent")))
+	require.NoError(t, b.Store(newBufferedPayload("in flight")))
+	require.NoError(t, b.Store(newBufferedPayload("buffered")))
+
+	payload, err := b.Pop()
+	require.NoError(t, err)
+	b.Ack(payload)
+	_, err = b.Pop()
+	require.NoError(t, err)
+
+	// the payloads which were not acknowledged are sent after a restart
+	b, err = NewDiskBuffer(path, 1024, time.Hour)
+	require.NoError(t, err)
+	for _, expected := range []string{"in flight", "buffered"} {
+		payload, err = b.Pop()
+		require.NoError(t, err)
+		assert.Equal(t, []byte(expected), payload.Encoded)
+	}
+	assert.True(t, b.IsEmpty())
+}
+
+func TestDiskBufferMaxSize(t *testing.T) {
+	b, err := NewDiskBuffer(t.TempDir(), 40, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, b.Store(newBufferedPayload("0123456789")))
+	assert.Equal(t, errDiskBufferFull, b.Store(newBufferedPayload("0123456789")))
+
+	payload, err := b.Pop()
+	require.NoError(t, err)
+	b.Ack(payload)
+	assert.NoError(t, b.Store(newBufferedPayload("0123456789")))
+}
+
+func TestDiskBufferMaxAge(t *testing.T) {
+	path := t.TempDir()
+	b, err := NewDiskBuffer(path, 1024, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, b.Store(newBufferedPayload("expired")))
+	require.NoError(t, b.Store(newBufferedPayload("recent")))
+	b.files[0].created = time.Now().Add(-2 * time.Hour)
+
+	payload, err := b.Pop()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("recent"), payload.Encoded)
+
+	files, err := filepath.Glob(filepath.Join(path, "*"+diskBufferExtension))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestDiskBufferDropsCorruptedPayloads(t *testing.T) {
+	path := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(path, "00000000000000000001"+diskBufferExtension), []byte("garbage"), 0600))
+	b, err := NewDiskBuffer(path, 1024, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, b.Store(newBufferedPayload("valid")))
+
+	_, err = b.Pop()
+	assert.Error(t, err)
+	payload, err := b.Pop()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("valid"), payload.Encoded)
+}
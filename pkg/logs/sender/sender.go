@@ -12,8 +12,12 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
+// diskBufferReplayInterval is the interval at which the sender tries to send buffered payloads.
+const diskBufferReplayInterval = time.Second
+
 var (
 	tlmPayloadsDropped = telemetry.NewCounter("logs_sender", "payloads_dropped", []string{"reliable", "destination"}, "Payloads dropped")
 	tlmMessagesDropped = telemetry.NewCounter("logs_sender", "messages_dropped", []string{"reliable", "destination"}, "Messages dropped")
@@ -27,22 +31,32 @@ var (
 // one reliable destination is also sending logs. However they do not update
 // the auditor or block the pipeline if they fail. There will always be at
 // least 1 reliable destination (the main destination).
+// When a disk buffer is set, the payloads that no reliable destination can accept
+// are stored on disk instead of blocking the pipeline, and sent once a destination recovers.
 type Sender struct {
 	inputChan    chan *message.Payload
 	outputChan   chan *message.Payload
 	destinations *client.Destinations
 	done         chan struct{}
 	bufferSize   int
+	diskBuffer   *DiskBuffer
 }
 
 // NewSender returns a new sender.
 func NewSender(inputChan chan *message.Payload, outputChan chan *message.Payload, destinations *client.Destinations, bufferSize int) *Sender {
+	return NewSenderWithDiskBuffer(inputChan, outputChan, destinations, bufferSize, nil)
+}
+
+// NewSenderWithDiskBuffer returns a new sender buffering payloads in the given disk buffer
+// while its reliable destinations are unreachable.
+func NewSenderWithDiskBuffer(inputChan chan *message.Payload, outputChan chan *message.Payload, destinations *client.Destinations, bufferSize int, diskBuffer *DiskBuffer) *Sender {
 	return &Sender{
 		inputChan:    inputChan,
 		outputChan:   outputChan,
 		destinations: destinations,
 		done:         make(chan struct{}),
 		bufferSize:   bufferSize,
+		diskBuffer:   diskBuffer,
 	}
 }
 
@@ -59,62 +73,150 @@ func (s *Sender) Stop() {
 }
 
 func (s *Sender) run() {
-	reliableDestinations := buildDestinationSenders(s.destinations.Reliable, s.outputChan, s.bufferSize)
+	reliableOutput := s.outputChan
+	var acknowledged chan struct{}
+	var replayTick <-chan time.Time
+	if s.diskBuffer != nil {
+		// the payloads sent by the reliable destinations go through the disk buffer
+		// to remove the replayed ones from the disk
+		reliableOutput = make(chan *message.Payload, s.bufferSize)
+		acknowledged = make(chan struct{})
+		go s.acknowledgeSentPayloads(reliableOutput, acknowledged)
+
+		replayTicker := time.NewTicker(diskBufferReplayInterval)
+		defer replayTicker.Stop()
+		replayTick = replayTicker.C
+	}
+	reliableDestinations := buildDestinationSenders(s.destinations.Reliable, reliableOutput, s.bufferSize)
 
 	sink := additionalDestinationsSink(s.bufferSize)
 	unreliableDestinations := buildDestinationSenders(s.destinations.Unreliable, sink, s.bufferSize)
 
-	for payload := range s.inputChan {
-		var startInUse = time.Now()
-
-		sent := false
-		for !sent {
-			for _, destSender := range reliableDestinations {
-				if destSender.Send(payload) {
-					sent = true
-				}
+	for running := true; running; {
+		select {
+		case payload, ok := <-s.inputChan:
+			if !ok {
+				running = false
+				break
 			}
+			s.send(payload, reliableDestinations, unreliableDestinations)
+		case <-replayTick:
+			s.replayBufferedPayloads(reliableDestinations)
+		}
+	}
 
-			if !sent {
-				// Throttle the poll loop while waiting for a send to succeed
-				// This will only happen when all reliable destinations
-				// are blocked so logs have no where to go.
-				time.Sleep(100 * time.Millisecond)
+	// Cleanup the destinations
+	for _, destSender := range reliableDestinations {
+		destSender.Stop()
+	}
+	for _, destSender := range unreliableDestinations {
+		destSender.Stop()
+	}
+	close(sink)
+	if s.diskBuffer != nil {
+		close(reliableOutput)
+		<-acknowledged
+	}
+	s.done <- struct{}{}
+}
+
+func (s *Sender) send(payload *message.Payload, reliableDestinations []*DestinationSender, unreliableDestinations []*DestinationSender) {
+	var startInUse = time.Now()
+
+	sent := false
+	for !sent {
+		for _, destSender := range reliableDestinations {
+			if destSender.Send(payload) {
+				sent = true
 			}
 		}
 
-		for i, destSender := range reliableDestinations {
-			// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
-			// loss on intermittent failures.
-			if !destSender.lastSendSucceeded {
-				if !destSender.NonBlockingSend(payload) {
-					tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
-					tlmMessagesDropped.Add(float64(len(payload.Messages)), "true", strconv.Itoa(i))
-				}
+		if !sent {
+			if s.bufferOnDisk(payload) {
+				// the buffered payload is sent to the reliable destinations once they recover,
+				// and not to the unreliable ones which only send logs along with a reliable destination.
+				return
 			}
+			// Throttle the poll loop while waiting for a send to succeed
+			// This will only happen when all reliable destinations
+			// are blocked so logs have no where to go.
+			time.Sleep(100 * time.Millisecond)
 		}
+	}
 
-		// Attempt to send to unreliable destinations
-		for i, destSender := range unreliableDestinations {
+	for i, destSender := range reliableDestinations {
+		// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
+		// loss on intermittent failures.
+		if !destSender.lastSendSucceeded {
 			if !destSender.NonBlockingSend(payload) {
-				tlmPayloadsDropped.Inc("false", strconv.Itoa(i))
-				tlmMessagesDropped.Add(float64(len(payload.Messages)), "false", strconv.Itoa(i))
+				tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
+				tlmMessagesDropped.Add(float64(len(payload.Messages)), "true", strconv.Itoa(i))
 			}
 		}
+	}
+
+	// Attempt to send to unreliable destinations
+	for i, destSender := range unreliableDestinations {
+		if !destSender.NonBlockingSend(payload) {
+			tlmPayloadsDropped.Inc("false", strconv.Itoa(i))
+			tlmMessagesDropped.Add(float64(len(payload.Messages)), "false", strconv.Itoa(i))
+		}
+	}
 
-		inUse := float64(time.Since(startInUse) / time.Millisecond)
-		tlmSendWaitTime.Add(inUse)
+	inUse := float64(time.Since(startInUse) / time.Millisecond)
+	tlmSendWaitTime.Add(inUse)
+}
+
+// bufferOnDisk stores the payload in the disk buffer, if any, and reports it to the auditor
+// as the payload will be sent from the disk even if the agent restarts.
+// It returns false if the payload could not be stored.
+func (s *Sender) bufferOnDisk(payload *message.Payload) bool {
+	if s.diskBuffer == nil {
+		return false
+	}
+	if err := s.diskBuffer.Store(payload); err != nil {
+		if err != errDiskBufferFull {
+			log.Warnf("Cannot store the logs payload in the disk buffer: %v", err)
+		}
+		return false
 	}
+	s.outputChan <- payload
+	return true
+}
 
-	// Cleanup the destinations
-	for _, destSender := range reliableDestinations {
-		destSender.Stop()
+// replayBufferedPayloads sends the payloads of the disk buffer, oldest first,
+// until none is left or no reliable destination accepts them.
+func (s *Sender) replayBufferedPayloads(reliableDestinations []*DestinationSender) {
+	for {
+		payload, err := s.diskBuffer.Pop()
+		if err != nil {
+			log.Warn(err)
+			continue
+		}
+		if payload == nil {
+			return
+		}
+		sent := false
+		for _, destSender := range reliableDestinations {
+			if destSender.Send(payload) {
+				sent = true
+			}
+		}
+		if !sent {
+			s.diskBuffer.PushBack(payload)
+			return
+		}
 	}
-	for _, destSender := range unreliableDestinations {
-		destSender.Stop()
+}
+
+// acknowledgeSentPayloads removes the payloads sent by the reliable destinations from the disk buffer,
+// and forwards them to the output.
+func (s *Sender) acknowledgeSentPayloads(sent chan *message.Payload, done chan struct{}) {
+	for payload := range sent {
+		s.diskBuffer.Ack(payload)
+		s.outputChan <- payload
 	}
-	close(sink)
-	s.done <- struct{}{}
+	close(done)
 }
 
 // Drains the output channel from destinations that don't update the auditor.
@@ -7,6 +7,7 @@ package sender
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -301,3 +302,44 @@ func TestSenderReliableWhenOneFailsAndRecovers(t *testing.T) {
 	reliableServer2.Stop()
 	sender.Stop()
 }
+
+func TestSenderBuffersOnDiskWhenDestinationFails(t *testing.T) {
+	input := make(chan *message.Payload, 1)
+	output := make(chan *message.Payload, 1)
+
+	respondChan := make(chan int)
+	server := http.NewTestServerWithOptions(500, 0, true, respondChan)
+	destinations := client.NewDestinations([]client.Destination{server.Destination}, nil)
+
+	diskBuffer, err := NewDiskBuffer(t.TempDir(), 1024, time.Hour)
+	assert.NoError(t, err)
+	sender := NewSenderWithDiskBuffer(input, output, destinations, 10, diskBuffer)
+	sender.Start()
+
+	input <- &message.Payload{Encoded: []byte("first")}
+	<-respondChan // let it respond 500 once
+	<-respondChan // its in a loop now, once we respond 500 a second time we know the sender has marked the endpoint as retrying
+
+	// the payload is stored on disk and reported to the auditor instead of blocking the pipeline
+	buffered := &message.Payload{Encoded: []byte("second")}
+	input <- buffered
+	assert.Equal(t, buffered, <-output)
+	assert.False(t, diskBuffer.IsEmpty())
+
+	// Recover the server
+	server.ChangeStatus(200)
+	for {
+		if (<-respondChan) == 200 {
+			break
+		}
+	}
+	assert.Equal(t, []byte("first"), (<-output).Encoded)
+
+	// the buffered payload is sent and removed from the disk
+	<-respondChan
+	assert.Equal(t, []byte("second"), (<-output).Encoded)
+	assert.True(t, diskBuffer.IsEmpty())
+
+	server.Stop()
+	sender.Stop()
+}
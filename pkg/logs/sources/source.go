@@ -48,6 +48,8 @@ type LogSource struct {
 	// the duration between when a message is decoded by the tailer/listener/decoder and when the message is handled by a sender
 	LatencyStats     *util.StatsTracker
 	BytesRead        *status.CountInfo
+	DroppedLogs      *status.KeyedCountInfo
+	GeneratedMetrics *status.KeyedCountInfo
 	hiddenFromStatus bool
 }
 
@@ -61,12 +63,16 @@ func NewLogSource(name string, cfg *config.LogsConfig) *LogSource {
 		lock:             &sync.Mutex{},
 		Messages:         config.NewMessages(),
 		BytesRead:        status.NewCountInfo("Bytes Read"),
+		DroppedLogs:      status.NewKeyedCountInfo("Logs Dropped By Rules"),
+		GeneratedMetrics: status.NewKeyedCountInfo("Metrics Generated By Rules"),
 		info:             make(map[string]status.InfoProvider),
 		LatencyStats:     util.NewStatsTracker(time.Hour*24, time.Hour),
 		hiddenFromStatus: false,
 	}
 	source.RegisterInfo(source.BytesRead)
 	source.RegisterInfo(source.LatencyStats)
+	source.RegisterInfo(source.DroppedLogs)
+	source.RegisterInfo(source.GeneratedMetrics)
 	return source
 }
 
@@ -165,6 +171,26 @@ func (s *LogSource) RecordBytes(n int64) {
 	}
 }
 
+// RecordDroppedLog reports a log dropped by the given processing rule to the source expvars,
+// and to the parent source if any, like RecordBytes.
+func (s *LogSource) RecordDroppedLog(rule string) {
+	s.DroppedLogs.Add(rule, 1)
+
+	if s.ParentSource != nil {
+		s.ParentSource.DroppedLogs.Add(rule, 1)
+	}
+}
+
+// RecordGeneratedMetric reports a metric sample generated by a log_to_metric processing rule
+// to the source expvars, and to the parent source if any.
+func (s *LogSource) RecordGeneratedMetric(metricName string) {
+	s.GeneratedMetrics.Add(metricName, 1)
+
+	if s.ParentSource != nil {
+		s.ParentSource.GeneratedMetrics.Add(metricName, 1)
+	}
+}
+
 // Dump provides a dump of the LogSource contents, for debugging purposes.  If
 // multiline is true, the result contains newlines for readability.
 func (s *LogSource) Dump(multiline bool) string {
@@ -140,6 +140,9 @@ func (b *Builder) toDictionary(c *config.LogsConfig) map[string]interface{} {
 	switch c.Type {
 	case config.TCPType, config.UDPType:
 		dictionary["Port"] = c.Port
+	case config.SyslogType:
+		dictionary["Port"] = c.Port
+		dictionary["Protocol"] = c.Protocol
 	case config.FileType:
 		dictionary["Path"] = c.Path
 		dictionary["TailingMode"] = c.TailingMode
@@ -171,6 +174,9 @@ func (b *Builder) toDictionary(c *config.LogsConfig) map[string]interface{} {
 func (b *Builder) getMetricsStatus() map[string]int64 {
 	var metrics = make(map[string]int64, 2)
 	metrics["LogsProcessed"] = b.logsExpVars.Get("LogsProcessed").(*expvar.Int).Value()
+	metrics["LogsSampledOut"] = b.logsExpVars.Get("LogsSampledOut").(*expvar.Int).Value()
+	metrics["LogsRateLimited"] = b.logsExpVars.Get("LogsRateLimited").(*expvar.Int).Value()
+	metrics["LogsMetricsGenerated"] = b.logsExpVars.Get("LogsMetricsGenerated").(*expvar.Int).Value()
 	metrics["LogsSent"] = b.logsExpVars.Get("LogsSent").(*expvar.Int).Value()
 	metrics["BytesSent"] = b.logsExpVars.Get("BytesSent").(*expvar.Int).Value()
 	metrics["EncodedBytesSent"] = b.logsExpVars.Get("EncodedBytesSent").(*expvar.Int).Value()
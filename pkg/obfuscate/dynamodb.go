@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+// dynamoDBKeepValues holds the parameters of the DynamoDB requests which are never obfuscated:
+// they name the tables, indexes and attributes, or hold expressions in which the values are
+// replaced by placeholders.
+var dynamoDBKeepValues = []string{
+	"TableName",
+	"IndexName",
+	"Select",
+	"Limit",
+	"ConsistentRead",
+	"ScanIndexForward",
+	"ReturnValues",
+	"ReturnConsumedCapacity",
+	"ReturnItemCollectionMetrics",
+	"AttributesToGet",
+	"ProjectionExpression",
+	"KeyConditionExpression",
+	"FilterExpression",
+	"ConditionExpression",
+	"UpdateExpression",
+	"ExpressionAttributeNames",
+}
+
+// ObfuscateDynamoDBString obfuscates the given DynamoDB JSON request parameters. The values of
+// the keys, items and expression attributes are replaced by "?", while the table, the index and
+// the expressions are kept.
+func (o *Obfuscator) ObfuscateDynamoDBString(params string) string {
+	return obfuscateJSONString(params, o.dynamodb)
+}
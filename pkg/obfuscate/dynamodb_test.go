@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateDynamoDB(t *testing.T) {
+	for _, tt := range []struct {
+		cfg     JSONConfig
+		in, out string
+	}{
+		{
+			JSONConfig{Enabled: true},
+			`{"TableName": "users", "KeyConditionExpression": "#e = :email", "ExpressionAttributeNames": {"#e": "email"}, "ExpressionAttributeValues": {":email": {"S": "jane@example.com"}}, "Limit": 10}`,
+			`{"TableName":"users","KeyConditionExpression":"#e = :email","ExpressionAttributeNames":{"#e":"email"},"ExpressionAttributeValues":{":email":{"S":"?"}},"Limit":10}`,
+		},
+		{
+			JSONConfig{Enabled: true},
+			`{"TableName": "users", "Item": {"id": {"N": "42"}, "name": {"S": "Jane"}}}`,
+			`{"TableName":"users","Item":{"id":{"N":"?"},"name":{"S":"?"}}}`,
+		},
+		{
+			JSONConfig{Enabled: true, KeepValues: []string{"Key"}},
+			`{"TableName": "users", "Key": {"id": {"N": "42"}}}`,
+			`{"TableName":"users","Key":{"id":{"N":"42"}}}`,
+		},
+		{
+			JSONConfig{},
+			`{"TableName": "users", "Key": {"id": {"N": "42"}}}`,
+			`{"TableName": "users", "Key": {"id": {"N": "42"}}}`,
+		},
+	} {
+		o := NewObfuscator(Config{DynamoDB: tt.cfg})
+		assert.Equal(t, tt.out, o.ObfuscateDynamoDBString(tt.in))
+	}
+}
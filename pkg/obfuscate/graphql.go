@@ -0,0 +1,143 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"strings"
+)
+
+// ObfuscateGraphQLString obfuscates the given GraphQL query, replacing its string and number
+// literals with "?" and removing its comments. The operation, field, argument and variable
+// names are kept, and the whitespaces are compacted. The query is returned as is when GraphQL
+// obfuscation is disabled.
+func (o *Obfuscator) ObfuscateGraphQLString(query string) string {
+	if !o.opts.GraphQL.Enabled || query == "" {
+		return query
+	}
+	var (
+		out   strings.Builder
+		space bool // a whitespace is pending before the next token
+	)
+	out.Grow(len(query))
+	write := func(tok string) {
+		if space && out.Len() > 0 {
+			out.WriteByte(' ')
+		}
+		space = false
+		out.WriteString(tok)
+	}
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+		case c == '#':
+			// comment, up to the end of the line
+			for i < len(query) && query[i] != '\n' && query[i] != '\r' {
+				i++
+			}
+			space = true
+		case strings.HasPrefix(query[i:], `"""`):
+			i = scanGraphQLBlockString(query, i+3)
+			write("?")
+		case c == '"':
+			i = scanGraphQLString(query, i+1)
+			write("?")
+		case c == '-' || isDigit(rune(c)):
+			i = scanGraphQLNumber(query, i)
+			write("?")
+		case isGraphQLNameStart(c):
+			j := i + 1
+			for j < len(query) && (isGraphQLNameStart(query[j]) || isDigit(rune(query[j]))) {
+				j++
+			}
+			write(query[i:j])
+			i = j
+		default:
+			// punctuator: ! $ & ( ) ... : = @ [ ] { | } and the commas
+			write(query[i : i+1])
+			i++
+		}
+	}
+	return out.String()
+}
+
+// ObfuscateGraphQLVariable obfuscates the value of the GraphQL variable name, as found in
+// the "graphql.variables.<name>" tags. The value is replaced with "?", unless the variable is
+// part of the configured variables to keep or GraphQL obfuscation is disabled.
+func (o *Obfuscator) ObfuscateGraphQLVariable(name, value string) string {
+	if !o.opts.GraphQL.Enabled || value == "" {
+		return value
+	}
+	for _, k := range o.opts.GraphQL.KeepVariables {
+		if k == name {
+			return value
+		}
+	}
+	return "?"
+}
+
+// isGraphQLNameStart reports whether c can start a GraphQL name.
+func isGraphQLNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// scanGraphQLString returns the position following the end of the string starting at i,
+// right after its opening quote. Unterminated strings end with the line.
+func scanGraphQLString(query string, i int) int {
+	for i < len(query) {
+		switch query[i] {
+		case '\\':
+			i += 2
+			continue
+		case '"':
+			return i + 1
+		case '\n', '\r':
+			return i
+		}
+		i++
+	}
+	return len(query)
+}
+
+// scanGraphQLBlockString returns the position following the end of the block string starting
+// at i, right after its opening quotes. Unterminated block strings end with the query.
+func scanGraphQLBlockString(query string, i int) int {
+	for i < len(query) {
+		if strings.HasPrefix(query[i:], `\"""`) {
+			i += 4
+			continue
+		}
+		if strings.HasPrefix(query[i:], `"""`) {
+			return i + 3
+		}
+		i++
+	}
+	return len(query)
+}
+
+// scanGraphQLNumber returns the position following the end of the integer or float value
+// starting at i.
+func scanGraphQLNumber(query string, i int) int {
+	if query[i] == '-' {
+		i++
+	}
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case isDigit(rune(c)), c == '.':
+		case c == 'e' || c == 'E':
+			if i+1 < len(query) && (query[i+1] == '+' || query[i+1] == '-') {
+				i++
+			}
+		default:
+			return i
+		}
+		i++
+	}
+	return len(query)
+}
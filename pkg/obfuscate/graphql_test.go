@@ -0,0 +1,66 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateGraphQL(t *testing.T) {
+	o := NewObfuscator(Config{GraphQL: GraphQLConfig{Enabled: true}})
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			`{ user(id: 4) { name } }`,
+			`{ user(id: ?) { name } }`,
+		},
+		{
+			"query GetUser($id: ID!, $first: Int = 10) {\n  user(id: $id) {\n    friends(first: $first) { name }\n  }\n}",
+			`query GetUser($id: ID!, $first: Int = ?) { user(id: $id) { friends(first: $first) { name } } }`,
+		},
+		{
+			`mutation { createUser(input: {email: "jane@example.com", age: -42, score: 1.5e+3, tags: ["a", "b"], active: true}) { id } }`,
+			`mutation { createUser(input: {email: ?, age: ?, score: ?, tags: [?, ?], active: true}) { id } }`,
+		},
+		{
+			`query { search(text: "say \"hi\"", status: ACTIVE) @include(if: $all) { ...Result } }`,
+			`query { search(text: ?, status: ACTIVE) @include(if: $all) { ...Result } }`,
+		},
+		{
+			"query Q { note(body: \"\"\"\nsecret\n\\\"\"\" still secret\n\"\"\") { id } } # user 123",
+			`query Q { note(body: ?) { id } }`,
+		},
+		{
+			"# leading comment\nquery { field_2 }",
+			`query { field_2 }`,
+		},
+		{
+			`{ user(name: "unterminated) { id } }`,
+			`{ user(name: ?`,
+		},
+		{
+			"",
+			"",
+		},
+	} {
+		assert.Equal(t, tt.out, o.ObfuscateGraphQLString(tt.in), tt.in)
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		q := `{ user(id: 4) { name } }`
+		assert.Equal(t, q, NewObfuscator(Config{}).ObfuscateGraphQLString(q))
+	})
+}
+
+func TestObfuscateGraphQLVariable(t *testing.T) {
+	o := NewObfuscator(Config{GraphQL: GraphQLConfig{Enabled: true, KeepVariables: []string{"first"}}})
+	assert.Equal(t, "?", o.ObfuscateGraphQLVariable("email", "jane@example.com"))
+	assert.Equal(t, "10", o.ObfuscateGraphQLVariable("first", "10"))
+	assert.Equal(t, "jane@example.com", NewObfuscator(Config{}).ObfuscateGraphQLVariable("email", "jane@example.com"))
+}
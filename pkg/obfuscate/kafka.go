@@ -0,0 +1,30 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import "strings"
+
+// ObfuscateKafkaMessageKey obfuscates the given Kafka message key, unless the keys are
+// configured to be kept.
+func (o *Obfuscator) ObfuscateKafkaMessageKey(key string) string {
+	if !o.opts.Kafka.Enabled || o.opts.Kafka.KeepMessageKeys || key == "" {
+		return key
+	}
+	return "?"
+}
+
+// ObfuscateKafkaMessagePayload obfuscates the given Kafka message payload. The values of JSON
+// payloads are replaced by "?", keeping their structure, and other payloads are replaced by "?"
+// altogether.
+func (o *Obfuscator) ObfuscateKafkaMessagePayload(payload string) string {
+	if o.kafka == nil || payload == "" {
+		return payload
+	}
+	if s := strings.TrimSpace(payload); strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[") {
+		return obfuscateJSONString(s, o.kafka)
+	}
+	return "?"
+}
@@ -0,0 +1,34 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateKafka(t *testing.T) {
+	t.Run("enabled", func(t *testing.T) {
+		o := NewObfuscator(Config{Kafka: KafkaConfig{Enabled: true}})
+		assert.Equal(t, "?", o.ObfuscateKafkaMessageKey("user-42"))
+		assert.Equal(t, `{"user":"?","items":["?","?"]}`, o.ObfuscateKafkaMessagePayload(` {"user": "jane", "items": [1, 2]}`))
+		assert.Equal(t, "?", o.ObfuscateKafkaMessagePayload("jane,42"))
+		assert.Equal(t, "", o.ObfuscateKafkaMessagePayload(""))
+	})
+
+	t.Run("keep-keys", func(t *testing.T) {
+		o := NewObfuscator(Config{Kafka: KafkaConfig{Enabled: true, KeepMessageKeys: true}})
+		assert.Equal(t, "user-42", o.ObfuscateKafkaMessageKey("user-42"))
+		assert.Equal(t, "?", o.ObfuscateKafkaMessagePayload("jane,42"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		o := NewObfuscator(Config{})
+		assert.Equal(t, "user-42", o.ObfuscateKafkaMessageKey("user-42"))
+		assert.Equal(t, "jane,42", o.ObfuscateKafkaMessagePayload("jane,42"))
+	})
+}
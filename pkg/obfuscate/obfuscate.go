@@ -27,6 +27,8 @@ type Obfuscator struct {
 	mongo                *jsonObfuscator // nil if disabled
 	sqlExecPlan          *jsonObfuscator // nil if disabled
 	sqlExecPlanNormalize *jsonObfuscator // nil if disabled
+	dynamodb             *jsonObfuscator // nil if disabled
+	kafka                *jsonObfuscator // nil if disabled
 	// sqlLiteralEscapes reports whether we should treat escape characters literally or as escape characters.
 	// Different SQL engines behave in different ways and the tokenizer needs to be generic.
 	sqlLiteralEscapes *atomic.Bool
@@ -82,6 +84,17 @@ type Config struct {
 	// HTTP holds the obfuscation settings for HTTP URLs.
 	HTTP HTTPConfig
 
+	// GraphQL holds the obfuscation settings for GraphQL queries and variables.
+	GraphQL GraphQLConfig
+
+	// DynamoDB holds the obfuscation configuration for DynamoDB request parameters. Its
+	// KeepValues are added to the parameters which are never obfuscated, such as the
+	// table name or the expressions.
+	DynamoDB JSONConfig
+
+	// Kafka holds the obfuscation settings for Kafka message keys and payloads.
+	Kafka KafkaConfig
+
 	// Statsd specifies the statsd client to use for reporting metrics.
 	Statsd StatsClient
 
@@ -152,6 +165,25 @@ type HTTPConfig struct {
 	RemovePathDigits bool
 }
 
+// GraphQLConfig holds the configuration settings for GraphQL obfuscation.
+type GraphQLConfig struct {
+	// Enabled specifies whether GraphQL queries and variables should be obfuscated.
+	Enabled bool
+
+	// KeepVariables specifies the names of the variables whose values will not be obfuscated.
+	KeepVariables []string
+}
+
+// KafkaConfig holds the configuration settings for Kafka obfuscation.
+type KafkaConfig struct {
+	// Enabled specifies whether Kafka message keys and payloads should be obfuscated.
+	Enabled bool
+
+	// KeepMessageKeys specifies whether message keys will not be obfuscated, for example
+	// when they hold identifiers which are needed to follow the messages.
+	KeepMessageKeys bool
+}
+
 // JSONConfig holds the obfuscation configuration for sensitive
 // data found in JSON objects.
 type JSONConfig struct {
@@ -190,6 +222,14 @@ func NewObfuscator(cfg Config) *Obfuscator {
 	if cfg.SQLExecPlanNormalize.Enabled {
 		o.sqlExecPlanNormalize = newJSONObfuscator(&cfg.SQLExecPlanNormalize, &o)
 	}
+	if cfg.DynamoDB.Enabled {
+		dcfg := cfg.DynamoDB
+		dcfg.KeepValues = append(append([]string{}, dynamoDBKeepValues...), cfg.DynamoDB.KeepValues...)
+		o.dynamodb = newJSONObfuscator(&dcfg, &o)
+	}
+	if cfg.Kafka.Enabled {
+		o.kafka = newJSONObfuscator(&JSONConfig{Enabled: true}, &o)
+	}
 	if cfg.Statsd == nil {
 		cfg.Statsd = &statsd.NoOpClient{}
 	}
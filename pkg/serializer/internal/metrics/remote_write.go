@@ -0,0 +1,274 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/golang/snappy"
+	"github.com/richardartoul/molecule"
+
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+// constants for the protobuf data we will be writing, taken from WriteRequest in
+// https://github.com/prometheus/prometheus/blob/main/prompb/remote.proto
+const (
+	writeRequestTimeseries = 1
+	timeseriesLabels       = 1
+	timeseriesSamples      = 2
+	labelName              = 1
+	labelValue             = 2
+	sampleValue            = 1
+	sampleTimestamp        = 2
+)
+
+// RemoteWriteBuilder converts series and sketches into Prometheus remote-write
+// payloads: snappy-compressed protobuf WriteRequest messages.
+//
+// Series keep their name, sanitized to a valid Prometheus metric name. Sketches
+// are converted to summaries: one time series per quantile with a `quantile`
+// label, plus the `_sum` and `_count` time series. The host and the tags become
+// labels, tags without value being dropped and the values of a repeated tag key
+// being joined with a comma.
+type RemoteWriteBuilder struct {
+	maxSeriesPerPayload int
+	quantiles           []float64
+
+	buf               *bytes.Buffer
+	ps                *molecule.ProtoStream
+	seriesThisPayload int
+	pointsThisPayload int
+	payloads          transaction.BytesPayloads
+}
+
+// NewRemoteWriteBuilder returns a new RemoteWriteBuilder. Each payload holds at
+// most maxSeriesPerPayload time series, and sketches are converted to the given
+// quantiles.
+func NewRemoteWriteBuilder(maxSeriesPerPayload int, quantiles []float64) *RemoteWriteBuilder {
+	if maxSeriesPerPayload <= 0 {
+		maxSeriesPerPayload = 1
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, 1024))
+	return &RemoteWriteBuilder{
+		maxSeriesPerPayload: maxSeriesPerPayload,
+		quantiles:           quantiles,
+		buf:                 buf,
+		ps:                  molecule.NewProtoStream(buf),
+		payloads:            transaction.BytesPayloads{},
+	}
+}
+
+// AddSerie adds a serie to the payloads.
+func (b *RemoteWriteBuilder) AddSerie(serie *metrics.Serie) error {
+	labels := remoteWriteLabels(serie.Name, serie.Host, serie.Device, serie.Tags)
+
+	return b.addTimeseries(labels, len(serie.Points), func(ps *molecule.ProtoStream) error {
+		for _, p := range serie.Points {
+			if err := writeSample(ps, p.Value, int64(p.Ts*1000)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddSketch adds a sketch to the payloads, as a summary.
+func (b *RemoteWriteBuilder) AddSketch(sketch *metrics.SketchSeries) error {
+	c := quantile.Default()
+	labels := remoteWriteLabels(sketch.Name, sketch.Host, "", sketch.Tags)
+
+	for _, q := range b.quantiles {
+		q := q
+		quantileLabels := withLabel(labels, "quantile", strconv.FormatFloat(q, 'f', -1, 64))
+		err := b.addSketchTimeseries(quantileLabels, sketch.Points, func(s *quantile.Sketch) float64 {
+			return s.Quantile(c, q)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err := b.addSketchTimeseries(withName(labels, sketch.Name+"_sum"), sketch.Points, func(s *quantile.Sketch) float64 {
+		return s.Basic.Sum
+	})
+	if err != nil {
+		return err
+	}
+
+	return b.addSketchTimeseries(withName(labels, sketch.Name+"_count"), sketch.Points, func(s *quantile.Sketch) float64 {
+		return float64(s.Basic.Cnt)
+	})
+}
+
+// Payloads returns the payloads built so far. The builder must not be used
+// afterwards.
+func (b *RemoteWriteBuilder) Payloads() transaction.BytesPayloads {
+	b.finishPayload()
+	return b.payloads
+}
+
+func (b *RemoteWriteBuilder) addSketchTimeseries(labels []remoteWriteLabel, points []metrics.SketchPoint, value func(*quantile.Sketch) float64) error {
+	return b.addTimeseries(labels, len(points), func(ps *molecule.ProtoStream) error {
+		for _, p := range points {
+			if err := writeSample(ps, value(p.Sketch), p.Ts*1000); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// addTimeseries writes a time series to the current payload, starting a new
+// one when it is full.
+func (b *RemoteWriteBuilder) addTimeseries(labels []remoteWriteLabel, pointCount int, writeSamples func(*molecule.ProtoStream) error) error {
+	if b.seriesThisPayload >= b.maxSeriesPerPayload {
+		b.finishPayload()
+	}
+
+	err := b.ps.Embedded(writeRequestTimeseries, func(ps *molecule.ProtoStream) error {
+		for _, l := range labels {
+			err := ps.Embedded(timeseriesLabels, func(ps *molecule.ProtoStream) error {
+				if err := ps.String(labelName, l.name); err != nil {
+					return err
+				}
+				return ps.String(labelValue, l.value)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return writeSamples(ps)
+	})
+	if err != nil {
+		return err
+	}
+
+	b.seriesThisPayload++
+	b.pointsThisPayload += pointCount
+	return nil
+}
+
+func (b *RemoteWriteBuilder) finishPayload() {
+	if b.seriesThisPayload > 0 {
+		payload := snappy.Encode(nil, b.buf.Bytes())
+		b.payloads = append(b.payloads, transaction.NewBytesPayload(payload, b.pointsThisPayload))
+	}
+	b.buf.Reset()
+	b.seriesThisPayload = 0
+	b.pointsThisPayload = 0
+}
+
+func writeSample(ps *molecule.ProtoStream, value float64, timestampMs int64) error {
+	return ps.Embedded(timeseriesSamples, func(ps *molecule.ProtoStream) error {
+		if err := ps.Double(sampleValue, value); err != nil {
+			return err
+		}
+		return ps.Int64(sampleTimestamp, timestampMs)
+	})
+}
+
+type remoteWriteLabel struct {
+	name  string
+	value string
+}
+
+const remoteWriteNameLabel = "__name__"
+
+// remoteWriteLabels returns the labels of a time series, sorted by name as
+// required by the remote-write protocol.
+func remoteWriteLabels(name, host, device string, tags tagset.CompositeTags) []remoteWriteLabel {
+	labels := make([]remoteWriteLabel, 0, tags.Len()+3)
+	labels = append(labels, remoteWriteLabel{remoteWriteNameLabel, sanitizeRemoteWriteName(name, true)})
+	if host != "" {
+		labels = append(labels, remoteWriteLabel{"host", host})
+	}
+	if device != "" {
+		labels = append(labels, remoteWriteLabel{"device", device})
+	}
+	tags.ForEach(func(tag string) {
+		idx := strings.IndexByte(tag, ':')
+		if idx <= 0 || idx == len(tag)-1 {
+			return
+		}
+		key := sanitizeRemoteWriteName(tag[:idx], false)
+		// label names starting with __ are reserved
+		if strings.HasPrefix(key, "__") {
+			return
+		}
+		labels = append(labels, remoteWriteLabel{key, tag[idx+1:]})
+	})
+
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].name != labels[j].name {
+			return labels[i].name < labels[j].name
+		}
+		return labels[i].value < labels[j].value
+	})
+
+	// a label name must be unique, join the values of a repeated one
+	n := 0
+	last := ""
+	for i := range labels {
+		if n > 0 && labels[n-1].name == labels[i].name {
+			if labels[i].value != last {
+				labels[n-1].value += "," + labels[i].value
+				last = labels[i].value
+			}
+			continue
+		}
+		labels[n] = labels[i]
+		last = labels[i].value
+		n++
+	}
+	return labels[:n]
+}
+
+// withName returns a copy of labels with a different metric name.
+func withName(labels []remoteWriteLabel, name string) []remoteWriteLabel {
+	return withLabel(labels, remoteWriteNameLabel, sanitizeRemoteWriteName(name, true))
+}
+
+// withLabel returns a copy of labels with the label set, keeping them sorted.
+func withLabel(labels []remoteWriteLabel, name, value string) []remoteWriteLabel {
+	res := make([]remoteWriteLabel, 0, len(labels)+1)
+	i := 0
+	for ; i < len(labels) && labels[i].name < name; i++ {
+		res = append(res, labels[i])
+	}
+	res = append(res, remoteWriteLabel{name, value})
+	if i < len(labels) && labels[i].name == name {
+		i++
+	}
+	return append(res, labels[i:]...)
+}
+
+// sanitizeRemoteWriteName replaces the characters not allowed in Prometheus metric
+// names, or in label names if colons is false, with underscores.
+func sanitizeRemoteWriteName(name string, colons bool) string {
+	var sb strings.Builder
+	sb.Grow(len(name))
+	for i, r := range name {
+		switch {
+		case r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (colons && r == ':'):
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
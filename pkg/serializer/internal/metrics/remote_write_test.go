@@ -0,0 +1,202 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/golang/snappy"
+	"github.com/richardartoul/molecule"
+	"github.com/richardartoul/molecule/src/codec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+type remoteWriteTimeseries struct {
+	labels  []string
+	samples []remoteWriteSample
+}
+
+type remoteWriteSample struct {
+	value     float64
+	timestamp int64
+}
+
+// decodeRemoteWrite decodes a WriteRequest, the labels being returned as `name=value`.
+func decodeRemoteWrite(t *testing.T, payload *transaction.BytesPayload) []remoteWriteTimeseries {
+	data, err := snappy.Decode(nil, payload.GetContent())
+	require.NoError(t, err)
+
+	decodeEmbedded := func(value molecule.Value, fn func(fieldNum int32, value molecule.Value)) {
+		b, err := value.AsBytesUnsafe()
+		require.NoError(t, err)
+		err = molecule.MessageEach(codec.NewBuffer(b), func(fieldNum int32, value molecule.Value) (bool, error) {
+			fn(fieldNum, value)
+			return true, nil
+		})
+		require.NoError(t, err)
+	}
+
+	var timeseries []remoteWriteTimeseries
+	err = molecule.MessageEach(codec.NewBuffer(data), func(fieldNum int32, value molecule.Value) (bool, error) {
+		require.EqualValues(t, writeRequestTimeseries, fieldNum)
+
+		var ts remoteWriteTimeseries
+		decodeEmbedded(value, func(fieldNum int32, value molecule.Value) {
+			switch fieldNum {
+			case timeseriesLabels:
+				var name, labelVal string
+				decodeEmbedded(value, func(fieldNum int32, value molecule.Value) {
+					s, err := value.AsStringUnsafe()
+					require.NoError(t, err)
+					if fieldNum == labelName {
+						name = s
+					} else {
+						labelVal = s
+					}
+				})
+				ts.labels = append(ts.labels, name+"="+labelVal)
+			case timeseriesSamples:
+				var sample remoteWriteSample
+				decodeEmbedded(value, func(fieldNum int32, value molecule.Value) {
+					var err error
+					if fieldNum == sampleValue {
+						sample.value, err = value.AsDouble()
+					} else {
+						sample.timestamp, err = value.AsInt64()
+					}
+					require.NoError(t, err)
+				})
+				ts.samples = append(ts.samples, sample)
+			default:
+				t.Fatalf("unexpected field %d", fieldNum)
+			}
+		})
+		timeseries = append(timeseries, ts)
+		return true, nil
+	})
+	require.NoError(t, err)
+	return timeseries
+}
+
+func TestRemoteWriteSeries(t *testing.T) {
+	series := metrics.Series{{
+		Name:   "system.cpu-usage",
+		Host:   "my-host",
+		Device: "sda1",
+		Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod", "role:db", "standalone", "role:cache", "9lives:yes", "__meta:x", "role:db"}),
+		Points: []metrics.Point{{Ts: 10, Value: 1.5}, {Ts: 20.5, Value: 2}},
+	}}
+
+	builder := NewRemoteWriteBuilder(10, nil)
+	for _, serie := range series {
+		require.NoError(t, builder.AddSerie(serie))
+	}
+	payloads := builder.Payloads()
+	require.Len(t, payloads, 1)
+	assert.Equal(t, 2, payloads[0].GetPointCount())
+
+	assert.Equal(t, []remoteWriteTimeseries{{
+		labels:  []string{"_9lives=yes", "__name__=system_cpu_usage", "device=sda1", "env=prod", "host=my-host", "role=cache,db"},
+		samples: []remoteWriteSample{{1.5, 10000}, {2, 20500}},
+	}}, decodeRemoteWrite(t, payloads[0]))
+}
+
+func TestRemoteWriteSeriesSplit(t *testing.T) {
+	var series metrics.Series
+	for i := 0; i < 5; i++ {
+		series = append(series, &metrics.Serie{Name: "metric", Points: []metrics.Point{{Ts: 10, Value: float64(i)}}})
+	}
+
+	builder := NewRemoteWriteBuilder(2, nil)
+	for _, serie := range series {
+		require.NoError(t, builder.AddSerie(serie))
+	}
+	payloads := builder.Payloads()
+	require.Len(t, payloads, 3)
+
+	var values []float64
+	for i, payload := range payloads {
+		timeseries := decodeRemoteWrite(t, payload)
+		assert.Equal(t, len(timeseries), payload.GetPointCount())
+		if i < 2 {
+			assert.Len(t, timeseries, 2)
+		}
+		for _, ts := range timeseries {
+			values = append(values, ts.samples[0].value)
+		}
+	}
+	assert.Equal(t, []float64{0, 1, 2, 3, 4}, values)
+}
+
+func TestRemoteWriteNoSeries(t *testing.T) {
+	builder := NewRemoteWriteBuilder(10, nil)
+	assert.Empty(t, builder.Payloads())
+}
+
+func TestRemoteWriteSketches(t *testing.T) {
+	sketch := Makeseries(0)
+	sketch.Points = sketch.Points[1:3]
+
+	builder := NewRemoteWriteBuilder(10, []float64{0.5, 0.99})
+	require.NoError(t, builder.AddSketch(sketch))
+	payloads := builder.Payloads()
+	require.Len(t, payloads, 1)
+	assert.Equal(t, 8, payloads[0].GetPointCount())
+
+	samples := func(value func(s *quantile.Sketch) float64) []remoteWriteSample {
+		var res []remoteWriteSample
+		for _, p := range sketch.Points {
+			res = append(res, remoteWriteSample{value(p.Sketch), p.Ts * 1000})
+		}
+		return res
+	}
+	c := quantile.Default()
+
+	assert.Equal(t, []remoteWriteTimeseries{
+		{
+			labels:  []string{"__name__=name_0", "a=0", "b=0", "host=host.0", "quantile=0.5"},
+			samples: samples(func(s *quantile.Sketch) float64 { return s.Quantile(c, 0.5) }),
+		},
+		{
+			labels:  []string{"__name__=name_0", "a=0", "b=0", "host=host.0", "quantile=0.99"},
+			samples: samples(func(s *quantile.Sketch) float64 { return s.Quantile(c, 0.99) }),
+		},
+		{
+			labels:  []string{"__name__=name_0_sum", "a=0", "b=0", "host=host.0"},
+			samples: samples(func(s *quantile.Sketch) float64 { return s.Basic.Sum }),
+		},
+		{
+			labels:  []string{"__name__=name_0_count", "a=0", "b=0", "host=host.0"},
+			samples: samples(func(s *quantile.Sketch) float64 { return float64(s.Basic.Cnt) }),
+		},
+	}, decodeRemoteWrite(t, payloads[0]))
+}
+
+func TestSanitizeRemoteWriteName(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		colons   bool
+		expected string
+	}{
+		{"system.cpu.user", true, "system_cpu_user"},
+		{"ns:metric_total", true, "ns:metric_total"},
+		{"ns:label", false, "ns_label"},
+		{"2xx.count", true, "_2xx_count"},
+		{"http-status/é", false, "http_status__"},
+		{"", true, ""},
+	} {
+		assert.Equal(t, tc.expected, sanitizeRemoteWriteName(tc.name, tc.colons), tc.name)
+	}
+}
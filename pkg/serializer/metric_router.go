@@ -0,0 +1,353 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package serializer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// routedChanSize and routedBufferSize size the channels feeding the serialization
+	// of the series and sketches of each set of destinations.
+	routedChanSize   = 200
+	routedBufferSize = 4000
+)
+
+// metricRoute restricts the series and sketches sent with the API keys of the route.
+type metricRoute struct {
+	name            string
+	destinations    *transaction.Destinations
+	includePrefixes []string
+	includeTags     map[string]struct{}
+	excludePrefixes []string
+	excludeTags     map[string]struct{}
+}
+
+func newMetricRoute(route config.MetricRoute, apiKeys []string) *metricRoute {
+	r := &metricRoute{
+		name:            route.Name,
+		destinations:    transaction.NewDestinations(apiKeys),
+		includePrefixes: route.IncludePrefixes,
+		includeTags:     make(map[string]struct{}, len(route.IncludeTags)),
+		excludePrefixes: route.ExcludePrefixes,
+		excludeTags:     make(map[string]struct{}, len(route.ExcludeTags)),
+	}
+	for _, tag := range route.IncludeTags {
+		r.includeTags[tag] = struct{}{}
+	}
+	for _, tag := range route.ExcludeTags {
+		r.excludeTags[tag] = struct{}{}
+	}
+	return r
+}
+
+// accept returns whether a metric is sent with the API keys of the route. The metric
+// must match one of the include prefixes or tags, if any, and none of the exclude ones.
+func (r *metricRoute) accept(name string, tags tagset.CompositeTags) bool {
+	if hasAnyPrefix(name, r.excludePrefixes) || hasAnyTag(tags, r.excludeTags) {
+		return false
+	}
+	if len(r.includePrefixes) == 0 && len(r.includeTags) == 0 {
+		return true
+	}
+	return hasAnyPrefix(name, r.includePrefixes) || hasAnyTag(tags, r.includeTags)
+}
+
+func hasAnyPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyTag(tags tagset.CompositeTags, set map[string]struct{}) bool {
+	if len(set) == 0 {
+		return false
+	}
+	return tags.Find(func(tag string) bool {
+		_, found := set[tag]
+		return found
+	})
+}
+
+// metricRouter sends the series and sketches accepted by each route with the API keys of
+// the route, and all of them with the API keys without route.
+type metricRouter struct {
+	routes []*metricRoute
+	// others accepts the API keys without route, nil if every API key has a route
+	others *transaction.Destinations
+}
+
+// newMetricRouterFromConfig returns the metric router configured with `metric_routes`, or
+// nil if no route is configured.
+func newMetricRouterFromConfig() *metricRouter {
+	routes, err := config.GetMetricRoutes()
+	if err != nil {
+		log.Errorf("Metric routing disabled: %v", err)
+		return nil
+	}
+	if len(routes) == 0 {
+		return nil
+	}
+	keysPerDomain, err := config.GetMultipleEndpoints()
+	if err != nil {
+		log.Errorf("Metric routing disabled: %v", err)
+		return nil
+	}
+	var apiKeys []string
+	for _, keys := range keysPerDomain {
+		apiKeys = append(apiKeys, keys...)
+	}
+	router, err := newMetricRouter(routes, apiKeys)
+	if err != nil {
+		log.Errorf("Metric routing disabled: %v", err)
+		return nil
+	}
+	return router
+}
+
+// newMetricRouter returns a metric router for the metrics sent with apiKeys, or nil if
+// routes is empty. An API key listed by several routes only belongs to the first of them.
+func newMetricRouter(routes []config.MetricRoute, apiKeys []string) (*metricRouter, error) {
+	if len(routes) == 0 {
+		return nil, nil
+	}
+
+	router := &metricRouter{}
+	seen := make(map[string]string)
+	for i, route := range routes {
+		if route.Name == "" {
+			route.Name = fmt.Sprintf("#%d", i)
+		}
+		if len(route.APIKeys) == 0 {
+			return nil, fmt.Errorf("the metric route %q has no API key", route.Name)
+		}
+
+		routeAPIKeys := make([]string, 0, len(route.APIKeys))
+		for _, apiKey := range route.APIKeys {
+			if other, ok := seen[apiKey]; ok {
+				log.Warnf("An API key of the metric route %q is already used by the route %q, ignoring it", route.Name, other)
+				continue
+			}
+			seen[apiKey] = route.Name
+			routeAPIKeys = append(routeAPIKeys, apiKey)
+		}
+		if len(routeAPIKeys) == 0 {
+			log.Warnf("All the API keys of the metric route %q are already used by other routes, ignoring it", route.Name)
+			continue
+		}
+		router.routes = append(router.routes, newMetricRoute(route, routeAPIKeys))
+	}
+
+	routedAPIKeys := make([]string, 0, len(seen))
+	for apiKey := range seen {
+		routedAPIKeys = append(routedAPIKeys, apiKey)
+	}
+	for _, apiKey := range apiKeys {
+		if _, routed := seen[apiKey]; !routed {
+			router.others = transaction.NewDestinationsExcept(routedAPIKeys)
+			break
+		}
+	}
+	return router, nil
+}
+
+// routeSeries iterates once over the series of source, calling send in its own goroutine
+// for each set of destinations: the API keys without route receive all the series, and the
+// API keys of each route the series it accepts. The routes are matched before the series
+// are serialized, the serialization moving some tags, and send isn't called for the
+// destinations receiving no series.
+func (r *metricRouter) routeSeries(source metrics.SerieSource, send func(metrics.SerieSource, *transaction.Destinations) error) error {
+	var outputs routedOutputs
+
+	var others *routedSeries
+	if r.others != nil {
+		others = newRoutedSeries()
+		outputs.start("", &others.routedSource, func() error { return send(others, r.others) })
+	}
+	routed := make([]*routedSeries, len(r.routes))
+	for source.MoveNext() {
+		serie := source.Current()
+		for i, route := range r.routes {
+			if !route.accept(serie.Name, serie.Tags) {
+				continue
+			}
+			if routed[i] == nil {
+				series, destinations := newRoutedSeries(), route.destinations
+				outputs.start(route.name, &series.routedSource, func() error { return send(series, destinations) })
+				routed[i] = series
+			}
+			// each serialization gets its own copy of the serie, as it moves some of its tags
+			routedSerie := *serie
+			routed[i].append(&routedSerie)
+		}
+		if others != nil {
+			others.append(serie)
+		}
+	}
+	return outputs.wait()
+}
+
+// routeSketches iterates once over the sketches of source, calling send in its own goroutine
+// for each set of destinations: the API keys without route receive all the sketches, and the
+// API keys of each route the sketches it accepts. send isn't called for the destinations
+// receiving no sketches.
+func (r *metricRouter) routeSketches(source metrics.SketchesSource, send func(metrics.SketchesSource, *transaction.Destinations) error) error {
+	var outputs routedOutputs
+
+	var others *routedSketches
+	if r.others != nil {
+		others = newRoutedSketches()
+		outputs.start("", &others.routedSource, func() error { return send(others, r.others) })
+	}
+	routed := make([]*routedSketches, len(r.routes))
+	for source.MoveNext() {
+		sketch := source.Current()
+		for i, route := range r.routes {
+			if !route.accept(sketch.Name, sketch.Tags) {
+				continue
+			}
+			if routed[i] == nil {
+				sketches, destinations := newRoutedSketches(), route.destinations
+				outputs.start(route.name, &sketches.routedSource, func() error { return send(sketches, destinations) })
+				routed[i] = sketches
+			}
+			// the serialization of the sketches doesn't modify them, they can be shared
+			routed[i].append(sketch)
+		}
+		if others != nil {
+			others.append(sketch)
+		}
+	}
+	return outputs.wait()
+}
+
+// routedOutputs runs the serializations of the routed series or sketches, each of them in
+// its own goroutine.
+type routedOutputs struct {
+	wg      sync.WaitGroup
+	sources []*routedSource
+	mu      sync.Mutex
+	errs    error
+}
+
+// start runs send in a new goroutine, which serializes source for the route named
+// routeName, the API keys without route having no name.
+func (o *routedOutputs) start(routeName string, source *routedSource, send func() error) {
+	o.sources = append(o.sources, source)
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		err := send()
+		// unblock the router if send returns before the end of the iteration
+		source.iterationStopped()
+		if err == nil {
+			return
+		}
+		if routeName != "" {
+			err = fmt.Errorf("metric route %q: %w", routeName, err)
+		}
+		o.mu.Lock()
+		o.errs = multierror.Append(o.errs, err)
+		o.mu.Unlock()
+	}()
+}
+
+// wait ends the iteration of the sources and waits for their serialization.
+func (o *routedOutputs) wait() error {
+	for _, source := range o.sources {
+		source.senderStopped()
+	}
+	o.wg.Wait()
+	return o.errs
+}
+
+// routedSource is fed by the router with the series or sketches of a set of destinations,
+// while another goroutine iterates over them to serialize them.
+type routedSource struct {
+	count   *atomic.Uint64
+	ch      *util.BufferedChan
+	cancel  context.CancelFunc
+	current interface{}
+}
+
+func newRoutedSource() routedSource {
+	ctx, cancel := context.WithCancel(context.Background())
+	return routedSource{
+		count:  atomic.NewUint64(0),
+		ch:     util.NewBufferedChan(ctx, routedChanSize, routedBufferSize),
+		cancel: cancel,
+	}
+}
+
+func (s *routedSource) append(value interface{}) {
+	s.count.Inc()
+	// the value is dropped if the serialization stopped
+	s.ch.Put(value)
+}
+
+func (s *routedSource) senderStopped() {
+	s.ch.Close()
+}
+
+func (s *routedSource) iterationStopped() {
+	s.cancel()
+}
+
+func (s *routedSource) MoveNext() bool {
+	v, ok := s.ch.Get()
+	s.current = v
+	return ok
+}
+
+func (s *routedSource) Count() uint64 {
+	return s.count.Load()
+}
+
+// routedSeries is a metrics.SerieSource fed by the router.
+type routedSeries struct {
+	routedSource
+}
+
+func newRoutedSeries() *routedSeries {
+	return &routedSeries{routedSource: newRoutedSource()}
+}
+
+func (s *routedSeries) Current() *metrics.Serie {
+	return s.current.(*metrics.Serie)
+}
+
+// routedSketches is a metrics.SketchesSource fed by the router.
+type routedSketches struct {
+	routedSource
+}
+
+func newRoutedSketches() *routedSketches {
+	return &routedSketches{routedSource: newRoutedSource()}
+}
+
+func (s *routedSketches) Current() *metrics.SketchSeries {
+	return s.current.(*metrics.SketchSeries)
+}
+
+func (s *routedSketches) WaitForValue() bool {
+	return s.ch.WaitForValue()
+}
@@ -0,0 +1,256 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package serializer
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+var testMetricRoutes = []config.MetricRoute{
+	{Name: "team-a", APIKeys: []string{"key-a"}, IncludePrefixes: []string{"team_a."}, IncludeTags: []string{"team:a"}},
+	{Name: "secondary", APIKeys: []string{"key-b", "key-a"}, ExcludePrefixes: []string{"debug."}, ExcludeTags: []string{"env:dev"}},
+}
+
+var testAPIKeys = []string{"key-main", "key-a", "key-b"}
+
+// sentMetrics records the names of the metrics sent for each set of destinations, the
+// metrics of each set being sent from its own goroutine.
+type sentMetrics struct {
+	mu    sync.Mutex
+	names map[*transaction.Destinations][]string
+}
+
+func (s *sentMetrics) record(destinations *transaction.Destinations, names []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.names == nil {
+		s.names = make(map[*transaction.Destinations][]string)
+	}
+	s.names[destinations] = names
+}
+
+func TestNewMetricRouter(t *testing.T) {
+	router, err := newMetricRouter(nil, testAPIKeys)
+	assert.NoError(t, err)
+	assert.Nil(t, router)
+
+	_, err = newMetricRouter([]config.MetricRoute{{Name: "no-keys", IncludePrefixes: []string{"a."}}}, testAPIKeys)
+	assert.Error(t, err)
+
+	router, err = newMetricRouter(testMetricRoutes, testAPIKeys)
+	require.NoError(t, err)
+	require.Len(t, router.routes, 2)
+
+	// an API key belongs to the first route listing it
+	assert.True(t, router.routes[0].destinations.Accept("key-a"))
+	assert.False(t, router.routes[1].destinations.Accept("key-a"))
+	assert.True(t, router.routes[1].destinations.Accept("key-b"))
+
+	assert.False(t, router.others.Accept("key-a"))
+	assert.False(t, router.others.Accept("key-b"))
+	assert.True(t, router.others.Accept("key-main"))
+
+	// a route without API key of its own is ignored
+	router, err = newMetricRouter(append(testMetricRoutes, config.MetricRoute{Name: "duplicate", APIKeys: []string{"key-b"}}), testAPIKeys)
+	require.NoError(t, err)
+	assert.Len(t, router.routes, 2)
+
+	// there is no other destination when every API key has a route
+	router, err = newMetricRouter(testMetricRoutes, []string{"key-a", "key-b"})
+	require.NoError(t, err)
+	assert.Nil(t, router.others)
+}
+
+func TestMetricRouteAccept(t *testing.T) {
+	router, err := newMetricRouter(testMetricRoutes, testAPIKeys)
+	require.NoError(t, err)
+	teamA, secondary := router.routes[0], router.routes[1]
+
+	for _, tc := range []struct {
+		name              string
+		tags              []string
+		expectedTeamA     bool
+		expectedSecondary bool
+	}{
+		{"team_a.requests", nil, true, true},
+		{"system.cpu", []string{"team:a"}, true, true},
+		{"system.cpu", []string{"team:b"}, false, true},
+		{"debug.team_a.requests", nil, false, false},
+		{"team_a.requests", []string{"env:dev"}, true, false},
+	} {
+		tags := tagset.CompositeTagsFromSlice(tc.tags)
+		assert.Equal(t, tc.expectedTeamA, teamA.accept(tc.name, tags), "%s %v", tc.name, tc.tags)
+		assert.Equal(t, tc.expectedSecondary, secondary.accept(tc.name, tags), "%s %v", tc.name, tc.tags)
+	}
+}
+
+func TestMetricRouterRouteSeries(t *testing.T) {
+	router, err := newMetricRouter(testMetricRoutes, testAPIKeys)
+	require.NoError(t, err)
+
+	series := metrics.Series{
+		{Name: "team_a.requests"},
+		{Name: "system.cpu"},
+		{Name: "debug.queue"},
+	}
+	var sent sentMetrics
+	err = router.routeSeries(metricsserializer.CreateSerieSource(series), func(source metrics.SerieSource, destinations *transaction.Destinations) error {
+		names := []string{}
+		for source.MoveNext() {
+			names = append(names, source.Current().Name)
+		}
+		sent.record(destinations, names)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[*transaction.Destinations][]string{
+		router.others:                 {"team_a.requests", "system.cpu", "debug.queue"},
+		router.routes[0].destinations: {"team_a.requests"},
+		router.routes[1].destinations: {"team_a.requests", "system.cpu"},
+	}, sent.names)
+}
+
+func TestMetricRouterRouteSeriesBeforeSerialization(t *testing.T) {
+	routes := []config.MetricRoute{
+		{Name: "disks", APIKeys: []string{"key-a"}, IncludeTags: []string{"device:sda"}},
+		{Name: "unused", APIKeys: []string{"key-b"}, IncludePrefixes: []string{"unused."}},
+	}
+	router, err := newMetricRouter(routes, testAPIKeys)
+	require.NoError(t, err)
+
+	series := metrics.Series{
+		{Name: "system.disk.used", Tags: tagset.CompositeTagsFromSlice([]string{"device:sda"})},
+		{Name: "system.disk.free", Tags: tagset.CompositeTagsFromSlice([]string{"device:sdb"})},
+	}
+	var sent sentMetrics
+	err = router.routeSeries(metricsserializer.CreateSerieSource(series), func(source metrics.SerieSource, destinations *transaction.Destinations) error {
+		names := []string{}
+		for source.MoveNext() {
+			// the serialization moves the device tag to the device field
+			source.Current().PopulateDeviceField()
+			names = append(names, source.Current().Name)
+		}
+		sent.record(destinations, names)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// the routes accepting no series aren't sent anything
+	assert.Equal(t, map[*transaction.Destinations][]string{
+		router.others:                 {"system.disk.used", "system.disk.free"},
+		router.routes[0].destinations: {"system.disk.used"},
+	}, sent.names)
+	assert.Equal(t, "sda", series[0].Device)
+}
+
+func TestMetricRouterRouteSeriesWithoutOthers(t *testing.T) {
+	router, err := newMetricRouter(testMetricRoutes, []string{"key-a", "key-b"})
+	require.NoError(t, err)
+
+	var sent sentMetrics
+	err = router.routeSeries(metricsserializer.CreateSerieSource(metrics.Series{{Name: "team_a.requests"}}), func(source metrics.SerieSource, destinations *transaction.Destinations) error {
+		names := []string{}
+		for source.MoveNext() {
+			names = append(names, source.Current().Name)
+		}
+		sent.record(destinations, names)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[*transaction.Destinations][]string{
+		router.routes[0].destinations: {"team_a.requests"},
+		router.routes[1].destinations: {"team_a.requests"},
+	}, sent.names)
+}
+
+func TestMetricRouterRouteSketches(t *testing.T) {
+	router, err := newMetricRouter(testMetricRoutes[:1], testAPIKeys)
+	require.NoError(t, err)
+
+	sketches := metrics.NewSketchesSourceTest()
+	sketches.Append(&metrics.SketchSeries{Name: "team_a.latency"})
+	sketches.Append(&metrics.SketchSeries{Name: "system.latency"})
+	var sent sentMetrics
+	err = router.routeSketches(sketches, func(source metrics.SketchesSource, destinations *transaction.Destinations) error {
+		if destinations != router.others {
+			// the router isn't blocked by a serialization stopping early
+			return errors.New("some error")
+		}
+		names := []string{}
+		for source.MoveNext() {
+			names = append(names, source.Current().Name)
+		}
+		sent.record(destinations, names)
+		return nil
+	})
+	assert.ErrorContains(t, err, `metric route "team-a": some error`)
+
+	assert.Equal(t, map[*transaction.Destinations][]string{
+		router.others: {"team_a.latency", "system.latency"},
+	}, sent.names)
+}
+
+func TestSendSeriesWithMetricRoutes(t *testing.T) {
+	config.Datadog.Set("api_key", "key-main")
+	defer config.Datadog.Set("api_key", "")
+	config.Datadog.Set("additional_endpoints", map[string][]string{"https://app.datadoghq.com": {"key-a"}})
+	defer config.Datadog.Set("additional_endpoints", nil)
+	config.Datadog.Set("metric_routes", testMetricRoutes[:1])
+	defer config.Datadog.Set("metric_routes", nil)
+
+	for _, tc := range []struct {
+		name         string
+		useV1API     bool
+		submit       string
+		extraHeaders interface{}
+	}{
+		{"v2", false, "SubmitSeries", protobufExtraHeadersWithCompression},
+		{"v1 json stream", true, "SubmitV1Series", jsonExtraHeadersWithCompression},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config.Datadog.Set("use_v2_api.series", !tc.useV1API)
+			defer config.Datadog.Set("use_v2_api.series", true)
+
+			f := &forwarder.MockedForwarder{}
+			for _, apiKey := range []string{"key-main", "key-a"} {
+				apiKey := apiKey
+				f.On(tc.submit, mock.MatchedBy(func(payloads transaction.BytesPayloads) bool {
+					return len(payloads) == 1 && payloads[0].GetDestinations().Accept(apiKey)
+				}), tc.extraHeaders).Return(nil).Once()
+			}
+
+			s := NewSerializer(f, nil)
+			require.NotNil(t, s.metricRouter)
+
+			// more series than the shared buffers of the JSON stream serialization can hold
+			series := make(metrics.Series, 0, 2*routedBufferSize)
+			for i := 0; i < routedBufferSize; i++ {
+				series = append(series, &metrics.Serie{Name: "team_a.requests"}, &metrics.Serie{Name: "system.cpu"})
+			}
+			err := s.SendIterableSeries(metricsserializer.CreateSerieSource(series))
+			require.NoError(t, err)
+			f.AssertExpectations(t)
+		})
+	}
+}
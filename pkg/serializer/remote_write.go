@@ -0,0 +1,157 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package serializer
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// remoteWriteSubmitter submits Prometheus remote-write payloads, see forwarder.RemoteWriteForwarder.
+type remoteWriteSubmitter interface {
+	SubmitRemoteWrite(payloads transaction.BytesPayloads) error
+}
+
+// remoteWriteOutput sends the series, and optionally the sketches as summaries, to
+// Prometheus remote-write endpoints.
+type remoteWriteOutput struct {
+	forwarder           remoteWriteSubmitter
+	maxSeriesPerPayload int
+	sendSketches        bool
+	sketchQuantiles     []float64
+}
+
+func newRemoteWriteOutput(forwarder remoteWriteSubmitter) *remoteWriteOutput {
+	return &remoteWriteOutput{
+		forwarder:           forwarder,
+		maxSeriesPerPayload: config.Datadog.GetInt("prometheus_remote_write.max_series_per_payload"),
+		sendSketches:        config.Datadog.GetBool("prometheus_remote_write.send_sketches"),
+		sketchQuantiles:     remoteWriteSketchQuantiles(),
+	}
+}
+
+var defaultRemoteWriteSketchQuantiles = []float64{0.5, 0.75, 0.9, 0.95, 0.99}
+
+func remoteWriteSketchQuantiles() []float64 {
+	quantiles, err := config.Datadog.GetFloat64SliceE("prometheus_remote_write.sketch_quantiles")
+	if err != nil {
+		log.Errorf("%s, falling back to default values", err)
+		return defaultRemoteWriteSketchQuantiles
+	}
+	for _, q := range quantiles {
+		if q < 0 || q > 1 {
+			log.Errorf("'prometheus_remote_write.sketch_quantiles' must be between 0 and 1, got %v, falling back to default values", q)
+			return defaultRemoteWriteSketchQuantiles
+		}
+	}
+	return quantiles
+}
+
+// teeSeries returns a source iterating over the series of source, which converts them while
+// they are iterated by the Datadog serialization, before it moves some of their tags.
+func (o *remoteWriteOutput) teeSeries(source metrics.SerieSource) *remoteWriteSerieSource {
+	return &remoteWriteSerieSource{
+		SerieSource: source,
+		output:      o,
+		builder:     metricsserializer.NewRemoteWriteBuilder(o.maxSeriesPerPayload, o.sketchQuantiles),
+	}
+}
+
+// teeSketches returns a source iterating over the sketches of source, which converts them
+// while they are iterated by the Datadog serialization.
+func (o *remoteWriteOutput) teeSketches(source metrics.SketchesSource) *remoteWriteSketchesSource {
+	return &remoteWriteSketchesSource{
+		SketchesSource: source,
+		output:         o,
+		builder:        metricsserializer.NewRemoteWriteBuilder(o.maxSeriesPerPayload, o.sketchQuantiles),
+	}
+}
+
+// remoteWriteSerieSource is a metrics.SerieSource converting each serie to remote-write when
+// moving to it.
+type remoteWriteSerieSource struct {
+	metrics.SerieSource
+	output  *remoteWriteOutput
+	builder *metricsserializer.RemoteWriteBuilder
+	err     error
+}
+
+func (s *remoteWriteSerieSource) MoveNext() bool {
+	if !s.SerieSource.MoveNext() {
+		return false
+	}
+	if s.err == nil {
+		s.err = s.builder.AddSerie(s.SerieSource.Current())
+	}
+	return true
+}
+
+// send converts the series left, if the Datadog serialization stopped early, and sends the
+// remote-write payloads.
+func (s *remoteWriteSerieSource) send() error {
+	for s.MoveNext() {
+	}
+	if s.err != nil {
+		return fmt.Errorf("dropping remote write series payload: %s", s.err)
+	}
+	return s.output.submit(s.builder.Payloads())
+}
+
+// remoteWriteSketchesSource is a metrics.SketchesSource converting each sketch to
+// remote-write when moving to it.
+type remoteWriteSketchesSource struct {
+	metrics.SketchesSource
+	output  *remoteWriteOutput
+	builder *metricsserializer.RemoteWriteBuilder
+	err     error
+}
+
+func (s *remoteWriteSketchesSource) MoveNext() bool {
+	if !s.SketchesSource.MoveNext() {
+		return false
+	}
+	if s.err == nil {
+		s.err = s.builder.AddSketch(s.SketchesSource.Current())
+	}
+	return true
+}
+
+// send converts the sketches left, if the Datadog serialization stopped early, and sends
+// the remote-write payloads.
+func (s *remoteWriteSketchesSource) send() error {
+	for s.MoveNext() {
+	}
+	if s.err != nil {
+		return fmt.Errorf("dropping remote write sketch payload: %s", s.err)
+	}
+	return s.output.submit(s.builder.Payloads())
+}
+
+func (o *remoteWriteOutput) submit(payloads transaction.BytesPayloads) error {
+	if len(payloads) == 0 {
+		return nil
+	}
+	if err := o.forwarder.SubmitRemoteWrite(payloads); err != nil {
+		return fmt.Errorf("remote write: %w", err)
+	}
+	return nil
+}
+
+// appendRemoteWriteErr combines the error of the Datadog output with the one of the
+// remote-write output, a remote-write failure not preventing the Datadog one.
+func appendRemoteWriteErr(err, remoteWriteErr error) error {
+	if remoteWriteErr == nil {
+		return err
+	}
+	return multierror.Append(err, remoteWriteErr)
+}
@@ -0,0 +1,133 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package serializer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+type remoteWriteSubmitterMock struct {
+	mock.Mock
+}
+
+func (m *remoteWriteSubmitterMock) SubmitRemoteWrite(payloads transaction.BytesPayloads) error {
+	return m.Called(payloads).Error(0)
+}
+
+func TestSendSeriesWithRemoteWrite(t *testing.T) {
+	config.Datadog.Set("use_v2_api.series", true) // default value, but just to be sure
+
+	f := &forwarder.MockedForwarder{}
+	f.On("SubmitSeries", mock.Anything, protobufExtraHeadersWithCompression).Return(nil).Once()
+	rw := &remoteWriteSubmitterMock{}
+	rw.On("SubmitRemoteWrite", mock.MatchedBy(func(payloads transaction.BytesPayloads) bool {
+		return len(payloads) == 1 && payloads[0].GetPointCount() == 3
+	})).Return(errors.New("some error")).Once()
+
+	s := NewSerializer(f, nil)
+	s.remoteWrite = newRemoteWriteOutput(rw)
+
+	series := metrics.Series{
+		{Name: "system.cpu", Points: []metrics.Point{{Ts: 10, Value: 1}, {Ts: 20, Value: 2}}},
+		{Name: "system.mem", Points: []metrics.Point{{Ts: 10, Value: 3}}},
+	}
+	// a remote write failure does not prevent sending to Datadog
+	err := s.SendIterableSeries(metricsserializer.CreateSerieSource(series))
+	assert.ErrorContains(t, err, "remote write: some error")
+	f.AssertExpectations(t)
+	rw.AssertExpectations(t)
+}
+
+func TestSendSeriesWithRemoteWriteAndMetricRoutes(t *testing.T) {
+	config.Datadog.Set("api_key", "key-main")
+	defer config.Datadog.Set("api_key", "")
+	config.Datadog.Set("metric_routes", testMetricRoutes[:1])
+	defer config.Datadog.Set("metric_routes", nil)
+	config.Datadog.Set("use_v2_api.series", true) // default value, but just to be sure
+
+	f := &forwarder.MockedForwarder{}
+	f.On("SubmitSeries", mock.Anything, protobufExtraHeadersWithCompression).Return(nil).Twice()
+	var remoteWritePayloads transaction.BytesPayloads
+	rw := &remoteWriteSubmitterMock{}
+	rw.On("SubmitRemoteWrite", mock.Anything).Run(func(args mock.Arguments) {
+		remoteWritePayloads = args.Get(0).(transaction.BytesPayloads)
+	}).Return(nil).Once()
+
+	s := NewSerializer(f, nil)
+	s.remoteWrite = newRemoteWriteOutput(rw)
+	require.NotNil(t, s.metricRouter)
+
+	series := metrics.Series{
+		{Name: "team_a.disk", Tags: tagset.CompositeTagsFromSlice([]string{"device:sda"}), Points: []metrics.Point{{Ts: 10, Value: 1}}},
+		{Name: "system.cpu", Points: []metrics.Point{{Ts: 10, Value: 2}}},
+	}
+	// the series are iterated once, and converted before their serialization moves the device tag
+	require.NoError(t, s.SendIterableSeries(metricsserializer.CreateSerieSource(series)))
+	f.AssertExpectations(t)
+	rw.AssertExpectations(t)
+	require.Len(t, remoteWritePayloads, 1)
+	assert.Equal(t, 2, remoteWritePayloads[0].GetPointCount())
+	payload, err := snappy.Decode(nil, remoteWritePayloads[0].GetContent())
+	require.NoError(t, err)
+	assert.Contains(t, string(payload), "sda")
+}
+
+func TestSendSketchWithRemoteWrite(t *testing.T) {
+	f := &forwarder.MockedForwarder{}
+	f.On("SubmitSketchSeries", mock.Anything, mock.Anything).Return(nil).Twice()
+	rw := &remoteWriteSubmitterMock{}
+
+	newSketchesSource := func() metrics.SketchesSource {
+		source := metrics.NewSketchesSourceTest()
+		source.Append(&metrics.SketchSeries{Name: "latency"})
+		return source
+	}
+
+	// sketches are not sent by default
+	s := NewSerializer(f, nil)
+	s.remoteWrite = newRemoteWriteOutput(rw)
+	require.NoError(t, s.SendSketch(newSketchesSource()))
+	rw.AssertNotCalled(t, "SubmitRemoteWrite", mock.Anything)
+
+	config.Datadog.Set("prometheus_remote_write.send_sketches", true)
+	defer config.Datadog.Set("prometheus_remote_write.send_sketches", false)
+	rw.On("SubmitRemoteWrite", mock.MatchedBy(func(payloads transaction.BytesPayloads) bool {
+		return len(payloads) == 1
+	})).Return(nil).Once()
+
+	s.remoteWrite = newRemoteWriteOutput(rw)
+	require.NoError(t, s.SendSketch(newSketchesSource()))
+	f.AssertExpectations(t)
+	rw.AssertExpectations(t)
+}
+
+func TestRemoteWriteSketchQuantiles(t *testing.T) {
+	assert.Equal(t, defaultRemoteWriteSketchQuantiles, remoteWriteSketchQuantiles())
+	defer config.Datadog.Set("prometheus_remote_write.sketch_quantiles", []string{"0.5", "0.75", "0.9", "0.95", "0.99"})
+
+	// as parsed from the configuration file
+	config.Datadog.Set("prometheus_remote_write.sketch_quantiles", []interface{}{0.5, 0.999})
+	assert.Equal(t, []float64{0.5, 0.999}, remoteWriteSketchQuantiles())
+
+	config.Datadog.Set("prometheus_remote_write.sketch_quantiles", []interface{}{0.5, 99})
+	assert.Equal(t, defaultRemoteWriteSketchQuantiles, remoteWriteSketchQuantiles())
+}
@@ -124,6 +124,14 @@ type Serializer struct {
 	enableServiceChecksJSONStream bool
 	enableEventsJSONStream        bool
 	enableSketchProtobufStream    bool
+
+	// metricRouter restricts the series and sketches sent with some API keys,
+	// nil if no route is configured.
+	metricRouter *metricRouter
+
+	// remoteWrite also sends the series and sketches to Prometheus remote-write
+	// endpoints, nil if disabled.
+	remoteWrite *remoteWriteOutput
 }
 
 // NewSerializer returns a new Serializer initialized
@@ -142,6 +150,7 @@ func NewSerializer(forwarder, orchestratorForwarder forwarder.Forwarder) *Serial
 		enableServiceChecksJSONStream: stream.Available && config.Datadog.GetBool("enable_service_checks_stream_payload_serialization"),
 		enableEventsJSONStream:        stream.Available && config.Datadog.GetBool("enable_events_stream_payload_serialization"),
 		enableSketchProtobufStream:    stream.Available && config.Datadog.GetBool("enable_sketch_stream_payload_serialization"),
+		metricRouter:                  newMetricRouterFromConfig(),
 	}
 
 	if !s.enableEvents {
@@ -317,6 +326,39 @@ func (s *Serializer) SendIterableSeries(serieSource metrics.SerieSource) error {
 		return nil
 	}
 
+	var remoteWriteSeries *remoteWriteSerieSource
+	if s.remoteWrite != nil {
+		remoteWriteSeries = s.remoteWrite.teeSeries(serieSource)
+		serieSource = remoteWriteSeries
+	}
+
+	var err error
+	if s.metricRouter != nil {
+		err = s.metricRouter.routeSeries(serieSource, s.sendIterableSeries)
+	} else {
+		err = s.sendIterableSeries(serieSource, nil)
+	}
+
+	var remoteWriteErr error
+	if remoteWriteSeries != nil {
+		remoteWriteErr = remoteWriteSeries.send()
+	}
+	return appendRemoteWriteErr(err, remoteWriteErr)
+}
+
+// SetRemoteWriteForwarder makes the serializer also send the series, and the sketches if
+// `prometheus_remote_write.send_sketches` is enabled, to the endpoints of forwarder.
+func (s *Serializer) SetRemoteWriteForwarder(forwarder *forwarder.RemoteWriteForwarder) {
+	if forwarder == nil {
+		s.remoteWrite = nil
+		return
+	}
+	s.remoteWrite = newRemoteWriteOutput(forwarder)
+}
+
+// sendIterableSeries serializes a list of series and sends the payload to the forwarder
+// for the given destinations, nil sending it to all of them
+func (s *Serializer) sendIterableSeries(serieSource metrics.SerieSource, destinations *transaction.Destinations) error {
 	seriesSerializer := metricsserializer.CreateIterableSeries(serieSource)
 	useV1API := !config.Datadog.GetBool("use_v2_api.series")
 
@@ -324,7 +366,12 @@ func (s *Serializer) SendIterableSeries(serieSource metrics.SerieSource) error {
 	var extraHeaders http.Header
 	var err error
 
-	if useV1API && s.enableJSONStream {
+	if useV1API && s.enableJSONStream && destinations != nil {
+		// the routed series are serialized concurrently for each set of destinations while
+		// being iterated once, the serializations can't wait for the shared buffers
+		seriesBytesPayloads, err = stream.NewJSONPayloadBuilder(false).BuildWithOnErrItemTooBigPolicy(seriesSerializer, stream.DropItemOnErrItemTooBig)
+		extraHeaders = jsonExtraHeadersWithCompression
+	} else if useV1API && s.enableJSONStream {
 		seriesBytesPayloads, extraHeaders, err = s.serializeIterableStreamablePayload(seriesSerializer, stream.DropItemOnErrItemTooBig)
 	} else if useV1API && !s.enableJSONStream {
 		seriesBytesPayloads, extraHeaders, err = s.serializePayloadJSON(seriesSerializer, true)
@@ -336,6 +383,7 @@ func (s *Serializer) SendIterableSeries(serieSource metrics.SerieSource) error {
 	if err != nil {
 		return fmt.Errorf("dropping series payload: %s", err)
 	}
+	seriesBytesPayloads.SetDestinations(destinations)
 
 	if useV1API {
 		return s.Forwarder.SubmitV1Series(seriesBytesPayloads, extraHeaders)
@@ -354,12 +402,37 @@ func (s *Serializer) SendSketch(sketches metrics.SketchesSource) error {
 		log.Debug("sketches payloads are disabled: dropping it")
 		return nil
 	}
+
+	var remoteWriteSketches *remoteWriteSketchesSource
+	if s.remoteWrite != nil && s.remoteWrite.sendSketches {
+		remoteWriteSketches = s.remoteWrite.teeSketches(sketches)
+		sketches = remoteWriteSketches
+	}
+
+	var err error
+	if s.metricRouter != nil {
+		err = s.metricRouter.routeSketches(sketches, s.sendSketch)
+	} else {
+		err = s.sendSketch(sketches, nil)
+	}
+
+	var remoteWriteErr error
+	if remoteWriteSketches != nil {
+		remoteWriteErr = remoteWriteSketches.send()
+	}
+	return appendRemoteWriteErr(err, remoteWriteErr)
+}
+
+// sendSketch serializes a list of SketSeriesList and sends the payload to the forwarder
+// for the given destinations, nil sending it to all of them
+func (s *Serializer) sendSketch(sketches metrics.SketchesSource, destinations *transaction.Destinations) error {
 	sketchesSerializer := metricsserializer.SketchSeriesList{SketchesSource: sketches}
 	if s.enableSketchProtobufStream {
 		payloads, err := sketchesSerializer.MarshalSplitCompress(marshaler.NewBufferContext())
 		if err != nil {
 			return fmt.Errorf("dropping sketch payload: %v", err)
 		}
+		payloads.SetDestinations(destinations)
 
 		return s.Forwarder.SubmitSketchSeries(payloads, protobufExtraHeadersWithCompression)
 	} else {
@@ -368,6 +441,7 @@ func (s *Serializer) SendSketch(sketches metrics.SketchesSource) error {
 		if err != nil {
 			return fmt.Errorf("dropping sketch payload: %s", err)
 		}
+		splitSketches.SetDestinations(destinations)
 
 		return s.Forwarder.SubmitSketchSeries(splitSketches, extraHeaders)
 	}
@@ -53,6 +53,7 @@ type Agent struct {
 	ErrorsSampler         *sampler.ErrorsSampler
 	RareSampler           *sampler.RareSampler
 	NoPrioritySampler     *sampler.NoPrioritySampler
+	TailSampler           *sampler.TailSampler
 	EventProcessor        *event.Processor
 	TraceWriter           *writer.TraceWriter
 	StatsWriter           *writer.StatsWriter
@@ -65,6 +66,15 @@ type Agent struct {
 	obfuscator     *obfuscate.Obfuscator
 	cardObfuscator *ccObfuscator
 
+	// tailBuffer holds the spans of the traces until they are complete when tail sampling is enabled.
+	tailBuffer *tailBuffer
+
+	// extraDimensions keeps the extra dimensions of the client computed stats.
+	extraDimensions *stats.ExtraDimensions
+
+	// spanMetrics generates the metrics of the span metric rules.
+	spanMetrics *spanMetrics
+
 	// DiscardSpan will be called on all spans, if non-nil. If it returns true, the span will be deleted before processing.
 	DiscardSpan func(*pb.Span) bool
 
@@ -88,12 +98,14 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 	dynConf := sampler.NewDynamicConfig()
 	in := make(chan *api.Payload, 1000)
 	statsChan := make(chan pb.StatsPayload, 100)
+	// the stats computed by the agent and by the tracers share the cardinality of their extra dimensions
+	extraDimensions := stats.NewExtraDimensions(conf)
 	oconf := conf.Obfuscation.Export(conf)
 	if oconf.Statsd == nil {
 		oconf.Statsd = metrics.Client
 	}
 	agnt := &Agent{
-		Concentrator:          stats.NewConcentrator(conf, statsChan, time.Now()),
+		Concentrator:          stats.NewConcentrator(conf, statsChan, time.Now(), extraDimensions),
 		ClientStatsAggregator: stats.NewClientStatsAggregator(conf, statsChan),
 		Blacklister:           filters.NewBlacklister(conf.Ignore["resource"]),
 		Replacer:              filters.NewReplacer(conf.ReplaceTags),
@@ -105,11 +117,17 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 		StatsWriter:           writer.NewStatsWriter(conf, statsChan, telemetryCollector),
 		obfuscator:            obfuscate.NewObfuscator(oconf),
 		cardObfuscator:        newCreditCardsObfuscator(conf.Obfuscation.CreditCards),
+		extraDimensions:       extraDimensions,
+		spanMetrics:           newSpanMetrics(conf),
 		In:                    in,
 		conf:                  conf,
 		ctx:                   ctx,
 		DebugServer:           api.NewDebugServer(conf),
 	}
+	if conf.TailSampling.Enabled {
+		agnt.TailSampler = sampler.NewTailSampler(conf)
+		agnt.tailBuffer = newTailBuffer(conf)
+	}
 	agnt.Receiver = api.NewHTTPReceiver(conf, dynConf, in, agnt, telemetryCollector)
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf)
 	agnt.RemoteConfigHandler = remoteconfighandler.New(conf, agnt.PrioritySampler, agnt.RareSampler, agnt.ErrorsSampler)
@@ -140,6 +158,9 @@ func (a *Agent) Run() {
 	for i := 0; i < runtime.NumCPU(); i++ {
 		go a.work()
 	}
+	if a.tailBuffer != nil {
+		go a.runTailSampling()
+	}
 
 	a.loop()
 }
@@ -183,6 +204,10 @@ func (a *Agent) loop() {
 			if err := a.Receiver.Stop(); err != nil {
 				log.Error(err)
 			}
+			if a.tailBuffer != nil {
+				// sample the buffered traces before stopping the writers
+				a.sampleTailTraces(time.Now(), true)
+			}
 			for _, stopper := range []interface{ Stop() }{
 				a.Concentrator,
 				a.ClientStatsAggregator,
@@ -260,6 +285,15 @@ func (a *Agent) Process(p *api.Payload) {
 			continue
 		}
 
+		if a.tailBuffer != nil {
+			// The root span may be received in another chunk: the trace is filtered, sampled
+			// and its stats computed once all its spans are received.
+			a.sanitizeChunk(chunk, p.ClientComputedTopLevel)
+			a.bufferTailTrace(now, p, chunk)
+			p.RemoveChunk(i)
+			continue
+		}
+
 		// Root span is used to carry some trace-level metadata, such as sampling rate and priority.
 		root := traceutil.GetRoot(chunk.Spans)
 		normalizeChunk(chunk, root)
@@ -279,25 +313,7 @@ func (a *Agent) Process(p *api.Payload) {
 			continue
 		}
 
-		// Extra sanitization steps of the trace.
-		for _, span := range chunk.Spans {
-			for k, v := range a.conf.GlobalTags {
-				if k == tagOrigin {
-					chunk.Origin = v
-				} else {
-					traceutil.SetMeta(span, k, v)
-				}
-			}
-			if a.ModifySpan != nil {
-				a.ModifySpan(chunk, span)
-			}
-			a.obfuscateSpan(span)
-			a.Truncate(span)
-			if p.ClientComputedTopLevel {
-				traceutil.UpdateTracerTopLevel(span)
-			}
-		}
-		a.Replacer.Replace(chunk.Spans)
+		a.sanitizeChunk(chunk, p.ClientComputedTopLevel)
 
 		a.setRootSpanTags(root)
 		if !p.ClientComputedTopLevel {
@@ -316,6 +332,7 @@ func (a *Agent) Process(p *api.Payload) {
 		if p.TracerPayload.AppVersion == "" {
 			p.TracerPayload.AppVersion = traceutil.GetAppVersion(root, chunk)
 		}
+		a.spanMetrics.process(p.TracerPayload.Env, chunk.Spans)
 
 		pt := processedTrace(p, chunk, root)
 		if !p.ClientComputedStats {
@@ -365,6 +382,28 @@ func (a *Agent) Process(p *api.Payload) {
 	}
 }
 
+// sanitizeChunk runs the extra sanitization steps of the trace on the spans of the chunk.
+func (a *Agent) sanitizeChunk(chunk *pb.TraceChunk, clientComputedTopLevel bool) {
+	for _, span := range chunk.Spans {
+		for k, v := range a.conf.GlobalTags {
+			if k == tagOrigin {
+				chunk.Origin = v
+			} else {
+				traceutil.SetMeta(span, k, v)
+			}
+		}
+		if a.ModifySpan != nil {
+			a.ModifySpan(chunk, span)
+		}
+		a.obfuscateSpan(span)
+		a.Truncate(span)
+		if clientComputedTopLevel {
+			traceutil.UpdateTracerTopLevel(span)
+		}
+	}
+	a.Replacer.Replace(chunk.Spans)
+}
+
 // processedTrace creates a ProcessedTrace based on the provided chunk and root.
 // It makes a deep copy of the provided chunk to ensure that any subsequent changes
 // to the original chunk will not affect the TraceChunk of the ProcessedTrace.
@@ -442,6 +481,7 @@ func (a *Agent) processStats(in pb.ClientStatsPayload, lang, tracerVersion strin
 			}
 			a.obfuscateStatsGroup(&b)
 			a.Replacer.ReplaceStatsGroup(&b)
+			b.Tags = a.extraDimensions.FromTags(b.Tags)
 			group.Stats[n] = b
 			n++
 		}
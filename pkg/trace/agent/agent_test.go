@@ -1022,7 +1022,7 @@ func TestPartialSamplingFree(t *testing.T) {
 	dynConf := sampler.NewDynamicConfig()
 	in := make(chan *api.Payload, 1000)
 	agnt := &Agent{
-		Concentrator:      stats.NewConcentrator(cfg, statsChan, time.Now()),
+		Concentrator:      stats.NewConcentrator(cfg, statsChan, time.Now(), nil),
 		Blacklister:       filters.NewBlacklister(cfg.Ignore["resource"]),
 		Replacer:          filters.NewReplacer(cfg.ReplaceTags),
 		NoPrioritySampler: sampler.NewNoPrioritySampler(cfg),
@@ -1537,6 +1537,42 @@ func TestConvertStats(t *testing.T) {
 	}
 }
 
+func TestProcessStatsExtraDimensions(t *testing.T) {
+	cfg := &config.AgentConfig{
+		DefaultEnv:                         "agent_env",
+		StatsExtraDimensions:               []string{"peer.service"},
+		StatsExtraDimensionsMaxCardinality: 10,
+		MaxResourceLen:                     5000,
+	}
+	a := Agent{
+		Blacklister:     filters.NewBlacklister(nil),
+		obfuscator:      obfuscate.NewObfuscator(obfuscate.Config{}),
+		Replacer:        filters.NewReplacer(nil),
+		extraDimensions: stats.NewExtraDimensions(cfg),
+		conf:            cfg,
+	}
+	group := func(hits uint64, tags ...string) pb.ClientGroupedStats {
+		return pb.ClientGroupedStats{Service: "service", Name: "name", Resource: "resource", Hits: hits, Tags: tags}
+	}
+	out := a.processStats(pb.ClientStatsPayload{
+		Stats: []pb.ClientStatsBucket{{
+			Stats: []pb.ClientGroupedStats{
+				group(1, "peer.service:billing", "customer.tier:beta"),
+				group(2, "peer.service:billing"),
+				group(3, "peer.service:users"),
+				group(4),
+			},
+		}},
+	}, "go", "v1")
+	// the tags which aren't extra dimensions are discarded, the groups being merged accordingly
+	assert.Equal(t, []pb.ClientGroupedStats{
+		group(3, "peer.service:billing"),
+		group(0, "peer.service:billing"),
+		group(3, "peer.service:users"),
+		group(4),
+	}, out.Stats[0].Stats)
+}
+
 func TestMergeDuplicates(t *testing.T) {
 	in := pb.ClientStatsBucket{
 		Stats: []pb.ClientGroupedStats{
@@ -22,6 +22,13 @@ const (
 	tagElasticBody      = "elasticsearch.body"
 	tagSQLQuery         = "sql.query"
 	tagHTTPURL          = "http.url"
+
+	tagGraphQLQuery          = "graphql.query"
+	tagGraphQLSource         = "graphql.source"
+	tagGraphQLVariablePrefix = "graphql.variables."
+	tagDynamoDBParameters    = "aws.dynamodb.request_parameters"
+	tagKafkaMessageKey       = "messaging.kafka.message.key"
+	tagKafkaMessagePayload   = "messaging.kafka.message.payload"
 )
 
 const (
@@ -30,6 +37,7 @@ const (
 
 func (a *Agent) obfuscateSpan(span *pb.Span) {
 	o := a.obfuscator
+	a.obfuscatePayloadTags(span)
 	switch span.Type {
 	case "sql", "cassandra":
 		if span.Resource == "" {
@@ -97,6 +105,41 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 			return
 		}
 		span.Meta[tagElasticBody] = o.ObfuscateElasticSearchString(v)
+	case "graphql":
+		if !a.conf.Obfuscation.GraphQL.Enabled {
+			return
+		}
+		span.Resource = o.ObfuscateGraphQLString(span.Resource)
+		for k, v := range span.Meta {
+			switch {
+			case k == tagGraphQLQuery || k == tagGraphQLSource:
+				span.Meta[k] = o.ObfuscateGraphQLString(v)
+			case strings.HasPrefix(k, tagGraphQLVariablePrefix):
+				span.Meta[k] = o.ObfuscateGraphQLVariable(strings.TrimPrefix(k, tagGraphQLVariablePrefix), v)
+			}
+		}
+	}
+}
+
+// obfuscatePayloadTags obfuscates the tags holding the payloads sent to DynamoDB and Kafka.
+// They are found on spans of various types, depending on the tracer.
+func (a *Agent) obfuscatePayloadTags(span *pb.Span) {
+	if span.Meta == nil || a.conf.Obfuscation == nil {
+		return
+	}
+	o := a.obfuscator
+	if a.conf.Obfuscation.DynamoDB.Enabled {
+		if v, ok := span.Meta[tagDynamoDBParameters]; ok {
+			span.Meta[tagDynamoDBParameters] = o.ObfuscateDynamoDBString(v)
+		}
+	}
+	if a.conf.Obfuscation.Kafka.Enabled {
+		if v, ok := span.Meta[tagKafkaMessageKey]; ok {
+			span.Meta[tagKafkaMessageKey] = o.ObfuscateKafkaMessageKey(v)
+		}
+		if v, ok := span.Meta[tagKafkaMessagePayload]; ok {
+			span.Meta[tagKafkaMessagePayload] = o.ObfuscateKafkaMessagePayload(v)
+		}
 	}
 }
 
@@ -113,6 +156,8 @@ func (a *Agent) obfuscateStatsGroup(b *pb.ClientGroupedStats) {
 		}
 	case "redis":
 		b.Resource = o.QuantizeRedisString(b.Resource)
+	case "graphql":
+		b.Resource = o.ObfuscateGraphQLString(b.Resource)
 	}
 }
 
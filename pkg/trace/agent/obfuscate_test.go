@@ -9,6 +9,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/DataDog/datadog-agent/pkg/obfuscate"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
@@ -65,12 +66,23 @@ func TestObfuscateStatsGroup(t *testing.T) {
 		{statsGroup("sql", "SELECT 1\nFROM Blogs AS [b\nORDER BY [b]"), textNonParsable},
 		{statsGroup("redis", "ADD 1, 2"), "ADD"},
 		{statsGroup("other", "ADD 1, 2"), "ADD 1, 2"},
+		{statsGroup("graphql", `{ user(id: 4) { name } }`), `{ user(id: 4) { name } }`},
 	} {
 		agnt, stop := agentWithDefaults()
 		defer stop()
 		agnt.obfuscateStatsGroup(tt.in)
 		assert.Equal(t, tt.in.Resource, tt.out)
 	}
+
+	t.Run("graphql", func(t *testing.T) {
+		agnt, stop := agentWithDefaults()
+		defer stop()
+		agnt.conf.Obfuscation.GraphQL.Enabled = true
+		agnt.obfuscator = obfuscate.NewObfuscator(agnt.conf.Obfuscation.Export(agnt.conf))
+		in := statsGroup("graphql", `{ user(id: 4) { name } }`)
+		agnt.obfuscateStatsGroup(in)
+		assert.Equal(t, `{ user(id: ?) { name } }`, in.Resource)
+	})
 }
 
 // TestObfuscateDefaults ensures that running the obfuscator with no config continues to obfuscate/quantize
@@ -223,6 +235,70 @@ func TestObfuscateConfig(t *testing.T) {
 		"set key 0 0 0 noreply\r\nvalue",
 		&config.ObfuscationConfig{},
 	))
+
+	t.Run("graphql/enabled", testConfig(
+		"graphql",
+		"graphql.query",
+		`query { user(email: "jane@example.com") { id } }`,
+		`query { user(email: ?) { id } }`,
+		&config.ObfuscationConfig{GraphQL: config.GraphQLObfuscationConfig{Enabled: true}},
+	))
+
+	t.Run("graphql/variables", testConfig(
+		"graphql",
+		"graphql.variables.email",
+		"jane@example.com",
+		"?",
+		&config.ObfuscationConfig{GraphQL: config.GraphQLObfuscationConfig{Enabled: true}},
+	))
+
+	t.Run("graphql/disabled", testConfig(
+		"graphql",
+		"graphql.query",
+		`query { user(email: "jane@example.com") { id } }`,
+		`query { user(email: "jane@example.com") { id } }`,
+		&config.ObfuscationConfig{},
+	))
+
+	t.Run("dynamodb/enabled", testConfig(
+		"http",
+		"aws.dynamodb.request_parameters",
+		`{"TableName": "users", "Key": {"id": {"S": "jane"}}}`,
+		`{"TableName":"users","Key":{"id":{"S":"?"}}}`,
+		&config.ObfuscationConfig{DynamoDB: config.JSONObfuscationConfig{Enabled: true}},
+	))
+
+	t.Run("dynamodb/disabled", testConfig(
+		"http",
+		"aws.dynamodb.request_parameters",
+		`{"TableName": "users", "Key": {"id": {"S": "jane"}}}`,
+		`{"TableName": "users", "Key": {"id": {"S": "jane"}}}`,
+		&config.ObfuscationConfig{},
+	))
+
+	t.Run("kafka/enabled", testConfig(
+		"queue",
+		"messaging.kafka.message.payload",
+		`{"user": "jane"}`,
+		`{"user":"?"}`,
+		&config.ObfuscationConfig{Kafka: config.KafkaObfuscationConfig{Enabled: true}},
+	))
+
+	t.Run("kafka/keys", testConfig(
+		"queue",
+		"messaging.kafka.message.key",
+		"user-42",
+		"?",
+		&config.ObfuscationConfig{Kafka: config.KafkaObfuscationConfig{Enabled: true}},
+	))
+
+	t.Run("kafka/disabled", testConfig(
+		"queue",
+		"messaging.kafka.message.payload",
+		`{"user": "jane"}`,
+		`{"user": "jane"}`,
+		&config.ObfuscationConfig{},
+	))
 }
 
 func SQLSpan(query string) *pb.Span {
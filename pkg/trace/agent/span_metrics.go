@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// spanMetrics generates the metrics of the span metric rules from the received spans. It runs on
+// all the spans before they are sampled, so that the metrics account for every span received.
+type spanMetrics struct {
+	rules      []*config.SpanMetricRule
+	defaultEnv string
+}
+
+// newSpanMetrics returns the span metrics of the configuration, or nil if there are no rules.
+func newSpanMetrics(conf *config.AgentConfig) *spanMetrics {
+	if len(conf.SpanMetrics) == 0 {
+		return nil
+	}
+	return &spanMetrics{rules: conf.SpanMetrics, defaultEnv: conf.DefaultEnv}
+}
+
+// process emits the metrics of the rules matching the spans, tagged with the env and
+// the tags of the spans listed in group_by.
+func (m *spanMetrics) process(env string, spans []*pb.Span) {
+	if m == nil {
+		return
+	}
+	if env == "" {
+		env = m.defaultEnv
+	}
+	for _, span := range spans {
+		for _, rule := range m.rules {
+			if !matchesSpanMetricRule(rule, span) {
+				continue
+			}
+			tags := make([]string, 0, len(rule.GroupBy)+1)
+			tags = append(tags, "env:"+env)
+			for _, k := range rule.GroupBy {
+				if v, ok := spanTag(span, k); ok {
+					tags = append(tags, k+":"+v)
+				}
+			}
+			switch rule.Type {
+			case config.SpanMetricDistribution:
+				if v, ok := spanMetricValue(span, rule.Value); ok {
+					metrics.Distribution(rule.Name, v, tags, 1)
+				}
+			default:
+				metrics.Count(rule.Name, 1, tags, 1)
+			}
+		}
+	}
+}
+
+// matchesSpanMetricRule returns true if the span matches all the criteria of the rule.
+func matchesSpanMetricRule(rule *config.SpanMetricRule, span *pb.Span) bool {
+	if rule.Service != "" && span.Service != rule.Service {
+		return false
+	}
+	if rule.OperationName != "" && span.Name != rule.OperationName {
+		return false
+	}
+	if rule.Resource != nil && !rule.Resource.MatchString(span.Resource) {
+		return false
+	}
+	if rule.Error && span.Error == 0 {
+		return false
+	}
+	for _, tag := range rule.Tags {
+		if v, ok := spanTag(span, tag.K); !ok || (tag.V != "" && v != tag.V) {
+			return false
+		}
+	}
+	return true
+}
+
+// spanTag returns the value of the tag k of the span: its service, operation_name or
+// resource_name, or the value of its meta or metric k.
+func spanTag(span *pb.Span, k string) (string, bool) {
+	switch k {
+	case "service":
+		return span.Service, true
+	case "operation_name":
+		return span.Name, true
+	case "resource_name":
+		return span.Resource, true
+	}
+	if v, ok := span.Meta[k]; ok {
+		return v, true
+	}
+	if v, ok := span.Metrics[k]; ok {
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// spanMetricValue returns the value recorded by a distribution: the duration of the span
+// in seconds, or the value of its metric or numeric meta k.
+func spanMetricValue(span *pb.Span, k string) (float64, bool) {
+	if k == config.SpanMetricDuration {
+		return float64(span.Duration) / 1e9, true
+	}
+	if v, ok := span.Metrics[k]; ok {
+		return v, true
+	}
+	if v, ok := span.Meta[k]; ok {
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/teststatsd"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+)
+
+func TestSpanMetrics(t *testing.T) {
+	statsclient := &teststatsd.Client{}
+	defer testutil.WithStatsClient(statsclient)()
+
+	conf := config.New()
+	assert.Nil(t, newSpanMetrics(conf))
+	conf.SpanMetrics = []*config.SpanMetricRule{
+		{
+			Name:    "checkout.errors",
+			Type:    config.SpanMetricCount,
+			Service: "checkout",
+			Error:   true,
+			Tags:    []*config.Tag{{K: "http.route", V: "/checkout"}},
+			GroupBy: []string{"resource_name", "customer.tier"},
+		},
+		{
+			Name:     "db.duration",
+			Type:     config.SpanMetricDistribution,
+			Resource: regexp.MustCompile("^SELECT"),
+			Value:    config.SpanMetricDuration,
+		},
+		{
+			Name:          "cart.items",
+			Type:          config.SpanMetricDistribution,
+			OperationName: "cart.add",
+			Value:         "items",
+		},
+	}
+	m := newSpanMetrics(conf)
+
+	m.process("prod", []*pb.Span{
+		{Service: "checkout", Resource: "POST /checkout", Error: 1, Meta: map[string]string{"http.route": "/checkout", "customer.tier": "beta"}},
+		{Service: "checkout", Resource: "POST /checkout", Meta: map[string]string{"http.route": "/checkout"}},
+		{Service: "checkout", Resource: "GET /cart", Error: 1, Meta: map[string]string{"http.route": "/cart"}},
+		{Service: "db", Resource: "SELECT * FROM users", Duration: int64(250 * time.Millisecond)},
+		{Service: "db", Resource: "UPDATE users", Duration: int64(time.Second)},
+		{Service: "cart", Name: "cart.add", Metrics: map[string]float64{"items": 3}},
+		{Service: "cart", Name: "cart.add", Meta: map[string]string{"items": "2"}},
+		{Service: "cart", Name: "cart.add"},
+	})
+	m.process("", []*pb.Span{
+		{Service: "checkout", Resource: "POST /checkout", Error: 1, Meta: map[string]string{"http.route": "/checkout"}},
+	})
+
+	assert.Equal(t, []teststatsd.MetricsArgs{
+		{Name: "checkout.errors", Value: 1, Tags: []string{"env:prod", "resource_name:POST /checkout", "customer.tier:beta"}, Rate: 1},
+		{Name: "checkout.errors", Value: 1, Tags: []string{"env:none", "resource_name:POST /checkout"}, Rate: 1},
+	}, statsclient.CountCalls)
+	assert.Equal(t, []teststatsd.MetricsArgs{
+		{Name: "db.duration", Value: 0.25, Tags: []string{"env:prod"}, Rate: 1},
+		{Name: "cart.items", Value: 3, Tags: []string{"env:prod"}, Rate: 1},
+		{Name: "cart.items", Value: 2, Tags: []string{"env:prod"}, Rate: 1},
+	}, statsclient.DistributionCalls)
+}
@@ -0,0 +1,308 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/stats"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+)
+
+// tailSamplingInterval is the frequency at which the buffered traces are checked for completion.
+const tailSamplingInterval = time.Second
+
+// pendingTrace holds the spans of a trace buffered by the tail sampling mode, along with
+// the metadata of the payload they were first received with.
+type pendingTrace struct {
+	payload                *pb.TracerPayload // without its chunks
+	source                 *info.TagStats
+	clientComputedStats    bool
+	clientComputedTopLevel bool
+	clientDroppedP0sWeight float64
+	chunk                  *pb.TraceChunk
+	lastSeen               time.Time
+	complete               bool
+	element                *list.Element
+}
+
+// tailBuffer buffers the spans of the traces per trace ID until the traces are complete,
+// or haven't received any span for the decision wait. It is bounded in number of spans.
+type tailBuffer struct {
+	decisionWait time.Duration
+	maxSpans     int
+
+	mu     sync.Mutex
+	spans  int
+	traces map[uint64]*pendingTrace
+	order  *list.List // traces by first seen
+}
+
+func newTailBuffer(conf *config.AgentConfig) *tailBuffer {
+	return &tailBuffer{
+		decisionWait: conf.TailSampling.DecisionWait,
+		maxSpans:     conf.TailSampling.MaxSpans,
+		traces:       make(map[uint64]*pendingTrace),
+		order:        list.New(),
+	}
+}
+
+// add buffers the chunk with the other chunks of its trace. It returns the oldest traces
+// evicted to make room for it, which must be sampled right away.
+func (b *tailBuffer) add(now time.Time, p *api.Payload, chunk *pb.TraceChunk) []*pendingTrace {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	traceID := chunk.Spans[0].TraceID
+	t, ok := b.traces[traceID]
+	if !ok {
+		t = newPendingTrace(p, chunk)
+		t.element = b.order.PushBack(traceID)
+		b.traces[traceID] = t
+	} else {
+		mergeChunk(t.chunk, chunk)
+	}
+	t.lastSeen = now
+	t.complete = isComplete(t.chunk.Spans)
+	b.spans += len(chunk.Spans)
+
+	var evicted []*pendingTrace
+	for b.spans > b.maxSpans && b.order.Len() > 1 {
+		evicted = append(evicted, b.remove(b.order.Front().Value.(uint64)))
+	}
+	return evicted
+}
+
+// ready removes and returns the traces that are complete or quiet.
+func (b *tailBuffer) ready(now time.Time) []*pendingTrace {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var traces []*pendingTrace
+	for traceID, t := range b.traces {
+		if t.complete || now.Sub(t.lastSeen) >= b.decisionWait {
+			traces = append(traces, b.remove(traceID))
+		}
+	}
+	return traces
+}
+
+// flush removes and returns all the buffered traces.
+func (b *tailBuffer) flush() []*pendingTrace {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	traces := make([]*pendingTrace, 0, len(b.traces))
+	for traceID := range b.traces {
+		traces = append(traces, b.remove(traceID))
+	}
+	return traces
+}
+
+func (b *tailBuffer) remove(traceID uint64) *pendingTrace {
+	t := b.traces[traceID]
+	delete(b.traces, traceID)
+	b.order.Remove(t.element)
+	b.spans -= len(t.chunk.Spans)
+	return t
+}
+
+// len returns the number of buffered traces and spans.
+func (b *tailBuffer) len() (traces, spans int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.traces), b.spans
+}
+
+func newPendingTrace(p *api.Payload, chunk *pb.TraceChunk) *pendingTrace {
+	payload := *p.TracerPayload
+	payload.Chunks = nil
+	return &pendingTrace{
+		payload:                &payload,
+		source:                 p.Source,
+		clientComputedStats:    p.ClientComputedStats,
+		clientComputedTopLevel: p.ClientComputedTopLevel,
+		clientDroppedP0sWeight: float64(p.ClientDroppedP0s) / float64(len(p.Chunks())),
+		chunk: &pb.TraceChunk{
+			Priority: chunk.Priority,
+			Origin:   chunk.Origin,
+			Spans:    append([]*pb.Span(nil), chunk.Spans...),
+			Tags:     chunk.Tags,
+		},
+	}
+}
+
+// mergeChunk adds the spans and the tags of src to dst, the first chunk holding
+// a sampling priority deciding the priority of the trace.
+func mergeChunk(dst, src *pb.TraceChunk) {
+	dst.Spans = append(dst.Spans, src.Spans...)
+	if _, ok := sampler.GetSamplingPriority(dst); !ok {
+		dst.Priority = src.Priority
+	}
+	if dst.Origin == "" {
+		dst.Origin = src.Origin
+	}
+	for k, v := range src.Tags {
+		if dst.Tags == nil {
+			dst.Tags = make(map[string]string, len(src.Tags))
+		}
+		if _, ok := dst.Tags[k]; !ok {
+			dst.Tags[k] = v
+		}
+	}
+}
+
+// isComplete returns true if the trace has a root span and the parent of each of its spans.
+func isComplete(trace pb.Trace) bool {
+	spanIDs := make(map[uint64]struct{}, len(trace))
+	for _, span := range trace {
+		spanIDs[span.SpanID] = struct{}{}
+	}
+	hasRoot := false
+	for _, span := range trace {
+		if span.ParentID == 0 {
+			hasRoot = true
+			continue
+		}
+		if _, ok := spanIDs[span.ParentID]; !ok {
+			return false
+		}
+	}
+	return hasRoot
+}
+
+// bufferTailTrace buffers the chunk until its trace is complete or quiet.
+func (a *Agent) bufferTailTrace(now time.Time, p *api.Payload, chunk *pb.TraceChunk) {
+	for _, t := range a.tailBuffer.add(now, p, chunk) {
+		metrics.Count("datadog.trace_agent.tail_sampler.evicted", 1, nil, 1)
+		a.sampleTailTrace(now, t)
+	}
+}
+
+// runTailSampling samples the buffered traces as they become complete or quiet.
+func (a *Agent) runTailSampling() {
+	ticker := time.NewTicker(tailSamplingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			a.sampleTailTraces(now, false)
+		case <-a.ctx.Done():
+			return
+		}
+	}
+}
+
+// sampleTailTraces samples the buffered traces that are ready, or all of them when forced.
+func (a *Agent) sampleTailTraces(now time.Time, force bool) {
+	var traces []*pendingTrace
+	if force {
+		traces = a.tailBuffer.flush()
+	} else {
+		traces = a.tailBuffer.ready(now)
+	}
+	for _, t := range traces {
+		a.sampleTailTrace(now, t)
+	}
+	numTraces, numSpans := a.tailBuffer.len()
+	metrics.Gauge("datadog.trace_agent.tail_sampler.buffered_traces", float64(numTraces), nil, 1)
+	metrics.Gauge("datadog.trace_agent.tail_sampler.buffered_spans", float64(numSpans), nil, 1)
+}
+
+// sampleTailTrace runs the filters and the samplers on the whole trace and sends it to the writer
+// when kept. The traces matching a tail sampling policy are kept on top of the ones kept by the
+// other samplers. The stats are computed at this point, once the samplers are done with the spans.
+func (a *Agent) sampleTailTrace(now time.Time, t *pendingTrace) {
+	chunk := t.chunk
+	root := traceutil.GetRoot(chunk.Spans)
+	normalizeChunk(chunk, root)
+	if !a.Blacklister.Allows(root) {
+		log.Debugf("Trace rejected by ignore resources rules. root: %v", root)
+		t.source.TracesFiltered.Inc()
+		t.source.SpansFiltered.Add(int64(len(chunk.Spans)))
+		return
+	}
+	if filteredByTags(root, a.conf.RequireTags, a.conf.RejectTags) {
+		log.Debugf("Trace rejected as it fails to meet tag requirements. root: %v", root)
+		t.source.TracesFiltered.Inc()
+		t.source.SpansFiltered.Add(int64(len(chunk.Spans)))
+		return
+	}
+
+	a.setRootSpanTags(root)
+	if !t.clientComputedTopLevel {
+		traceutil.ComputeTopLevel(chunk.Spans)
+	}
+	if t.payload.Hostname == "" {
+		t.payload.Hostname = root.Meta[tagHostname]
+	}
+	if t.payload.Env == "" {
+		t.payload.Env = traceutil.GetEnv(root, chunk)
+	}
+	if t.payload.AppVersion == "" {
+		t.payload.AppVersion = traceutil.GetAppVersion(root, chunk)
+	}
+	a.spanMetrics.process(t.payload.Env, chunk.Spans)
+
+	ptChunk := new(pb.TraceChunk)
+	*ptChunk = *chunk
+	pt := traceutil.ProcessedTrace{
+		TraceChunk:             ptChunk,
+		Root:                   root,
+		AppVersion:             t.payload.AppVersion,
+		TracerEnv:              t.payload.Env,
+		TracerHostname:         t.payload.Hostname,
+		ClientDroppedP0sWeight: t.clientDroppedP0sWeight,
+	}
+	if !t.clientComputedStats {
+		statsInput := stats.NewStatsInput(1, t.payload.ContainerID, false, a.conf)
+		statsInput.Traces = append(statsInput.Traces, pt)
+		defer func() { a.Concentrator.In <- statsInput }()
+	}
+
+	numEvents, keep, filteredChunk := a.sample(now, t.source, pt)
+	// a nil filtered chunk means the trace was dropped by the user, which the policies respect
+	if !keep && filteredChunk != nil {
+		if policy, ok := a.TailSampler.Sample(chunk.Spans, root); ok {
+			log.Debugf("Trace %d kept by tail sampling policy %s", root.TraceID, policy)
+			keep = true
+			if chunk.Priority < int32(sampler.PriorityAutoKeep) {
+				chunk.Priority = int32(sampler.PriorityAutoKeep)
+			}
+		}
+	}
+	if !keep {
+		keep = sampler.ApplySpanSampling(chunk)
+	}
+	if !keep {
+		if numEvents == 0 {
+			return
+		}
+		chunk = filteredChunk
+	}
+
+	payload := *t.payload
+	payload.Chunks = []*pb.TraceChunk{chunk}
+	ss := &writer.SampledChunks{
+		TracerPayload: &payload,
+		Size:          chunk.Msgsize(),
+		EventCount:    numEvents,
+	}
+	if !chunk.DroppedTrace {
+		ss.SpanCount = int64(len(chunk.Spans))
+	}
+	a.TraceWriter.In <- ss
+}
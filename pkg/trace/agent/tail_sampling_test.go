@@ -0,0 +1,207 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+)
+
+func newTailPayload(env string, chunk *pb.TraceChunk) *api.Payload {
+	tp := testutil.TracerPayloadWithChunk(chunk)
+	tp.Env = env
+	return &api.Payload{
+		TracerPayload: tp,
+		Source:        info.NewReceiverStats().GetTagStats(info.Tags{}),
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	cfg := config.New()
+	cfg.TailSampling.DecisionWait = 10 * time.Second
+	cfg.TailSampling.MaxSpans = 4
+	b := newTailBuffer(cfg)
+	now := time.Now()
+
+	child := &pb.Span{TraceID: 1, SpanID: 2, ParentID: 1}
+	root := &pb.Span{TraceID: 1, SpanID: 1}
+	other := &pb.Span{TraceID: 2, SpanID: 3, ParentID: 42}
+
+	assert.Empty(t, b.add(now, newTailPayload("prod", testutil.TraceChunkWithSpan(child)), testutil.TraceChunkWithSpan(child)))
+	assert.Empty(t, b.add(now, newTailPayload("prod", testutil.TraceChunkWithSpan(other)), testutil.TraceChunkWithSpan(other)))
+	// nothing is ready until the root span is received
+	assert.Empty(t, b.ready(now))
+
+	rootChunk := testutil.TraceChunkWithSpan(root)
+	rootChunk.Priority = int32(sampler.PriorityAutoKeep)
+	rootChunk.Tags = map[string]string{"_dd.p.dm": "-1"}
+	assert.Empty(t, b.add(now, newTailPayload("staging", rootChunk), rootChunk))
+	traces, spans := b.len()
+	assert.Equal(t, 2, traces)
+	assert.Equal(t, 3, spans)
+
+	ready := b.ready(now)
+	require.Len(t, ready, 1)
+	assert.ElementsMatch(t, []*pb.Span{child, root}, ready[0].chunk.Spans)
+	assert.Equal(t, int32(sampler.PriorityAutoKeep), ready[0].chunk.Priority)
+	assert.Equal(t, map[string]string{"_dd.p.dm": "-1"}, ready[0].chunk.Tags)
+	// the payload metadata is the one of the first chunk
+	assert.Equal(t, "prod", ready[0].payload.Env)
+	assert.Empty(t, ready[0].payload.Chunks)
+
+	// incomplete traces are ready once quiet
+	assert.Empty(t, b.ready(now.Add(5*time.Second)))
+	ready = b.ready(now.Add(10 * time.Second))
+	require.Len(t, ready, 1)
+	assert.Equal(t, []*pb.Span{other}, ready[0].chunk.Spans)
+
+	// the oldest traces are evicted above the maximum number of spans
+	for i := uint64(10); i < 14; i++ {
+		span := &pb.Span{TraceID: i, SpanID: i}
+		assert.Empty(t, b.add(now, newTailPayload("prod", testutil.TraceChunkWithSpan(span)), testutil.TraceChunkWithSpan(span)))
+	}
+	span := &pb.Span{TraceID: 14, SpanID: 14}
+	evicted := b.add(now, newTailPayload("prod", testutil.TraceChunkWithSpan(span)), testutil.TraceChunkWithSpan(span))
+	require.Len(t, evicted, 1)
+	assert.Equal(t, uint64(10), evicted[0].chunk.Spans[0].TraceID)
+
+	assert.Len(t, b.flush(), 4)
+	traces, spans = b.len()
+	assert.Equal(t, 0, traces)
+	assert.Equal(t, 0, spans)
+}
+
+func TestTailSampling(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSampling.Enabled = true
+	cfg.TailSampling.Policies = []*config.TailSamplingPolicy{
+		{Name: "slow_checkout", Service: "checkout", MinDuration: time.Second},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewAgent(ctx, cfg, telemetry.NewNoopCollector())
+
+	now := time.Now()
+	newTrace := func(traceID uint64, duration time.Duration) (root, child *pb.Span) {
+		root = &pb.Span{
+			TraceID:  traceID,
+			SpanID:   1,
+			Service:  "checkout",
+			Name:     "http.request",
+			Resource: "POST /checkout",
+			Start:    now.Add(-5 * time.Second).UnixNano(),
+			Duration: duration.Nanoseconds(),
+		}
+		child = &pb.Span{
+			TraceID:  traceID,
+			SpanID:   2,
+			ParentID: 1,
+			Service:  "checkout",
+			Name:     "payment.charge",
+			Resource: "charge",
+			Start:    root.Start,
+			Duration: duration.Nanoseconds() / 2,
+		}
+		return root, child
+	}
+	process := func(span *pb.Span) {
+		chunk := testutil.TraceChunkWithSpan(span)
+		chunk.Priority = int32(sampler.PriorityAutoDrop)
+		agnt.Process(newTailPayload("prod", chunk))
+	}
+
+	slowRoot, slowChild := newTrace(1, 2*time.Second)
+	fastRoot, fastChild := newTrace(2, 100*time.Millisecond)
+	process(slowChild)
+	process(fastChild)
+	process(slowRoot)
+	process(fastRoot)
+	// the chunks are buffered until their traces are sampled
+	assert.Len(t, agnt.TraceWriter.In, 0)
+	assert.Len(t, agnt.Concentrator.In, 0)
+
+	agnt.sampleTailTraces(time.Now(), false)
+	// the slow trace is kept despite its priority, the fast one is dropped
+	require.Len(t, agnt.TraceWriter.In, 1)
+	ss := <-agnt.TraceWriter.In
+	assert.Equal(t, "prod", ss.TracerPayload.Env)
+	require.Len(t, ss.TracerPayload.Chunks, 1)
+	chunk := ss.TracerPayload.Chunks[0]
+	assert.ElementsMatch(t, []*pb.Span{slowRoot, slowChild}, chunk.Spans)
+	assert.Equal(t, int32(sampler.PriorityAutoKeep), chunk.Priority)
+	assert.Equal(t, "slow_checkout", slowRoot.Meta["_dd.tail_sampling.policy"])
+	assert.Equal(t, int64(2), ss.SpanCount)
+
+	// the stats are computed on both traces
+	assert.Len(t, agnt.Concentrator.In, 2)
+	traces, _ := agnt.tailBuffer.len()
+	assert.Equal(t, 0, traces)
+}
+
+func TestTailSamplingSplitTrace(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSampling.Enabled = true
+	cfg.Ignore["resource"] = []string{"GET /health"}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewAgent(ctx, cfg, telemetry.NewNoopCollector())
+
+	newTrace := func(traceID uint64, resource string) (root, child1, child2 *pb.Span) {
+		root = &pb.Span{TraceID: traceID, SpanID: 1, Service: "web", Name: "http.request", Resource: resource, Duration: 100}
+		child1 = &pb.Span{TraceID: traceID, SpanID: 2, ParentID: 1, Service: "web", Name: "render", Resource: "render", Duration: 10}
+		child2 = &pb.Span{TraceID: traceID, SpanID: 3, ParentID: 1, Service: "db", Name: "query", Resource: "SELECT", Duration: 10}
+		return root, child1, child2
+	}
+	process := func(spans ...*pb.Span) *api.Payload {
+		chunk := testutil.TraceChunkWithSpans(spans)
+		chunk.Priority = int32(sampler.PriorityUserKeep)
+		p := newTailPayload("prod", chunk)
+		agnt.Process(p)
+		return p
+	}
+
+	// the children are received before their root, in another chunk
+	root, child1, child2 := newTrace(1, "GET /users")
+	process(child1, child2)
+	process(root)
+	agnt.sampleTailTraces(time.Now(), false)
+
+	require.Len(t, agnt.TraceWriter.In, 1)
+	ss := <-agnt.TraceWriter.In
+	require.Len(t, ss.TracerPayload.Chunks, 1)
+	assert.ElementsMatch(t, []*pb.Span{root, child1, child2}, ss.TracerPayload.Chunks[0].Spans)
+	// the top-level spans are computed on the whole trace
+	assert.Equal(t, float64(1), root.Metrics["_top_level"])
+	assert.NotContains(t, child1.Metrics, "_top_level")
+	assert.Equal(t, float64(1), child2.Metrics["_top_level"])
+	assert.Len(t, agnt.Concentrator.In, 1)
+	<-agnt.Concentrator.In
+
+	// the ignore resources rules apply to the root, even when received last
+	root, child1, child2 = newTrace(2, "GET /health")
+	p := process(child1, child2)
+	process(root)
+	agnt.sampleTailTraces(time.Now(), false)
+
+	assert.Len(t, agnt.TraceWriter.In, 0)
+	assert.Len(t, agnt.Concentrator.In, 0)
+	assert.EqualValues(t, 1, p.Source.TracesFiltered.Load())
+	assert.EqualValues(t, 3, p.Source.SpansFiltered.Load())
+}
@@ -72,6 +72,8 @@ type HTTPReceiver struct {
 	server              *http.Server
 	statsProcessor      StatsProcessor
 	containerIDProvider IDProvider
+	otlp                *OTLPReceiver // converts the spans received from other tracing protocols
+	capture             *capture      // captures the payloads received, if enabled
 
 	telemetryCollector telemetry.TelemetryCollector
 
@@ -99,6 +101,7 @@ func NewHTTPReceiver(conf *config.AgentConfig, dynConf *sampler.DynamicConfig, o
 		conf:                conf,
 		dynConf:             dynConf,
 		containerIDProvider: NewIDProvider(conf.ContainerProcRoot),
+		otlp:                NewOTLPReceiver(out, conf),
 
 		telemetryCollector: telemetryCollector,
 
@@ -142,6 +145,16 @@ func (r *HTTPReceiver) Start() {
 		return
 	}
 
+	if r.conf.Capture.Enabled {
+		c, err := newCapture(r.conf.Capture)
+		if err != nil {
+			log.Errorf("Could not start capturing payloads: %v", err)
+		} else {
+			r.capture = c
+			log.Infof("Capturing the payloads received to %s", r.conf.Capture.Dir)
+		}
+	}
+
 	timeout := 5 * time.Second
 	if r.conf.ReceiverTimeout > 0 {
 		timeout = time.Duration(r.conf.ReceiverTimeout) * time.Second
@@ -269,6 +282,9 @@ func (r *HTTPReceiver) Stop() error {
 		return err
 	}
 	r.wg.Wait()
+	if err := r.capture.close(); err != nil {
+		log.Errorf("Error closing capture file: %v", err)
+	}
 	close(r.out)
 	return nil
 }
@@ -286,6 +302,9 @@ func (r *HTTPReceiver) handleWithVersion(v Version, f func(Version, http.Respons
 			return
 		}
 
+		if r.capture != nil {
+			r.capture.record(req, r.conf.MaxRequestBytes)
+		}
 		// TODO(x): replace with http.MaxBytesReader?
 		req.Body = apiutil.NewLimitedReader(req.Body, r.conf.MaxRequestBytes)
 
@@ -423,6 +442,9 @@ type StatsProcessor interface {
 func (r *HTTPReceiver) handleStats(w http.ResponseWriter, req *http.Request) {
 	defer timing.Since("datadog.trace_agent.receiver.stats_process_ms", time.Now())
 
+	if r.capture != nil {
+		r.capture.record(req, r.conf.MaxRequestBytes)
+	}
 	ts := r.tagStats(V07, req.Header)
 	rd := apiutil.NewLimitedReader(req.Body, r.conf.MaxRequestBytes)
 	req.Header.Set("Accept", "application/msgpack")
@@ -0,0 +1,457 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+)
+
+// captureMagic starts every capture file. Its last byte is the version of the file format.
+var captureMagic = []byte{'D', 'D', 'T', 'R', 'C', 'A', 'P', 1}
+
+// captureFilePrefix is the prefix of the names of the capture files. It is followed by the
+// time at which the file was created, in nanoseconds, so that the files sort by age.
+const captureFilePrefix = "trace-capture-"
+
+// maxCapturedRequestSize bounds the size of a request read from a capture file.
+const maxCapturedRequestSize = 1 << 30
+
+// ErrNotCaptureFile is returned when reading a file which is not a capture file.
+var ErrNotCaptureFile = errors.New("not a trace-agent capture file")
+
+// CapturedRequest is a request received by the trace-agent, as written to a capture file.
+type CapturedRequest struct {
+	// Time is the time at which the request was received.
+	Time time.Time
+	// Method is the HTTP method of the request.
+	Method string
+	// Path is the path of the request, including its query.
+	Path string
+	// Header holds the headers of the request describing the payload: its content type and
+	// encoding, and the Datadog headers such as the language or the container ID.
+	Header http.Header
+	// Body is the raw payload of the request.
+	Body []byte
+}
+
+// isCapturedHeader reports whether the header k is written to the capture files. The headers
+// which are not needed to decode the payloads, such as API keys, are left out.
+func isCapturedHeader(k string) bool {
+	switch k {
+	case "Content-Type", "Content-Encoding":
+		return true
+	}
+	return strings.HasPrefix(k, "Datadog-") || strings.HasPrefix(k, "X-Datadog-")
+}
+
+// capture writes the requests received by the trace-agent to capture files, starting a
+// new file above the maximum file size and removing the oldest files above the maximum
+// number of files.
+type capture struct {
+	conf config.CaptureConfig
+
+	mu    sync.Mutex
+	f     *os.File // current capture file, nil until the first request
+	size  int64    // size of f
+	files []string // paths of the capture files, oldest first
+}
+
+// newCapture returns a capture writing to the directory of conf. The capture files left
+// in the directory by previous runs count towards the maximum number of files.
+func newCapture(conf config.CaptureConfig) (*capture, error) {
+	if conf.Dir == "" {
+		return nil, errors.New("no capture directory")
+	}
+	if err := os.MkdirAll(conf.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	files, err := CaptureFiles(conf.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return &capture{conf: conf, files: files}, nil
+}
+
+// record writes req to the capture, its body being read up to maxBytes. The body of req
+// is replaced by an equivalent one, so that the request can then be handled as usual.
+func (c *capture) record(req *http.Request, maxBytes int64) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxBytes+1))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+	if err != nil || int64(len(body)) > maxBytes {
+		// the request is rejected by its handler
+		return
+	}
+	cr := &CapturedRequest{
+		Time:   time.Now(),
+		Method: req.Method,
+		Path:   req.URL.RequestURI(),
+		Header: make(http.Header),
+		Body:   body,
+	}
+	for k, v := range req.Header {
+		if isCapturedHeader(k) {
+			cr.Header[k] = v
+		}
+	}
+	if err := c.write(cr); err != nil {
+		log.Errorf("Error capturing payload: %v", err)
+	}
+}
+
+// write appends cr to the current capture file.
+func (c *capture) write(cr *CapturedRequest) error {
+	b := cr.marshal()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.f == nil || (c.size+int64(len(b)) > c.conf.MaxFileSize && c.size > int64(len(captureMagic))) {
+		if err := c.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := c.f.Write(b)
+	c.size += int64(n)
+	return err
+}
+
+// rotate closes the current capture file and starts a new one, removing the oldest files
+// above the maximum number of files.
+func (c *capture) rotate() error {
+	if c.f != nil {
+		if err := c.f.Close(); err != nil {
+			log.Errorf("Error closing capture file: %v", err)
+		}
+		c.f = nil
+	}
+	path := filepath.Join(c.conf.Dir, fmt.Sprintf("%s%d", captureFilePrefix, time.Now().UnixNano()))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(captureMagic); err != nil {
+		f.Close()
+		return err
+	}
+	c.f = f
+	c.size = int64(len(captureMagic))
+	c.files = append(c.files, path)
+	for c.conf.MaxFiles > 0 && len(c.files) > c.conf.MaxFiles {
+		if err := os.Remove(c.files[0]); err != nil && !os.IsNotExist(err) {
+			log.Errorf("Error removing capture file: %v", err)
+		}
+		c.files = c.files[1:]
+	}
+	log.Debugf("Capturing payloads to %s", path)
+	return nil
+}
+
+// close closes the current capture file. It is safe to call on a nil capture.
+func (c *capture) close() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.f == nil {
+		return nil
+	}
+	err := c.f.Close()
+	c.f = nil
+	return err
+}
+
+// marshal encodes cr as a record of a capture file: its size on 4 bytes followed by the
+// time, the method, the path, the headers and the body of the request. All the integers
+// are big endian and all the strings are prefixed by their size on 4 bytes.
+func (cr *CapturedRequest) marshal() []byte {
+	keys := make([]string, 0, len(cr.Header))
+	var nvalues int
+	for k, v := range cr.Header {
+		keys = append(keys, k)
+		nvalues += len(v)
+	}
+	sort.Strings(keys)
+
+	b := make([]byte, 4, 64+len(cr.Path)+len(cr.Body))
+	b = appendUint(b, uint64(cr.Time.UnixNano()), 8)
+	b = appendCaptureBytes(b, []byte(cr.Method))
+	b = appendCaptureBytes(b, []byte(cr.Path))
+	b = appendUint(b, uint64(nvalues), 4)
+	for _, k := range keys {
+		for _, v := range cr.Header[k] {
+			b = appendCaptureBytes(b, []byte(k))
+			b = appendCaptureBytes(b, []byte(v))
+		}
+	}
+	b = appendCaptureBytes(b, cr.Body)
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	return b
+}
+
+// appendUint appends the n least significant bytes of v to b, big endian.
+func appendUint(b []byte, v uint64, n int) []byte {
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+// appendCaptureBytes appends v to b, prefixed by its size.
+func appendCaptureBytes(b, v []byte) []byte {
+	return append(appendUint(b, uint64(len(v)), 4), v...)
+}
+
+// captureDecoder decodes the fields of a record of a capture file.
+type captureDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *captureDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.b) {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *captureDecoder) uint32() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *captureDecoder) uint64() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *captureDecoder) bytes() []byte {
+	return d.next(int(d.uint32()))
+}
+
+// unmarshal decodes the record b, without its size, into cr.
+func (cr *CapturedRequest) unmarshal(b []byte) error {
+	d := captureDecoder{b: b}
+	cr.Time = time.Unix(0, int64(d.uint64()))
+	cr.Method = string(d.bytes())
+	cr.Path = string(d.bytes())
+	nvalues := d.uint32()
+	cr.Header = make(http.Header)
+	for i := uint32(0); i < nvalues && d.err == nil; i++ {
+		k, v := string(d.bytes()), string(d.bytes())
+		cr.Header[k] = append(cr.Header[k], v)
+	}
+	cr.Body = d.bytes()
+	return d.err
+}
+
+// CaptureReader reads the requests of a capture file.
+type CaptureReader struct {
+	r *bufio.Reader
+}
+
+// NewCaptureReader returns a reader of the capture file r. It returns ErrNotCaptureFile
+// if r is not a capture file.
+func NewCaptureReader(r io.Reader) (*CaptureReader, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(captureMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || !bytes.Equal(magic, captureMagic) {
+		return nil, ErrNotCaptureFile
+	}
+	return &CaptureReader{r: br}, nil
+}
+
+// Next returns the next request of the capture file, or io.EOF at its end. A capture file
+// cut in the middle of a request, for instance if the trace-agent was killed while writing
+// it, ends with io.ErrUnexpectedEOF.
+func (cr *CaptureReader) Next() (*CapturedRequest, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(cr.r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxCapturedRequestSize {
+		return nil, fmt.Errorf("captured request too large: %d bytes", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(cr.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	var req CapturedRequest
+	if err := req.unmarshal(b); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// CaptureFiles returns the capture files found at path: path itself if it is a file, or
+// the capture files it contains, oldest first, if it is a directory.
+func CaptureFiles(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), captureFilePrefix) {
+			files = append(files, filepath.Join(path, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ReplayStats holds the outcome of the replay of a capture.
+type ReplayStats struct {
+	// Requests is the number of requests sent.
+	Requests int
+	// Errors is the number of requests which were not accepted by the trace-agent.
+	Errors int
+}
+
+// ReplayCapture sends the requests of the capture files to the trace-agent at the URL
+// addr, such as "http://localhost:8126". The requests are sent at the pace at which they
+// were captured, accelerated by the factor speed. If speed is 0, they are sent as fast as
+// possible. Replaying stops at the first request which could not be sent.
+func ReplayCapture(ctx context.Context, files []string, addr string, speed float64) (ReplayStats, error) {
+	r := replayer{
+		client: &http.Client{Timeout: 10 * time.Second},
+		addr:   strings.TrimSuffix(addr, "/"),
+		speed:  speed,
+	}
+	for _, path := range files {
+		if err := r.replayFile(ctx, path); err != nil {
+			return r.stats, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return r.stats, nil
+}
+
+// replayer sends the requests of capture files.
+type replayer struct {
+	client *http.Client
+	addr   string
+	speed  float64
+
+	first time.Time // capture time of the first request
+	start time.Time // time at which the first request was sent
+	stats ReplayStats
+}
+
+// replayFile sends the requests of the capture file path.
+func (r *replayer) replayFile(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	cr, err := NewCaptureReader(f)
+	if err != nil {
+		return err
+	}
+	for {
+		req, err := cr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err == io.ErrUnexpectedEOF {
+			log.Warnf("Capture file %s is truncated, its last request is skipped.", path)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := r.wait(ctx, req.Time); err != nil {
+			return err
+		}
+		if err := r.send(ctx, req); err != nil {
+			return err
+		}
+	}
+}
+
+// wait waits until the request captured at t is due.
+func (r *replayer) wait(ctx context.Context, t time.Time) error {
+	if r.speed <= 0 {
+		return nil
+	}
+	if r.first.IsZero() {
+		r.first = t
+		r.start = time.Now()
+		return nil
+	}
+	due := r.start.Add(time.Duration(float64(t.Sub(r.first)) / r.speed))
+	d := time.Until(due)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// send sends the captured request cr.
+func (r *replayer) send(ctx context.Context, cr *CapturedRequest) error {
+	req, err := http.NewRequestWithContext(ctx, cr.Method, r.addr+cr.Path, bytes.NewReader(cr.Body))
+	if err != nil {
+		return err
+	}
+	req.Header = cr.Header.Clone()
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body) //nolint:errcheck
+	resp.Body.Close()
+	r.stats.Requests++
+	if resp.StatusCode >= http.StatusBadRequest {
+		r.stats.Errors++
+		log.Warnf("Replayed request %s %s was refused: %s", cr.Method, cr.Path, resp.Status)
+	}
+	return nil
+}
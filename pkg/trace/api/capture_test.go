@@ -0,0 +1,201 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+)
+
+// readCapture returns all the requests of the capture files in dir.
+func readCapture(t *testing.T, dir string) []*CapturedRequest {
+	files, err := CaptureFiles(dir)
+	require.NoError(t, err)
+	var reqs []*CapturedRequest
+	for _, path := range files {
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		cr, err := NewCaptureReader(f)
+		require.NoError(t, err)
+		for {
+			req, err := cr.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			reqs = append(reqs, req)
+		}
+		f.Close()
+	}
+	return reqs
+}
+
+func TestCaptureReceiver(t *testing.T) {
+	assert := assert.New(t)
+	bts, err := testutil.GetTestTraces(10, 10, true).MarshalMsg(nil)
+	require.NoError(t, err)
+
+	conf := newTestReceiverConfig()
+	conf.Capture = config.CaptureConfig{Enabled: true, Dir: t.TempDir(), MaxFileSize: 1 << 20, MaxFiles: 2}
+	rcv := newTestReceiverFromConfig(conf)
+	rcv.capture, err = newCapture(conf.Capture)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/v0.4/traces?q=1", bytes.NewReader(bts))
+	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("Datadog-Meta-Lang", "go")
+	req.Header.Set("Datadog-Container-ID", "abc123")
+	req.Header.Set("Dd-Api-Key", "secret")
+	rcv.handleWithVersion(v04, rcv.handleTraces).ServeHTTP(httptest.NewRecorder(), req)
+	require.NoError(t, rcv.capture.close())
+
+	// the payload is handled as usual
+	ts, ok := rcv.Stats.Stats[info.Tags{Lang: "go", EndpointVersion: "v0.4"}]
+	require.True(t, ok)
+	assert.Equal(int64(10), ts.TracesReceived.Load())
+	assert.Equal(int64(len(bts)), ts.TracesBytes.Load())
+
+	reqs := readCapture(t, conf.Capture.Dir)
+	require.Len(t, reqs, 1)
+	assert.Equal("POST", reqs[0].Method)
+	assert.Equal("/v0.4/traces?q=1", reqs[0].Path)
+	assert.Equal(http.Header{
+		"Content-Type":         {"application/msgpack"},
+		"Datadog-Meta-Lang":    {"go"},
+		"Datadog-Container-Id": {"abc123"},
+	}, reqs[0].Header)
+	assert.Equal(bts, reqs[0].Body)
+	assert.WithinDuration(time.Now(), reqs[0].Time, time.Minute)
+
+	t.Run("too-large", func(t *testing.T) {
+		defer func(old int64) { rcv.conf.MaxRequestBytes = old }(rcv.conf.MaxRequestBytes)
+		rcv.conf.MaxRequestBytes = 10
+		req, _ := http.NewRequest("POST", "/v0.4/traces", bytes.NewReader(bts))
+		req.Header.Set("Content-Type", "application/msgpack")
+		rr := httptest.NewRecorder()
+		rcv.handleWithVersion(v04, rcv.handleTraces).ServeHTTP(rr, req)
+		assert.Equal(http.StatusRequestEntityTooLarge, rr.Code)
+		require.NoError(t, rcv.capture.close())
+		assert.Len(readCapture(t, conf.Capture.Dir), 1)
+	})
+}
+
+func TestCaptureRotation(t *testing.T) {
+	dir := t.TempDir()
+	// a capture file left by a previous run
+	old := filepath.Join(dir, captureFilePrefix+"1")
+	require.NoError(t, os.WriteFile(old, captureMagic, 0o600))
+
+	c, err := newCapture(config.CaptureConfig{Dir: dir, MaxFileSize: 100, MaxFiles: 2})
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, c.write(&CapturedRequest{Method: "PUT", Path: "/v0.4/traces", Body: make([]byte, 60)}))
+	}
+	require.NoError(t, c.close())
+
+	// every request exceeds the maximum file size, the two last files are kept
+	files, err := CaptureFiles(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.NotContains(t, files, old)
+	assert.Len(t, readCapture(t, dir), 2)
+}
+
+func TestReplayCapture(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		got  []*CapturedRequest
+		fail bool
+	)
+	reset := func(refuse bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		got, fail = nil, refuse
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, &CapturedRequest{Method: req.Method, Path: req.URL.RequestURI(), Header: req.Header, Body: body})
+		if fail {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	c, err := newCapture(config.CaptureConfig{Dir: dir, MaxFileSize: 1 << 20})
+	require.NoError(t, err)
+	now := time.Now()
+	in := []*CapturedRequest{
+		{Time: now, Method: "PUT", Path: "/v0.4/traces", Header: http.Header{"Content-Type": {"application/msgpack"}, "Datadog-Meta-Lang": {"go"}}, Body: []byte("traces")},
+		{Time: now.Add(200 * time.Millisecond), Method: "POST", Path: "/v0.6/stats", Header: http.Header{}, Body: []byte("stats")},
+	}
+	for _, req := range in {
+		require.NoError(t, c.write(req))
+	}
+	require.NoError(t, c.close())
+	files, err := CaptureFiles(dir)
+	require.NoError(t, err)
+
+	t.Run("pace", func(t *testing.T) {
+		reset(false)
+		start := time.Now()
+		stats, err := ReplayCapture(context.Background(), files, srv.URL, 2)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+		assert.Equal(t, ReplayStats{Requests: 2}, stats)
+		require.Len(t, got, 2)
+		for i, req := range got {
+			assert.Equal(t, in[i].Method, req.Method)
+			assert.Equal(t, in[i].Path, req.Path)
+			assert.Equal(t, in[i].Body, req.Body)
+			for k := range in[i].Header {
+				assert.Equal(t, in[i].Header.Get(k), req.Header.Get(k))
+			}
+		}
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		reset(true)
+		defer reset(false)
+		stats, err := ReplayCapture(context.Background(), files, srv.URL, 0)
+		require.NoError(t, err)
+		assert.Equal(t, ReplayStats{Requests: 2, Errors: 2}, stats)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		reset(false)
+		fi, err := os.Stat(files[0])
+		require.NoError(t, err)
+		require.NoError(t, os.Truncate(files[0], fi.Size()-1))
+		stats, err := ReplayCapture(context.Background(), files, srv.URL, 0)
+		require.NoError(t, err)
+		assert.Equal(t, ReplayStats{Requests: 1}, stats)
+	})
+
+	t.Run("not-a-capture", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "payload")
+		require.NoError(t, os.WriteFile(path, []byte("{}"), 0o600))
+		_, err := ReplayCapture(context.Background(), []string{path}, srv.URL, 0)
+		assert.ErrorIs(t, err, ErrNotCaptureFile)
+	})
+}
@@ -132,4 +132,14 @@ var endpoints = []Endpoint{
 		Pattern: "/dogstatsd/v2/proxy",
 		Handler: func(r *HTTPReceiver) http.Handler { return r.dogstatsdProxyHandler() },
 	},
+	{
+		Pattern:   "/api/v2/spans",
+		Handler:   func(r *HTTPReceiver) http.Handler { return http.HandlerFunc(r.handleZipkin) },
+		IsEnabled: func(cfg *config.AgentConfig) bool { return cfg.ZipkinReceiverEnabled },
+	},
+	{
+		Pattern:   "/api/traces",
+		Handler:   func(r *HTTPReceiver) http.Handler { return http.HandlerFunc(r.handleJaeger) },
+		IsEnabled: func(cfg *config.AgentConfig) bool { return cfg.JaegerReceiverEnabled },
+	},
 }
@@ -0,0 +1,432 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/collector/semconv/v1.6.1"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
+)
+
+// Values of the TagType enum of jaeger.thrift.
+const (
+	jaegerTagString int32 = 0
+	jaegerTagDouble int32 = 1
+	jaegerTagBool   int32 = 2
+	jaegerTagLong   int32 = 3
+	jaegerTagBinary int32 = 4
+)
+
+const (
+	// jaegerRefChildOf is the CHILD_OF value of the SpanRefType enum of jaeger.thrift.
+	jaegerRefChildOf int32 = 0
+	// jaegerFlagDebug is the flag of the debug spans, which must be kept.
+	jaegerFlagDebug int32 = 2
+)
+
+// jaegerSpanKinds maps the values of the span.kind tag to OTLP span kinds.
+var jaegerSpanKinds = map[string]ptrace.SpanKind{
+	"client":   ptrace.SpanKindClient,
+	"server":   ptrace.SpanKindServer,
+	"producer": ptrace.SpanKindProducer,
+	"consumer": ptrace.SpanKindConsumer,
+	"internal": ptrace.SpanKindInternal,
+}
+
+// jaegerErrorLogFields maps the fields of the OpenTracing error logs to the attributes
+// of the OTLP exception events.
+var jaegerErrorLogFields = map[string]string{
+	"message":    semconv.AttributeExceptionMessage,
+	"error.kind": semconv.AttributeExceptionType,
+	"stack":      semconv.AttributeExceptionStacktrace,
+}
+
+// jaegerBatch is the Batch struct of jaeger.thrift: the spans of a process.
+type jaegerBatch struct {
+	process jaegerProcess
+	spans   []jaegerSpan
+}
+
+// jaegerProcess is the Process struct of jaeger.thrift, describing the traced process.
+type jaegerProcess struct {
+	serviceName string
+	tags        []jaegerTag
+}
+
+// jaegerSpan is the Span struct of jaeger.thrift.
+type jaegerSpan struct {
+	traceIDLow    int64
+	traceIDHigh   int64
+	spanID        int64
+	parentSpanID  int64
+	operationName string
+	references    []jaegerSpanRef
+	flags         int32
+	startTime     int64 // microseconds
+	duration      int64 // microseconds
+	tags          []jaegerTag
+	logs          []jaegerLog
+}
+
+// jaegerSpanRef is the SpanRef struct of jaeger.thrift, referencing a causal parent of a span.
+type jaegerSpanRef struct {
+	refType     int32
+	traceIDLow  int64
+	traceIDHigh int64
+	spanID      int64
+}
+
+// jaegerTag is the Tag struct of jaeger.thrift, the value being set according to vType.
+type jaegerTag struct {
+	key     string
+	vType   int32
+	vStr    string
+	vDouble float64
+	vBool   bool
+	vLong   int64
+	vBinary []byte
+}
+
+// jaegerLog is the Log struct of jaeger.thrift, a timed event of a span.
+type jaegerLog struct {
+	timestamp int64 // microseconds
+	fields    []jaegerTag
+}
+
+// handleJaeger handles the batches of spans sent by the Jaeger clients over HTTP, encoded
+// with the Thrift binary protocol. The spans are converted to OTLP and processed in the
+// same way as the spans received by the OTLP receiver.
+func (r *HTTPReceiver) handleJaeger(w http.ResponseWriter, req *http.Request) {
+	defer timing.Since("datadog.trace_agent.receiver.jaeger_process_ms", time.Now())
+
+	switch mt := getMediaType(req); mt {
+	case "application/x-thrift", "application/vnd.apache.thrift.binary":
+	default:
+		log.Errorf("Unsupported media type for Jaeger spans: %q", mt)
+		http.Error(w, "unsupported media type", http.StatusUnsupportedMediaType)
+		return
+	}
+	body, err := readRequestBody(req, r.conf.MaxRequestBytes)
+	var batch jaegerBatch
+	if err == nil {
+		err = batch.unmarshalThrift(&thriftReader{b: body})
+	}
+	if err != nil {
+		log.Errorf("Error decoding Jaeger spans: %v", err)
+		httpDecodingError(err, []string{"handler:jaeger", "codec:thrift"}, w)
+		return
+	}
+	r.otlp.receiveTraces(req.Context(), batch.toTraces(), req.Header, "jaeger_thrift")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// toTraces converts the batch to OTLP traces, the process being the resource of the spans.
+func (b *jaegerBatch) toTraces() ptrace.Traces {
+	traces := ptrace.NewTraces()
+	rspans := traces.ResourceSpans().AppendEmpty()
+	attrs := rspans.Resource().Attributes()
+	if b.process.serviceName != "" {
+		attrs.PutStr(semconv.AttributeServiceName, b.process.serviceName)
+	}
+	for i := range b.process.tags {
+		tag := &b.process.tags[i]
+		if tag.key == "hostname" {
+			attrs.PutStr(semconv.AttributeHostName, tag.asString())
+			continue
+		}
+		tag.putTo(attrs)
+	}
+	spans := rspans.ScopeSpans().AppendEmpty().Spans()
+	for i := range b.spans {
+		b.spans[i].toOTLP(spans.AppendEmpty())
+	}
+	return traces
+}
+
+// toOTLP converts the Jaeger span to the OTLP span out. The span.kind tag sets its kind,
+// and the error tag its error status.
+func (js *jaegerSpan) toOTLP(out ptrace.Span) {
+	var traceID [16]byte
+	binary.BigEndian.PutUint64(traceID[:8], uint64(js.traceIDHigh))
+	binary.BigEndian.PutUint64(traceID[8:], uint64(js.traceIDLow))
+	out.SetTraceID(pcommon.TraceID(traceID))
+	out.SetSpanID(jaegerSpanID(js.spanID))
+	parentID := js.parentSpanID
+	if parentID == 0 {
+		// the parent can also be set as a reference only
+		for _, ref := range js.references {
+			if ref.refType == jaegerRefChildOf && ref.traceIDLow == js.traceIDLow && ref.traceIDHigh == js.traceIDHigh {
+				parentID = ref.spanID
+				break
+			}
+		}
+	}
+	out.SetParentSpanID(jaegerSpanID(parentID))
+	out.SetName(js.operationName)
+	out.SetStartTimestamp(pcommon.Timestamp(js.startTime * 1000))
+	out.SetEndTimestamp(pcommon.Timestamp((js.startTime + js.duration) * 1000))
+
+	attrs := out.Attributes()
+	for i := range js.tags {
+		tag := &js.tags[i]
+		switch tag.key {
+		case "span.kind":
+			out.SetKind(jaegerSpanKinds[tag.asString()])
+		case "error":
+			if tag.asString() == "true" {
+				out.Status().SetCode(ptrace.StatusCodeError)
+			}
+		case semconv.OtelStatusCode:
+			if tag.asString() == "ERROR" {
+				out.Status().SetCode(ptrace.StatusCodeError)
+			}
+		case semconv.OtelStatusDescription:
+			out.Status().SetMessage(tag.asString())
+		default:
+			tag.putTo(attrs)
+		}
+	}
+	if _, ok := attrs.Get("sampling.priority"); !ok && js.flags&jaegerFlagDebug != 0 {
+		attrs.PutInt("sampling.priority", 2)
+	}
+	for _, l := range js.logs {
+		e := out.Events().AppendEmpty()
+		e.SetTimestamp(pcommon.Timestamp(l.timestamp * 1000))
+		e.SetName("log")
+		isError := false
+		for i := range l.fields {
+			if f := &l.fields[i]; f.key == "event" {
+				e.SetName(f.asString())
+				isError = e.Name() == "error"
+			}
+		}
+		if isError {
+			// OpenTracing error logs are exception events in OpenTelemetry
+			e.SetName("exception")
+		}
+		for i := range l.fields {
+			f := &l.fields[i]
+			if f.key == "event" {
+				continue
+			}
+			if k, ok := jaegerErrorLogFields[f.key]; ok && isError {
+				e.Attributes().PutStr(k, f.asString())
+				continue
+			}
+			f.putTo(e.Attributes())
+		}
+	}
+}
+
+// jaegerSpanID converts a Jaeger span ID to an OTLP span ID.
+func jaegerSpanID(id int64) pcommon.SpanID {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(id))
+	return pcommon.SpanID(b)
+}
+
+// asString returns the value of the tag as a string.
+func (t *jaegerTag) asString() string {
+	if t.vType == jaegerTagString {
+		return t.vStr
+	}
+	v := pcommon.NewValueEmpty()
+	t.setTo(v)
+	return v.AsString()
+}
+
+// putTo adds the tag to the attributes m.
+func (t *jaegerTag) putTo(m pcommon.Map) {
+	t.setTo(m.PutEmpty(t.key))
+}
+
+// setTo sets the value of the tag to v.
+func (t *jaegerTag) setTo(v pcommon.Value) {
+	switch t.vType {
+	case jaegerTagDouble:
+		v.SetDouble(t.vDouble)
+	case jaegerTagBool:
+		v.SetBool(t.vBool)
+	case jaegerTagLong:
+		v.SetInt(t.vLong)
+	case jaegerTagBinary:
+		v.SetEmptyBytes().FromRaw(t.vBinary)
+	default:
+		v.SetStr(t.vStr)
+	}
+}
+
+var errJaegerNoBatch = errors.New("no Jaeger batch")
+
+// unmarshalThrift reads the Batch struct of jaeger.thrift from r.
+func (b *jaegerBatch) unmarshalThrift(r *thriftReader) error {
+	if len(r.b) == 0 {
+		return errJaegerNoBatch
+	}
+	return r.readStruct(func(id int16, typ byte) error {
+		switch {
+		case id == 1 && typ == thriftStruct:
+			return b.process.unmarshalThrift(r)
+		case id == 2 && typ == thriftList:
+			return r.readList(func(typ byte) error {
+				if typ != thriftStruct {
+					return r.skip(typ)
+				}
+				var s jaegerSpan
+				err := s.unmarshalThrift(r)
+				b.spans = append(b.spans, s)
+				return err
+			})
+		}
+		return r.skip(typ)
+	})
+}
+
+// unmarshalThrift reads the Process struct of jaeger.thrift from r.
+func (p *jaegerProcess) unmarshalThrift(r *thriftReader) error {
+	return r.readStruct(func(id int16, typ byte) (err error) {
+		switch {
+		case id == 1 && typ == thriftString:
+			p.serviceName, err = r.readString()
+		case id == 2 && typ == thriftList:
+			p.tags, err = readJaegerTags(r)
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+}
+
+// unmarshalThrift reads the Span struct of jaeger.thrift from r.
+func (s *jaegerSpan) unmarshalThrift(r *thriftReader) error {
+	return r.readStruct(func(id int16, typ byte) (err error) {
+		switch {
+		case id == 1 && typ == thriftI64:
+			s.traceIDLow, err = r.readI64()
+		case id == 2 && typ == thriftI64:
+			s.traceIDHigh, err = r.readI64()
+		case id == 3 && typ == thriftI64:
+			s.spanID, err = r.readI64()
+		case id == 4 && typ == thriftI64:
+			s.parentSpanID, err = r.readI64()
+		case id == 5 && typ == thriftString:
+			s.operationName, err = r.readString()
+		case id == 6 && typ == thriftList:
+			err = r.readList(func(typ byte) error {
+				if typ != thriftStruct {
+					return r.skip(typ)
+				}
+				var ref jaegerSpanRef
+				err := ref.unmarshalThrift(r)
+				s.references = append(s.references, ref)
+				return err
+			})
+		case id == 7 && typ == thriftI32:
+			s.flags, err = r.readI32()
+		case id == 8 && typ == thriftI64:
+			s.startTime, err = r.readI64()
+		case id == 9 && typ == thriftI64:
+			s.duration, err = r.readI64()
+		case id == 10 && typ == thriftList:
+			s.tags, err = readJaegerTags(r)
+		case id == 11 && typ == thriftList:
+			err = r.readList(func(typ byte) error {
+				if typ != thriftStruct {
+					return r.skip(typ)
+				}
+				var l jaegerLog
+				err := l.unmarshalThrift(r)
+				s.logs = append(s.logs, l)
+				return err
+			})
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+}
+
+// unmarshalThrift reads the SpanRef struct of jaeger.thrift from r.
+func (ref *jaegerSpanRef) unmarshalThrift(r *thriftReader) error {
+	return r.readStruct(func(id int16, typ byte) (err error) {
+		switch {
+		case id == 1 && typ == thriftI32:
+			ref.refType, err = r.readI32()
+		case id == 2 && typ == thriftI64:
+			ref.traceIDLow, err = r.readI64()
+		case id == 3 && typ == thriftI64:
+			ref.traceIDHigh, err = r.readI64()
+		case id == 4 && typ == thriftI64:
+			ref.spanID, err = r.readI64()
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+}
+
+// unmarshalThrift reads the Log struct of jaeger.thrift from r.
+func (l *jaegerLog) unmarshalThrift(r *thriftReader) error {
+	return r.readStruct(func(id int16, typ byte) (err error) {
+		switch {
+		case id == 1 && typ == thriftI64:
+			l.timestamp, err = r.readI64()
+		case id == 2 && typ == thriftList:
+			l.fields, err = readJaegerTags(r)
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+}
+
+// unmarshalThrift reads the Tag struct of jaeger.thrift from r.
+func (t *jaegerTag) unmarshalThrift(r *thriftReader) error {
+	return r.readStruct(func(id int16, typ byte) (err error) {
+		switch {
+		case id == 1 && typ == thriftString:
+			t.key, err = r.readString()
+		case id == 2 && typ == thriftI32:
+			t.vType, err = r.readI32()
+		case id == 3 && typ == thriftString:
+			t.vStr, err = r.readString()
+		case id == 4 && typ == thriftDouble:
+			t.vDouble, err = r.readDouble()
+		case id == 5 && typ == thriftBool:
+			t.vBool, err = r.readBool()
+		case id == 6 && typ == thriftI64:
+			t.vLong, err = r.readI64()
+		case id == 7 && typ == thriftString:
+			t.vBinary, err = r.readBinary()
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+}
+
+// readJaegerTags reads a list of Tag structs of jaeger.thrift from r.
+func readJaegerTags(r *thriftReader) ([]jaegerTag, error) {
+	var tags []jaegerTag
+	err := r.readList(func(typ byte) error {
+		if typ != thriftStruct {
+			return r.skip(typ)
+		}
+		var t jaegerTag
+		err := t.unmarshalThrift(r)
+		tags = append(tags, t)
+		return err
+	})
+	return tags, err
+}
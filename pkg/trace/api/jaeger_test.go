@@ -0,0 +1,215 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// thriftWriter encodes values with the Thrift binary protocol.
+type thriftWriter struct{ bytes.Buffer }
+
+func (w *thriftWriter) field(typ byte, id int16) {
+	w.WriteByte(typ)
+	w.Write([]byte{byte(id >> 8), byte(id)})
+}
+
+func (w *thriftWriter) i32(v int32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(v))
+	w.Write(b[:])
+}
+
+func (w *thriftWriter) i64(v int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v))
+	w.Write(b[:])
+}
+
+func (w *thriftWriter) str(s string) {
+	w.i32(int32(len(s)))
+	w.WriteString(s)
+}
+
+func (w *thriftWriter) list(typ byte, n int32) {
+	w.WriteByte(typ)
+	w.i32(n)
+}
+
+func (w *thriftWriter) stop() { w.WriteByte(thriftStop) }
+
+// tag writes a Tag struct of jaeger.thrift with a string value.
+func (w *thriftWriter) tag(k, v string) {
+	w.field(thriftString, 1)
+	w.str(k)
+	w.field(thriftI32, 2)
+	w.i32(jaegerTagString)
+	w.field(thriftString, 3)
+	w.str(v)
+	w.stop()
+}
+
+// jaegerTestBatch returns a Batch struct of jaeger.thrift holding a single server span.
+func jaegerTestBatch() []byte {
+	var w thriftWriter
+	w.field(thriftStruct, 1) // process
+	w.field(thriftString, 1)
+	w.str("inventory")
+	w.field(thriftList, 2)
+	w.list(thriftStruct, 1)
+	w.tag("hostname", "host-a")
+	w.stop()
+
+	w.field(thriftList, 2) // spans
+	w.list(thriftStruct, 1)
+	w.field(thriftI64, 1)
+	w.i64(42)
+	w.field(thriftI64, 2)
+	w.i64(1)
+	w.field(thriftI64, 3)
+	w.i64(7)
+	w.field(thriftI64, 4)
+	w.i64(0)
+	w.field(thriftString, 5)
+	w.str("GET /items")
+	w.field(thriftList, 6) // the parent is set as a reference
+	w.list(thriftStruct, 1)
+	w.field(thriftI32, 1)
+	w.i32(jaegerRefChildOf)
+	w.field(thriftI64, 2)
+	w.i64(42)
+	w.field(thriftI64, 3)
+	w.i64(1)
+	w.field(thriftI64, 4)
+	w.i64(3)
+	w.stop()
+	w.field(thriftI32, 7)
+	w.i32(1 | jaegerFlagDebug)
+	w.field(thriftI64, 8)
+	w.i64(1556604172355737)
+	w.field(thriftI64, 9)
+	w.i64(1431)
+	w.field(thriftList, 10)
+	w.list(thriftStruct, 5)
+	w.tag("span.kind", "server")
+	w.tag("http.method", "GET")
+	w.tag("http.route", "/items")
+	w.field(thriftString, 1)
+	w.str("error")
+	w.field(thriftI32, 2)
+	w.i32(jaegerTagBool)
+	w.field(thriftBool, 5)
+	w.WriteByte(1)
+	w.stop()
+	w.field(thriftString, 1)
+	w.str("http.status_code")
+	w.field(thriftI32, 2)
+	w.i32(jaegerTagLong)
+	w.field(thriftI64, 6)
+	w.i64(500)
+	w.stop()
+	w.field(thriftList, 11)
+	w.list(thriftStruct, 1)
+	w.field(thriftI64, 1)
+	w.i64(1556604172355800)
+	w.field(thriftList, 2)
+	w.list(thriftStruct, 2)
+	w.tag("event", "error")
+	w.tag("message", "out of stock")
+	w.stop()
+	w.field(thriftMap, 99) // unknown fields are skipped
+	w.WriteByte(thriftString)
+	w.WriteByte(thriftI32)
+	w.i32(1)
+	w.str("k")
+	w.i32(1)
+	w.stop() // span
+
+	w.stop() // batch
+	return w.Bytes()
+}
+
+func TestJaegerReceiver(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.JaegerReceiverEnabled = true
+	rcv := newTestReceiverFromConfig(conf)
+	mux := rcv.buildMux()
+	batch := jaegerTestBatch()
+
+	t.Run("thrift", func(t *testing.T) {
+		assert := assert.New(t)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/traces", bytes.NewReader(batch))
+		req.Header.Set("Content-Type", "application/x-thrift")
+		mux.ServeHTTP(w, req)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		require.Len(t, rcv.out, 1)
+
+		p := <-rcv.out
+		assert.Equal("jaeger_thrift", p.Source.EndpointVersion)
+		assert.Equal("host-a", p.TracerPayload.Hostname)
+		require.Len(t, p.TracerPayload.Chunks, 1)
+		chunk := p.TracerPayload.Chunks[0]
+		// debug spans are kept
+		assert.Equal(int32(2), chunk.Priority)
+		require.Len(t, chunk.Spans, 1)
+		span := chunk.Spans[0]
+		assert.Equal("inventory", span.Service)
+		assert.Equal("opentelemetry.server", span.Name)
+		assert.Equal("GET /items", span.Resource)
+		assert.Equal("web", span.Type)
+		assert.Equal(uint64(42), span.TraceID)
+		assert.Equal(uint64(7), span.SpanID)
+		assert.Equal(uint64(3), span.ParentID)
+		assert.Equal(int64(1556604172355737000), span.Start)
+		assert.Equal(int64(1431000), span.Duration)
+		assert.Equal(int32(1), span.Error)
+		assert.Equal("out of stock", span.Meta["error.msg"])
+		assert.Equal(float64(500), span.Metrics["http.status_code"])
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, n := range []int{0, 1, 10, len(batch) - 1} {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/traces", bytes.NewReader(batch[:n]))
+			req.Header.Set("Content-Type", "application/x-thrift")
+			mux.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, n)
+		}
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/traces", bytes.NewReader(batch))
+		req.Header.Set("Content-Type", "application/json")
+		mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+		assert.Len(t, rcv.out, 0)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		mux := newTestReceiverFromConfig(newTestReceiverConfig()).buildMux()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/traces", bytes.NewReader(batch))
+		req.Header.Set("Content-Type", "application/x-thrift")
+		mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestThriftReaderDepth(t *testing.T) {
+	var w thriftWriter
+	w.field(thriftList, 3)
+	for i := 0; i < 2*thriftMaxDepth; i++ {
+		w.list(thriftList, 1)
+	}
+	var b jaegerBatch
+	assert.Equal(t, errThriftDepth, b.unmarshalThrift(&thriftReader{b: w.Bytes()}))
+}
@@ -0,0 +1,228 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// Types of the Thrift binary protocol.
+const (
+	thriftStop   byte = 0
+	thriftBool   byte = 2
+	thriftByte   byte = 3
+	thriftDouble byte = 4
+	thriftI16    byte = 6
+	thriftI32    byte = 8
+	thriftI64    byte = 10
+	thriftString byte = 11
+	thriftStruct byte = 12
+	thriftMap    byte = 13
+	thriftSet    byte = 14
+	thriftList   byte = 15
+)
+
+// thriftMaxDepth is the maximum nesting of the Thrift containers and structs that can be decoded.
+const thriftMaxDepth = 64
+
+var (
+	errThriftType  = errors.New("thrift: unknown type")
+	errThriftDepth = errors.New("thrift: maximum depth exceeded")
+	errThriftSize  = errors.New("thrift: invalid size")
+)
+
+// thriftReader reads the values encoded with the Thrift binary protocol in b.
+type thriftReader struct {
+	b     []byte
+	depth int
+}
+
+// next returns the next n bytes.
+func (r *thriftReader) next(n int) ([]byte, error) {
+	if n > len(r.b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v, nil
+}
+
+func (r *thriftReader) readByte() (byte, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *thriftReader) readBool() (bool, error) {
+	b, err := r.readByte()
+	return b != 0, err
+}
+
+func (r *thriftReader) readI16() (int16, error) {
+	b, err := r.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (r *thriftReader) readI32() (int32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *thriftReader) readI64() (int64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *thriftReader) readDouble() (float64, error) {
+	v, err := r.readI64()
+	return math.Float64frombits(uint64(v)), err
+}
+
+// readBinary reads a binary value. The returned slice references the input.
+func (r *thriftReader) readBinary() ([]byte, error) {
+	n, err := r.readI32()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, errThriftSize
+	}
+	return r.next(int(n))
+}
+
+func (r *thriftReader) readString() (string, error) {
+	b, err := r.readBinary()
+	return string(b), err
+}
+
+// enter increments the nesting depth, failing above thriftMaxDepth. Callers must call
+// leave once done reading the nested value.
+func (r *thriftReader) enter() error {
+	r.depth++
+	if r.depth > thriftMaxDepth {
+		return errThriftDepth
+	}
+	return nil
+}
+
+func (r *thriftReader) leave() { r.depth-- }
+
+// readStruct reads a struct, calling f with the id and the type of each of its fields.
+// f has to read the value of the field, or skip it.
+func (r *thriftReader) readStruct(f func(id int16, typ byte) error) error {
+	if err := r.enter(); err != nil {
+		return err
+	}
+	defer r.leave()
+	for {
+		typ, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		if typ == thriftStop {
+			return nil
+		}
+		id, err := r.readI16()
+		if err != nil {
+			return err
+		}
+		if err := f(id, typ); err != nil {
+			return err
+		}
+	}
+}
+
+// readList reads a list or a set, calling f with the type of each of its elements.
+// f has to read the element, or skip it.
+func (r *thriftReader) readList(f func(typ byte) error) error {
+	if err := r.enter(); err != nil {
+		return err
+	}
+	defer r.leave()
+	typ, err := r.readByte()
+	if err != nil {
+		return err
+	}
+	n, err := r.readI32()
+	if err != nil {
+		return err
+	}
+	if n < 0 || int(n) > len(r.b) {
+		// every element takes at least one byte
+		return errThriftSize
+	}
+	for i := 0; i < int(n); i++ {
+		if err := f(typ); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skip skips a value of type typ.
+func (r *thriftReader) skip(typ byte) error {
+	var err error
+	switch typ {
+	case thriftBool, thriftByte:
+		_, err = r.next(1)
+	case thriftI16:
+		_, err = r.next(2)
+	case thriftI32:
+		_, err = r.next(4)
+	case thriftDouble, thriftI64:
+		_, err = r.next(8)
+	case thriftString:
+		_, err = r.readBinary()
+	case thriftStruct:
+		err = r.readStruct(func(_ int16, typ byte) error { return r.skip(typ) })
+	case thriftSet, thriftList:
+		err = r.readList(r.skip)
+	case thriftMap:
+		err = r.skipMap()
+	default:
+		err = errThriftType
+	}
+	return err
+}
+
+// skipMap skips a map.
+func (r *thriftReader) skipMap() error {
+	if err := r.enter(); err != nil {
+		return err
+	}
+	defer r.leave()
+	header, err := r.next(6)
+	if err != nil {
+		return err
+	}
+	ktyp, vtyp, n := header[0], header[1], int32(binary.BigEndian.Uint32(header[2:]))
+	if n < 0 || int(n) > len(r.b) {
+		return errThriftSize
+	}
+	for i := 0; i < int(n); i++ {
+		if err := r.skip(ktyp); err != nil {
+			return err
+		}
+		if err := r.skip(vtyp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -173,6 +173,19 @@ func (o *OTLPReceiver) sample(tid uint64) sampler.SamplingPriority {
 
 // ReceiveResourceSpans processes the given rspans and returns the source that it identified from processing them.
 func (o *OTLPReceiver) ReceiveResourceSpans(ctx context.Context, rspans ptrace.ResourceSpans, httpHeader http.Header) source.Source {
+	return o.receiveResourceSpans(ctx, rspans, httpHeader, "opentelemetry_grpc_v1")
+}
+
+// receiveTraces processes all the resource spans of traces received from another tracing protocol
+// and converted to OTLP, such as Zipkin or Jaeger. The payloads are tagged with endpointVersion.
+func (o *OTLPReceiver) receiveTraces(ctx context.Context, traces ptrace.Traces, httpHeader http.Header, endpointVersion string) {
+	for i := 0; i < traces.ResourceSpans().Len(); i++ {
+		o.receiveResourceSpans(ctx, traces.ResourceSpans().At(i), httpHeader, endpointVersion)
+	}
+}
+
+// receiveResourceSpans implements ReceiveResourceSpans, tagging the payload with endpointVersion.
+func (o *OTLPReceiver) receiveResourceSpans(ctx context.Context, rspans ptrace.ResourceSpans, httpHeader http.Header, endpointVersion string) source.Source {
 	// each rspans is coming from a different resource and should be considered
 	// a separate payload; typically there is only one item in this slice
 	attr := rspans.Resource().Attributes()
@@ -208,7 +221,7 @@ func (o *OTLPReceiver) ReceiveResourceSpans(ctx context.Context, rspans ptrace.R
 			Interpreter:     fastHeaderGet(httpHeader, header.LangInterpreter),
 			LangVendor:      fastHeaderGet(httpHeader, header.LangInterpreterVendor),
 			TracerVersion:   fmt.Sprintf("otlp-%s", rattr[string(semconv.AttributeTelemetrySDKVersion)]),
-			EndpointVersion: "opentelemetry_grpc_v1",
+			EndpointVersion: endpointVersion,
 		},
 		Stats: info.NewStats(),
 	}
@@ -0,0 +1,364 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/collector/semconv/v1.6.1"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api/apiutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
+)
+
+// zipkinSpan is a span of the Zipkin v2 API, as defined in https://zipkin.io/zipkin-api/zipkin2-api.yaml.
+type zipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ParentID       string             `json:"parentId"`
+	ID             string             `json:"id"`
+	Kind           string             `json:"kind"`
+	Name           string             `json:"name"`
+	Timestamp      uint64             `json:"timestamp"` // microseconds
+	Duration       uint64             `json:"duration"`  // microseconds
+	Debug          bool               `json:"debug"`
+	LocalEndpoint  *zipkinEndpoint    `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint    `json:"remoteEndpoint"`
+	Annotations    []zipkinAnnotation `json:"annotations"`
+	Tags           map[string]string  `json:"tags"`
+}
+
+// zipkinEndpoint is the network context of a node in the service graph.
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int32  `json:"port"`
+}
+
+// zipkinAnnotation associates an event that explains latency with a timestamp.
+type zipkinAnnotation struct {
+	Timestamp uint64 `json:"timestamp"` // microseconds
+	Value     string `json:"value"`
+}
+
+// zipkinSpanKinds maps the kinds of the Zipkin spans to OTLP span kinds.
+var zipkinSpanKinds = map[string]ptrace.SpanKind{
+	"CLIENT":   ptrace.SpanKindClient,
+	"SERVER":   ptrace.SpanKindServer,
+	"PRODUCER": ptrace.SpanKindProducer,
+	"CONSUMER": ptrace.SpanKindConsumer,
+}
+
+// zipkinProtoSpanKinds maps the values of the Kind enum of zipkin.proto to the kinds of the JSON API.
+var zipkinProtoSpanKinds = map[uint64]string{
+	1: "CLIENT",
+	2: "SERVER",
+	3: "PRODUCER",
+	4: "CONSUMER",
+}
+
+// handleZipkin handles the spans sent to the Zipkin v2 API, encoded either in JSON or protobuf.
+// The spans are converted to OTLP and processed in the same way as the spans received by the
+// OTLP receiver.
+func (r *HTTPReceiver) handleZipkin(w http.ResponseWriter, req *http.Request) {
+	defer timing.Since("datadog.trace_agent.receiver.zipkin_process_ms", time.Now())
+
+	codec := "json"
+	if getMediaType(req) == "application/x-protobuf" {
+		codec = "protobuf"
+	}
+	body, err := readRequestBody(req, r.conf.MaxRequestBytes)
+	var spans []zipkinSpan
+	if err == nil {
+		if codec == "protobuf" {
+			spans, err = decodeZipkinProto(body)
+		} else {
+			err = json.Unmarshal(body, &spans)
+		}
+	}
+	var traces ptrace.Traces
+	if err == nil {
+		traces, err = zipkinToTraces(spans)
+	}
+	if err != nil {
+		log.Errorf("Error decoding Zipkin spans: %v", err)
+		httpDecodingError(err, []string{"handler:zipkin", "codec:" + codec}, w)
+		return
+	}
+	r.otlp.receiveTraces(req.Context(), traces, req.Header, "zipkin_v2")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// readRequestBody reads the body of req, limited to limit bytes, and decompresses it if
+// it is gzipped. The decompressed body is limited to limit bytes as well.
+func readRequestBody(req *http.Request, limit int64) ([]byte, error) {
+	rd := apiutil.NewLimitedReader(req.Body, limit)
+	if req.Header.Get("Content-Encoding") != "gzip" {
+		return io.ReadAll(rd)
+	}
+	gz, err := gzip.NewReader(rd)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return io.ReadAll(apiutil.NewLimitedReader(gz, limit))
+}
+
+// zipkinToTraces converts the Zipkin spans to OTLP traces, grouping them into a resource
+// per local service.
+func zipkinToTraces(spans []zipkinSpan) (ptrace.Traces, error) {
+	traces := ptrace.NewTraces()
+	byService := make(map[string]ptrace.SpanSlice)
+	for i := range spans {
+		zs := &spans[i]
+		var service string
+		if zs.LocalEndpoint != nil {
+			service = zs.LocalEndpoint.ServiceName
+		}
+		out, ok := byService[service]
+		if !ok {
+			rspans := traces.ResourceSpans().AppendEmpty()
+			if service != "" {
+				rspans.Resource().Attributes().PutStr(semconv.AttributeServiceName, service)
+			}
+			out = rspans.ScopeSpans().AppendEmpty().Spans()
+			byService[service] = out
+		}
+		if err := zs.toOTLP(out.AppendEmpty()); err != nil {
+			return traces, err
+		}
+	}
+	return traces, nil
+}
+
+// toOTLP converts the Zipkin span to the OTLP span out. The "error" tag sets the error status,
+// and the remote endpoint is set as the net.peer.* attributes.
+func (zs *zipkinSpan) toOTLP(out ptrace.Span) error {
+	var (
+		traceID  [16]byte
+		spanID   [8]byte
+		parentID [8]byte
+	)
+	if err := decodeHexID(traceID[:], zs.TraceID); err != nil {
+		return fmt.Errorf("invalid trace ID: %v", err)
+	}
+	if err := decodeHexID(spanID[:], zs.ID); err != nil {
+		return fmt.Errorf("invalid span ID: %v", err)
+	}
+	if zs.ParentID != "" {
+		if err := decodeHexID(parentID[:], zs.ParentID); err != nil {
+			return fmt.Errorf("invalid parent ID: %v", err)
+		}
+	}
+	out.SetTraceID(pcommon.TraceID(traceID))
+	out.SetSpanID(pcommon.SpanID(spanID))
+	out.SetParentSpanID(pcommon.SpanID(parentID))
+	out.SetName(zs.Name)
+	if kind, ok := zipkinSpanKinds[zs.Kind]; ok {
+		out.SetKind(kind)
+	} else {
+		out.SetKind(ptrace.SpanKindInternal)
+	}
+	out.SetStartTimestamp(pcommon.Timestamp(zs.Timestamp * 1000))
+	out.SetEndTimestamp(pcommon.Timestamp((zs.Timestamp + zs.Duration) * 1000))
+
+	attrs := out.Attributes()
+	for k, v := range zs.Tags {
+		if k == "error" {
+			// the error tag is set on failed spans, its value being the error message if any
+			out.Status().SetCode(ptrace.StatusCodeError)
+			if v != "" && v != "true" {
+				out.Status().SetMessage(v)
+			}
+			continue
+		}
+		attrs.PutStr(k, v)
+	}
+	if ep := zs.RemoteEndpoint; ep != nil {
+		if ep.ServiceName != "" {
+			attrs.PutStr(semconv.AttributeNetPeerName, ep.ServiceName)
+		}
+		if ip := ep.ip(); ip != "" {
+			attrs.PutStr(semconv.AttributeNetPeerIP, ip)
+		}
+		if ep.Port != 0 {
+			attrs.PutInt(semconv.AttributeNetPeerPort, int64(ep.Port))
+		}
+	}
+	if zs.Debug {
+		attrs.PutInt("sampling.priority", 2)
+	}
+	for _, a := range zs.Annotations {
+		e := out.Events().AppendEmpty()
+		e.SetTimestamp(pcommon.Timestamp(a.Timestamp * 1000))
+		e.SetName(a.Value)
+	}
+	return nil
+}
+
+// ip returns the IPv4 address of the endpoint, or its IPv6 address.
+func (ep *zipkinEndpoint) ip() string {
+	if ep.IPv4 != "" {
+		return ep.IPv4
+	}
+	return ep.IPv6
+}
+
+// decodeHexID decodes the hexadecimal ID s into dst. Shorter IDs, such as 64-bit trace IDs,
+// are left-padded with zeros.
+func decodeHexID(dst []byte, s string) error {
+	if s == "" || len(s) > 2*len(dst) {
+		return fmt.Errorf("%q is not a %d-bit hexadecimal ID", s, 8*len(dst))
+	}
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	_, err := hex.Decode(dst[len(dst)-len(s)/2:], []byte(s))
+	return err
+}
+
+// decodeZipkinProto decodes the ListOfSpans protobuf message b of zipkin.proto.
+func decodeZipkinProto(b []byte) ([]zipkinSpan, error) {
+	var spans []zipkinSpan
+	err := protoFields(b, func(num protowire.Number, _ uint64, data []byte) error {
+		if num != 1 {
+			return nil
+		}
+		var zs zipkinSpan
+		if err := zs.unmarshalProto(data); err != nil {
+			return err
+		}
+		spans = append(spans, zs)
+		return nil
+	})
+	return spans, err
+}
+
+// unmarshalProto decodes the Span protobuf message b of zipkin.proto into zs.
+func (zs *zipkinSpan) unmarshalProto(b []byte) error {
+	return protoFields(b, func(num protowire.Number, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			zs.TraceID = hex.EncodeToString(data)
+		case 2:
+			zs.ParentID = hex.EncodeToString(data)
+		case 3:
+			zs.ID = hex.EncodeToString(data)
+		case 4:
+			zs.Kind = zipkinProtoSpanKinds[v]
+		case 5:
+			zs.Name = string(data)
+		case 6:
+			zs.Timestamp = v
+		case 7:
+			zs.Duration = v
+		case 8:
+			zs.LocalEndpoint = new(zipkinEndpoint)
+			return zs.LocalEndpoint.unmarshalProto(data)
+		case 9:
+			zs.RemoteEndpoint = new(zipkinEndpoint)
+			return zs.RemoteEndpoint.unmarshalProto(data)
+		case 10:
+			var a zipkinAnnotation
+			err := protoFields(data, func(num protowire.Number, v uint64, data []byte) error {
+				switch num {
+				case 1:
+					a.Timestamp = v
+				case 2:
+					a.Value = string(data)
+				}
+				return nil
+			})
+			zs.Annotations = append(zs.Annotations, a)
+			return err
+		case 11:
+			var k, val string
+			err := protoFields(data, func(num protowire.Number, _ uint64, data []byte) error {
+				switch num {
+				case 1:
+					k = string(data)
+				case 2:
+					val = string(data)
+				}
+				return nil
+			})
+			if zs.Tags == nil {
+				zs.Tags = make(map[string]string)
+			}
+			zs.Tags[k] = val
+			return err
+		case 12:
+			zs.Debug = v != 0
+		}
+		return nil
+	})
+}
+
+// unmarshalProto decodes the Endpoint protobuf message b of zipkin.proto into ep.
+func (ep *zipkinEndpoint) unmarshalProto(b []byte) error {
+	return protoFields(b, func(num protowire.Number, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			ep.ServiceName = string(data)
+		case 2:
+			ep.IPv4 = net.IP(data).String()
+		case 3:
+			ep.IPv6 = net.IP(data).String()
+		case 4:
+			ep.Port = int32(v)
+		}
+		return nil
+	})
+}
+
+// protoFields calls f with the number and the value of each field of the protobuf message b:
+// v holds the value of the numeric fields and data the bytes of the length-delimited fields.
+func protoFields(b []byte, f func(num protowire.Number, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		var (
+			v    uint64
+			v32  uint32
+			data []byte
+		)
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			v32, n = protowire.ConsumeFixed32(b)
+			v = uint64(v32)
+		case protowire.BytesType:
+			data, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := f(num, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const zipkinTestPayload = `[
+	{
+		"traceId": "5af7183fb1d4cf5f",
+		"parentId": "352bff9a74ca9ad2",
+		"id": "6b221d5bc9e6496c",
+		"kind": "SERVER",
+		"name": "get /api",
+		"timestamp": 1556604172355737,
+		"duration": 1431,
+		"localEndpoint": {"serviceName": "backend", "ipv4": "192.168.99.1", "port": 3306},
+		"remoteEndpoint": {"ipv4": "172.19.0.2", "port": 58648},
+		"annotations": [{"timestamp": 1556604172355800, "value": "wr"}],
+		"tags": {"http.method": "GET", "http.route": "/api", "error": "500 Internal Server Error"}
+	},
+	{
+		"traceId": "463ac35c9f6413ad48485a3953bb6124",
+		"id": "a2fb4a1d1a96d312",
+		"kind": "CLIENT",
+		"name": "get",
+		"timestamp": 1556604172355000,
+		"duration": 2000,
+		"debug": true,
+		"localEndpoint": {"serviceName": "frontend"},
+		"remoteEndpoint": {"serviceName": "backend"}
+	}
+]`
+
+func TestZipkinReceiver(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.ZipkinReceiverEnabled = true
+	rcv := newTestReceiverFromConfig(conf)
+	mux := rcv.buildMux()
+
+	t.Run("json", func(t *testing.T) {
+		assert := assert.New(t)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v2/spans", strings.NewReader(zipkinTestPayload))
+		req.Header.Set("Content-Type", "application/json")
+		mux.ServeHTTP(w, req)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		require.Len(t, rcv.out, 2)
+
+		// a payload is created for each local service
+		p := <-rcv.out
+		assert.Equal("zipkin_v2", p.Source.EndpointVersion)
+		require.Len(t, p.TracerPayload.Chunks, 1)
+		require.Len(t, p.TracerPayload.Chunks[0].Spans, 1)
+		span := p.TracerPayload.Chunks[0].Spans[0]
+		assert.Equal("backend", span.Service)
+		assert.Equal("opentelemetry.server", span.Name)
+		assert.Equal("GET /api", span.Resource)
+		assert.Equal("web", span.Type)
+		assert.Equal(uint64(0x5af7183fb1d4cf5f), span.TraceID)
+		assert.Equal(uint64(0x6b221d5bc9e6496c), span.SpanID)
+		assert.Equal(uint64(0x352bff9a74ca9ad2), span.ParentID)
+		assert.Equal(int64(1556604172355737000), span.Start)
+		assert.Equal(int64(1431000), span.Duration)
+		assert.Equal(int32(1), span.Error)
+		assert.Equal("500 Internal Server Error", span.Meta["error.msg"])
+		assert.Equal("172.19.0.2", span.Meta["net.peer.ip"])
+		assert.Equal(float64(58648), span.Metrics["net.peer.port"])
+		assert.Contains(span.Meta["events"], `"name":"wr"`)
+
+		p = <-rcv.out
+		span = p.TracerPayload.Chunks[0].Spans[0]
+		assert.Equal("frontend", span.Service)
+		assert.Equal("opentelemetry.client", span.Name)
+		assert.Equal("http", span.Type)
+		assert.Equal(uint64(0x48485a3953bb6124), span.TraceID)
+		assert.Equal(uint64(0), span.ParentID)
+		assert.Equal(int32(0), span.Error)
+		// the remote service is not the service of the span
+		assert.Equal("backend", span.Meta["net.peer.name"])
+		// debug spans are kept
+		assert.Equal(int32(2), p.TracerPayload.Chunks[0].Priority)
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(zipkinTestPayload))
+		gz.Close()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v2/spans", &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Len(t, rcv.out, 2)
+		<-rcv.out
+		<-rcv.out
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, body := range []string{
+			`{"traceId": "5af7183fb1d4cf5f"}`,
+			`[{"traceId": "not-hex", "id": "6b221d5bc9e6496c"}]`,
+			`[{"traceId": "5af7183fb1d4cf5f"}]`,
+		} {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/v2/spans", strings.NewReader(body)))
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+		assert.Len(t, rcv.out, 0)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		mux := newTestReceiverFromConfig(newTestReceiverConfig()).buildMux()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/v2/spans", strings.NewReader(zipkinTestPayload)))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestDecodeZipkinProto(t *testing.T) {
+	message := func(fields ...func([]byte) []byte) []byte {
+		var b []byte
+		for _, f := range fields {
+			b = f(b)
+		}
+		return b
+	}
+	bytesField := func(num protowire.Number, v []byte) func([]byte) []byte {
+		return func(b []byte) []byte {
+			return protowire.AppendBytes(protowire.AppendTag(b, num, protowire.BytesType), v)
+		}
+	}
+	varintField := func(num protowire.Number, v uint64) func([]byte) []byte {
+		return func(b []byte) []byte {
+			return protowire.AppendVarint(protowire.AppendTag(b, num, protowire.VarintType), v)
+		}
+	}
+	fixed64Field := func(num protowire.Number, v uint64) func([]byte) []byte {
+		return func(b []byte) []byte {
+			return protowire.AppendFixed64(protowire.AppendTag(b, num, protowire.Fixed64Type), v)
+		}
+	}
+	span := message(
+		bytesField(1, []byte{0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8}),
+		bytesField(3, []byte{0, 0, 0, 0, 0, 0, 0, 9}),
+		varintField(4, 2),
+		bytesField(5, []byte("get")),
+		fixed64Field(6, 1000),
+		varintField(7, 20),
+		bytesField(8, message(
+			bytesField(1, []byte("backend")),
+			bytesField(2, []byte{10, 0, 0, 1}),
+			varintField(4, 8080),
+		)),
+		bytesField(10, message(fixed64Field(1, 1005), bytesField(2, []byte("ws")))),
+		bytesField(11, message(bytesField(1, []byte("http.method")), bytesField(2, []byte("GET")))),
+		varintField(12, 1),
+		varintField(99, 1), // unknown fields are skipped
+	)
+	list := message(bytesField(1, span))
+
+	spans, err := decodeZipkinProto(list)
+	require.NoError(t, err)
+	assert.Equal(t, []zipkinSpan{{
+		TraceID:       "00000000000000000102030405060708",
+		ID:            "0000000000000009",
+		Kind:          "SERVER",
+		Name:          "get",
+		Timestamp:     1000,
+		Duration:      20,
+		Debug:         true,
+		LocalEndpoint: &zipkinEndpoint{ServiceName: "backend", IPv4: "10.0.0.1", Port: 8080},
+		Annotations:   []zipkinAnnotation{{Timestamp: 1005, Value: "ws"}},
+		Tags:          map[string]string{"http.method": "GET"},
+	}}, spans)
+
+	_, err = decodeZipkinProto(list[:len(list)-1])
+	assert.Error(t, err)
+}
@@ -113,6 +113,18 @@ type ObfuscationConfig struct {
 	// for spans of type "memcached".
 	Memcached Enablable `mapstructure:"memcached"`
 
+	// GraphQL holds the configuration for obfuscating the queries and the variables
+	// of spans of type "graphql".
+	GraphQL GraphQLObfuscationConfig `mapstructure:"graphql"`
+
+	// DynamoDB holds the obfuscation configuration for the DynamoDB request parameters
+	// found in the "aws.dynamodb.request_parameters" tag.
+	DynamoDB JSONObfuscationConfig `mapstructure:"dynamodb"`
+
+	// Kafka holds the configuration for obfuscating the message keys and payloads
+	// found in the "messaging.kafka.message.key" and "messaging.kafka.message.payload" tags.
+	Kafka KafkaObfuscationConfig `mapstructure:"kafka"`
+
 	// CreditCards holds the configuration for obfuscating credit cards.
 	CreditCards CreditCardsConfig `mapstructure:"credit_cards"`
 }
@@ -151,6 +163,18 @@ func (o *ObfuscationConfig) Export(conf *AgentConfig) obfuscate.Config {
 			RemoveQueryString: o.HTTP.RemoveQueryString,
 			RemovePathDigits:  o.HTTP.RemovePathDigits,
 		},
+		GraphQL: obfuscate.GraphQLConfig{
+			Enabled:       o.GraphQL.Enabled,
+			KeepVariables: o.GraphQL.KeepVariables,
+		},
+		DynamoDB: obfuscate.JSONConfig{
+			Enabled:    o.DynamoDB.Enabled,
+			KeepValues: o.DynamoDB.KeepValues,
+		},
+		Kafka: obfuscate.KafkaConfig{
+			Enabled:         o.Kafka.Enabled,
+			KeepMessageKeys: o.Kafka.KeepMessageKeys,
+		},
 		Logger: new(debugLogger),
 	}
 }
@@ -182,6 +206,24 @@ type HTTPObfuscationConfig struct {
 	RemovePathDigits bool `mapstructure:"remove_paths_with_digits" json:"remove_path_digits"`
 }
 
+// GraphQLObfuscationConfig holds the configuration settings for GraphQL obfuscation.
+type GraphQLObfuscationConfig struct {
+	// Enabled specifies whether GraphQL queries and variables should be obfuscated.
+	Enabled bool `mapstructure:"enabled"`
+
+	// KeepVariables specifies the names of the variables whose values will not be obfuscated.
+	KeepVariables []string `mapstructure:"keep_variables"`
+}
+
+// KafkaObfuscationConfig holds the configuration settings for Kafka obfuscation.
+type KafkaObfuscationConfig struct {
+	// Enabled specifies whether Kafka message keys and payloads should be obfuscated.
+	Enabled bool `mapstructure:"enabled"`
+
+	// KeepMessageKeys specifies whether message keys will not be obfuscated.
+	KeepMessageKeys bool `mapstructure:"keep_message_keys"`
+}
+
 // Enablable can represent any option that has an "enabled" boolean sub-field.
 type Enablable struct {
 	Enabled bool `mapstructure:"enabled"`
@@ -316,6 +358,13 @@ type AgentConfig struct {
 	BucketInterval   time.Duration // the size of our pre-aggregation per bucket
 	ExtraAggregators []string
 
+	// StatsExtraDimensions lists the span meta keys used as extra dimensions of the stats,
+	// on top of the service, name, resource, type, status code and synthetics.
+	StatsExtraDimensions []string
+	// StatsExtraDimensionsMaxCardinality is the maximum number of distinct values of each
+	// extra dimension. Above it, the new values are aggregated together.
+	StatsExtraDimensionsMaxCardinality int
+
 	// Sampler configuration
 	ExtraSampleRate float64
 	TargetTPS       float64
@@ -329,6 +378,12 @@ type AgentConfig struct {
 	RareSamplerCooldownPeriod time.Duration
 	RareSamplerCardinality    int
 
+	// TailSampling holds the configuration of the tail-based sampling mode.
+	TailSampling TailSamplingConfig
+
+	// SpanMetrics lists the rules generating metrics from the received spans.
+	SpanMetrics []*SpanMetricRule
+
 	// Receiver
 	ReceiverHost    string
 	ReceiverPort    int
@@ -401,6 +456,15 @@ type AgentConfig struct {
 	// OTLPReceiver holds the configuration for OpenTelemetry receiver.
 	OTLPReceiver *OTLP
 
+	// ZipkinReceiverEnabled reports whether the trace-agent accepts Zipkin v2 spans on /api/v2/spans.
+	ZipkinReceiverEnabled bool
+
+	// JaegerReceiverEnabled reports whether the trace-agent accepts Jaeger Thrift batches on /api/traces.
+	JaegerReceiverEnabled bool
+
+	// Capture holds the configuration of the capture of the payloads received by the trace-agent.
+	Capture CaptureConfig
+
 	// ProfilingProxy specifies settings for the profiling proxy.
 	ProfilingProxy ProfilingProxyConfig
 
@@ -421,6 +485,9 @@ type AgentConfig struct {
 	// catalog. If not set (0) it will default to 5000.
 	MaxCatalogEntries int
 
+	// SamplerState holds the configuration of the persistence of the priority sampler's state.
+	SamplerState SamplerStateConfig
+
 	// RemoteSamplingClient retrieves sampling updates from the remote config backend
 	RemoteSamplingClient RemoteClient `json:"-"`
 
@@ -437,6 +504,92 @@ type AgentConfig struct {
 	DebugServerPort int
 }
 
+// TailSamplingConfig holds the configuration of the tail-based sampling mode. In this mode, the spans
+// are buffered per trace ID until the trace is complete or quiet, and the whole trace is then sampled.
+type TailSamplingConfig struct {
+	// Enabled reports whether traces are sampled once complete instead of as soon as they are received.
+	Enabled bool
+	// DecisionWait is how long a trace is buffered after its last span was received.
+	DecisionWait time.Duration
+	// MaxSpans bounds the number of buffered spans, the oldest traces being sampled early above it.
+	MaxSpans int
+	// Policies keep the traces matching any of them, on top of the traces kept by the other samplers.
+	Policies []*TailSamplingPolicy
+}
+
+// TailSamplingPolicy keeps the whole traces matching all of its criteria.
+type TailSamplingPolicy struct {
+	// Name identifies the policy in the telemetry and on the kept traces.
+	Name string
+	// Service restricts the policy to the traces whose root span belongs to this service.
+	Service string
+	// MinDuration keeps the traces whose spans cover at least this duration.
+	MinDuration time.Duration
+	// Error keeps the traces containing a span in error.
+	Error bool
+	// Tags keeps the traces containing a span with one of these tags, a tag without value
+	// matching any value.
+	Tags []*Tag
+}
+
+const (
+	// SpanMetricCount is the type of the span metrics counting the matching spans.
+	SpanMetricCount = "count"
+	// SpanMetricDistribution is the type of the span metrics recording a distribution of a value of the matching spans.
+	SpanMetricDistribution = "distribution"
+	// SpanMetricDuration is the value of the span metrics recording the duration of the spans, in seconds.
+	SpanMetricDuration = "duration"
+)
+
+// SpanMetricRule generates a metric from the received spans matching all of its criteria.
+type SpanMetricRule struct {
+	// Name is the name of the generated metric.
+	Name string
+	// Type is either SpanMetricCount or SpanMetricDistribution.
+	Type string
+	// Service restricts the rule to the spans of this service.
+	Service string
+	// OperationName restricts the rule to the spans with this operation name.
+	OperationName string
+	// Resource restricts the rule to the spans whose resource matches this regular expression.
+	Resource *regexp.Regexp
+	// Error restricts the rule to the spans in error.
+	Error bool
+	// Tags restricts the rule to the spans with all of these tags, a tag without value
+	// matching any value.
+	Tags []*Tag
+	// Value is the span metric recorded by distributions, or SpanMetricDuration.
+	Value string
+	// GroupBy lists the span tags added to the metric.
+	GroupBy []string
+}
+
+// CaptureConfig holds the configuration of the capture mode, in which the trace and stats payloads
+// received by the trace-agent are written, along with their headers, to rotating capture files.
+// The capture files can be replayed into a trace-agent with the -replay flag.
+type CaptureConfig struct {
+	// Enabled reports whether the payloads received are captured.
+	Enabled bool
+	// Dir is the directory where the capture files are written.
+	Dir string
+	// MaxFileSize is the size in bytes above which a new capture file is started.
+	MaxFileSize int64
+	// MaxFiles is the number of capture files kept, the oldest ones being removed.
+	MaxFiles int
+}
+
+// SamplerStateConfig holds the configuration of the persistence of the priority sampler's state. The
+// state is written periodically and restored on startup, so that the tracers are sent the rates computed
+// before a restart instead of the default ones.
+type SamplerStateConfig struct {
+	// Enabled reports whether the state of the priority sampler is persisted.
+	Enabled bool
+	// Path is the path of the file where the state is written.
+	Path string
+	// MaxAge is the age above which a persisted state is not restored.
+	MaxAge time.Duration
+}
+
 // RemoteClient client is used to APM Sampling Updates from a remote source.
 // This is an interface around the client provided by pkg/config/remote to allow for easier testing.
 type RemoteClient interface {
@@ -460,7 +613,8 @@ func New() *AgentConfig {
 		Site:                "datadoghq.com",
 		MaxCatalogEntries:   5000,
 
-		BucketInterval: time.Duration(10) * time.Second,
+		BucketInterval:                     time.Duration(10) * time.Second,
+		StatsExtraDimensionsMaxCardinality: 100,
 
 		ExtraSampleRate: 1.0,
 		TargetTPS:       10,
@@ -473,6 +627,18 @@ func New() *AgentConfig {
 		RareSamplerCooldownPeriod: 5 * time.Minute,
 		RareSamplerCardinality:    200,
 
+		TailSampling: TailSamplingConfig{
+			DecisionWait: 10 * time.Second,
+			MaxSpans:     100000,
+		},
+		Capture: CaptureConfig{
+			MaxFileSize: 100 * 1024 * 1024, // 100MB
+			MaxFiles:    5,
+		},
+		SamplerState: SamplerStateConfig{
+			MaxAge: 10 * time.Minute,
+		},
+
 		ReceiverHost:           "localhost",
 		ReceiverPort:           8126,
 		MaxRequestBytes:        25 * 1024 * 1024, // 25MB
@@ -185,6 +185,10 @@ func (ts *testStatsClient) Histogram(name string, value float64, tags []string,
 	return nil
 }
 
+func (ts *testStatsClient) Distribution(name string, value float64, tags []string, rate float64) error {
+	return nil
+}
+
 func (ts *testStatsClient) Timing(name string, value time.Duration, tags []string, rate float64) error {
 	return nil
 }
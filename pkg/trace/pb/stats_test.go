@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package pb
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClientGroupedStatsDescriptor ensures that the descriptor embedded in stats.pb.go
+// declares the fields of the generated ClientGroupedStats struct.
+func TestClientGroupedStatsDescriptor(t *testing.T) {
+	_, msg := descriptor.ForMessage(&ClientGroupedStats{})
+	require.Len(t, msg.Field, 14)
+	tags := msg.Field[13]
+	assert.Equal(t, "tags", tags.GetName())
+	assert.Equal(t, int32(14), tags.GetNumber())
+	assert.Equal(t, descriptor.FieldDescriptorProto_LABEL_REPEATED, tags.GetLabel())
+	assert.Equal(t, descriptor.FieldDescriptorProto_TYPE_STRING, tags.GetType())
+}
+
+func TestClientGroupedStatsTags(t *testing.T) {
+	stats := &ClientGroupedStats{Service: "web", Tags: []string{"region:us-east-1", "tier:gold"}}
+	b, err := stats.Marshal()
+	require.NoError(t, err)
+	var decoded ClientGroupedStats
+	require.NoError(t, decoded.Unmarshal(b))
+	assert.Equal(t, stats.Tags, decoded.Tags)
+}
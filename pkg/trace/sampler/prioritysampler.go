@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 )
 
@@ -44,7 +45,9 @@ type PrioritySampler struct {
 	// This struct is shared with the agent API which sends the rates in http responses to spans post requests
 	rateByService *RateByService
 	catalog       *serviceKeyCatalog
-	exit          chan struct{}
+	// stateConf configures the persistence of the sampler's state across restarts.
+	stateConf config.SamplerStateConfig
+	exit      chan struct{}
 }
 
 // NewPrioritySampler returns an initialized Sampler
@@ -54,8 +57,12 @@ func NewPrioritySampler(conf *config.AgentConfig, dynConf *DynamicConfig) *Prior
 		sampler:       newSampler(conf.ExtraSampleRate, conf.TargetTPS, []string{"sampler:priority"}),
 		rateByService: &dynConf.RateByService,
 		catalog:       newServiceLookup(conf.MaxCatalogEntries),
+		stateConf:     conf.SamplerState,
 		exit:          make(chan struct{}),
 	}
+	if s.stateConf.Enabled {
+		s.loadState(time.Now())
+	}
 	return s
 }
 
@@ -64,10 +71,20 @@ func (s *PrioritySampler) Start() {
 	go func() {
 		statsTicker := time.NewTicker(10 * time.Second)
 		defer statsTicker.Stop()
+		var persist <-chan time.Time
+		if s.stateConf.Enabled {
+			persistTicker := time.NewTicker(samplerStatePersistInterval)
+			defer persistTicker.Stop()
+			persist = persistTicker.C
+		}
 		for {
 			select {
 			case <-statsTicker.C:
 				s.sampler.report()
+			case now := <-persist:
+				if err := s.persistState(now); err != nil {
+					log.Errorf("Error persisting the priority sampler state: %v", err)
+				}
 			case <-s.exit:
 				return
 			}
@@ -88,9 +105,14 @@ func (s *PrioritySampler) updateRates() {
 	s.rateByService.SetAll(s.ratesByService())
 }
 
-// Stop stops the sampler main loop
+// Stop stops the sampler main loop, persisting its state a last time when enabled.
 func (s *PrioritySampler) Stop() {
 	close(s.exit)
+	if s.stateConf.Enabled {
+		if err := s.persistState(time.Now()); err != nil {
+			log.Errorf("Error persisting the priority sampler state: %v", err)
+		}
+	}
 }
 
 // Sample counts an incoming trace and returns the trace sampling decision and the applied sampling rate
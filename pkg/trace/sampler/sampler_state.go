@@ -0,0 +1,179 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+)
+
+// samplerStatePersistInterval is the interval at which the state of the priority sampler is persisted.
+const samplerStatePersistInterval = 30 * time.Second
+
+// samplerState is the state of the priority sampler, persisted to warm-start the sampler when the
+// agent restarts. The buckets are ordered from the oldest to the most recent one.
+type samplerState struct {
+	// Time is the time at which the state was persisted.
+	Time time.Time `json:"time"`
+	// AllSigsSeen holds the counts of all the signatures.
+	AllSigsSeen [numBuckets]float32 `json:"all_sigs_seen"`
+	// LowestRate is the lowest rate of all the signatures.
+	LowestRate float64 `json:"lowest_rate"`
+	// Services holds the state of each service signature.
+	Services []serviceState `json:"services"`
+}
+
+// serviceState is the state of a service signature of the priority sampler.
+type serviceState struct {
+	Service string              `json:"service"`
+	Env     string              `json:"env"`
+	Seen    [numBuckets]float32 `json:"seen"`
+	// Rate is the rate computed for the signature, nil if none was computed yet.
+	Rate *float64 `json:"rate,omitempty"`
+}
+
+// services returns the service signatures of the catalog, by signature.
+func (cat *serviceKeyCatalog) services() map[Signature]ServiceSignature {
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+	svcs := make(map[Signature]ServiceSignature, len(cat.items))
+	for key, el := range cat.items {
+		svcs[el.Value.(catalogEntry).sig] = key
+	}
+	return svcs
+}
+
+// orderBuckets returns buckets ordered from the oldest to the most recent one, lastBucketID being
+// the ID of the most recent one.
+func orderBuckets(buckets [numBuckets]float32, lastBucketID int64) [numBuckets]float32 {
+	var ordered [numBuckets]float32
+	for i := range ordered {
+		ordered[i] = buckets[(lastBucketID+1+int64(i))%numBuckets]
+	}
+	return ordered
+}
+
+// placeBuckets is the reverse of orderBuckets: it returns the ordered buckets placed so that the
+// most recent one precedes bucketID, the current bucket. The current bucket is left empty, the
+// oldest bucket being dropped.
+func placeBuckets(ordered [numBuckets]float32, bucketID int64) [numBuckets]float32 {
+	var buckets [numBuckets]float32
+	for i := 1; i < numBuckets; i++ {
+		buckets[(bucketID+int64(i))%numBuckets] = ordered[i]
+	}
+	return buckets
+}
+
+// state returns the current state of the priority sampler.
+func (s *PrioritySampler) state(now time.Time) *samplerState {
+	svcs := s.catalog.services()
+	st := &samplerState{Time: now}
+
+	s.sampler.muSeen.RLock()
+	defer s.sampler.muSeen.RUnlock()
+	s.sampler.muRates.RLock()
+	defer s.sampler.muRates.RUnlock()
+	st.AllSigsSeen = orderBuckets(s.sampler.allSigsSeen, s.sampler.lastBucketID)
+	st.LowestRate = s.sampler.lowestRate
+	for sig, buckets := range s.sampler.seen {
+		svc, ok := svcs[sig]
+		if !ok {
+			// dropped from the catalog
+			continue
+		}
+		ss := serviceState{
+			Service: svc.Name,
+			Env:     svc.Env,
+			Seen:    orderBuckets(buckets, s.sampler.lastBucketID),
+		}
+		if rate, ok := s.sampler.rates[sig]; ok {
+			ss.Rate = &rate
+		}
+		st.Services = append(st.Services, ss)
+	}
+	return st
+}
+
+// restore restores the state st of the priority sampler, as if the buckets of st were the ones
+// preceding now, and updates the rates sent to the tracers.
+func (s *PrioritySampler) restore(st *samplerState, now time.Time) {
+	bucketID := now.Unix() / int64(bucketDuration.Seconds())
+	seen := make(map[Signature][numBuckets]float32, len(st.Services))
+	rates := make(map[Signature]float64, len(st.Services))
+	for _, ss := range st.Services {
+		sig := s.catalog.register(ServiceSignature{Name: ss.Service, Env: ss.Env})
+		seen[sig] = placeBuckets(ss.Seen, bucketID)
+		if ss.Rate != nil {
+			rates[sig] = *ss.Rate
+		}
+	}
+
+	s.sampler.muSeen.Lock()
+	s.sampler.seen = seen
+	s.sampler.allSigsSeen = placeBuckets(st.AllSigsSeen, bucketID)
+	s.sampler.lastBucketID = bucketID
+	s.sampler.muSeen.Unlock()
+
+	s.sampler.muRates.Lock()
+	s.sampler.rates = rates
+	s.sampler.lowestRate = st.LowestRate
+	s.sampler.muRates.Unlock()
+
+	s.updateRates()
+}
+
+// persistState writes the current state of the priority sampler to the configured path.
+func (s *PrioritySampler) persistState(now time.Time) error {
+	b, err := json.Marshal(s.state(now))
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(s.stateConf.Path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	// write to a temporary file first, so that a partially written state is never loaded
+	f, err := os.CreateTemp(dir, filepath.Base(s.stateConf.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.stateConf.Path)
+}
+
+// loadState restores the state of the priority sampler persisted to the configured path, unless
+// it is older than the configured maximum age.
+func (s *PrioritySampler) loadState(now time.Time) {
+	b, err := os.ReadFile(s.stateConf.Path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("Error reading the priority sampler state: %v", err)
+		}
+		return
+	}
+	var st samplerState
+	if err := json.Unmarshal(b, &st); err != nil {
+		log.Warnf("Error decoding the priority sampler state %s: %v", s.stateConf.Path, err)
+		return
+	}
+	if age := now.Sub(st.Time); age < 0 || (s.stateConf.MaxAge > 0 && age > s.stateConf.MaxAge) {
+		log.Infof("Ignoring the priority sampler state persisted at %s, its age is out of bounds.", st.Time)
+		return
+	}
+	s.restore(&st, now)
+	log.Infof("Restored the priority sampler state of %d services persisted at %s.", len(st.Services), st.Time)
+}
@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+)
+
+func TestSamplerStateBuckets(t *testing.T) {
+	buckets := [numBuckets]float32{0, 1, 2, 3, 4, 5}
+	ordered := orderBuckets(buckets, 8)
+	assert.Equal(t, [numBuckets]float32{3, 4, 5, 0, 1, 2}, ordered)
+	// placed back before the next bucket, the oldest bucket is dropped
+	assert.Equal(t, [numBuckets]float32{0, 1, 2, 0, 4, 5}, placeBuckets(ordered, 9))
+}
+
+func TestSamplerStatePersist(t *testing.T) {
+	conf := &config.AgentConfig{
+		ExtraSampleRate: 1,
+		TargetTPS:       1,
+		SamplerState: config.SamplerStateConfig{
+			Enabled: true,
+			Path:    filepath.Join(t.TempDir(), "state", "sampler.json"),
+			MaxAge:  time.Minute,
+		},
+	}
+	newSampler := func() (*PrioritySampler, *DynamicConfig) {
+		dynConf := NewDynamicConfig()
+		return NewPrioritySampler(conf, dynConf), dynConf
+	}
+
+	s, dynConf := newSampler()
+	// 20 traces per second over 4 buckets
+	start := time.Now().Add(-4 * bucketDuration)
+	for b := 0; b < 4; b++ {
+		for i := 0; i < 100; i++ {
+			chunk, root := getTestTraceWithService("my-service", s)
+			chunk.Priority = int32(PriorityAutoKeep)
+			s.Sample(start.Add(time.Duration(b)*bucketDuration), chunk, root, defaultEnv, 0)
+		}
+	}
+	rates := dynConf.RateByService.GetNewState("").Rates
+	require.InDelta(t, 0.05, rates["service:my-service,env:"+defaultEnv], 0.001)
+	require.NoError(t, s.persistState(time.Now()))
+
+	t.Run("restored", func(t *testing.T) {
+		s2, dynConf2 := newSampler()
+		assert.Equal(t, rates, dynConf2.RateByService.GetNewState("").Rates)
+
+		// the restored buckets are used to compute the next rates
+		sig := ServiceSignature{Name: "my-service", Env: defaultEnv}.Hash()
+		s2.sampler.countWeightedSig(time.Now().Add(bucketDuration), sig, 1)
+		assert.InDelta(t, 0.05, s2.sampler.getSignatureSampleRate(sig), 0.001)
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		st := s.state(time.Now().Add(-2 * time.Minute))
+		b, err := json.Marshal(st)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(conf.SamplerState.Path, b, 0o644))
+		_, dynConf2 := newSampler()
+		assert.Empty(t, dynConf2.RateByService.GetNewState("").Rates)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(conf.SamplerState.Path, []byte("{"), 0o644))
+		_, dynConf2 := newSampler()
+		assert.Empty(t, dynConf2.RateByService.GetNewState("").Rates)
+	})
+}
@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// tailPolicyKey is set on the root span of the traces kept by a tail sampling policy.
+const tailPolicyKey = "_dd.tail_sampling.policy"
+
+// TailSampler keeps the whole traces matching any of the tail sampling policies, such as the
+// traces lasting longer than a threshold or containing an error. Unlike the other samplers it
+// runs once all the spans of a trace have been received, on top of them.
+type TailSampler struct {
+	policies []*config.TailSamplingPolicy
+}
+
+// NewTailSampler returns a TailSampler applying the policies of the configuration.
+func NewTailSampler(conf *config.AgentConfig) *TailSampler {
+	return &TailSampler{policies: conf.TailSampling.Policies}
+}
+
+// Sample returns the name of the first policy matching the trace, and whether one did.
+// The policy is recorded on the root span of the trace.
+func (s *TailSampler) Sample(trace pb.Trace, root *pb.Span) (string, bool) {
+	for _, policy := range s.policies {
+		if !matchesTailPolicy(policy, trace, root) {
+			continue
+		}
+		if root.Meta == nil {
+			root.Meta = make(map[string]string)
+		}
+		root.Meta[tailPolicyKey] = policy.Name
+		metrics.Count("datadog.trace_agent.tail_sampler.kept", 1, []string{"policy:" + policy.Name}, 1)
+		return policy.Name, true
+	}
+	return "", false
+}
+
+// matchesTailPolicy returns true if the trace matches all the criteria of the policy.
+func matchesTailPolicy(policy *config.TailSamplingPolicy, trace pb.Trace, root *pb.Span) bool {
+	if policy.Service != "" && root.Service != policy.Service {
+		return false
+	}
+	if policy.MinDuration > 0 && traceDuration(trace) < policy.MinDuration {
+		return false
+	}
+	if policy.Error && !hasError(trace) {
+		return false
+	}
+	if len(policy.Tags) > 0 && !hasTag(trace, policy.Tags) {
+		return false
+	}
+	return true
+}
+
+// traceDuration returns the duration covered by the spans of the trace.
+func traceDuration(trace pb.Trace) time.Duration {
+	var start, end int64
+	for i, span := range trace {
+		if i == 0 || span.Start < start {
+			start = span.Start
+		}
+		if i == 0 || span.Start+span.Duration > end {
+			end = span.Start + span.Duration
+		}
+	}
+	return time.Duration(end - start)
+}
+
+func hasError(trace pb.Trace) bool {
+	for _, span := range trace {
+		if span.Error != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTag returns true if a span of the trace has one of the tags.
+func hasTag(trace pb.Trace, tags []*config.Tag) bool {
+	for _, span := range trace {
+		for _, tag := range tags {
+			if v, ok := span.Meta[tag.K]; ok && (tag.V == "" || v == tag.V) {
+				return true
+			}
+		}
+	}
+	return false
+}
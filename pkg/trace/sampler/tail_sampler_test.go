@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+func TestTailSampler(t *testing.T) {
+	conf := config.New()
+	conf.TailSampling.Policies = []*config.TailSamplingPolicy{
+		{Name: "slow_checkout", Service: "checkout", MinDuration: time.Second},
+		{Name: "errors", Error: true},
+		{Name: "beta", Tags: []*config.Tag{{K: "customer.tier", V: "beta"}, {K: "debug"}}},
+	}
+	s := NewTailSampler(conf)
+
+	newTrace := func(service string, duration time.Duration, childError int32, childMeta map[string]string) (pb.Trace, *pb.Span) {
+		root := &pb.Span{TraceID: 1, SpanID: 1, Service: service, Start: 100, Duration: int64(duration / 2)}
+		child := &pb.Span{TraceID: 1, SpanID: 2, ParentID: 1, Service: "db", Start: 100 + int64(duration/2), Duration: int64(duration / 2), Error: childError, Meta: childMeta}
+		return pb.Trace{root, child}, root
+	}
+
+	for _, tt := range []struct {
+		name   string
+		trace  func() (pb.Trace, *pb.Span)
+		policy string
+	}{
+		{"slow-checkout", func() (pb.Trace, *pb.Span) { return newTrace("checkout", 2*time.Second, 0, nil) }, "slow_checkout"},
+		{"fast-checkout", func() (pb.Trace, *pb.Span) { return newTrace("checkout", 500*time.Millisecond, 0, nil) }, ""},
+		{"slow-cart", func() (pb.Trace, *pb.Span) { return newTrace("cart", 2*time.Second, 0, nil) }, ""},
+		{"error-in-child", func() (pb.Trace, *pb.Span) { return newTrace("cart", time.Millisecond, 1, nil) }, "errors"},
+		{"tag-value", func() (pb.Trace, *pb.Span) {
+			return newTrace("cart", time.Millisecond, 0, map[string]string{"customer.tier": "beta"})
+		}, "beta"},
+		{"other-tag-value", func() (pb.Trace, *pb.Span) {
+			return newTrace("cart", time.Millisecond, 0, map[string]string{"customer.tier": "gold"})
+		}, ""},
+		{"tag-any-value", func() (pb.Trace, *pb.Span) {
+			return newTrace("cart", time.Millisecond, 0, map[string]string{"debug": "true"})
+		}, "beta"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			trace, root := tt.trace()
+			policy, keep := s.Sample(trace, root)
+			assert.Equal(t, tt.policy, policy)
+			assert.Equal(t, tt.policy != "", keep)
+			if keep {
+				assert.Equal(t, tt.policy, root.Meta[tailPolicyKey])
+			} else {
+				assert.NotContains(t, root.Meta, tailPolicyKey)
+			}
+		})
+	}
+}
+
+func TestTraceDuration(t *testing.T) {
+	assert.Equal(t, time.Duration(0), traceDuration(nil))
+	assert.Equal(t, time.Duration(250), traceDuration(pb.Trace{
+		{Start: 100, Duration: 50},
+		{Start: 50, Duration: 20},
+		{Start: 120, Duration: 180},
+	}))
+}
@@ -33,6 +33,7 @@ type BucketsAggregationKey struct {
 	Type       string
 	StatusCode uint32
 	Synthetics bool
+	ExtraTags  string // the extra dimensions, joined by tagsKey
 }
 
 // PayloadAggregationKey specifies the key by which a payload is aggregated.
@@ -61,8 +62,9 @@ func getStatusCode(s *pb.Span) uint32 {
 	return uint32(c)
 }
 
-// NewAggregationFromSpan creates a new aggregation from the provided span and env
-func NewAggregationFromSpan(s *pb.Span, origin string, aggKey PayloadAggregationKey) Aggregation {
+// NewAggregationFromSpan creates a new aggregation from the provided span and env,
+// extraTags being its extra dimensions.
+func NewAggregationFromSpan(s *pb.Span, origin string, aggKey PayloadAggregationKey, extraTags []string) Aggregation {
 	synthetics := strings.HasPrefix(origin, tagSynthetics)
 	return Aggregation{
 		PayloadAggregationKey: aggKey,
@@ -73,6 +75,7 @@ func NewAggregationFromSpan(s *pb.Span, origin string, aggKey PayloadAggregation
 			Type:       s.Type,
 			StatusCode: getStatusCode(s),
 			Synthetics: synthetics,
+			ExtraTags:  tagsKey(extraTags),
 		},
 	}
 }
@@ -86,6 +89,7 @@ func NewAggregationFromGroup(g pb.ClientGroupedStats) Aggregation {
 			Name:       g.Name,
 			StatusCode: g.HTTPStatusCode,
 			Synthetics: g.Synthetics,
+			ExtraTags:  tagsKey(g.Tags),
 		},
 	}
 }
@@ -208,7 +208,7 @@ func (b *bucket) aggregateCounts(p pb.ClientStatsPayload) {
 			aggKey := newBucketAggregationKey(sb)
 			agg, ok := payloadAgg[aggKey]
 			if !ok {
-				agg = &aggregatedCounts{}
+				agg = &aggregatedCounts{tags: sb.Tags}
 				payloadAgg[aggKey] = agg
 			}
 			agg.hits += sb.Hits
@@ -240,6 +240,7 @@ func (b *bucket) aggregationToPayloads() []pb.ClientStatsPayload {
 				Hits:           counts.hits,
 				Errors:         counts.errors,
 				Duration:       counts.duration,
+				Tags:           counts.tags,
 			})
 		}
 		clientBuckets := []pb.ClientStatsBucket{
@@ -271,6 +272,7 @@ func newBucketAggregationKey(b pb.ClientGroupedStats) BucketsAggregationKey {
 		Type:       b.Type,
 		Synthetics: b.Synthetics,
 		StatusCode: b.HTTPStatusCode,
+		ExtraTags:  tagsKey(b.Tags),
 	}
 }
 
@@ -291,4 +293,5 @@ func trimCounts(p pb.ClientStatsPayload) pb.ClientStatsPayload {
 // Distributions and TopLevelCount will stay on the initial payload
 type aggregatedCounts struct {
 	hits, errors, duration uint64
+	tags                   []string
 }
@@ -45,10 +45,13 @@ type Concentrator struct {
 	agentEnv      string
 	agentHostname string
 	agentVersion  string
+	// extraDimensions extracts the extra dimensions of the stats from the spans
+	extraDimensions *ExtraDimensions
 }
 
-// NewConcentrator initializes a new concentrator ready to be started
-func NewConcentrator(conf *config.AgentConfig, out chan pb.StatsPayload, now time.Time) *Concentrator {
+// NewConcentrator initializes a new concentrator ready to be started. The extra dimensions
+// may be shared with the aggregation of the client computed stats, and be nil.
+func NewConcentrator(conf *config.AgentConfig, out chan pb.StatsPayload, now time.Time, extraDimensions *ExtraDimensions) *Concentrator {
 	bsize := conf.BucketInterval.Nanoseconds()
 	c := Concentrator{
 		bsize:   bsize,
@@ -57,13 +60,14 @@ func NewConcentrator(conf *config.AgentConfig, out chan pb.StatsPayload, now tim
 		// override buckets which could have been sent before an Agent restart.
 		oldestTs: alignTs(now.UnixNano(), bsize),
 		// TODO: Move to configuration.
-		bufferLen:     defaultBufferLen,
-		In:            make(chan Input, 100),
-		Out:           out,
-		exit:          make(chan struct{}),
-		agentEnv:      conf.DefaultEnv,
-		agentHostname: conf.Hostname,
-		agentVersion:  conf.AgentVersion,
+		bufferLen:       defaultBufferLen,
+		In:              make(chan Input, 100),
+		Out:             out,
+		exit:            make(chan struct{}),
+		agentEnv:        conf.DefaultEnv,
+		agentHostname:   conf.Hostname,
+		agentVersion:    conf.AgentVersion,
+		extraDimensions: extraDimensions,
 	}
 	return &c
 }
@@ -181,7 +185,7 @@ func (c *Concentrator) addNow(pt *traceutil.ProcessedTrace, containerID string)
 			b = NewRawBucket(uint64(btime), uint64(c.bsize))
 			c.buckets[btime] = b
 		}
-		b.HandleSpan(s, weight, isTop, pt.TraceChunk.Origin, aggKey)
+		b.HandleSpan(s, weight, isTop, pt.TraceChunk.Origin, aggKey, c.extraDimensions.FromSpan(s))
 	}
 }
 
@@ -34,7 +34,7 @@ func NewTestConcentrator(now time.Time) *Concentrator {
 		DefaultEnv:     "env",
 		Hostname:       "hostname",
 	}
-	return NewConcentrator(&cfg, statsChan, now)
+	return NewConcentrator(&cfg, statsChan, now, nil)
 }
 
 // getTsInBucket gives a timestamp in ns which is `offset` buckets late
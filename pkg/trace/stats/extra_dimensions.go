@@ -0,0 +1,133 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+const (
+	// overflowValue replaces the values of an extra dimension above its maximum cardinality.
+	overflowValue = "_other"
+	// cardinalityResetInterval is the frequency at which the distinct values seen are forgotten.
+	cardinalityResetInterval = 10 * time.Minute
+)
+
+// ExtraDimensions extracts the extra dimensions of the stats, set with apm_config.stats_extra_dimensions,
+// from the meta of the spans or the tags of the grouped stats sent by the tracers. It guards against
+// a high cardinality: above the maximum number of distinct values of a dimension, the new values are
+// aggregated together under "_other".
+type ExtraDimensions struct {
+	keys           []string // sorted
+	maxCardinality int
+
+	mu        sync.Mutex
+	values    map[string]map[string]struct{} // distinct values seen per key
+	lastReset time.Time
+}
+
+// NewExtraDimensions returns the ExtraDimensions set in the configuration, or nil if there are none.
+func NewExtraDimensions(conf *config.AgentConfig) *ExtraDimensions {
+	if len(conf.StatsExtraDimensions) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(conf.StatsExtraDimensions))
+	seen := make(map[string]struct{}, len(conf.StatsExtraDimensions))
+	for _, k := range conf.StatsExtraDimensions {
+		if _, ok := seen[k]; ok || k == "" {
+			continue
+		}
+		seen[k] = struct{}{}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return &ExtraDimensions{
+		keys:           keys,
+		maxCardinality: conf.StatsExtraDimensionsMaxCardinality,
+		values:         make(map[string]map[string]struct{}, len(keys)),
+		lastReset:      time.Now(),
+	}
+}
+
+// FromSpan returns the extra dimensions of the span as key:value tags sorted by key.
+func (d *ExtraDimensions) FromSpan(s *pb.Span) []string {
+	if d == nil {
+		return nil
+	}
+	var tags []string
+	for _, k := range d.keys {
+		if v, ok := s.Meta[k]; ok {
+			tags = append(tags, k+":"+d.limit(k, v))
+		}
+	}
+	return tags
+}
+
+// FromTags returns the extra dimensions found in the tags of grouped stats as key:value
+// tags sorted by key. The tags not matching any of the extra dimensions are discarded.
+func (d *ExtraDimensions) FromTags(in []string) []string {
+	if d == nil || len(in) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(in))
+	for _, t := range in {
+		k, v := splitTag(t)
+		if _, ok := values[k]; !ok {
+			values[k] = v
+		}
+	}
+	var tags []string
+	for _, k := range d.keys {
+		if v, ok := values[k]; ok {
+			tags = append(tags, k+":"+d.limit(k, v))
+		}
+	}
+	return tags
+}
+
+// limit returns the value, or overflowValue if the dimension already has too many distinct values.
+func (d *ExtraDimensions) limit(key, value string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if now := time.Now(); now.Sub(d.lastReset) >= cardinalityResetInterval {
+		d.values = make(map[string]map[string]struct{}, len(d.keys))
+		d.lastReset = now
+	}
+	values, ok := d.values[key]
+	if !ok {
+		values = make(map[string]struct{})
+		d.values[key] = values
+	}
+	if _, ok := values[value]; ok {
+		return value
+	}
+	if d.maxCardinality > 0 && len(values) >= d.maxCardinality {
+		metrics.Count("datadog.trace_agent.stats.extra_dimensions.overflow", 1, []string{"dimension:" + key}, 1)
+		return overflowValue
+	}
+	values[value] = struct{}{}
+	return value
+}
+
+// splitTag splits a key:value tag, the value being empty if there is no colon.
+func splitTag(tag string) (key, value string) {
+	if i := strings.IndexByte(tag, ':'); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
+
+// tagsKey returns a comparable key for the extra dimensions, used in aggregation keys.
+func tagsKey(tags []string) string {
+	return strings.Join(tags, "\x00")
+}
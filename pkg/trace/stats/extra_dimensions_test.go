@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+func TestExtraDimensions(t *testing.T) {
+	conf := config.New()
+	assert.Nil(t, NewExtraDimensions(conf))
+
+	var d *ExtraDimensions
+	assert.Nil(t, d.FromSpan(&pb.Span{Meta: map[string]string{"peer.service": "billing"}}))
+	assert.Nil(t, d.FromTags([]string{"peer.service:billing"}))
+
+	conf.StatsExtraDimensions = []string{"peer.service", "customer.tier", "peer.service"}
+	conf.StatsExtraDimensionsMaxCardinality = 2
+	d = NewExtraDimensions(conf)
+	assert.Equal(t, []string{"customer.tier", "peer.service"}, d.keys)
+
+	t.Run("span", func(t *testing.T) {
+		s := &pb.Span{Meta: map[string]string{"peer.service": "billing", "customer.tier": "beta", "env": "prod"}}
+		assert.Equal(t, []string{"customer.tier:beta", "peer.service:billing"}, d.FromSpan(s))
+		assert.Nil(t, d.FromSpan(&pb.Span{}))
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		assert.Equal(t, []string{"customer.tier:beta", "peer.service:billing"}, d.FromTags([]string{"peer.service:billing", "env:prod", "customer.tier:beta"}))
+		assert.Equal(t, []string{"peer.service:"}, d.FromTags([]string{"peer.service"}))
+		assert.Nil(t, d.FromTags([]string{"env:prod"}))
+	})
+
+	t.Run("cardinality", func(t *testing.T) {
+		d := NewExtraDimensions(conf)
+		assert.Equal(t, "a", d.limit("peer.service", "a"))
+		assert.Equal(t, "b", d.limit("peer.service", "b"))
+		assert.Equal(t, overflowValue, d.limit("peer.service", "c"))
+		// known values and other dimensions are not affected
+		assert.Equal(t, "a", d.limit("peer.service", "a"))
+		assert.Equal(t, "c", d.limit("customer.tier", "c"))
+
+		// the values seen are forgotten periodically
+		d.lastReset = time.Now().Add(-cardinalityResetInterval)
+		assert.Equal(t, "c", d.limit("peer.service", "c"))
+	})
+}
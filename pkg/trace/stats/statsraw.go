@@ -36,6 +36,7 @@ type groupedStats struct {
 	duration        float64
 	okDistribution  *ddsketch.DDSketch
 	errDistribution *ddsketch.DDSketch
+	extraTags       []string
 }
 
 // round a float to an int, uniformly choosing
@@ -72,10 +73,11 @@ func (s *groupedStats) export(a Aggregation) (pb.ClientGroupedStats, error) {
 		OkSummary:      okSummary,
 		ErrorSummary:   errSummary,
 		Synthetics:     a.Synthetics,
+		Tags:           s.extraTags,
 	}, nil
 }
 
-func newGroupedStats() *groupedStats {
+func newGroupedStats(extraTags []string) *groupedStats {
 	okSketch, err := ddsketch.LogCollapsingLowestDenseDDSketch(relativeAccuracy, maxNumBins)
 	if err != nil {
 		log.Errorf("Error when creating ddsketch: %v", err)
@@ -87,6 +89,7 @@ func newGroupedStats() *groupedStats {
 	return &groupedStats{
 		okDistribution:  okSketch,
 		errDistribution: errSketch,
+		extraTags:       extraTags,
 	}
 }
 
@@ -144,20 +147,21 @@ func (sb *RawBucket) Export() map[PayloadAggregationKey]pb.ClientStatsBucket {
 }
 
 // HandleSpan adds the span to this bucket stats, aggregated with the finest grain matching given aggregators
-func (sb *RawBucket) HandleSpan(s *pb.Span, weight float64, isTop bool, origin string, aggKey PayloadAggregationKey) {
+// and extra dimensions.
+func (sb *RawBucket) HandleSpan(s *pb.Span, weight float64, isTop bool, origin string, aggKey PayloadAggregationKey, extraTags []string) {
 	if aggKey.Env == "" {
 		panic("env should never be empty")
 	}
-	aggr := NewAggregationFromSpan(s, origin, aggKey)
-	sb.add(s, weight, isTop, aggr)
+	aggr := NewAggregationFromSpan(s, origin, aggKey, extraTags)
+	sb.add(s, weight, isTop, aggr, extraTags)
 }
 
-func (sb *RawBucket) add(s *pb.Span, weight float64, isTop bool, aggr Aggregation) {
+func (sb *RawBucket) add(s *pb.Span, weight float64, isTop bool, aggr Aggregation, extraTags []string) {
 	var gs *groupedStats
 	var ok bool
 
 	if gs, ok = sb.data[aggr]; !ok {
-		gs = newGroupedStats()
+		gs = newGroupedStats(extraTags)
 		sb.data[aggr] = gs
 	}
 	if isTop {
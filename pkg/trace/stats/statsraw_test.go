@@ -6,6 +6,7 @@
 package stats
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
@@ -20,7 +21,7 @@ func TestGrain(t *testing.T) {
 		Env:         "default",
 		Hostname:    "default",
 		ContainerID: "cid",
-	})
+	}, nil)
 	assert.Equal(Aggregation{
 		PayloadAggregationKey: PayloadAggregationKey{
 			Env:         "default",
@@ -43,7 +44,7 @@ func TestGrainWithExtraTags(t *testing.T) {
 		Version:     "v0",
 		Env:         "default",
 		ContainerID: "cid",
-	})
+	}, nil)
 	assert.Equal(Aggregation{
 		PayloadAggregationKey: PayloadAggregationKey{
 			Hostname:    "host-id",
@@ -61,13 +62,42 @@ func TestGrainWithExtraTags(t *testing.T) {
 	}, aggr)
 }
 
+func TestGrainWithExtraDimensions(t *testing.T) {
+	assert := assert.New(t)
+	sb := NewRawBucket(0, 1e9)
+	aggKey := PayloadAggregationKey{Env: "default"}
+	extraTags := []string{"customer.tier:beta", "peer.service:billing"}
+	for _, tags := range [][]string{extraTags, extraTags, nil} {
+		s := &pb.Span{Service: "thing", Name: "other", Resource: "yo", Duration: 100}
+		sb.HandleSpan(s, 1, true, "", aggKey, tags)
+	}
+	assert.Len(sb.data, 2)
+	assert.Contains(sb.data, Aggregation{
+		PayloadAggregationKey: aggKey,
+		BucketsAggregationKey: BucketsAggregationKey{
+			Service:   "thing",
+			Name:      "other",
+			Resource:  "yo",
+			ExtraTags: "customer.tier:beta\x00peer.service:billing",
+		},
+	})
+
+	groups := sb.Export()[aggKey].Stats
+	assert.Len(groups, 2)
+	hits := make(map[string]uint64)
+	for _, g := range groups {
+		hits[strings.Join(g.Tags, ",")] = g.Hits
+	}
+	assert.Equal(map[string]uint64{"customer.tier:beta,peer.service:billing": 2, "": 1}, hits)
+}
+
 func BenchmarkHandleSpanRandom(b *testing.B) {
 	sb := NewRawBucket(0, 1e9)
 	b.ResetTimer()
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		for _, span := range benchSpans {
-			sb.HandleSpan(span, 1, true, "", PayloadAggregationKey{"a", "b", "c", "d"})
+			sb.HandleSpan(span, 1, true, "", PayloadAggregationKey{"a", "b", "c", "d"}, nil)
 		}
 	}
 }
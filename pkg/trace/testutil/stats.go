@@ -30,7 +30,7 @@ func BucketWithSpans(spans []*pb.Span) pb.ClientStatsBucket {
 	for _, s := range spans {
 		// override version to ensure all buckets will have the same payload key.
 		s.Meta["version"] = ""
-		srb.HandleSpan(s, 0, true, "", aggKey)
+		srb.HandleSpan(s, 0, true, "", aggKey, nil)
 	}
 	buckets := srb.Export()
 	if len(buckets) != 1 {
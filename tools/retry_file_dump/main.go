@@ -8,6 +8,10 @@ package main
 import (
 	"bytes"
 	"compress/zlib"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -16,31 +20,38 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 
 	proto "github.com/golang/protobuf/proto"
 )
 
 func main() {
-	folder, err := parseArg()
+	folder, keyFiles, err := parseArg()
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	if err = dumpRetryFiles(folder); err != nil {
+	keys, err := readKeys(keyFiles)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err = dumpRetryFiles(folder, keys); err != nil {
 		fmt.Println(err)
 	}
 }
 
-func parseArg() (string, error) {
+func parseArg() (string, string, error) {
 	var folder = flag.String("folder", "", "The folder containing `.retry` files.")
+	var keyFiles = flag.String("key_files", "", "Comma separated list of files containing the keys of the encrypted `.retry` files.")
 	flag.Parse()
 	if *folder == "" {
-		return "", errors.New("Invalid folder: Usage `./retry_file_dump --folder=/opt/datadog-agent/run/transactions_to_retry/c47da40ac935c8fd5ca1441a5ee3d068/`")
+		return "", "", errors.New("Invalid folder: Usage `./retry_file_dump --folder=/opt/datadog-agent/run/transactions_to_retry/c47da40ac935c8fd5ca1441a5ee3d068/`")
 	}
-	return *folder, nil
+	return *folder, *keyFiles, nil
 }
 
-func dumpRetryFiles(folder string) error {
+func dumpRetryFiles(folder string, keys map[string]cipher.AEAD) error {
 	entries, err := os.ReadDir(folder)
 	if err != nil {
 		return err
@@ -50,7 +61,7 @@ func dumpRetryFiles(folder string) error {
 		if entry.Type().IsRegular() && filepath.Ext(entry.Name()) == ".retry" {
 			fmt.Println(entry.Name())
 			filePath := path.Join(folder, entry.Name())
-			fileContent, err := dumpRetryFile(filePath)
+			fileContent, err := dumpRetryFile(filePath, keys)
 			if err != nil {
 				return err
 			}
@@ -64,11 +75,14 @@ func dumpRetryFiles(folder string) error {
 	return nil
 }
 
-func dumpRetryFile(file string) ([]byte, error) {
+func dumpRetryFile(file string, keys map[string]cipher.AEAD) ([]byte, error) {
 	content, err := os.ReadFile(file)
 	if err != nil {
 		return nil, err
 	}
+	if content, err = decrypt(content, keys); err != nil {
+		return nil, err
+	}
 	collection := HttpTransactionProtoCollection{}
 
 	if err := proto.Unmarshal(content, &collection); err != nil {
@@ -119,3 +133,54 @@ func decodePayload(payload []byte, buffer *bytes.Buffer) (string, error) {
 	}
 	return string(buff[:n]), nil
 }
+
+// The encrypted files are made of a header and of the transactions sealed with AES-256-GCM:
+// "DDENC" | version (1 byte) | key ID (first 8 bytes of the SHA-256 of the key) | nonce (12 bytes) | ciphertext and tag
+const encryptedHeaderSize = 5 + 1 + 8
+
+// readKeys returns the AEAD of each key, by key ID.
+func readKeys(keyFiles string) (map[string]cipher.AEAD, error) {
+	keys := make(map[string]cipher.AEAD)
+	if keyFiles == "" {
+		return keys, nil
+	}
+	for _, keyFile := range strings.Split(keyFiles, ",") {
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+		if err != nil {
+			return nil, err
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		hash := sha256.Sum256(key)
+		keys[string(hash[:8])] = aead
+	}
+	return keys, nil
+}
+
+func decrypt(content []byte, keys map[string]cipher.AEAD) ([]byte, error) {
+	if !bytes.HasPrefix(content, []byte("DDENC")) {
+		return content, nil
+	}
+	if len(content) < encryptedHeaderSize {
+		return nil, errors.New("The encrypted file is truncated")
+	}
+	aead, found := keys[string(content[6:encryptedHeaderSize])]
+	if !found {
+		return nil, errors.New("The file is encrypted: use --key_files with the key used to encrypt it")
+	}
+	if len(content) < encryptedHeaderSize+aead.NonceSize() {
+		return nil, errors.New("The encrypted file is truncated")
+	}
+	nonce := content[encryptedHeaderSize : encryptedHeaderSize+aead.NonceSize()]
+	return aead.Open(nil, nonce, content[encryptedHeaderSize+aead.NonceSize():], content[:encryptedHeaderSize])
+}